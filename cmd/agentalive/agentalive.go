@@ -30,8 +30,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
-	"github.com/tidwall/gjson"
 )
 
 const AGENT_ALIV_EXIT_CODE_0 = 0
@@ -48,7 +48,7 @@ const AGENT_ALIV_EXIT_CODE_6 = 6
 func main() {
 	var bfgDataPath string
 	var bfgConfigFilePath string
-	var agentConfig string
+	var agentConfig *config.Configuration
 	var e error
 
 	//Name of the agent is retrieved from environment variable MFT_AGENT_NAME
@@ -60,26 +60,18 @@ func main() {
 
 	/*
 	 * Read the name of an agent configuration file from environment
-	 * variable MFT_AGENT_CONFIG_FILE
+	 * variable MFT_AGENT_CONFIG_FILE. If it is not set, config.json file in
+	 * /run/mqmft directory is used or, if that does not exist either, the
+	 * configuration is built from environment variables as runagent does.
 	 */
-	bfgConfigFilePath, bfgConfigFilePathSet := os.LookupEnv("MFT_AGENT_CONFIG_FILE")
-	if !bfgConfigFilePathSet {
-		// MFT_AGENT_CONFIG_FILE environment variable not specified. Looking for
-		// config.json file in /run/mqmft directory.
-		msg := fmt.Sprintf(utils.MFT_ENV_AGNT_CFG_FILE_NOT_SPECIFIED, utils.MFT_DEFAULT_CONFIG_JSON)
-		utils.PrintLog(msg)
-		// Assign the default config filename, so that rest of the processing goes on.
-		bfgConfigFilePath = utils.MFT_DEFAULT_CONFIG_JSON
-	} else {
-		bfgConfigFilePath = strings.TrimSpace(bfgConfigFilePath)
-		if bfgConfigFilePath == "" {
-			utils.PrintLog(utils.MFT_CONT_ENV_AGNT_CFG_FILE_BLANK_0012)
-			os.Exit(AGENT_ALIV_EXIT_CODE_2)
-		}
+	bfgConfigFilePath = config.Source()
+	if bfgConfigFilePath == "" {
+		utils.PrintLog(utils.MFT_CONT_ENV_AGNT_CFG_FILE_BLANK_0012)
+		os.Exit(AGENT_ALIV_EXIT_CODE_2)
 	}
 
-	// Read agent configuration data from JSON file.
-	agentConfig, e = utils.ReadConfigurationDataFromFile(bfgConfigFilePath)
+	// Read agent configuration data from JSON file or environment variables.
+	agentConfig, e = config.Load(bfgConfigFilePath)
 	// Exit if we had any error when reading configuration file
 	if e != nil {
 		utils.PrintLog(fmt.Sprintf(utils.AGENT_ALIV_ENV_CFG_FILE_READ_4003, bfgConfigFilePath, e))
@@ -101,25 +93,44 @@ func main() {
 		bfgDataPath = utils.FIXED_BFG_DATAPATH
 	}
 
+	coordinationQMgr := agentConfig.CoordinationQMgr.Name
+	// MFT_AGENT_NAME may name several agents run in the same container. All of
+	// them must be running.
+	agentNames := agentConfig.AgentNames(agentNameEnv)
+	if len(agentNames) == 0 {
+		utils.PrintLog(fmt.Sprintf(utils.AGENT_ALIV_NOT_RUNNING_4004, agentNameEnv))
+		os.Exit(AGENT_ALIV_EXIT_CODE_6)
+	}
+	for _, agentName := range agentNames {
+		exitCode := checkAgentAlive(bfgDataPath, coordinationQMgr, agentName)
+		if exitCode != AGENT_ALIV_EXIT_CODE_0 {
+			os.Exit(exitCode)
+		}
+	}
+	os.Exit(AGENT_ALIV_EXIT_CODE_0)
+}
+
+// Check if the agent is running. Returns the exit code of the probe.
+func checkAgentAlive(bfgDataPath string, coordinationQMgr string, agentName string) int {
 	// Read the agentPid file from the agent logs directory
-	agentPidPath := bfgDataPath + "/mqft/logs/" + gjson.Get(agentConfig, "coordinationQMgr.name").String() + "/agents/" + agentNameEnv + "/agent.pid"
+	agentPidPath := bfgDataPath + "/mqft/logs/" + coordinationQMgr + "/agents/" + agentName + "/agent.pid"
 	// Open agent.pid file and read the pid from the file.
 	agentPid, _ := utils.GetAgentPid(agentPidPath)
 	if agentPid > 1 {
 		agentRunning, err := utils.IsAgentRunning(agentPid)
 		if err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.AGENT_ALIV_NOT_RUNNING_4004, agentNameEnv))
-			os.Exit(AGENT_ALIV_EXIT_CODE_4)
+			utils.PrintLog(fmt.Sprintf(utils.AGENT_ALIV_NOT_RUNNING_4004, agentName))
+			return AGENT_ALIV_EXIT_CODE_4
 		} else {
 			if agentRunning {
-				os.Exit(AGENT_ALIV_EXIT_CODE_0)
+				return AGENT_ALIV_EXIT_CODE_0
 			} else {
-				utils.PrintLog(fmt.Sprintf(utils.AGENT_ALIV_NOT_RUNNING_4004, agentNameEnv))
-				os.Exit(AGENT_ALIV_EXIT_CODE_5)
+				utils.PrintLog(fmt.Sprintf(utils.AGENT_ALIV_NOT_RUNNING_4004, agentName))
+				return AGENT_ALIV_EXIT_CODE_5
 			}
 		}
 	} else {
-		utils.PrintLog(fmt.Sprintf(utils.AGENT_ALIV_NOT_RUNNING_4004, agentNameEnv))
-		os.Exit(AGENT_ALIV_EXIT_CODE_6)
+		utils.PrintLog(fmt.Sprintf(utils.AGENT_ALIV_NOT_RUNNING_4004, agentName))
+		return AGENT_ALIV_EXIT_CODE_6
 	}
 }
@@ -21,8 +21,8 @@ import (
 	"os"
 	"strings"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
-	"github.com/tidwall/gjson"
 )
 
 const AGENT_REDY_EXIT_CODE_0 = 0
@@ -46,7 +46,7 @@ const AGENT_REDY_EXIT_CODE_7 = 7
 func main() {
 	var bfgDataPath string
 	var bfgConfigFilePath string
-	var agentConfig string
+	var agentConfig *config.Configuration
 	var e error
 
 	//Name of the agent is retrieved from environment variable MFT_AGENT_NAME
@@ -58,26 +58,18 @@ func main() {
 
 	/*
 	 * Read the name of an agent configuration file from environment
-	 * variable MFT_AGENT_CONFIG_FILE
+	 * variable MFT_AGENT_CONFIG_FILE. If it is not set, config.json file in
+	 * /run/mqmft directory is used or, if that does not exist either, the
+	 * configuration is built from environment variables as runagent does.
 	 */
-	bfgConfigFilePath, bfgConfigFilePathSet := os.LookupEnv("MFT_AGENT_CONFIG_FILE")
-	if !bfgConfigFilePathSet {
-		// MFT_AGENT_CONFIG_FILE environment variable not specified. Looking for
-		// config.json file in /run/mqmft directory.
-		msg := fmt.Sprintf(utils.MFT_ENV_AGNT_CFG_FILE_NOT_SPECIFIED, utils.MFT_DEFAULT_CONFIG_JSON)
-		utils.PrintLog(msg)
-		// Assign the default config filename, so that rest of the processing goes on.
-		bfgConfigFilePath = utils.MFT_DEFAULT_CONFIG_JSON
-	} else {
-		bfgConfigFilePath = strings.TrimSpace(bfgConfigFilePath)
-		if bfgConfigFilePath == "" {
-			utils.PrintLog(utils.MFT_CONT_ENV_AGNT_CFG_FILE_BLANK_0012)
-			os.Exit(AGENT_REDY_EXIT_CODE_7)
-		}
+	bfgConfigFilePath = config.Source()
+	if bfgConfigFilePath == "" {
+		utils.PrintLog(utils.MFT_CONT_ENV_AGNT_CFG_FILE_BLANK_0012)
+		os.Exit(AGENT_REDY_EXIT_CODE_7)
 	}
 
-	// Read agent configuration data from JSON file.
-	agentConfig, e = utils.ReadConfigurationDataFromFile(bfgConfigFilePath)
+	// Read agent configuration data from JSON file or environment variables.
+	agentConfig, e = config.Load(bfgConfigFilePath)
 	// Exit if we had any error when reading configuration file
 	if e != nil {
 		utils.PrintLog(fmt.Sprintf(utils.AGENT_REDY_ENV_CFG_FILE_READ_3003, bfgConfigFilePath, e))
@@ -99,33 +91,51 @@ func main() {
 		bfgDataPath = utils.FIXED_BFG_DATAPATH
 	}
 
-	coordinationQMgr := gjson.Get(agentConfig, "coordinationQMgr.name").String()
+	coordinationQMgr := agentConfig.CoordinationQMgr.Name
+	// MFT_AGENT_NAME may name several agents run in the same container. All of
+	// them must be ready.
+	agentNames := agentConfig.AgentNames(agentNameEnv)
+	if len(agentNames) == 0 {
+		utils.PrintLog(fmt.Sprintf(utils.AGENT_REDY_NOT_RUNNING_3004, agentNameEnv))
+		os.Exit(AGENT_REDY_EXIT_CODE_7)
+	}
+	for _, agentName := range agentNames {
+		exitCode := checkAgentReady(bfgDataPath, coordinationQMgr, agentName)
+		if exitCode != AGENT_REDY_EXIT_CODE_0 {
+			os.Exit(exitCode)
+		}
+	}
+	os.Exit(AGENT_REDY_EXIT_CODE_0)
+}
+
+// Check if the agent is running and ready. Returns the exit code of the probe.
+func checkAgentReady(bfgDataPath string, coordinationQMgr string, agentName string) int {
 	// Read the agentPid file from the agent logs directory
-	agentPidPath := bfgDataPath + "/mqft/logs/" + coordinationQMgr + "/agents/" + agentNameEnv + "/agent.pid"
+	agentPidPath := bfgDataPath + "/mqft/logs/" + coordinationQMgr + "/agents/" + agentName + "/agent.pid"
 	// Open agent.pid file and read the pid from the file.
 	agentPid, _ := utils.GetAgentPid(agentPidPath)
 	if agentPid > 1 {
 		agentRunning, err := utils.IsAgentRunning(agentPid)
 		if err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.AGENT_REDY_NOT_RUNNING_3004, agentNameEnv))
-			os.Exit(AGENT_REDY_EXIT_CODE_4)
+			utils.PrintLog(fmt.Sprintf(utils.AGENT_REDY_NOT_RUNNING_3004, agentName))
+			return AGENT_REDY_EXIT_CODE_4
 		} else {
 			if agentRunning {
 				// Agent is running, so check if it is ready.
-				agentStatus, _ := utils.IsAgentReady(bfgDataPath, agentNameEnv, coordinationQMgr)
+				agentStatus, _ := utils.IsAgentReady(bfgDataPath, agentName, coordinationQMgr)
 				if agentStatus {
-					os.Exit(AGENT_REDY_EXIT_CODE_0)
+					return AGENT_REDY_EXIT_CODE_0
 				} else {
 					utils.PrintLog(utils.AGENT_REDY_EVNT_NOT_FOUND_3005)
-					os.Exit(AGENT_REDY_EXIT_CODE_5)
+					return AGENT_REDY_EXIT_CODE_5
 				}
 			} else {
-				utils.PrintLog(fmt.Sprintf(utils.AGENT_REDY_NOT_RUNNING_3004, agentNameEnv))
-				os.Exit(AGENT_REDY_EXIT_CODE_6)
+				utils.PrintLog(fmt.Sprintf(utils.AGENT_REDY_NOT_RUNNING_3004, agentName))
+				return AGENT_REDY_EXIT_CODE_6
 			}
 		}
 	} else {
-		utils.PrintLog(fmt.Sprintf(utils.AGENT_REDY_NOT_RUNNING_3004, agentNameEnv))
-		os.Exit(AGENT_REDY_EXIT_CODE_7)
+		utils.PrintLog(fmt.Sprintf(utils.AGENT_REDY_NOT_RUNNING_3004, agentName))
+		return AGENT_REDY_EXIT_CODE_7
 	}
 }
@@ -33,9 +33,9 @@ import (
 
 	"github.com/Jeffail/gabs"
 	"github.com/antchfx/xmlquery"
-	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/ibm-messaging/mq-container-mft/pkg/bfg"
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	flag "github.com/spf13/pflag"
-	"github.com/tidwall/gjson"
 )
 
 // A hashmap to cache transfer ids already processed
@@ -202,7 +202,7 @@ func getLogPath(logFilePath string) string {
 		outputLogFilePath = logFilePath
 	} else {
 		var bfgDataPath string
-		var agentConfig string
+		var agentConfig *config.Configuration
 		var agentNameEnv string
 		var e error
 		var coordinationQMgr string
@@ -214,13 +214,13 @@ func getLogPath(logFilePath string) string {
 			bfgConfigFilePath, bfgConfigFilePathSet := os.LookupEnv("MFT_AGENT_CONFIG_FILE")
 			if bfgConfigFilePathSet {
 				// Read agent configuration data from JSON file.
-				agentConfig, e = utils.ReadConfigurationDataFromFile(bfgConfigFilePath)
+				agentConfig, e = config.Load(bfgConfigFilePath)
 				// Exit if we had any error when reading configuration file
 				if e != nil {
 					fmt.Print(e)
 					os.Exit(1)
 				}
-				coordinationQMgr = gjson.Get(agentConfig, "coordinationQMgr.name").String()
+				coordinationQMgr = agentConfig.CoordinationQMgr.Name
 			} else {
 				coordinationQMgrLocal, coordinationQMgrSet := os.LookupEnv("MFT_COORDINATION_QM")
 				if !coordinationQMgrSet {
@@ -756,27 +756,27 @@ func parseXml(xmlMessage string, displayTransferType int) {
 						supplementNode := status.SelectElement("supplement")
 						if supplementNode != nil {
 							supplement := status.SelectElement("supplement").InnerText()
-							if strings.Contains(supplement, "BFGRP0032I") {
+							if bfg.Contains(supplement, bfg.TRANSFER_SUCCESSFUL) {
 								if displayTransferType == transferSUCCESSFUL || displayTransferType == 0 {
 									statusText = "Successful"
 									counter++
 								}
-							} else if strings.Contains(supplement, "BFGRP0034I") {
+							} else if bfg.Contains(supplement, bfg.TRANSFER_FAILED) {
 								if displayTransferType == transferFAILED || displayTransferType == 0 {
 									statusText = "Failed"
 									counter++
 								}
-							} else if strings.Contains(supplement, "BFGRP0033I") {
+							} else if bfg.Contains(supplement, bfg.TRANSFER_PARTIALLY_SUCCESSFUL) {
 								if displayTransferType == transferPARTIALSUCCESS || displayTransferType == 0 {
 									statusText = "Partially successful"
 									counter++
 								}
-							} else if strings.Contains(supplement, "BFGRP0036I") {
+							} else if bfg.Contains(supplement, bfg.TRANSFER_NO_FILES_TRANSFERRED) {
 								if displayTransferType == transferFAILED || displayTransferType == 0 {
 									statusText = "Completed but no files transferred"
 									counter++
 								}
-							} else if strings.Contains(supplement, "BFGRP0037I") {
+							} else if bfg.Contains(supplement, bfg.TRANSFER_NOT_COMPLETED) {
 								if displayTransferType == transferFAILED || displayTransferType == 0 {
 									statusText = "Failed"
 									counter++
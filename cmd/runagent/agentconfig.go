@@ -16,126 +16,68 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
-	"os/user"
 	"path/filepath"
-	"strconv"
 	"strings"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/bfg"
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
 	"github.com/subchen/go-xmldom"
-	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
 )
 
-// Call fteStartAgent command to submit a request to start an agent.
-func StartAgent(agentName string, coordinationQMgr string) bool {
-	var outb, errb bytes.Buffer
-	var startSubmitted bool = false
-
-	// Get the path of MFT fteStartAgent command.
-	cmdStrAgntPath, lookPathErr := exec.LookPath("fteStartAgent")
-	if lookPathErr == nil {
-		// We are done with creating agent. Start it now.
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_STARTING_0041, agentName))
-		var cmdArgs []string
-		cmdArgs = append(cmdArgs, cmdStrAgntPath, "-p", coordinationQMgr, agentName)
-		if commandTracingEnabled {
-			cmdArgs = append(cmdArgs, "-trace", "com.ibm.wmqfte=all")
-			cmdTracePath := GetCommandTracePath()
-			if len(cmdTracePath) > 0 {
-				cmdArgs = append(cmdArgs, "-tracePath", cmdTracePath)
-			}
-		}
-
-		cmdStrAgnt := &exec.Cmd{
-			Path: cmdStrAgntPath,
-			Args: cmdArgs,
-		}
-
-		cmdStrAgnt.Stdout = &outb
-		cmdStrAgnt.Stderr = &errb
-		// Run fteStartAgent command. Log and exit in case of any error.
-		if err := cmdStrAgnt.Run(); err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
-		} else {
-			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, outb.String()))
-			}
-			startSubmitted = true
-		}
-	} else {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, lookPathErr))
+// Call fteStartAgent command to submit a request to start an agent. JVM
+// options of the agent are added to BFG_JVM_PROPERTIES of the command.
+// Returns the result of fteStartAgent if it failed.
+func StartAgent(agentName string, coordinationQMgr string, jvmOptions []string) (bool, *commandResult) {
+	// We are done with creating agent. Start it now.
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_STARTING_0041, agentName))
+	cmdStartAgent := fteCommand("fteStartAgent", "-p", coordinationQMgr, agentName)
+	if len(jvmOptions) > 0 {
+		cmdStartAgent.env = []string{MFT_BFG_JVM_PROPERTIES + "=" + agentJVMProperties(jvmOptions)}
+	}
+	result := runner.run(cmdStartAgent)
+	if result.failed() {
+		result.logFailure()
+		return false, result
 	}
-	return startSubmitted
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
+	}
+	return true, nil
 }
 
 // Verify the status of agent by calling fteListAgents command.
 func VerifyAgentStatus(coordinationQMgr string, agentName string) string {
-	var outb, errb bytes.Buffer
-	var agentStatus string
-
 	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_VRFY_STATUS_0044, agentName))
-	cmdListAgentPath, lookPathErr := exec.LookPath("fteListAgents")
-	if lookPathErr == nil {
-		var cmdArgs []string
-		cmdArgs = append(cmdArgs, cmdListAgentPath, "-p", coordinationQMgr, agentName)
-		if commandTracingEnabled {
-			cmdArgs = append(cmdArgs, "-trace", "com.ibm.wmqfte=all")
-			cmdTracePath := GetCommandTracePath()
-			if len(cmdTracePath) > 0 {
-				cmdArgs = append(cmdArgs, "-tracePath", cmdTracePath)
-			}
-		}
-
-		cmdListAgents := &exec.Cmd{
-			Path: cmdListAgentPath,
-			Args: cmdArgs,
-		}
-
-		cmdListAgents.Stdout = &outb
-		cmdListAgents.Stderr = &errb
-		// Execute and get the output of the command into a byte buffer
-		if err := cmdListAgents.Run(); err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
-		} else {
-			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, outb.String()))
-			}
-			// Now parse the output of fteListAgents command and take appropriate actions.
-			agentStatus = outb.String()
-		}
-	} else {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, lookPathErr))
+	result := runFteCommand("fteListAgents", "-p", coordinationQMgr, agentName)
+	if result.failed() {
+		result.logFailure()
+		return ""
 	}
-
-	return agentStatus
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
+	}
+	// Output of fteListAgents command is parsed by the caller
+	return result.stdout
 }
 
-// Calls fteCreateAgent/fteCreateBridgeAgent commands to setup agent configuration
-func setupAgent(agentConfig string, bfgDataPath string, coordinationQMgr string) bool {
-	// Variables for Stdout and Stderr
-	var outb, errb bytes.Buffer
+// Calls fteCreateAgent/fteCreateBridgeAgent/fteCreateCDAgent commands to setup agent configuration.
+// Returns the result of the command if it failed.
+func setupAgent(agent *config.Agent, bfgDataPath string, coordinationQMgr string) (bool, *commandResult) {
 	var created bool = false
 	var cmdSetup bool = false
-	var agentType string = AGENT_TYPE_STANDARD
 	var standardAgent bool
-	var agentName string
-	var protocolBridgeConfigs []gjson.Result
-
-	// Get the type of the agent from configuration file. Assume type as STANDARD
-	// if not specified or an invalid type was specified.
-	if gjson.Get(agentConfig, "type").Exists() {
-		agentType = strings.ToUpper(strings.TrimSpace(gjson.Get(agentConfig, "type").String()))
-		if !strings.EqualFold(agentType, AGENT_TYPE_STANDARD) && !strings.EqualFold(agentType, AGENT_TYPE_BRIDGE) {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_INVALID_TYPE_0045, agentType, AGENT_TYPE_STANDARD))
-			agentType = AGENT_TYPE_STANDARD
-		}
+
+	// Type of the agent defaults to STANDARD if not specified. Assume type as
+	// STANDARD if an invalid type was specified.
+	agentType := strings.ToUpper(agent.Type)
+	if agentType != AGENT_TYPE_STANDARD && agentType != AGENT_TYPE_BRIDGE && agentType != AGENT_TYPE_CD {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_INVALID_TYPE_0045, agentType, AGENT_TYPE_STANDARD))
+		agentType = AGENT_TYPE_STANDARD
 	}
 
 	// Determine if we will be creating a standard or a bridge agent
@@ -145,118 +87,69 @@ func setupAgent(agentConfig string, bfgDataPath string, coordinationQMgr string)
 		standardAgent = false
 	}
 
-	agentName = gjson.Get(agentConfig, "name").String()
+	agentName := agent.Name
 	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_CREATING_0046, agentType, agentName))
 
-	var cmdCrtAgnt *exec.Cmd
-	// Cance the agent attributes
-	agentQMgrName := gjson.Get(agentConfig, "qmgrName").String()
-	agentQMgrHost := gjson.Get(agentConfig, "qmgrHost").String()
-	var agentQMgrPort string
-	if gjson.Get(agentConfig, "qmgrPort").Exists() {
-		agentQMgrPort = gjson.Get(agentConfig, "qmgrPort").String()
-	} else {
-		// Not found, use default 1414
-		agentQMgrPort = "1414"
-	}
-
-	var agentQMgrChannel string
-	if gjson.Get(agentConfig, "qmgrChannel").Exists() {
-		agentQMgrChannel = gjson.Get(agentConfig, "qmgrChannel").String()
-	} else {
-		// Default to SYSTEM.DEF.SVRCONN
-		agentQMgrChannel = "SYSTEM.DEF.SVRCONN"
-	}
+	var cmdCrtAgnt *command
+	var ftpsStores []ftpsStore
+	// Cache the agent attributes. Port and channel default to 1414 and
+	// SYSTEM.DEF.SVRCONN if not specified.
+	// The first entry of a connection name list is used, and none of them if a
+	// CCDT is specified.
+	agentQMgrName := agent.QMgrName
+	connection := agentConnection(agent)
+	params := append([]string{
+		"-p", coordinationQMgr,
+		"-agentName", agentName,
+		"-agentQMgr", agentQMgrName}, connection.commandArgs("agentQMgr")...)
+	params = append(params, "-f")
 
 	// We are creating a STANDARD agent
 	if standardAgent {
-		// Get the path of MFT fteCreateAgent command.
-		cmdCrtAgntPath, lookPathErr := exec.LookPath("fteCreateAgent")
-		if lookPathErr == nil {
-			// Creating a standard agent
-			var params []string
-			params = append(params, cmdCrtAgntPath,
-				"-p", coordinationQMgr,
-				"-agentName", agentName,
-				"-agentQMgr", agentQMgrName,
-				"-agentQMgrHost", agentQMgrHost,
-				"-agentQMgrPort", agentQMgrPort,
-				"-agentQMgrChannel", agentQMgrChannel, "-f")
-			if commandTracingEnabled {
-				params = append(params, "-trace", "com.ibm.wmqfte=all")
-				cmdTracePath := GetCommandTracePath()
-				if len(cmdTracePath) > 0 {
-					params = append(params, "-tracePath", cmdTracePath)
-				}
-			}
-
-			// Now build the command to create standard agent
-			cmdCrtAgnt = &exec.Cmd{Path: cmdCrtAgntPath, Args: params}
+		// Now build the command to create standard agent
+		cmdCrtAgnt = fteCommand("fteCreateAgent", params...)
+		cmdSetup = true
+	} else if agentType == AGENT_TYPE_CD {
+		// We are creating a Connect:Direct bridge agent for the first node.
+		if len(agent.CDNodes) > 0 {
+			cmdCrtAgnt = fteCommand("fteCreateCDAgent", updateCDAgentParameters(agent, params)...)
 			cmdSetup = true
 		} else {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, lookPathErr))
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CD_NOT_ENOUGH_INFO_0146, agentName))
 		}
 	} else {
-		// Initialize BridgeProperties.
-		BuildBridgePropertyList()
-
-		// We are creating a BRIDGE agent
-		// Get the path of MFT fteCreateBridgeAgent command
-		cmdCrtBridgeAgntPath, lookPathErr := exec.LookPath("fteCreateBridgeAgent")
-		if lookPathErr == nil {
-			// Creating a bridge agent
-			var params []string
-			params = append(params, cmdCrtBridgeAgntPath,
-				"-p", coordinationQMgr,
-				"-agentName", agentName,
-				"-agentQMgr", agentQMgrName,
-				"-agentQMgrHost", agentQMgrHost,
-				"-agentQMgrPort", agentQMgrPort,
-				"-agentQMgrChannel", agentQMgrChannel, "-f")
-			if commandTracingEnabled {
-				params = append(params, "-trace", "com.ibm.wmqfte=all")
-				cmdTracePath := GetCommandTracePath()
-				if len(cmdTracePath) > 0 {
-					params = append(params, "-tracePath", cmdTracePath)
-				}
-			}
-
-			protocolBridgeConfigs = gjson.Get(agentConfig, "protocolServers").Array()
-			// For creating the agent, take the first element in the array. We will updated the
-			// ProtocolBridgeProperties.xml with other elements in the array.
-			if len(protocolBridgeConfigs) > 0 {
-				singleBridgeConfig := protocolBridgeConfigs[0].String()
-				if updateBridgeParameters(singleBridgeConfig, params) {
-					// Now build the command to create a bridge agent
-					cmdCrtAgnt = &exec.Cmd{
-						Path: cmdCrtBridgeAgntPath,
-						Args: params,
-					}
-					cmdSetup = true
-				} else {
-					utils.PrintLog(utils.MFT_CONT_BRIDGE_NOT_ENOUGH_INFO)
-				}
+		// Stores of FTPS servers are built before the agent is created as
+		// fteCreateBridgeAgent requires the trust store if the first server is
+		// a FTPS server.
+		var storesCreated bool
+		ftpsStores, storesCreated = createFTPSStores(agent.ProtocolServers, bridgeServerCertPath)
+
+		// We are creating a BRIDGE agent. For creating the agent, take the first
+		// element in the array. We will updated the ProtocolBridgeProperties.xml
+		// with other elements in the array.
+		if len(agent.ProtocolServers) > 0 && storesCreated {
+			if bridgeParams, valid := updateBridgeParameters(agent.ProtocolServers[0], params); valid {
+				// Now build the command to create a bridge agent
+				cmdCrtAgnt = fteCommand("fteCreateBridgeAgent", bridgeParams...)
+				cmdSetup = true
 			} else {
 				utils.PrintLog(utils.MFT_CONT_BRIDGE_NOT_ENOUGH_INFO)
 			}
 		} else {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, lookPathErr))
+			utils.PrintLog(utils.MFT_CONT_BRIDGE_NOT_ENOUGH_INFO)
 		}
 	}
 
 	// Ready to execute the command
 	if cmdSetup {
-		// Reuse the same buffer
-		cmdCrtAgnt.Stdout = &outb
-		cmdCrtAgnt.Stderr = &errb
-
-		// Execute the fteCreateAgent/fteCreateBridgeAgent to create agent configuration.
+		// Execute the fteCreateAgent/fteCreateBridgeAgent/fteCreateCDAgent to create agent configuration.
 		// Log an error an exit in case of any error.
-		if err := cmdCrtAgnt.Run(); err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
+		if result := runner.run(cmdCrtAgnt); result.failed() {
+			result.logFailure()
+			return false, result
 		} else {
 			// If it is bridge agent, then update the ProtocolBridgeProperties file with any additional properties specified.
-			if !standardAgent {
+			if agentType == AGENT_TYPE_BRIDGE && !dryRunEnabled {
 				// Copy the custom credentials exit to agent's exit directory.
 				protocolBridgeCustExit := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQMgr + MFT_AGENTS_SLASH + agentName + MFT_EXITS_SLASH + PBA_CUSTOM_CRED_EXIT_NAME
 				utils.CopyFile(PBA_CUSTOM_CRED_EXIT, protocolBridgeCustExit)
@@ -278,12 +171,22 @@ func setupAgent(agentConfig string, bfgDataPath string, coordinationQMgr string)
 			// Start XML document for credentials file
 			credentialsDoc := InitializeCredentialsDocumentWriter()
 			// Configure TLS for agent connections
-			created, agentConfig = configTLSAgent(agentConfig, credentialsDoc, agentCredFilePath)
+			created = configTLSAgent(agent, credentialsDoc, agentCredFilePath)
+			// Connection name list or CCDT of the agent queue manager
+			if created {
+				created = configureCcdt(connection, bfgDataPath+MFT_CONFIG_PATH_SUFFIX+coordinationQMgr+MFT_AGENTS_SLASH+agentName+MFT_AGENT_CCDT_SLASH,
+					&agent.AdditionalProperties)
+			}
 
 			if created {
-				if gjson.Get(agentConfig, "qmgrCredentials").Exists() {
+				// Passwords of stores built for FTPS servers
+				for _, store := range ftpsStores {
+					UpdateXmlWithKeyStoreCredentials(credentialsDoc, store.path, store.password)
+				}
+
+				if agent.Credentials != nil {
 					// Write agent queue manager credentials
-					err := UpdateXmlWithQmgrCredentials(credentialsDoc, gjson.Get(agentConfig, "qmgrCredentials").String(), agentQMgrName)
+					err := UpdateXmlWithQmgrCredentials(credentialsDoc, agent.Credentials, agentQMgrName)
 					if err != nil {
 						if logLevel >= LOG_LEVEL_VERBOSE {
 							utils.PrintLog(err.Error())
@@ -296,31 +199,42 @@ func setupAgent(agentConfig string, bfgDataPath string, coordinationQMgr string)
 				if errorSetCred == nil {
 					// Attempt to encrypt the credentials file with a fixed key
 					EncryptCredentialsFile(agentCredFilePath)
-					agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentQMgrAuthenticationCredentialsFile", agentCredFilePath)
+					agent.AdditionalProperties.Set(credentialsFileProperty(PROPERTY_PREFIX_AGENT), agentCredFilePath)
 				} else {
 					utils.PrintLog(errorSetCred.Error())
 				}
 
-				if logLevel >= LOG_LEVEL_VERBOSE && len(agentConfig) > 0 {
-					utils.PrintLog(fmt.Sprintf("Updated agent configuration - %v", agentConfig))
+				if logLevel >= LOG_LEVEL_VERBOSE {
+					utils.PrintLog(fmt.Sprintf("Updated agent configuration - %v", agent))
 				}
 
 				// Update UserSandbox XML file - valid only for STANDARD agents
+				// that have not turned off user sandboxes.
 				if standardAgent {
-					errCusbox := createUserSandbox(bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQMgr + MFT_AGENTS_SLASH + agentName + MFT_USER_SANDBOX_SLASH)
-					if errCusbox != nil {
-						utils.PrintLog(errCusbox.Error())
-						created = false
-					} else {
-						if logLevel >= LOG_LEVEL_VERBOSE {
-							utils.PrintLog("User sandbox setup complete.")
-							created = true
+					if !userSandboxesDisabled(agent.AdditionalProperties) {
+						errCusbox := createUserSandbox(bfgDataPath+MFT_CONFIG_PATH_SUFFIX+coordinationQMgr+MFT_AGENTS_SLASH+agentName+MFT_USER_SANDBOX_SLASH, agent.Sandboxes)
+						if errCusbox != nil {
+							utils.PrintLog(errCusbox.Error())
+							created = false
+						} else {
+							if logLevel >= LOG_LEVEL_VERBOSE {
+								utils.PrintLog("User sandbox setup complete.")
+								created = true
+							}
 						}
 					}
+				} else if agentType == AGENT_TYPE_CD {
+					// This is a Connect:Direct bridge agent. Write the node properties and
+					// credentials of the nodes specified in configuration JSON file.
+					created = createCDNodeFiles(bfgDataPath+MFT_CONFIG_PATH_SUFFIX+coordinationQMgr+MFT_AGENTS_SLASH+agentName, agent)
 				} else {
 					// This is a bridge agent. We need to update the ProtocolBridgeProperties.xml file for all other servers specified
 					// in configuration JSON file.
-					created = updateProtocolBridgePropertiesFile(protocolBridgePropertiesFile, agentConfig)
+					created = updateProtocolBridgePropertiesFile(protocolBridgePropertiesFile, agent, agentCredFilePath)
+					// Credentials of protocol servers for the credential exit
+					if created && bridgeCredentialsSpecified(agent) {
+						created = createBridgeCredentialsFile(bfgDataPath+MFT_CONFIG_PATH_SUFFIX+coordinationQMgr+MFT_AGENTS_SLASH+agentName, agent)
+					}
 					if !created {
 						if logLevel >= LOG_LEVEL_VERBOSE {
 							utils.PrintLog("Failed to configure Bridge agent properties.")
@@ -331,7 +245,7 @@ func setupAgent(agentConfig string, bfgDataPath string, coordinationQMgr string)
 
 			if created {
 				// Update agent properties file with additional attributes specified.
-				created = updateAgentProperties(agentPropertiesFile, agentConfig, "additionalProperties", !standardAgent)
+				created = updateAgentProperties(agentPropertiesFile, agent.AdditionalProperties, agentType)
 				if created {
 					// Tell user that agent has been configured.
 					utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_CREATED_0047, agentName))
@@ -339,10 +253,10 @@ func setupAgent(agentConfig string, bfgDataPath string, coordinationQMgr string)
 			}
 		}
 	}
-	return created
+	return created, nil
 }
 
-func configTLSAgent(agentConfig string, credentialsDoc *xmldom.Document, agentCredFilePath string) (bool, string) {
+func configTLSAgent(agent *config.Agent, credentialsDoc *xmldom.Document, agentCredFilePath string) bool {
 	var created bool = true
 
 	// Create keystore using certificate provided if available.
@@ -351,14 +265,11 @@ func configTLSAgent(agentConfig string, credentialsDoc *xmldom.Document, agentCr
 		password := generateRandomPassword()
 		publicKeyFile := getKeyFile(agentQMCertPath, ".crt")
 		if len(publicKeyFile) > 0 {
-			agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentSslCipherSpec", cipherName)
+			agent.AdditionalProperties.Set("agentSslCipherSpec", cipherName)
 			// Update coordination properties file
 			errCreateKeyStore := CreateKeyStore(KEYSTORES_PATH, AGENT_QM_TRUSTSTORE, publicKeyFile, password)
 			if errCreateKeyStore == nil {
-				agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentSslCipherSpec", cipherName)
-				agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentSslTrustStore", filepath.Join(KEYSTORES_PATH, AGENT_QM_TRUSTSTORE))
-				agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentSslTrustStoreType", "pkcs12")
-				agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentSslTrustStoreCredentialsFile", agentCredFilePath)
+				setProperties(&agent.AdditionalProperties, tlsTrustStoreProperties(PROPERTY_PREFIX_AGENT, cipherName, AGENT_QM_TRUSTSTORE, agentCredFilePath))
 				UpdateXmlWithKeyStoreCredentials(credentialsDoc, filepath.Join(KEYSTORES_PATH, AGENT_QM_TRUSTSTORE), password)
 			} else {
 				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_KEYSTORE_CREATE_FAILED, AGENT_QM_TRUSTSTORE, errCreateKeyStore.Error()))
@@ -371,9 +282,7 @@ func configTLSAgent(agentConfig string, credentialsDoc *xmldom.Document, agentCr
 		if len(privateKeyCertPath) > 0 {
 			errCreateSslStore := CreateKeyStore(KEYSTORES_PATH, AGENT_QM_KEYSTORE, privateKeyCertPath, password)
 			if errCreateSslStore == nil {
-				agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentSslKeyStore", filepath.Join(KEYSTORES_PATH, AGENT_QM_KEYSTORE))
-				agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentSslKeyStoreType", "pkcs12")
-				agentConfig, _ = sjson.Set(agentConfig, "additionalProperties.agentSslKeyStoreCredentialsFile", agentCredFilePath)
+				setProperties(&agent.AdditionalProperties, tlsKeyStoreProperties(PROPERTY_PREFIX_AGENT, cipherName, AGENT_QM_KEYSTORE, agentCredFilePath))
 				UpdateXmlWithKeyStoreCredentials(credentialsDoc, filepath.Join(KEYSTORES_PATH, AGENT_QM_KEYSTORE), password)
 			} else {
 				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_KEYSTORE_CREATE_FAILED, AGENT_QM_TRUSTSTORE, errCreateSslStore.Error()))
@@ -383,32 +292,33 @@ func configTLSAgent(agentConfig string, credentialsDoc *xmldom.Document, agentCr
 		}
 	}
 
-	return created, agentConfig
+	return created
 }
 
-// Read and process protocol bridge server attributes from configuration JSON file
-func updateBridgeParameters(bridgeProperties string, params []string) bool {
+// Read and process protocol bridge server attributes from configuration JSON
+// file. Returns the parameters of fteCreateBridgeAgent with the attributes of
+// the server appended, and false if the attributes are not valid.
+func updateBridgeParameters(server *config.ProtocolServer, params []string) ([]string, bool) {
 	value := false
 	var serverType string
 	if logLevel >= LOG_LEVEL_VERBOSE {
-		utils.PrintLog("Bridge properties: " + bridgeProperties)
+		utils.PrintLog("Bridge properties: " + server.String())
 	}
 
-	serverName := gjson.Get(bridgeProperties, "name")
-	if serverName.Exists() && len(strings.TrimSpace(serverName.String())) > 0 {
+	serverName := server.Name
+	if len(strings.TrimSpace(serverName)) > 0 {
 		value = true
 	}
 
 	if value {
 		// Set protocol server type
-		serverTypeRef := gjson.Get(bridgeProperties, "type")
-		if serverTypeRef.Exists() {
-			if isBridgeTypeSupported(serverTypeRef.String()) {
+		if len(server.Type) > 0 {
+			if isBridgeTypeSupported(server.Type) {
 				// Use the supplied one.
-				params = append(params, "-bt", serverTypeRef.String())
-				serverType = serverTypeRef.String()
+				params = append(params, "-bt", server.Type)
+				serverType = server.Type
 			} else {
-				utils.PrintLog(fmt.Sprintf("%v is not a valid value for Protocol Bridge Type.", serverTypeRef.String()))
+				utils.PrintLog(fmt.Sprintf("%v is not a valid value for Protocol Bridge Type.", server.Type))
 				value = false
 			}
 		} else {
@@ -420,9 +330,8 @@ func updateBridgeParameters(bridgeProperties string, params []string) bool {
 
 	if value {
 		// Set the protocol server host
-		serverHost := gjson.Get(bridgeProperties, "host")
-		if serverHost.Exists() {
-			params = append(params, "-bh", serverHost.String())
+		if len(server.Host) > 0 {
+			params = append(params, "-bh", server.Host)
 		} else {
 			// Use local host if host name is not specified.
 			params = append(params, "-bh", "localhost")
@@ -432,9 +341,8 @@ func updateBridgeParameters(bridgeProperties string, params []string) bool {
 	if value {
 		// Set the protocol server timezone, valid only for FTP and FTPS server
 		if serverType != "SFTP" {
-			serverTimezone := gjson.Get(bridgeProperties, "timeZone")
-			if serverTimezone.Exists() {
-				params = append(params, "-btz", serverTimezone.String())
+			if len(server.TimeZone) > 0 {
+				params = append(params, "-btz", server.TimeZone)
 			} else {
 				// TimeZone not specified return an error
 				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_PROPERTY_NOT_SET, "timeZone", serverName))
@@ -445,12 +353,11 @@ func updateBridgeParameters(bridgeProperties string, params []string) bool {
 
 	if value {
 		// Set the protocol server platform.
-		serverPlatform := gjson.Get(bridgeProperties, "platform")
-		if serverPlatform.Exists() {
-			if isValidBridgePlatform(serverPlatform.String()) {
-				params = append(params, "-bm", serverPlatform.String())
+		if len(server.Platform) > 0 {
+			if isValidBridgePlatform(server.Platform) {
+				params = append(params, "-bm", server.Platform)
 			} else {
-				utils.PrintLog(fmt.Sprintf("%v is not a valid value for Protocol Bridge platform.", serverPlatform.String()))
+				utils.PrintLog(fmt.Sprintf("%v is not a valid value for Protocol Bridge platform.", server.Platform))
 				value = false
 			}
 		}
@@ -459,9 +366,8 @@ func updateBridgeParameters(bridgeProperties string, params []string) bool {
 	if value {
 		// Set the protocol server locale
 		if serverType != "SFTP" {
-			serverLocale := gjson.Get(bridgeProperties, "locale")
-			if serverLocale.Exists() {
-				params = append(params, "-bsl", serverLocale.String())
+			if len(server.Locale) > 0 {
+				params = append(params, "-bsl", server.Locale)
 			} else {
 				// Mandatory property not set
 				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_PROPERTY_NOT_SET, "locale", serverName))
@@ -472,9 +378,8 @@ func updateBridgeParameters(bridgeProperties string, params []string) bool {
 
 	if value {
 		// Set protocol server file encoding
-		serverFileEncoding := gjson.Get(bridgeProperties, "fileEncoding")
-		if serverFileEncoding.Exists() {
-			params = append(params, "-bfe", serverFileEncoding.String())
+		if len(server.FileEncoding) > 0 {
+			params = append(params, "-bfe", server.FileEncoding)
 		} else {
 			// Mandatory property not set
 			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_PROPERTY_NOT_SET, "fileEncoding", serverName))
@@ -484,44 +389,44 @@ func updateBridgeParameters(bridgeProperties string, params []string) bool {
 
 	if value {
 		// Set the protocol server port
-		serverPort := gjson.Get(bridgeProperties, "port")
-		if serverPort.Exists() {
-			params = append(params, "-bp", serverPort.String())
+		if server.Port != nil {
+			params = append(params, "-bp", server.Port.String())
 		}
 	}
 
 	if value {
-		// Set the protocol server trust store file
-		serverTrustStoreFile := gjson.Get(bridgeProperties, "trustStoreFile")
-		if serverTrustStoreFile.Exists() {
-			params = append(params, "-bts", serverTrustStoreFile.String())
+		// Set the protocol server trust store file, valid only for FTPS server
+		if strings.EqualFold(serverType, "FTPS") {
+			if len(server.TrustStoreFile) > 0 {
+				params = append(params, "-bts", server.TrustStoreFile)
+			} else {
+				// Trust store is required to verify the certificate of server
+				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_PROPERTY_NOT_SET, "trustStoreFile", serverName))
+				value = false
+			}
 		}
 	}
 
 	if value {
 		// Set protocol server limited write flag
-		serverLimitedWrite := gjson.Get(bridgeProperties, "limitedWrite")
-		if serverLimitedWrite.Exists() {
-			if strings.EqualFold(serverLimitedWrite.String(), "true") {
-				params = append(params, "-blw")
-			}
+		if server.LimitedWrite != nil && bool(*server.LimitedWrite) {
+			params = append(params, "-blw")
 		}
 	}
 
 	if value {
 		// Set the protocol server list format.
-		serverListFormat := gjson.Get(bridgeProperties, "listFormat")
-		if serverListFormat.Exists() {
-			if isValidBridgeListFormat(serverListFormat.String()) {
-				params = append(params, "-blf", serverListFormat.String())
+		if len(server.ListFormat) > 0 {
+			if isValidBridgeListFormat(server.ListFormat) {
+				params = append(params, "-blf", server.ListFormat)
 			} else {
-				utils.PrintLog(fmt.Sprintf("%v is not a valid Protocol Server List Format", serverListFormat.String()))
+				utils.PrintLog(fmt.Sprintf("%v is not a valid Protocol Server List Format", server.ListFormat))
 				value = false
 			}
 		}
 	}
 
-	return value
+	return params, value
 }
 
 func isValidBridgeListFormat(platform string) bool {
@@ -557,81 +462,127 @@ func isBridgeTypeSupported(bridgeType string) bool {
 /*
 * Validate agent attributes
  */
-func ValidateAgentAttributes(jsonData string) error {
+func ValidateAgentAttributes(agent *config.Agent) error {
 	// Agent name is mandatory
-	if !gjson.Get(jsonData, "name").Exists() {
+	if len(strings.TrimSpace(agent.Name)) == 0 {
 		err := errors.New(utils.MFT_CONT_CFG_AGENT_NAME_MISSING_0020)
 		return err
 	}
 
 	// Agent queue manager name is mandatory
-	if !gjson.Get(jsonData, "qmgrName").Exists() {
+	if len(strings.TrimSpace(agent.QMgrName)) == 0 {
 		err := errors.New(utils.MFT_CONT_CFG_AGENT_QM_NAME_MISSING_0021)
 		return err
 	}
 
-	// Agent queue manager host is mandatory
-	if !gjson.Get(jsonData, "qmgrHost").Exists() {
+	// Agent queue manager host is mandatory unless a connection name list or
+	// CCDT is specified
+	connection := agentConnection(agent)
+	if !connection.specified() {
 		err := errors.New(utils.MFT_CONT_CFG_AGENT_QM_HOST_MISSING_0022)
 		return err
 	}
+	if err := connection.validate(); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_QMGR_CONNECTION_INVALID_0148, connection.role, connection.qmgrName, err)
+	}
+
+	// Sandboxes, if specified, must be valid
+	if err := validateSandboxes(agent); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_SANDBOX_INVALID_0126, agent.Name, err)
+	}
+
+	// Connect:Direct nodes are required for Connect:Direct bridge agents
+	if err := validateCDNodes(agent); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_CD_NODE_INVALID_0145, agent.Name, err)
+	}
+
+	// Credentials of protocol servers, if specified, must be valid
+	if err := validateBridgeCredentials(agent); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_BRIDGE_CREDENTIALS_INVALID_0159, agent.Name, err)
+	}
+
+	// Monitors, if specified, must be valid
+	if err := validateMonitors(agent); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_MONITOR_INVALID_0127, agent.Name, err)
+	}
+
+	// JVM options, if specified, must be valid
+	if err := validateJVMOptions(agent); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_JVM_INVALID_0154, agent.Name, err)
+	}
+
+	// Templates and scheduled transfers, if specified, must be valid
+	if err := validateTemplates(agent); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_TEMPLATE_INVALID_0132, agent.Name, err)
+	}
+	if err := validateSchedules(agent); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_SCHEDULE_INVALID_0133, agent.Name, err)
+	}
 
 	return nil
 }
 
+// Properties written to agent.properties before additionalProperties. Users
+// can override them in additionalProperties.
+var agentDefaultProperties = config.Properties{
+	// Enable logCapture by default. Customer can turn off by specifying it again in config map
+	{Name: "logCapture", Value: "true"},
+	// Set maximum restart count to 0, so that the container ends immediately
+	// if the first attempt fails.
+	{Name: "maxRestartCount", Value: "0"},
+}
+
+// Properties written to agent.properties of bridge agents after
+// additionalProperties. They override values specified by users.
+var bridgeAgentProperties = config.Properties{
+	// Custom exit that reads credentials of protocol servers
+	{Name: "protocolBridgeCredentialExitClasses", Value: "com.ibm.wmq.bridgecredentialexit.ProtocolBridgeCustomCredentialExit"},
+	// enableQueueInputOutput property is not valid for bridge agent
+	{Name: "enableQueueInputOutput", Value: "false"},
+}
+
 // Update agent.properties file with any additional properties specified in
 // configuration JSON file.
-func updateAgentProperties(propertiesFile string, agentConfig string, sectionName string, bridgeAgent bool) bool {
-	f, err := os.OpenFile(propertiesFile, os.O_APPEND|os.O_WRONLY, 0644)
+func updateAgentProperties(propertiesFile string, properties config.Properties, agentType string) bool {
+	f, err := os.OpenFile(propertiesFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_OPN_FILE_0067, propertiesFile, err))
 		return false
 	}
 	defer f.Close()
 
-	// Enable logCapture by default. Customer can turn off by specifying it again in config map
-	if _, err := f.WriteString("logCapture=true\n"); err != nil {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
-	}
-
-	// Set maximum restart count to 0, so that the container ends immediately
-	// if the first attempt fails.
-	if _, err := f.WriteString("maxRestartCount=0\n"); err != nil {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
+	for _, property := range agentDefaultProperties {
+		if _, err := f.WriteString(property.Name + "=" + property.Value + "\n"); err != nil {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
+		}
 	}
 
-	setUpUserSandbox := false
-	if gjson.Get(agentConfig, sectionName).Exists() {
-		result := gjson.Get(agentConfig, sectionName)
-		result.ForEach(func(key, value gjson.Result) bool {
-			// Set a flag to not setup userSandboxes if user has asked for
-			if strings.EqualFold(key.String(), "userSandboxes") && strings.EqualFold(value.String(), "false") {
-				setUpUserSandbox = false
-			}
-			if _, err := f.WriteString(key.String() + "=" + value.String() + "\n"); err != nil {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
-			}
-			return true // keep iterating
-		})
+	// User sandbox is setup unless user has specified userSandboxes property
+	setUpUserSandbox := !userSandboxesSpecified(properties)
+	for _, property := range properties {
+		if _, err := f.WriteString(property.Name + "=" + property.Value + "\n"); err != nil {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
+		}
 	}
 
 	retVal := false
 	// If this is a bridge agent, then configure custom exit
-	if bridgeAgent {
-		if _, err := f.WriteString("protocolBridgeCredentialExitClasses=com.ibm.wmq.bridgecredentialexit.ProtocolBridgeCustomCredentialExit\n"); err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
-		} else {
-			// enableQueueInputOutput property is not valid for bridge agent
-			if _, err := f.WriteString("enableQueueInputOutput=false\n"); err != nil {
+	if agentType == AGENT_TYPE_BRIDGE {
+		retVal = true
+		for _, property := range bridgeAgentProperties {
+			if _, err := f.WriteString(property.Name + "=" + property.Value + "\n"); err != nil {
 				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
-			} else {
-				retVal = true
+				retVal = false
+				break
 			}
 		}
+	} else if agentType == AGENT_TYPE_CD {
+		// User sandboxes are not valid for Connect:Direct bridge agents
+		retVal = true
 	} else {
 		// User sandbox is setup by default. But can be overridden by user
 		if setUpUserSandbox {
-			if _, err := f.WriteString("userSandboxes=true"); err != nil {
+			if _, err := f.WriteString(PROPERTY_USER_SANDBOXES + "=true\n"); err != nil {
 				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
 			} else {
 				retVal = true
@@ -644,14 +595,15 @@ func updateAgentProperties(propertiesFile string, agentConfig string, sectionNam
 	return retVal
 }
 
-// Updates ProtocolBridgeProperties file with specified additional attributes
-func updateProtocolBridgePropertiesFile(propertiesFile string, agentConfig string) bool {
+// Updates ProtocolBridgeProperties file with specified additional attributes.
+// Passwords of FTPS trust stores and keystores are read from the given
+// credentials file.
+func updateProtocolBridgePropertiesFile(propertiesFile string, agent *config.Agent, credentialsFile string) bool {
 	// First read the entire contents of the ProtocolBridgeProperties file and build a xml file
 	bridgeProperitesXml := readFileContents(propertiesFile)
 	if logLevel >= LOG_LEVEL_VERBOSE {
 		utils.PrintLog(bridgeProperitesXml)
 	}
-	protocolBridgeConfigs := gjson.Get(agentConfig, "protocolServers").Array()
 
 	// Create a new protocol bridge properties xml document
 	bridgePropetiesDoc := xmldom.NewDocument("tns:serverProperties")
@@ -660,29 +612,25 @@ func updateProtocolBridgePropertiesFile(propertiesFile string, agentConfig strin
 	bridgePropetiesDoc.Root.SetAttributeValue("xsi:schemaLocation", "http://wmqfte.ibm.com/ProtocolBridgeProperties ProtocolBridgeProperties.xsd")
 
 	// Max destination transfers - global
-	if gjson.Get(agentConfig, "maxActiveDestinationTransfers").Exists() {
-		maxTransfers := gjson.Get(agentConfig, "maxActiveDestinationTransfers").Int()
+	if agent.MaxActiveDestinationTransfers != nil {
 		node := bridgePropetiesDoc.Root.CreateNode("tns:maxActiveDestinationTransfers")
-		node.SetAttributeValue("value", strconv.Itoa(int(maxTransfers)))
+		node.SetAttributeValue("value", agent.MaxActiveDestinationTransfers.String())
 	}
 
 	// failTransferWhenCapacityReached - global
-	if gjson.Get(agentConfig, "failTransferWhenCapacityReached").Exists() {
-		failTransfer := gjson.Get(agentConfig, "failTransferWhenCapacityReached").Bool()
+	if agent.FailTransferWhenCapacityReached != nil {
 		node := bridgePropetiesDoc.Root.CreateNode("tns:failTransferWhenCapacityReached")
-		node.SetAttributeValue("value", strconv.FormatBool(failTransfer))
+		node.SetAttributeValue("value", agent.FailTransferWhenCapacityReached.String())
 	}
 
 	// setup default server if one is provided.
-	if gjson.Get(agentConfig, "defaultServer").Exists() {
-		defaultServerName := gjson.Get(agentConfig, "defaultServer").String()
+	if len(agent.DefaultServer) > 0 {
 		defaultServerNode := bridgePropetiesDoc.Root.CreateNode("tns:defaultServer")
-		defaultServerNode.SetAttributeValue("name", defaultServerName)
+		defaultServerNode.SetAttributeValue("name", agent.DefaultServer)
 	}
 
-	for index := 0; index < len(protocolBridgeConfigs); index++ {
-		serverJson := protocolBridgeConfigs[index]
-		updateServer(bridgePropetiesDoc, serverJson.String())
+	for _, server := range agent.ProtocolServers {
+		updateServer(bridgePropetiesDoc, server, credentialsFile)
 	}
 
 	if logLevel >= LOG_LEVEL_VERBOSE {
@@ -700,35 +648,44 @@ func updateProtocolBridgePropertiesFile(propertiesFile string, agentConfig strin
 }
 
 // Update ProtocolBridgeProperties.xml file
-func updateServer(bridgePropetiesDoc *xmldom.Document, serverJson string) {
+func updateServer(bridgePropetiesDoc *xmldom.Document, server *config.ProtocolServer, credentialsFile string) {
 	// Check if server definition already exists in Xml file.
-	if gjson.Get(serverJson, "name").Exists() && gjson.Get(serverJson, "type").Exists() {
-		serverType := gjson.Get(serverJson, "type").String()
-		serverName := gjson.Get(serverJson, "name").String()
+	if len(server.Name) > 0 && len(server.Type) > 0 {
+		serverType := server.Type
+		serverName := server.Name
 		if strings.EqualFold(serverType, "FTP") {
-			server := bridgePropetiesDoc.Root.QueryOne("//tns:ftpServer[@name='" + serverName + "']")
-			if server != nil {
+			serverNode := bridgePropetiesDoc.Root.QueryOne("//tns:ftpServer[@name='" + serverName + "']")
+			if serverNode != nil {
 				// Found matching server. Now update the Xml
-				updateFTPServerAttributes(server, serverJson)
+				updateFTPServerAttributes(serverNode, server)
 			} else {
 				// FTP server does not exist. Create a new enty
-				server := bridgePropetiesDoc.Root.CreateNode("tns:ftpServer")
-				server.SetAttributeValue("name", serverName)
-				updateFTPServerAttributes(server, serverJson)
+				serverNode := bridgePropetiesDoc.Root.CreateNode("tns:ftpServer")
+				serverNode.SetAttributeValue("name", serverName)
+				updateFTPServerAttributes(serverNode, server)
 			}
 		} else if strings.EqualFold(serverType, "SFTP") {
-			server := bridgePropetiesDoc.Root.QueryOne("//tns:sftpServer[@name='" + serverName + "']")
-			if server != nil {
+			serverNode := bridgePropetiesDoc.Root.QueryOne("//tns:sftpServer[@name='" + serverName + "']")
+			if serverNode != nil {
 				// Found matching server. Now update the Xml
-				updateSFTPServerAttributes(server, serverJson)
+				updateSFTPServerAttributes(serverNode, server)
 			} else {
 				// FTP server does not exist. Create a new enty
-				server := bridgePropetiesDoc.Root.CreateNode("tns:sftpServer")
-				server.SetAttributeValue("name", serverName)
-				updateSFTPServerAttributes(server, serverJson)
+				serverNode := bridgePropetiesDoc.Root.CreateNode("tns:sftpServer")
+				serverNode.SetAttributeValue("name", serverName)
+				updateSFTPServerAttributes(serverNode, server)
 			}
 		} else if strings.EqualFold(serverType, "FTPS") {
-			utils.PrintLog("FTPS protocol servers not supported in this version.")
+			serverNode := bridgePropetiesDoc.Root.QueryOne("//tns:ftpsServer[@name='" + serverName + "']")
+			if serverNode != nil {
+				// Found matching server. Now update the Xml
+				updateFTPSServerAttributes(serverNode, server, credentialsFile)
+			} else {
+				// FTPS server does not exist. Create a new enty
+				serverNode := bridgePropetiesDoc.Root.CreateNode("tns:ftpsServer")
+				serverNode.SetAttributeValue("name", serverName)
+				updateFTPSServerAttributes(serverNode, server, credentialsFile)
+			}
 		}
 	} else {
 		// log an informational message
@@ -739,195 +696,120 @@ func updateServer(bridgePropetiesDoc *xmldom.Document, serverJson string) {
 }
 
 // Update FTP specific attributes
-func updateFTPServerAttributes(serverNode *xmldom.Node, serverJson string) {
-	if gjson.Get(serverJson, "host").Exists() {
-		value := gjson.Get(serverJson, "host").String()
-		serverNode.SetAttributeValue("host", value)
-	}
-
-	if gjson.Get(serverJson, "port").Exists() {
-		value := gjson.Get(serverJson, "port").Int()
-		serverNode.SetAttributeValue("port", strconv.Itoa(int(value)))
-	}
-
-	if gjson.Get(serverJson, "platform").Exists() {
-		value := gjson.Get(serverJson, "platform").String()
-		serverNode.SetAttributeValue("platform", value)
-	}
-
-	if gjson.Get(serverJson, "timeZone").Exists() {
-		value := gjson.Get(serverJson, "timeZone").String()
-		serverNode.SetAttributeValue("timeZone", value)
-	}
-
-	if gjson.Get(serverJson, "controlEncoding").Exists() {
-		value := gjson.Get(serverJson, "controlEncoding").String()
-		serverNode.SetAttributeValue("controlEncoding", value)
-	}
-
-	if gjson.Get(serverJson, "locale").Exists() {
-		value := gjson.Get(serverJson, "locale").String()
-		serverNode.SetAttributeValue("locale", value)
-	}
-
-	if gjson.Get(serverJson, "fileEncoding").Exists() {
-		value := gjson.Get(serverJson, "fileEncoding").String()
-		serverNode.SetAttributeValue("fileEncoding", value)
-	}
-
-	if gjson.Get(serverJson, "listFormat").Exists() {
-		value := gjson.Get(serverJson, "listFormat").String()
-		serverNode.SetAttributeValue("listFormat", value)
-	}
-
-	if gjson.Get(serverJson, "listFileRecentDateFormat").Exists() {
-		value := gjson.Get(serverJson, "listFileRecentDateFormat").String()
-		serverNode.SetAttributeValue("listFileRecentDateFormat", value)
-	}
-
-	if gjson.Get(serverJson, "listFileOldDateFormat").Exists() {
-		value := gjson.Get(serverJson, "listFileOldDateFormat").String()
-		serverNode.SetAttributeValue("listFileOldDateFormat", value)
-	}
-
-	if gjson.Get(serverJson, "monthShortNames").Exists() {
-		value := gjson.Get(serverJson, "monthShortNames").String()
-		serverNode.SetAttributeValue("monthShortNames", value)
-	}
-
-	if gjson.Get(serverJson, "limitedWrite").Exists() {
-		value := gjson.Get(serverJson, "limitedWrite").Bool()
-		serverNode.SetAttributeValue("limitedWrite", strconv.FormatBool(value))
-	}
-
-	if gjson.Get(serverJson, "passiveMode").Exists() {
-		value := gjson.Get(serverJson, "passiveMode").Bool()
-		serverNode.SetAttributeValue("passiveMode", strconv.FormatBool(value))
-	}
+func updateFTPServerAttributes(serverNode *xmldom.Node, server *config.ProtocolServer) {
+	setServerAttribute(serverNode, server, "host", server.Host)
+	setIntAttribute(serverNode, "port", server.Port)
+	setServerAttribute(serverNode, server, "platform", server.Platform)
+	setServerAttribute(serverNode, server, "timeZone", server.TimeZone)
+	setServerAttribute(serverNode, server, "controlEncoding", server.ControlEncoding)
+	setServerAttribute(serverNode, server, "locale", server.Locale)
+	setServerAttribute(serverNode, server, "fileEncoding", server.FileEncoding)
+	setServerAttribute(serverNode, server, "listFormat", server.ListFormat)
+	setServerAttribute(serverNode, server, "listFileRecentDateFormat", server.ListFileRecentDateFormat)
+	setServerAttribute(serverNode, server, "listFileOldDateFormat", server.ListFileOldDateFormat)
+	setServerAttribute(serverNode, server, "monthShortNames", server.MonthShortNames)
+	setBoolAttribute(serverNode, "limitedWrite", server.LimitedWrite)
+	setBoolAttribute(serverNode, "passiveMode", server.PassiveMode)
 
 	// Create limits section
-	limits := serverNode.CreateNode("tns:limits")
-	if gjson.Get(serverJson, "maxListFileNames").Exists() {
-		value := gjson.Get(serverJson, "maxListFileNames").Int()
-		limits.SetAttributeValue("maxListFileNames", strconv.Itoa(int(value)))
-	}
-
-	if gjson.Get(serverJson, "maxListDirectoryLevels").Exists() {
-		value := gjson.Get(serverJson, "maxListDirectoryLevels").Int()
-		limits.SetAttributeValue("maxListDirectoryLevels", strconv.Itoa(int(value)))
-	}
-
-	if gjson.Get(serverJson, "maxSessions").Exists() {
-		value := gjson.Get(serverJson, "maxSessions").Int()
-		limits.SetAttributeValue("maxSessions", strconv.Itoa(int(value)))
-	}
+	updateServerLimits(serverNode.CreateNode("tns:limits"), server)
+}
 
-	if gjson.Get(serverJson, "socketTimeout").Exists() {
-		value := gjson.Get(serverJson, "socketTimeout").Int()
-		limits.SetAttributeValue("socketTimeout", strconv.Itoa(int(value)))
-	}
+// Update FTPS specific attributes. FTPS servers have the attributes of FTP
+// servers along with the TLS settings.
+func updateFTPSServerAttributes(serverNode *xmldom.Node, server *config.ProtocolServer, credentialsFile string) {
+	setServerAttribute(serverNode, server, "host", server.Host)
+	setIntAttribute(serverNode, "port", server.Port)
+	setServerAttribute(serverNode, server, "platform", server.Platform)
+	setServerAttribute(serverNode, server, "timeZone", server.TimeZone)
+	setServerAttribute(serverNode, server, "controlEncoding", server.ControlEncoding)
+	setServerAttribute(serverNode, server, "locale", server.Locale)
+	setServerAttribute(serverNode, server, "fileEncoding", server.FileEncoding)
+	setServerAttribute(serverNode, server, "listFormat", server.ListFormat)
+	setServerAttribute(serverNode, server, "listFileRecentDateFormat", server.ListFileRecentDateFormat)
+	setServerAttribute(serverNode, server, "listFileOldDateFormat", server.ListFileOldDateFormat)
+	setServerAttribute(serverNode, server, "monthShortNames", server.MonthShortNames)
+	setBoolAttribute(serverNode, "limitedWrite", server.LimitedWrite)
+	setBoolAttribute(serverNode, "passiveMode", server.PassiveMode)
+	setServerAttribute(serverNode, server, "ftpsType", strings.ToLower(server.FtpsType))
+	setStringAttribute(serverNode, "trustStorePath", server.TrustStoreFile)
+	setServerAttribute(serverNode, server, "trustStoreType", strings.ToLower(server.TrustStoreType))
+	if len(server.TrustStoreFile) > 0 {
+		setStringAttribute(serverNode, "trustStoreCredentialsFile", credentialsFile)
+	}
+	setStringAttribute(serverNode, "keyStorePath", server.KeyStoreFile)
+	setServerAttribute(serverNode, server, "keyStoreType", strings.ToLower(server.KeyStoreType))
+	if len(server.KeyStoreFile) > 0 {
+		setStringAttribute(serverNode, "keyStoreCredentialsFile", credentialsFile)
+	}
+	setServerAttribute(serverNode, server, "protectionLevel", strings.ToUpper(server.ProtectionLevel))
+	setServerAttribute(serverNode, server, "cipherSuites", server.CipherSuites)
 
-	if gjson.Get(serverJson, "maxActiveDestinationTransfers").Exists() {
-		value := gjson.Get(serverJson, "maxActiveDestinationTransfers").Int()
-		limits.SetAttributeValue("maxActiveDestinationTransfers", strconv.Itoa(int(value)))
-	}
+	// Create limits section
+	updateServerLimits(serverNode.CreateNode("tns:limits"), server)
 }
 
 // Update SFTP specific attributes
-func updateSFTPServerAttributes(serverNode *xmldom.Node, serverJson string) {
-	if gjson.Get(serverJson, "host").Exists() {
-		value := gjson.Get(serverJson, "host").String()
-		serverNode.SetAttributeValue("host", value)
-	}
-
-	if gjson.Get(serverJson, "port").Exists() {
-		value := gjson.Get(serverJson, "port").Int()
-		serverNode.SetAttributeValue("port", strconv.Itoa(int(value)))
-	}
-
-	if gjson.Get(serverJson, "platform").Exists() {
-		value := gjson.Get(serverJson, "platform").String()
-		serverNode.SetAttributeValue("platform", value)
-	}
-
-	if gjson.Get(serverJson, "controlEncoding").Exists() {
-		value := gjson.Get(serverJson, "controlEncoding").String()
-		serverNode.SetAttributeValue("controlEncoding", value)
-	}
-
-	if gjson.Get(serverJson, "fileEncoding").Exists() {
-		value := gjson.Get(serverJson, "fileEncoding").String()
-		serverNode.SetAttributeValue("fileEncoding", value)
-	}
-
-	if gjson.Get(serverJson, "limitedWrite").Exists() {
-		value := gjson.Get(serverJson, "limitedWrite").Bool()
-		serverNode.SetAttributeValue("limitedWrite", strconv.FormatBool(value))
-	}
-
-	if gjson.Get(serverJson, "cipherList").Exists() {
-		value := gjson.Get(serverJson, "cipherList").String()
-		serverNode.SetAttributeValue("cipherList", value)
-	}
-
-	if gjson.Get(serverJson, "hostKeyCipherList").Exists() {
-		value := gjson.Get(serverJson, "hostKeyCipherList").String()
-		serverNode.SetAttributeValue("hostKeyCipherList", value)
-	}
-
-	if gjson.Get(serverJson, "keyExchangeCipherList").Exists() {
-		value := gjson.Get(serverJson, "keyExchangeCipherList").String()
-		serverNode.SetAttributeValue("keyExchangeCipherList", value)
-	}
-
-	if gjson.Get(serverJson, "MACCipherList").Exists() {
-		value := gjson.Get(serverJson, "MACCipherList").String()
-		serverNode.SetAttributeValue("MACCipherList", value)
-	}
-
-	if gjson.Get(serverJson, "fingerprintHash").Exists() {
-		value := gjson.Get(serverJson, "fingerprintHash").String()
-		serverNode.SetAttributeValue("fingerprintHash", value)
-	}
+func updateSFTPServerAttributes(serverNode *xmldom.Node, server *config.ProtocolServer) {
+	setServerAttribute(serverNode, server, "host", server.Host)
+	setIntAttribute(serverNode, "port", server.Port)
+	setServerAttribute(serverNode, server, "platform", server.Platform)
+	setServerAttribute(serverNode, server, "controlEncoding", server.ControlEncoding)
+	setServerAttribute(serverNode, server, "fileEncoding", server.FileEncoding)
+	setBoolAttribute(serverNode, "limitedWrite", server.LimitedWrite)
+	setServerAttribute(serverNode, server, "cipherList", server.CipherList)
+	setServerAttribute(serverNode, server, "hostKeyCipherList", server.HostKeyCipherList)
+	setServerAttribute(serverNode, server, "keyExchangeCipherList", server.KeyExchangeCipherList)
+	setServerAttribute(serverNode, server, "MACCipherList", server.MACCipherList)
+	setServerAttribute(serverNode, server, "fingerprintHash", server.FingerprintHash)
 
 	// Create limits section
-	limits := serverNode.CreateNode("tns:limits")
-	if gjson.Get(serverJson, "maxListFileNames").Exists() {
-		value := gjson.Get(serverJson, "maxListFileNames").Int()
-		limits.SetAttributeValue("maxListFileNames", strconv.Itoa(int(value)))
-	}
+	updateServerLimits(serverNode.CreateNode("tns:limits"), server)
+}
 
-	if gjson.Get(serverJson, "maxListDirectoryLevels").Exists() {
-		value := gjson.Get(serverJson, "maxListDirectoryLevels").Int()
-		limits.SetAttributeValue("maxListDirectoryLevels", strconv.Itoa(int(value)))
-	}
+// Update the limits section of a protocol server
+func updateServerLimits(limits *xmldom.Node, server *config.ProtocolServer) {
+	setIntAttribute(limits, "maxListFileNames", server.MaxListFileNames)
+	setIntAttribute(limits, "maxListDirectoryLevels", server.MaxListDirectoryLevels)
+	setIntAttribute(limits, "maxSessions", server.MaxSessions)
+	setIntAttribute(limits, "socketTimeout", server.SocketTimeout)
+	setIntAttribute(limits, "maxActiveDestinationTransfers", server.MaxActiveDestinationTransfers)
+}
 
-	if gjson.Get(serverJson, "maxSessions").Exists() {
-		value := gjson.Get(serverJson, "maxSessions").Int()
-		limits.SetAttributeValue("maxSessions", strconv.Itoa(int(value)))
+// Set xml attribute if the value has been specified
+func setStringAttribute(node *xmldom.Node, name string, value string) {
+	if len(value) > 0 {
+		node.SetAttributeValue(name, value)
 	}
+}
 
-	if gjson.Get(serverJson, "socketTimeout").Exists() {
-		value := gjson.Get(serverJson, "socketTimeout").Int()
-		limits.SetAttributeValue("socketTimeout", strconv.Itoa(int(value)))
+// Set xml attribute of a protocol server if the value is not blank or the
+// attribute is specified in the configuration file. A blank value that is
+// specified clears the attribute of an existing server.
+func setServerAttribute(node *xmldom.Node, server *config.ProtocolServer, name string, value string) {
+	if len(value) > 0 || server.IsSpecified(name) {
+		node.SetAttributeValue(name, value)
 	}
+}
 
-	if gjson.Get(serverJson, "maxActiveDestinationTransfers").Exists() {
-		value := gjson.Get(serverJson, "maxActiveDestinationTransfers").Int()
-		limits.SetAttributeValue("maxActiveDestinationTransfers", strconv.Itoa(int(value)))
+// Set xml attribute if the integer value has been specified
+func setIntAttribute(node *xmldom.Node, name string, value *config.Int) {
+	if value != nil {
+		node.SetAttributeValue(name, value.String())
 	}
+}
 
-	/*if gjson.Get(serverJson, "failTransferWhenCapacityReached").Exists() {
-		value := gjson.Get(serverJson, "failTransferWhenCapacityReached").Bool()
-		limits.SetAttributeValue("failTransferWhenCapacityReached", strconv.FormatBool(value))
-	}*/
+// Set xml attribute if the boolean value has been specified
+func setBoolAttribute(node *xmldom.Node, name string, value *config.Bool) {
+	if value != nil {
+		node.SetAttributeValue(name, value.String())
+	}
 }
 
 // Clean agent before starting it.
-func cleanAgent(agentConfig string, coordinationQMgr string, agentName string) {
-	cleanOnStart := gjson.Get(agentConfig, "cleanOnStart")
-	if cleanOnStart.Exists() {
-		cleanItem := cleanOnStart.String()
+func cleanAgent(agent *config.Agent, coordinationQMgr string, agentName string) {
+	if len(agent.CleanOnStart) > 0 {
+		cleanItem := agent.CleanOnStart
 		if cleanItem == "transfers" {
 			cleanAgentItem(coordinationQMgr, agentName, cleanItem, "-trs")
 		} else if cleanItem == "monitors" {
@@ -946,39 +828,17 @@ func cleanAgent(agentConfig string, coordinationQMgr string, agentName string) {
 
 // Unregister and delete agent
 func deleteAgent(coordinationQMgr string, agentName string) error {
-	var outb, errb bytes.Buffer
 	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_DLTNG_0049, agentName))
 
-	// Get the path of MFT fteDeleteAgent command.
-	cmdDltAgentPath, lookErr := exec.LookPath("fteDeleteAgent")
-	if lookErr != nil {
-		return lookErr
-	}
-
-	var cmdArgs []string
-	cmdArgs = append(cmdArgs, cmdDltAgentPath, "-p", coordinationQMgr, "-f", agentName)
-	if commandTracingEnabled {
-		cmdArgs = append(cmdArgs, "-trace", "com.ibm.wmqfte=all")
-		cmdTracePath := GetCommandTracePath()
-		if len(cmdTracePath) > 0 {
-			cmdArgs = append(cmdArgs, "-tracePath", cmdTracePath)
-		}
-	}
-
-	// -f force option is not used so that monitor is not recreated if it already exists.
-	cmdDltAgentCmd := &exec.Cmd{
-		Path: cmdDltAgentPath,
-		Args: cmdArgs,
+	// Execute the fteDeleteAgent command. Log an error in case of any error.
+	result := runFteCommand("fteDeleteAgent", "-p", coordinationQMgr, "-f", agentName)
+	if result.notFound() {
+		return result.err
 	}
-
-	// Reuse the same buffer
-	cmdDltAgentCmd.Stdout = &outb
-	cmdDltAgentCmd.Stderr = &errb
-	// Execute the fteDeleteAgent command. Log an error an exit in case of any error.
-	if err := cmdDltAgentCmd.Run(); err != nil {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
-		// Return no error even if we fail to create monitor. We have output the
+	if result.failed() {
+		// Return no error even if we fail to delete the agent. We have output the
 		// information to console.
+		result.logFailure()
 	} else {
 		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_DLTED_0050, agentName))
 	}
@@ -987,42 +847,20 @@ func deleteAgent(coordinationQMgr string, agentName string) error {
 
 // Clean agent on start of container.
 func cleanAgentItem(coordinationQMgr string, agentName string, item string, option string) error {
-	var outb, errb bytes.Buffer
 	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_CLN_0051, item, agentName))
 
-	// Get the path of MFT fteCleanAgent command.
-	cmdCleanAgentPath, lookErr := exec.LookPath("fteCleanAgent")
-	if lookErr != nil {
-		return lookErr
-	}
-
-	var cmdArgs []string
-	cmdArgs = append(cmdArgs, cmdCleanAgentPath, "-p", coordinationQMgr, option, agentName)
-	if commandTracingEnabled {
-		cmdArgs = append(cmdArgs, "-trace", "com.ibm.wmqfte=all")
-		cmdTracePath := GetCommandTracePath()
-		if len(cmdTracePath) > 0 {
-			cmdArgs = append(cmdArgs, "-tracePath", cmdTracePath)
-		}
-	}
-
-	// Clean agent before starting
-	cmdCleanAgentCmd := &exec.Cmd{
-		Path: cmdCleanAgentPath,
-		Args: cmdArgs,
+	// Execute the fteCleanAgent command. Log an error in case of any error.
+	result := runFteCommand("fteCleanAgent", "-p", coordinationQMgr, option, agentName)
+	if result.notFound() {
+		return result.err
 	}
-
-	// Reuse the same buffer
-	cmdCleanAgentCmd.Stdout = &outb
-	cmdCleanAgentCmd.Stderr = &errb
-	// Execute the fteCleanAgent command. Log an error an exit in case of any error.
-	if err := cmdCleanAgentCmd.Run(); err != nil {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
-		// Return no error even if we fail to create monitor. We have output the
+	if result.failed() {
+		// Return no error even if we fail to clean the agent. We have output the
 		// information to console.
+		result.logFailure()
 	} else {
 		if logLevel >= LOG_LEVEL_VERBOSE {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, outb.String()))
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
 		}
 		if item == "all" {
 			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_ALL_ITEM_CLN_0076, agentName))
@@ -1033,40 +871,29 @@ func cleanAgentItem(coordinationQMgr string, agentName string, item string, opti
 	return nil
 }
 
-// Create resource monitor
+// Create resource monitor from the given Monitor XML file. The monitor is
+// replaced if replace is true. Returns false if the monitor was not created.
 func createResourceMonitor(coordinationQMgr string, agentName string, agentQMgr string,
-	monitorName string, fileName string) error {
-	var outb, errb bytes.Buffer
-	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_RM_CRT_0053, monitorName))
-
-	// Get the path of MFT fteCreateAgent command.
-	cmdCrtMonitorPath, lookErr := exec.LookPath("fteCreateMonitor")
-	if lookErr != nil {
-		return lookErr
-	}
-	// -f force option is not used so that monitor is not recreated if it already exists.
-	cmdCrtMonitorCmd := &exec.Cmd{
-		Path: cmdCrtMonitorPath,
-		Args: []string{cmdCrtMonitorPath, "-p", coordinationQMgr,
-			"-mm", agentQMgr,
-			"-ma", agentName,
-			"-mn", monitorName,
-			"-ix", fileName},
-	}
-
-	// Reuse the same buffer
-	cmdCrtMonitorCmd.Stdout = &outb
-	cmdCrtMonitorCmd.Stderr = &errb
-	// Execute the fteSetupCommands command. Log an error an exit in case of any error.
-	if err := cmdCrtMonitorCmd.Run(); err != nil {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
-		// Return no error even if we fail to create monitor. We have output the
-		// information to console.
-		return nil
+	monitorName string, fileName string, replace bool) bool {
+	args := []string{"-p", coordinationQMgr,
+		"-mm", agentQMgr,
+		"-ma", agentName,
+		"-mn", monitorName,
+		"-ix", fileName}
+	if replace {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_RM_REPLACE_0129, monitorName, agentName))
+		args = append(args, "-f")
 	} else {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, outb.String()))
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_RM_CRT_0053, monitorName))
 	}
-	return nil
+
+	result := runFteCommand("fteCreateMonitor", args...)
+	if result.failed() {
+		result.logFailure()
+		return false
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
+	return true
 }
 
 // Returns the contents of the specified file.
@@ -1094,78 +921,16 @@ func readFileContents(propertiesFile string) string {
 Ping the agent to determine if it's ready to process transfer requests
 */
 func PingAgent(coordinationQMgr string, agentName string, waitTime string) bool {
-	var outb, errb bytes.Buffer
-	retVal := false
-
 	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_VRFY_STATUS_0044, agentName))
-	cmdPingAgentPath, lookPathErr := exec.LookPath("ftePingAgent")
-	if lookPathErr == nil {
-		var cmdArgs []string
-		cmdArgs = append(cmdArgs, cmdPingAgentPath, "-p", coordinationQMgr, agentName, "-w", waitTime)
-		if commandTracingEnabled {
-			cmdArgs = append(cmdArgs, "-trace", "com.ibm.wmqfte=all")
-			cmdTracePath := GetCommandTracePath()
-			if len(cmdTracePath) > 0 {
-				cmdArgs = append(cmdArgs, "-tracePath", cmdTracePath)
-			}
-		}
-
-		cmdPingAgentPath := &exec.Cmd{
-			Path: cmdPingAgentPath,
-			Args: cmdArgs,
-		}
-
-		cmdPingAgentPath.Stdout = &outb
-		cmdPingAgentPath.Stderr = &errb
-		if err := cmdPingAgentPath.Run(); err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
-		} else {
-			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, outb.String()))
-			}
-			// The output must contain BFGCL0793I. Ideally we should check
-			// for return code of 0 from command execution. Need to figure
-			// out a way to do that in Go.
-			if strings.Contains(outb.String(), "BFGCL0793I:") == true {
-				retVal = true
-			}
-		}
-	} else {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, lookPathErr))
-	}
-	return retVal
-}
-
-func getMonitorXml(monitorConfig string) string {
-	startXml := "<?xml version=\"1.0\" encoding=\"UTF-8\"?><monitor:monitor version=\"6.00\"" +
-		" xmlns:monitor=\"http://www.ibm.com/xmlns/wmqfte/7.0.1/MonitorDefinition\"" +
-		" xmlns:xsi=\"http://www.w3.org/2001/XMLSchema-instance\"" +
-		" xsi:schemaLocation=\"http://www.ibm.com/xmlns/wmqfte/7.0.1/MonitorDefinition ./Monitor.xsd\">"
-	endXml := "</monitor:monitor>"
-	finalXml := ""
-
-	// Add originator
-	originator := ""
-	finalXml = startXml + originator + endXml
-	return finalXml
-}
-
-func getOriginatorXml(monitorConfig string) string {
-	originatorXmlBegin := "<originator>"
-	originatorXmlEnd := "</originator>"
-	osHostname, err := os.Hostname()
-	hostName := "<hostName>"
-	if err == nil {
-		hostName = "<hostName>" + osHostname + "</hostName>"
-	} else {
-		hostName = "<hostName>" + "</hostName>"
+	result := runFteCommand("ftePingAgent", "-p", coordinationQMgr, agentName, "-w", waitTime)
+	if result.failed() {
+		result.logFailure()
+		return false
 	}
-
-	curUser, err := user.Current()
-	userId := "<userID>"
-	if err == nil {
-		userId += curUser.Username
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
 	}
-	userId += "</userID>"
-	return originatorXmlBegin + hostName + userId + originatorXmlEnd
+	// The output must contain the message reporting that the agent
+	// responded to the ping.
+	return result.hasMessage(bfg.AGENT_PING_RESPONDED)
 }
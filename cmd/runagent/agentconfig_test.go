@@ -17,11 +17,12 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
-	"github.com/tidwall/gjson"
-
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
 )
 
@@ -29,15 +30,14 @@ import (
  * Unit test program to test methods of runagent.
  */
 func TestProtocolBridgePropertiesFileUpdates(t *testing.T) {
-	allAgentConfig, e := utils.ReadConfigurationDataFromFile("./data/test_agcfg.json")
+	allAgentConfig, e := config.Load("./data/test_agcfg.json")
 	if e != nil {
 		t.Fatal(e)
 	}
 
-	agentCfg := gjson.Get(allAgentConfig, "agents").Array()
-	//protocolBridgeConfigs := gjson.Get(agentCfg[0].String(), "protocolServers").Array()
-	if updateProtocolBridgePropertiesFile("./data/test_pba.xml", agentCfg[0].String()) {
-		updatedXml, err := utils.ReadConfigurationDataFromFile("./data/test_pba.xml")
+	pbaFile := copyTestFile(t, "test_pba.xml")
+	if updateProtocolBridgePropertiesFile(pbaFile, allAgentConfig.Agents[0], "/mnt/mftdata/mqft/config/QM1/agents/BRIDGE/MQMFTCredentials.xml") {
+		updatedXml, err := utils.ReadConfigurationDataFromFile(pbaFile)
 		if err == nil {
 			templateData, err := utils.ReadConfigurationDataFromFile("./data/test_pba_template.xml")
 			if err == nil {
@@ -67,5 +67,59 @@ func TestProtocolBridgePropertiesFileUpdates(t *testing.T) {
 }
 
 func TestUpdateBridgeParameters(t *testing.T) {
+	port := config.Int(990)
+	server := &config.ProtocolServer{Name: "myFTPSserver", Type: "FTPS", Host: "ftps.hursley.ibm.com", Port: &port,
+		TimeZone: "Europe/London", Locale: "en_GB", FileEncoding: "UTF-8", TrustStoreFile: "/run/keystores/ftpstruststore.jks"}
+	params, valid := updateBridgeParameters(server, []string{"-p", "QM1", "-agentName", "BRIDGE"})
+	expected := []string{"-p", "QM1", "-agentName", "BRIDGE", "-bt", "FTPS", "-bh", "ftps.hursley.ibm.com", "-btz", "Europe/London",
+		"-bsl", "en_GB", "-bfe", "UTF-8", "-bp", "990", "-bts", "/run/keystores/ftpstruststore.jks"}
+	if !valid || strings.Join(params, " ") != strings.Join(expected, " ") {
+		t.Errorf("Unexpected parameters %v, valid %v", params, valid)
+	}
+
+	// Trust store is required for FTPS servers only
+	server.TrustStoreFile = ""
+	if _, valid = updateBridgeParameters(server, nil); valid {
+		t.Error("FTPS server without a trust store accepted")
+	}
+	server.Type = "SFTP"
+	if params, valid = updateBridgeParameters(server, nil); !valid || strings.Contains(strings.Join(params, " "), "-bts") {
+		t.Errorf("Unexpected parameters of SFTP server %v, valid %v", params, valid)
+	}
+}
 
+func TestCreateFTPSStores(t *testing.T) {
+	dryRunEnabled = true
+	dryRunCommands = nil
+	runner = &dryRunCommandRunner{}
+	defer func() {
+		dryRunEnabled = false
+		dryRunCommands = nil
+	}()
+
+	certPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(certPath, "myFTPSserver"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"server.crt", "client.key"} {
+		if err := os.WriteFile(filepath.Join(certPath, "myFTPSserver", name), []byte("certificate"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	servers := []*config.ProtocolServer{
+		{Name: "mySFTPserver", Type: "SFTP"},
+		{Name: "myFTPSserver", Type: "FTPS"},
+		{Name: "otherFTPSserver", Type: "FTPS", TrustStoreFile: "/mnt/stores/trust.jks"},
+	}
+	stores, created := createFTPSStores(servers, certPath)
+	if !created || len(stores) != 2 || len(dryRunCommands) != 2 {
+		t.Fatalf("Unexpected stores %+v, commands %v", stores, dryRunCommands)
+	}
+	if servers[1].TrustStoreFile != filepath.Join(KEYSTORES_PATH, "ftpsmyFTPSservertruststore.p12") || servers[1].TrustStoreType != "pkcs12" ||
+		servers[1].KeyStoreFile != filepath.Join(KEYSTORES_PATH, "ftpsmyFTPSserverkeystore.p12") || stores[0].password != stores[1].password {
+		t.Errorf("Server not updated with stores %+v", servers[1])
+	}
+	if servers[0].TrustStoreFile != "" || servers[2].TrustStoreFile != "/mnt/stores/trust.jks" {
+		t.Errorf("Stores built for unexpected servers %+v %+v", servers[0], servers[2])
+	}
 }
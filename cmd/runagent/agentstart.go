@@ -0,0 +1,213 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Waits for an agent to start after fteStartAgent has been run. The agent is
+* polled until it is ready or the deadline is reached. Each poll reads the
+* events written to output0.log since the agent was started and checks that
+* the process recorded in agent.pid is running. Once the agent has reported
+* that it is ready, ftePingAgent confirms that it responds to requests. Start
+* failures reported by the agent before it is ready and the agent process
+* ending stop the wait early.
+ */
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/bfg"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Default interval between polls and deadline, in seconds
+const AGENT_START_DEFAULT_POLL_INTERVAL = 5
+const AGENT_START_DEFAULT_DEADLINE = 300
+
+// Default time, in seconds, ftePingAgent waits for a response
+const AGENT_START_DEFAULT_PING_WAIT = 10
+
+// Functions used for waiting between polls and reading the time. Replaced by
+// tests.
+var agentStartSleep = time.Sleep
+var agentStartNow = time.Now
+
+// Polling of agents that are starting
+type agentStartPolicy struct {
+	pollInterval time.Duration
+	deadline     time.Duration
+	// Time ftePingAgent waits for the agent to respond
+	pingWaitTime time.Duration
+}
+
+// Build the polling policy from environment variables
+func newAgentStartPolicy() *agentStartPolicy {
+	pollInterval := getRetrySeconds(MFT_AGENT_START_POLL_INTERVAL, AGENT_START_DEFAULT_POLL_INTERVAL)
+	deadline := getRetrySeconds(MFT_AGENT_START_DEADLINE, AGENT_START_DEFAULT_DEADLINE)
+	pingWaitTime := AGENT_START_DEFAULT_PING_WAIT
+	pingWaitTimeStr, pingWaitTimeSet := os.LookupEnv(MFT_AGENT_START_WAIT_TIME)
+	// Value is numeric and above 0, then use it.
+	if pingWaitTimeSet {
+		waitTime, err := strconv.Atoi(strings.TrimSpace(pingWaitTimeStr))
+		if err == nil && waitTime > 0 {
+			pingWaitTime = waitTime
+		} else if logLevel >= LOG_LEVEL_VERBOSE {
+			utils.PrintLog(utils.MFT_CONT_ENV_AGENT_START_TIME_0008)
+		}
+	}
+
+	if pollInterval < 1 {
+		pollInterval = 1
+	}
+	return &agentStartPolicy{
+		pollInterval: time.Duration(pollInterval) * time.Second,
+		deadline:     time.Duration(deadline) * time.Second,
+		pingWaitTime: time.Duration(pingWaitTime) * time.Second,
+	}
+}
+
+// State of an agent that is being started, as seen by the last poll
+type agentStartWatch struct {
+	outputLogPath string
+	pidPath       string
+	// Offset in output0.log up to which events have been read
+	logOffset int64
+	// Process of the agent has been seen running
+	processSeen bool
+	// Agent has reported that it is ready
+	readyEventSeen bool
+}
+
+// Start watching an agent. Events written to output0.log before the given
+// offset are ignored as they were written by an earlier run of the agent.
+func newAgentStartWatch(agentLogPath string, logOffset int64) *agentStartWatch {
+	return &agentStartWatch{
+		outputLogPath: agentLogPath + "/logs/output0.log",
+		pidPath:       agentLogPath + "/agent.pid",
+		logOffset:     logOffset,
+	}
+}
+
+// Size of the output0.log file of the agent. Zero if the file does not exist.
+func outputLogSize(agentLogPath string) int64 {
+	fileInfo, err := os.Stat(agentLogPath + "/logs/output0.log")
+	if err != nil {
+		return 0
+	}
+	return fileInfo.Size()
+}
+
+// Read new events from output0.log and check the agent process. Returns a
+// description of the state of the agent, or an error if the agent has failed
+// to start.
+func (watch *agentStartWatch) poll() (string, error) {
+	for _, message := range watch.readEvents() {
+		if bfg.Matches(message.Id, bfg.AGENT_STARTED) {
+			watch.readyEventSeen = true
+		} else if !watch.readyEventSeen && isAgentStartFailure(message) {
+			return "", fmt.Errorf("%s %s", message.Id, message.Text)
+		}
+	}
+
+	if isAgentProcessRunning(watch.pidPath) {
+		watch.processSeen = true
+	} else if watch.processSeen {
+		return "", fmt.Errorf(utils.MFT_CONT_AGNT_START_PROCESS_ENDED_0114, watch.pidPath)
+	} else {
+		return "waiting for the agent process to start", nil
+	}
+
+	if watch.readyEventSeen {
+		return "agent reported that it is ready, waiting for it to respond to ping", nil
+	}
+	return "agent process is running, waiting for it to report that it is ready", nil
+}
+
+// Determine if the message reports that the agent failed to start
+func isAgentStartFailure(message bfg.Message) bool {
+	for _, id := range bfg.AGENT_START_FAILED {
+		if bfg.Matches(message.Id, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Messages written to output0.log since the last poll. Only complete lines
+// are read. The log is read from the beginning if it has been replaced by a
+// smaller file.
+func (watch *agentStartWatch) readEvents() []bfg.Message {
+	outputLogFile, err := os.Open(watch.outputLogPath)
+	if err != nil {
+		return nil
+	}
+	defer outputLogFile.Close()
+	fileInfo, err := outputLogFile.Stat()
+	if err != nil {
+		return nil
+	}
+	if fileInfo.Size() < watch.logOffset {
+		watch.logOffset = 0
+	}
+	if _, err := outputLogFile.Seek(watch.logOffset, io.SeekStart); err != nil {
+		return nil
+	}
+	data, err := io.ReadAll(outputLogFile)
+	if err != nil {
+		return nil
+	}
+	lastLineEnd := strings.LastIndexByte(string(data), '\n')
+	if lastLineEnd < 0 {
+		return nil
+	}
+	watch.logOffset += int64(lastLineEnd + 1)
+	return bfg.Parse(string(data[:lastLineEnd+1]))
+}
+
+// Poll the agent until it is ready, it fails to start or the deadline is
+// reached. Progress is reported after every poll. Returns false if the agent
+// did not become ready.
+func (policy *agentStartPolicy) waitForAgent(agentName string, coordinationQMgr string, watch *agentStartWatch) bool {
+	start := agentStartNow()
+	pingWaitTime := strconv.Itoa(int(policy.pingWaitTime / time.Second))
+	for {
+		state, err := watch.poll()
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_START_FAILED_0113, agentName, err))
+			if message := bfg.Parse(err.Error()); len(message) > 0 {
+				if entry, found := message[0].Lookup(); found && len(entry.Action) > 0 {
+					utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_MESSAGE_ACTION_0111, message[0].Id, entry.Meaning, entry.Action))
+				}
+			}
+			return false
+		}
+		if watch.readyEventSeen && PingAgent(coordinationQMgr, agentName, pingWaitTime) {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_START_READY_0116, agentName, agentStartNow().Sub(start).Round(time.Second)))
+			return true
+		}
+
+		elapsed := agentStartNow().Sub(start)
+		if elapsed+policy.pollInterval > policy.deadline {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_START_DEADLINE_0115, agentName, policy.deadline, state))
+			return false
+		}
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_START_WAITING_0112, agentName, state, elapsed.Round(time.Second), policy.deadline))
+		agentStartSleep(policy.pollInterval)
+	}
+}
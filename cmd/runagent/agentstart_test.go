@@ -0,0 +1,185 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Create the log directory of an agent with the given output0.log contents
+// and the pid of the test process in agent.pid
+func createAgentLogDir(t *testing.T, output string) string {
+	agentLogPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(agentLogPath, "logs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	appendOutputLog(t, agentLogPath, output)
+	if err := os.WriteFile(filepath.Join(agentLogPath, "agent.pid"), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return agentLogPath
+}
+
+func appendOutputLog(t *testing.T, agentLogPath string, output string) {
+	outputLog, err := os.OpenFile(filepath.Join(agentLogPath, "logs", "output0.log"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outputLog.Close()
+	if _, err := outputLog.WriteString(output); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewAgentStartPolicy(t *testing.T) {
+	policy := newAgentStartPolicy()
+	if policy.pollInterval != AGENT_START_DEFAULT_POLL_INTERVAL*time.Second || policy.deadline != AGENT_START_DEFAULT_DEADLINE*time.Second ||
+		policy.pingWaitTime != AGENT_START_DEFAULT_PING_WAIT*time.Second {
+		t.Errorf("Unexpected default policy %+v", policy)
+	}
+
+	t.Setenv(MFT_AGENT_START_POLL_INTERVAL, "0")
+	t.Setenv(MFT_AGENT_START_DEADLINE, "600")
+	t.Setenv(MFT_AGENT_START_WAIT_TIME, "30")
+	policy = newAgentStartPolicy()
+	if policy.pollInterval != time.Second || policy.deadline != 600*time.Second || policy.pingWaitTime != 30*time.Second {
+		t.Errorf("Policy not read from environment variables %+v", policy)
+	}
+
+	t.Setenv(MFT_AGENT_START_WAIT_TIME, "soon")
+	if policy = newAgentStartPolicy(); policy.pingWaitTime != AGENT_START_DEFAULT_PING_WAIT*time.Second {
+		t.Errorf("Invalid ping wait time used %v", policy.pingWaitTime)
+	}
+}
+
+func TestAgentStartWatchPoll(t *testing.T) {
+	// Events of an earlier run of the agent are ignored
+	earlierRun := "[01/01/2024 10:00:00:000 UTC] 00000001 AgentRuntime  I   BFGAG0059I: The agent has successfully initialized.\n"
+	agentLogPath := createAgentLogDir(t, earlierRun)
+	watch := newAgentStartWatch(agentLogPath, outputLogSize(agentLogPath))
+
+	if _, err := watch.poll(); err != nil || watch.readyEventSeen || !watch.processSeen {
+		t.Fatalf("Unexpected state after first poll %+v, error %v", watch, err)
+	}
+
+	// Incomplete lines are read by the next poll
+	appendOutputLog(t, agentLogPath, "[01/01/2024 10:05:00:000 UTC] 00000001 AgentRuntime  I   BFGAG0059I")
+	if _, err := watch.poll(); err != nil || watch.readyEventSeen {
+		t.Fatalf("Ready event read from an incomplete line %+v, error %v", watch, err)
+	}
+	appendOutputLog(t, agentLogPath, ": The agent has successfully initialized.\n")
+	if _, err := watch.poll(); err != nil || !watch.readyEventSeen {
+		t.Fatalf("Ready event not found %+v, error %v", watch, err)
+	}
+
+	// Errors reported once the agent is ready, for example by a transfer, do
+	// not fail the start
+	appendOutputLog(t, agentLogPath, "[01/01/2024 10:05:01:000 UTC] 00000001 AgentRuntime  E   BFGIO0011E: Unable to read file.\n"+
+		"[01/01/2024 10:05:02:000 UTC] 00000001 AgentRuntime  E   BFGAG0061E: The agent ended abnormally.\n")
+	if _, err := watch.poll(); err != nil {
+		t.Errorf("Error event after the agent is ready failed the start: %v", err)
+	}
+}
+
+func TestAgentStartWatchPollStartFailure(t *testing.T) {
+	// Errors that are not start failures do not stop the wait
+	agentLogPath := createAgentLogDir(t, "[01/01/2024 10:05:00:000 UTC] 00000001 AgentRuntime  E   BFGIO0011E: Unable to read file.\n")
+	watch := newAgentStartWatch(agentLogPath, 0)
+	if _, err := watch.poll(); err != nil {
+		t.Fatalf("Error event that is not a start failure failed the start: %v", err)
+	}
+
+	appendOutputLog(t, agentLogPath, "[01/01/2024 10:05:01:000 UTC] 00000001 AgentRuntime  E   BFGAG0061E: The agent ended abnormally.\n")
+	if _, err := watch.poll(); err == nil || !strings.HasPrefix(err.Error(), "BFGAG0061E ") {
+		t.Errorf("Start failure reported by the agent not detected: %v", err)
+	}
+}
+
+func TestAgentStartWatchProcessEnded(t *testing.T) {
+	agentLogPath := createAgentLogDir(t, "")
+	watch := newAgentStartWatch(agentLogPath, 0)
+	if _, err := watch.poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Process that has ended and been reaped
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("Unable to run command: ", err)
+	}
+	if err := os.WriteFile(filepath.Join(agentLogPath, "agent.pid"), []byte(strconv.Itoa(cmd.Process.Pid)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watch.poll(); err == nil {
+		t.Error("Agent process ending not detected")
+	}
+
+	// A process that has not been seen running may not have started yet
+	watch = newAgentStartWatch(agentLogPath, 0)
+	if state, err := watch.poll(); err != nil || len(state) == 0 {
+		t.Errorf("Unexpected state %q, error %v", state, err)
+	}
+}
+
+func TestWaitForAgent(t *testing.T) {
+	now := time.Now()
+	var sleeps int
+	agentStartNow = func() time.Time { return now }
+	agentStartSleep = func(delay time.Duration) {
+		sleeps++
+		now = now.Add(delay)
+	}
+	defer func() {
+		agentStartNow = time.Now
+		agentStartSleep = time.Sleep
+	}()
+	fake := &fakeCommandRunner{result: commandResult{messageIds: []string{"BFGCL0793I"}}}
+	runner = fake
+	defer func() { runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second) }()
+	policy := &agentStartPolicy{pollInterval: 5 * time.Second, deadline: 30 * time.Second, pingWaitTime: 10 * time.Second}
+
+	// Agent does not report that it is ready
+	agentLogPath := createAgentLogDir(t, "")
+	if policy.waitForAgent("SRC", "QM1", newAgentStartWatch(agentLogPath, 0)) {
+		t.Error("Agent reported as ready without ready event")
+	}
+	if sleeps != 6 || len(fake.commands) != 0 {
+		t.Errorf("Unexpected number of polls %d or commands %d", sleeps, len(fake.commands))
+	}
+
+	sleeps = 0
+	appendOutputLog(t, agentLogPath, "BFGAG0059I: The agent has successfully initialized.\n")
+	if !policy.waitForAgent("SRC", "QM1", newAgentStartWatch(agentLogPath, 0)) {
+		t.Error("Agent not reported as ready")
+	}
+	if sleeps != 0 || len(fake.commands) != 1 || fake.commands[0].args[4] != "10" {
+		t.Errorf("Unexpected number of polls %d or commands %+v", sleeps, fake.commands)
+	}
+
+	// Errors end the wait without waiting for the deadline
+	sleeps = 0
+	agentLogPath = createAgentLogDir(t, "BFGAG0061E: The agent ended abnormally.\n")
+	if policy.waitForAgent("SRC", "QM1", newAgentStartWatch(agentLogPath, 0)) || sleeps != 0 {
+		t.Errorf("Error event did not end the wait, %d polls", sleeps)
+	}
+}
@@ -0,0 +1,259 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Credentials of the protocol servers of bridge agents. The credentials block
+* of a protocol server references secrets held in files or environment
+* variables. They are written to ProtocolBridgeCredentials.json, in the JSON
+* format read by the custom credential exit of bridge agents, and the
+* protocolBridgeCredentialConfiguration property is set to the file. The exit
+* finds credentials by the host of the server, then by the MQ user id of the
+* transfer requester, falling back on the credentials for any requester.
+ */
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Name of the agent property naming the file read by the credential exit
+const PROPERTY_BRIDGE_CREDENTIAL_CONFIGURATION = "protocolBridgeCredentialConfiguration"
+
+// Requester of credentials that apply to transfers of any requester
+const BRIDGE_REQUESTER_ANY = "*"
+
+// Content of ProtocolBridgeCredentials.json
+type bridgeCredentialsFile struct {
+	Servers []*bridgeCredentialEntry `json:"servers"`
+}
+
+// Credentials of a server for transfers of matching requesters. Private and
+// host keys are base64 encoded.
+type bridgeCredentialEntry struct {
+	ServerHostName      string `json:"serverHostName"`
+	TransferRequesterId string `json:"transferRequesterId"`
+	ServerType          string `json:"serverType"`
+	ServerUserId        string `json:"serverUserId"`
+	ServerAssocName     string `json:"serverAssocName,omitempty"`
+	ServerPassword      string `json:"serverPassword"`
+	ServerHostKey       string `json:"serverHostKey,omitempty"`
+	ServerPrivateKey    string `json:"serverPrivateKey,omitempty"`
+}
+
+// Protocol of a server, FTP if not specified
+func bridgeServerProtocol(server *config.ProtocolServer) string {
+	protocol := strings.ToUpper(strings.TrimSpace(server.Type))
+	if len(protocol) == 0 {
+		return BRIDGE_PROTOCOL_FTP
+	}
+	return protocol
+}
+
+// Determine if any protocol server of the agent has credentials
+func bridgeCredentialsSpecified(agent *config.Agent) bool {
+	for _, server := range agent.ProtocolServers {
+		if server.Credentials != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate the credentials of the protocol servers of a bridge agent. The
+// exit requires a password for FTP and FTPS servers, and a private key and
+// host key for SFTP servers, where the password is the passphrase of the key.
+func validateBridgeCredentials(agent *config.Agent) error {
+	if agent.Type != AGENT_TYPE_BRIDGE {
+		return nil
+	}
+	hosts := make(map[string]string)
+	for _, server := range agent.ProtocolServers {
+		credentials := server.Credentials
+		if credentials == nil {
+			continue
+		}
+		host := strings.TrimSpace(server.Host)
+		if len(host) == 0 {
+			return fmt.Errorf("server %s must specify host as credentials are found by host", server.Name)
+		}
+		if other, found := hosts[strings.ToLower(host)]; found {
+			return fmt.Errorf("servers %s and %s both have credentials for host %s", other, server.Name, host)
+		}
+		hosts[strings.ToLower(host)] = server.Name
+
+		sftp := bridgeServerProtocol(server) == BRIDGE_PROTOCOL_SFTP
+		if len(strings.TrimSpace(credentials.UserId)) == 0 && len(credentials.UserMappings) == 0 {
+			return fmt.Errorf("credentials of server %s must specify userId or userMappings", server.Name)
+		}
+		if len(strings.TrimSpace(credentials.UserId)) > 0 {
+			if err := validateBridgeSecrets(credentials.Password, credentials.PrivateKey, sftp); err != nil {
+				return fmt.Errorf("credentials of server %s: %v", server.Name, err)
+			}
+		} else if credentials.Password != nil || credentials.PrivateKey != nil {
+			return fmt.Errorf("credentials of server %s must specify userId with password or privateKey", server.Name)
+		}
+		if sftp {
+			if err := validateSecretRef(credentials.HostKey); err != nil {
+				return fmt.Errorf("credentials of server %s: hostKey %v", server.Name, err)
+			}
+		} else if credentials.HostKey != nil {
+			return fmt.Errorf("credentials of server %s: hostKey is valid only for %s servers", server.Name, BRIDGE_PROTOCOL_SFTP)
+		}
+
+		requesters := make(map[string]bool)
+		for index, mapping := range credentials.UserMappings {
+			requester := strings.TrimSpace(mapping.Requester)
+			if len(requester) == 0 || requester == BRIDGE_REQUESTER_ANY {
+				return fmt.Errorf("userMappings[%d] of server %s must specify a requester other than %s", index, server.Name, BRIDGE_REQUESTER_ANY)
+			}
+			if requesters[requester] {
+				return fmt.Errorf("userMappings[%d] of server %s maps requester %s more than once", index, server.Name, requester)
+			}
+			requesters[requester] = true
+			if len(strings.TrimSpace(mapping.UserId)) == 0 {
+				return fmt.Errorf("userMappings[%d] of server %s must specify userId", index, server.Name)
+			}
+			if err := validateBridgeSecrets(mapping.Password, mapping.PrivateKey, sftp); err != nil {
+				return fmt.Errorf("userMappings[%d] of server %s: %v", index, server.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Validate the password and private key of a user for the protocol. The
+// password of SFTP servers is the passphrase of the private key, if any.
+func validateBridgeSecrets(password *config.SecretRef, privateKey *config.SecretRef, sftp bool) error {
+	if sftp {
+		if err := validateSecretRef(privateKey); err != nil {
+			return fmt.Errorf("privateKey %v", err)
+		}
+	} else if privateKey != nil {
+		return fmt.Errorf("privateKey is valid only for %s servers", BRIDGE_PROTOCOL_SFTP)
+	}
+	if password != nil || !sftp {
+		if err := validateSecretRef(password); err != nil {
+			return fmt.Errorf("password %v", err)
+		}
+	}
+	return nil
+}
+
+// Build the content of ProtocolBridgeCredentials.json. Entries of user
+// mappings come before the entry for any requester of the same server, as the
+// exit uses the first entry matching the requester.
+func buildBridgeCredentials(agent *config.Agent) (*bridgeCredentialsFile, error) {
+	credentialsFile := &bridgeCredentialsFile{Servers: []*bridgeCredentialEntry{}}
+	for _, server := range agent.ProtocolServers {
+		credentials := server.Credentials
+		if credentials == nil {
+			continue
+		}
+		protocol := bridgeServerProtocol(server)
+		hostKey := ""
+		if protocol == BRIDGE_PROTOCOL_SFTP {
+			value, err := resolveSecret(credentials.HostKey)
+			if err != nil {
+				return nil, fmt.Errorf("hostKey of server %s: %v", server.Name, err)
+			}
+			hostKey = base64.StdEncoding.EncodeToString([]byte(value))
+		}
+
+		for _, mapping := range credentials.UserMappings {
+			entry, err := buildBridgeCredentialEntry(server, protocol, mapping.Requester, mapping.UserId, mapping.Password, mapping.PrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("requester %s of server %s: %v", mapping.Requester, server.Name, err)
+			}
+			entry.ServerHostKey = hostKey
+			credentialsFile.Servers = append(credentialsFile.Servers, entry)
+		}
+		if len(strings.TrimSpace(credentials.UserId)) > 0 {
+			entry, err := buildBridgeCredentialEntry(server, protocol, BRIDGE_REQUESTER_ANY, credentials.UserId, credentials.Password, credentials.PrivateKey)
+			if err != nil {
+				return nil, fmt.Errorf("server %s: %v", server.Name, err)
+			}
+			entry.ServerHostKey = hostKey
+			credentialsFile.Servers = append(credentialsFile.Servers, entry)
+		}
+	}
+	return credentialsFile, nil
+}
+
+// Build the entry of a user of a server, reading its secrets
+func buildBridgeCredentialEntry(server *config.ProtocolServer, protocol string, requester string, userId string,
+	passwordRef *config.SecretRef, privateKeyRef *config.SecretRef) (*bridgeCredentialEntry, error) {
+	entry := &bridgeCredentialEntry{
+		ServerHostName:      strings.TrimSpace(server.Host),
+		TransferRequesterId: strings.TrimSpace(requester),
+		ServerType:          protocol,
+		ServerUserId:        strings.TrimSpace(userId),
+	}
+	if passwordRef != nil {
+		password, err := resolveSecret(passwordRef)
+		if err != nil {
+			return nil, fmt.Errorf("password: %v", err)
+		}
+		entry.ServerPassword = password
+	}
+	if privateKeyRef != nil {
+		privateKey, err := resolveSecret(privateKeyRef)
+		if err != nil {
+			return nil, fmt.Errorf("privateKey: %v", err)
+		}
+		entry.ServerAssocName = server.Name
+		entry.ServerPrivateKey = base64.StdEncoding.EncodeToString([]byte(privateKey))
+		// Encoded key is as sensitive as the key itself
+		utils.AddSecretValue(entry.ServerPrivateKey)
+	}
+	return entry, nil
+}
+
+// Write ProtocolBridgeCredentials.json in the configuration directory of the
+// agent and point the credential exit at it. The file can be read only by the
+// container user.
+func createBridgeCredentialsFile(agentPath string, agent *config.Agent) bool {
+	credentialsFile := agentPath + MFT_PBA_CRED_SLASH
+	credentials, err := buildBridgeCredentials(agent)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_CREDENTIALS_FAILED_0160, credentialsFile, err))
+		return false
+	}
+	credentialsData, err := json.MarshalIndent(credentials, "", "  ")
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_CREDENTIALS_FAILED_0160, credentialsFile, err))
+		return false
+	}
+	credentialsJson := string(credentialsData)
+	// Secrets are not written to disk in dry-run mode
+	if dryRunEnabled {
+		credentialsJson = utils.RedactSecrets(credentialsJson)
+	}
+	if err := utils.WriteSecretFile(credentialsFile, []byte(credentialsJson)); err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_CREDENTIALS_FAILED_0160, credentialsFile, err))
+		return false
+	}
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(credentialsJson)
+	}
+	agent.AdditionalProperties.Set(PROPERTY_BRIDGE_CREDENTIAL_CONFIGURATION, credentialsFile)
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_CREDENTIALS_WRITTEN_0161, len(credentials.Servers), agent.Name, credentialsFile))
+	return true
+}
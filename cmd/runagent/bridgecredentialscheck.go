@@ -0,0 +1,289 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Check of the protocol server credentials file read by the credential exit of
+* bridge agents, whether written by the container or supplied by the user. The
+* exit reports mistakes in the file only when a transfer fails, so the file is
+* read as the exit reads it, and every entry is checked and matched against the
+* protocol servers of the agent before the agent is started.
+ */
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/tidwall/gjson"
+	"golang.org/x/crypto/ssh"
+)
+
+// Forms of host key fingerprints accepted by the exit
+const FINGERPRINT_MD5 = "md5"
+const FINGERPRINT_SHA256 = "sha256"
+
+// MD5 fingerprint, for example e4:f9:34:4e:d9:79:5f:7a:f5:0d:20:8e:8b:ce:62:11
+var md5FingerprintPattern = regexp.MustCompile(`^([0-9a-fA-F]{2}:){15}[0-9a-fA-F]{2}$`)
+
+// SHA256 fingerprint as displayed by ssh-keygen, with or without its prefix
+var sha256FingerprintPattern = regexp.MustCompile(`^(SHA256:)?[A-Za-z0-9+/]{43}=?$`)
+
+// Line break and the white space the exit removes from the start of the line
+var privateKeyIndentPattern = regexp.MustCompile(`\n[\t ]*`)
+
+// Path of the credentials file read by the exit of a bridge agent, blank if
+// the agent does not use the exit
+func bridgeCredentialsFilePath(agent *config.Agent, agentPath string) string {
+	if bridgeCredentialsSpecified(agent) {
+		return agentPath + MFT_PBA_CRED_SLASH
+	}
+	credentialsFile, _ := agent.AdditionalProperties.Get(PROPERTY_BRIDGE_CREDENTIAL_CONFIGURATION)
+	return strings.TrimSpace(credentialsFile)
+}
+
+// Check the credentials file of a bridge agent and report every problem.
+// Problems stop the container only if asked to. Returns false if the agent
+// must not be started.
+func checkBridgeCredentialsFile(agent *config.Agent, agentPath string) bool {
+	credentialsFile := bridgeCredentialsFilePath(agent, agentPath)
+	if agent.Type != AGENT_TYPE_BRIDGE || len(credentialsFile) == 0 {
+		return true
+	}
+	// Secrets are not written in dry-run mode, and the file written by the
+	// container is built from credentials that have been validated
+	if dryRunEnabled && bridgeCredentialsSpecified(agent) {
+		return true
+	}
+
+	var problems []error
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		problems = []error{fmt.Errorf(utils.MFT_CONT_BRIDGE_CREDENTIALS_UNREADABLE_0163, credentialsFile, agent.Name, err)}
+	} else {
+		problems = validateBridgeCredentialsData(credentialsFile, data, agent)
+	}
+	if len(problems) == 0 {
+		return true
+	}
+	for _, problem := range problems {
+		utils.PrintLog(problem.Error())
+	}
+	if isBridgeValidationStrict() {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_CREDENTIALS_CHECK_FAILED_0164, credentialsFile, len(problems)))
+		return false
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_CREDENTIALS_CHECK_WARNING_0165, credentialsFile, len(problems), MFT_BRIDGE_STRICT_VALIDATION))
+	return true
+}
+
+// Validate the content of a credentials file against the protocol servers of
+// the agent. Returns one error for every problem, naming the entry and the
+// server it applies to.
+func validateBridgeCredentialsData(credentialsFile string, data []byte, agent *config.Agent) []error {
+	// The exit joins the lines of the file and decodes them if they are base64
+	credentialsJson := strings.Join(strings.FieldsFunc(string(data), func(r rune) bool { return r == '\n' || r == '\r' }), "")
+	if decoded, err := base64.StdEncoding.DecodeString(credentialsJson); err == nil {
+		credentialsJson = string(decoded)
+	}
+	credentialsJson = strings.TrimSpace(credentialsJson)
+	if !strings.HasPrefix(credentialsJson, "{") {
+		// The exit reads files that are not JSON as key-value pairs
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_CREDENTIALS_KEY_VALUE_0166, credentialsFile))
+		return nil
+	}
+	if !gjson.Valid(credentialsJson) {
+		return []error{fmt.Errorf(utils.MFT_CONT_BRIDGE_CREDENTIALS_UNREADABLE_0163, credentialsFile, agent.Name, errors.New("the file is not valid JSON"))}
+	}
+	servers := gjson.Get(credentialsJson, "servers")
+	if !servers.IsArray() {
+		return []error{fmt.Errorf(utils.MFT_CONT_BRIDGE_CREDENTIALS_UNREADABLE_0163, credentialsFile, agent.Name, errors.New("the file does not have a servers array"))}
+	}
+
+	var problems []error
+	report := func(subject string, format string, args ...interface{}) {
+		problems = append(problems, fmt.Errorf(utils.MFT_CONT_BRIDGE_CREDENTIAL_INVALID_0162, credentialsFile, subject, fmt.Sprintf(format, args...)))
+	}
+	// Entries of each server, keyed by host and requester, to find duplicates
+	requesters := make(map[string]int)
+	hostsFound := make(map[string]bool)
+	for index, entry := range servers.Array() {
+		hostName := strings.TrimSpace(entry.Get("serverHostName").String())
+		server := bridgeServerForHost(agent, hostName)
+		subject := fmt.Sprintf("servers[%d]", index)
+		if server != nil {
+			subject += " for server " + server.Name
+		} else if len(hostName) > 0 {
+			subject += " for host " + hostName
+		}
+		if len(hostName) == 0 {
+			report(subject, "must specify serverHostName")
+		} else if server == nil {
+			report(subject, "has serverHostName %s which is not the host of any protocol server", hostName)
+		}
+		hostsFound[strings.ToLower(hostName)] = true
+
+		serverType := strings.ToUpper(strings.TrimSpace(entry.Get("serverType").String()))
+		if len(serverType) == 0 {
+			report(subject, "must specify serverType")
+			continue
+		}
+		if !containsFold(allBridgeProtocols, serverType) {
+			report(subject, "has serverType %s which is not one of %s", serverType, strings.Join(allBridgeProtocols, ", "))
+			continue
+		}
+		if server != nil && bridgeServerProtocol(server) != serverType {
+			report(subject, "has serverType %s but the server is %s", serverType, bridgeServerProtocol(server))
+		}
+		if len(strings.TrimSpace(entry.Get("serverUserId").String())) == 0 {
+			report(subject, "must specify serverUserId")
+		}
+
+		requester := entry.Get("transferRequesterId")
+		requesterId := strings.TrimSpace(requester.String())
+		if !requester.Exists() && serverType != BRIDGE_PROTOCOL_SFTP {
+			report(subject, "must specify transferRequesterId, * for any requester")
+		} else if len(requesterId) > 0 {
+			// The exit matches requesters as regular expressions with * escaped
+			if _, err := regexp.Compile("^(?:" + strings.ReplaceAll(requesterId, "*", `\*`) + ")$"); err != nil {
+				report(subject, "has transferRequesterId %s which is not a valid regular expression", requesterId)
+			}
+		}
+		if len(requesterId) == 0 {
+			requesterId = BRIDGE_REQUESTER_ANY
+		}
+		key := strings.ToLower(hostName) + " " + requesterId
+		if first, found := requesters[key]; found {
+			report(subject, "replaces the credentials of servers[%d] for requester %s", first, requesterId)
+		} else {
+			requesters[key] = index
+		}
+
+		password := entry.Get("serverPassword")
+		if serverType == BRIDGE_PROTOCOL_SFTP {
+			if !password.Exists() {
+				report(subject, "must specify serverPassword, blank if the private key is not encrypted")
+			}
+			if !entry.Get("serverAssocName").Exists() {
+				report(subject, "must specify serverAssocName")
+			}
+			if privateKey := strings.TrimSpace(entry.Get("serverPrivateKey").String()); len(privateKey) == 0 {
+				report(subject, "must specify serverPrivateKey")
+			} else if err := validateBridgePrivateKey(privateKey, password.String()); err != nil {
+				report(subject, "has serverPrivateKey %v", err)
+			}
+			if hostKey := strings.TrimSpace(entry.Get("serverHostKey").String()); len(hostKey) == 0 {
+				report(subject, "must specify serverHostKey")
+			} else if err := validateBridgeHostKey(hostKey, server); err != nil {
+				report(subject, "has serverHostKey %v", err)
+			}
+		} else {
+			if len(strings.TrimSpace(password.String())) == 0 {
+				report(subject, "must specify serverPassword")
+			}
+			for _, name := range []string{"serverPrivateKey", "serverHostKey"} {
+				if entry.Get(name).Exists() {
+					report(subject, "specifies %s which is not used for %s servers", name, serverType)
+				}
+			}
+		}
+	}
+
+	// Transfers to servers without credentials fail
+	for _, server := range agent.ProtocolServers {
+		if host := strings.TrimSpace(server.Host); len(host) > 0 && !hostsFound[strings.ToLower(host)] {
+			report("server "+server.Name, "has no credentials for host %s", host)
+		}
+	}
+	return problems
+}
+
+// Protocol server of the agent with the given host, nil if none
+func bridgeServerForHost(agent *config.Agent, host string) *config.ProtocolServer {
+	if len(host) == 0 {
+		return nil
+	}
+	for _, server := range agent.ProtocolServers {
+		if strings.EqualFold(strings.TrimSpace(server.Host), host) {
+			return server
+		}
+	}
+	return nil
+}
+
+// Validate a base64 encoded private key in PEM format, decrypting it with the
+// passphrase if it is encrypted
+func validateBridgePrivateKey(encodedKey string, passphrase string) error {
+	decoded, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return errors.New("which is not base64 encoded")
+	}
+	// The exit removes white space at the start of each line
+	key := strings.ReplaceAll(string(decoded), "\r\n", "\n")
+	key = privateKeyIndentPattern.ReplaceAllString(strings.TrimLeft(key, "\n\t "), "\n")
+	block, rest := pem.Decode([]byte(key))
+	if block == nil || !strings.HasPrefix(key, "-----BEGIN") {
+		return errors.New("which is not a private key in PEM format")
+	}
+	if len(strings.TrimSpace(string(rest))) > 0 {
+		return errors.New("which has content after the private key")
+	}
+	_, err = ssh.ParseRawPrivateKey([]byte(key))
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if len(passphrase) == 0 {
+			return errors.New("which is encrypted and serverPassword is blank")
+		}
+		_, err = ssh.ParseRawPrivateKeyWithPassphrase([]byte(key), []byte(passphrase))
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return errors.New("which can not be decrypted with serverPassword")
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("which can not be parsed: %v", err)
+	}
+	return nil
+}
+
+// Validate a base64 encoded host key fingerprint, which must be of the hash
+// set by fingerprintHash of the server, if any
+func validateBridgeHostKey(encodedKey string, server *config.ProtocolServer) error {
+	decoded, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return errors.New("which is not base64 encoded")
+	}
+	fingerprint := strings.TrimSpace(string(decoded))
+	hash := ""
+	if md5FingerprintPattern.MatchString(fingerprint) {
+		hash = FINGERPRINT_MD5
+	} else if sha256FingerprintPattern.MatchString(fingerprint) {
+		hash = FINGERPRINT_SHA256
+	} else {
+		return fmt.Errorf("%s which is not a MD5 or SHA256 fingerprint", fingerprint)
+	}
+	if server != nil && len(server.FingerprintHash) > 0 {
+		if serverFingerprintHash(server) != hash {
+			return fmt.Errorf("%s which is a %s fingerprint but fingerprintHash of the server is %s", fingerprint, strings.ToUpper(hash), server.FingerprintHash)
+		}
+	}
+	return nil
+}
@@ -16,136 +16,97 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
 	"github.com/subchen/go-xmldom"
-	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
 )
 
-// Calls fteSetupCommands to create command queue manager configuration.
-func setupCommands(allAgentConfig string, bfgDataPath string, agentName string) bool {
-	// Variables for Stdout and Stderr
-	var outb, errb bytes.Buffer
+// Calls fteSetupCommands to create command queue manager configuration. Names
+// of the agents are only logged. Returns the result of fteSetupCommands if it
+// failed.
+func setupCommands(agentConfiguration *config.Configuration, bfgDataPath string, agentNames string) (bool, *commandResult) {
 	var created bool = false
-	commandQueueManager := gjson.Get(allAgentConfig, "commandQMgr.name").String()
+	commandQMgr := &agentConfiguration.CommandQMgr
+	commandQueueManager := commandQMgr.Name
 
-	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_SETUP_STRT_0055, agentName, commandQueueManager))
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_SETUP_STRT_0055, agentNames, commandQueueManager))
 
-	// Get the path of MFT fteSetupCommands command.
-	cmdCmdsPath, lookPathErr := exec.LookPath("fteSetupCommands")
-	if lookPathErr == nil {
-		// Setup commands configuration
-		if !gjson.Get(allAgentConfig, "commandQMgr.name").Exists() {
-			utils.PrintLog("Command queue manager name not provided")
-			return false
+	// Setup commands configuration
+	if len(strings.TrimSpace(commandQueueManager)) == 0 {
+		utils.PrintLog("Command queue manager name not provided")
+		return false, nil
+	}
+
+	// Execute the fteSetupCommands command. Log an error an exit in case of any error.
+	connection := commandConnection(commandQMgr)
+	params := append([]string{"-p", agentConfiguration.CoordinationQMgr.Name, "-connectionQMgr", commandQueueManager},
+		connection.commandArgs("connectionQMgr")...)
+	result := runFteCommand("fteSetupCommands", append(params, "-f")...)
+	if result.failed() {
+		// Caller ends the container if setup fails after being retried
+		result.logFailure()
+		return false, result
+	} else {
+		if logLevel >= LOG_LEVEL_VERBOSE {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
 		}
-		var port string
-		var channel string
-		var hostName string
-		if gjson.Get(allAgentConfig, "commandQMgr.host").Exists() {
-			hostName = gjson.Get(allAgentConfig, "commandQMgr.host").String()
-		} else {
-			hostName = "localhost"
+
+		coordinationQmgrName := agentConfiguration.CoordinationQMgr.Name
+		// Start XML document for credentials file
+		credentialsDoc := InitializeCredentialsDocumentWriter()
+		cmdCredFilePath := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQmgrName + MFT_CMD_CRED_SLASH
+		// Configure TLS for command queue manager
+		created = configTLSCommand(commandQMgr, credentialsDoc, cmdCredFilePath)
+		// Connection name list or CCDT of the command queue manager
+		if !configureCcdt(connection, bfgDataPath+MFT_CONFIG_PATH_SUFFIX+coordinationQmgrName+MFT_CMD_CCDT_SLASH,
+			&commandQMgr.AdditionalProperties) {
+			return false, nil
 		}
 
-		if gjson.Get(allAgentConfig, "commandQMgr.port").Exists() {
-			port = gjson.Get(allAgentConfig, "commandQMgr.port").String()
-		} else {
-			port = "1414"
+		if commandQMgr.Credentials != nil {
+			// Write coordination queue manager credentials
+			UpdateXmlWithQmgrCredentials(credentialsDoc, commandQMgr.Credentials, commandQueueManager)
 		}
 
-		if gjson.Get(allAgentConfig, "commandQMgr.channel").Exists() {
-			channel = gjson.Get(allAgentConfig, "commandQMgr.channel").String()
+		errSetCred := setupCredentials(cmdCredFilePath, credentialsDoc.XMLPretty())
+		if errSetCred == nil {
+			// Attempt to encrypt the credentials file with a fixed key
+			EncryptCredentialsFile(cmdCredFilePath)
+			commandQMgr.AdditionalProperties.Set(credentialsFileProperty(PROPERTY_PREFIX_COMMAND), cmdCredFilePath)
 		} else {
-			channel = "SYSTEM.DEF.SVRCONN"
+			utils.PrintLog(errSetCred.Error())
 		}
 
-		var cmdArgs []string
-		cmdArgs = append(cmdArgs, cmdCmdsPath,
-			"-p", gjson.Get(allAgentConfig, "coordinationQMgr.name").String(),
-			"-connectionQMgr", commandQueueManager,
-			"-connectionQMgrHost", hostName,
-			"-connectionQMgrPort", port, "-connectionQMgrChannel", channel, "-f")
-		if commandTracingEnabled {
-			cmdArgs = append(cmdArgs, "-trace", "com.ibm.wmqfte=all")
-			cmdTracePath := GetCommandTracePath()
-			if len(cmdTracePath) > 0 {
-				cmdArgs = append(cmdArgs, "-tracePath", cmdTracePath)
-			}
+		if logLevel >= LOG_LEVEL_VERBOSE && len(cmdCredFilePath) > 0 {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_QMGR_CRED_PATH_0056, cmdCredFilePath))
 		}
 
-		cmdSetupCmds := &exec.Cmd{
-			Path: cmdCmdsPath,
-			Args: cmdArgs,
+		if logLevel >= LOG_LEVEL_VERBOSE {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_UPDATED_CMD_CONFIG, agentConfiguration))
 		}
 
-		cmdSetupCmds.Stdout = &outb
-		cmdSetupCmds.Stderr = &errb
-		// Execute the fteSetupCommands command. Log an error an exit in case of any error.
-		if err := cmdSetupCmds.Run(); err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
-			os.Exit(1)
+		// Update command properties file with additional attributes specified.
+		commandsPropertiesFile := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQmgrName + MFT_CMD_PROPS_SLASH
+		err := UpdateProperties(commandsPropertiesFile, commandQMgr.AdditionalProperties)
+		if err != nil {
+			utils.PrintLog(err.Error())
 		} else {
-			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, outb.String()))
-			}
-
-			coordinationQmgrName := gjson.Get(allAgentConfig, "coordinationQMgr.name").String()
-			// Start XML document for credentials file
-			credentialsDoc := InitializeCredentialsDocumentWriter()
-			cmdCredFilePath := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQmgrName + MFT_CMD_CRED_SLASH
-			// Configure TLS for command queue manager
-			created, allAgentConfig = configTLSCommand(allAgentConfig, credentialsDoc, cmdCredFilePath)
-
-			if gjson.Get(allAgentConfig, "commandQMgr.qmgrCredentials").Exists() {
-				// Write coordination queue manager credentials
-				UpdateXmlWithQmgrCredentials(credentialsDoc, gjson.Get(allAgentConfig, "commandQMgr.qmgrCredentials").String(), commandQueueManager)
-			}
-
-			errSetCred := setupCredentials(cmdCredFilePath, credentialsDoc.XMLPretty())
-			if errSetCred == nil {
-				// Attempt to encrypt the credentials file with a fixed key
-				EncryptCredentialsFile(cmdCredFilePath)
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionQMgrAuthenticationCredentialsFile", cmdCredFilePath)
-			} else {
-				utils.PrintLog(errSetCred.Error())
-			}
-
-			if logLevel >= LOG_LEVEL_VERBOSE && len(cmdCredFilePath) > 0 {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_QMGR_CRED_PATH_0056, cmdCredFilePath))
-			}
-
-			if logLevel >= LOG_LEVEL_VERBOSE && len(allAgentConfig) > 0 {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_UPDATED_CMD_CONFIG, allAgentConfig))
-			}
-
-			// Update command properties file with additional attributes specified.
-			commandsPropertiesFile := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQmgrName + MFT_CMD_PROPS_SLASH
-			err := UpdateProperties(commandsPropertiesFile, allAgentConfig, "commandQMgr.additionalProperties")
-			if err != nil {
-				utils.PrintLog(err.Error())
-			} else {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_SETUP_COMP_0057, commandQueueManager))
-				created = true
-			}
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_SETUP_COMP_0057, commandQueueManager))
+			created = true
 		}
-	} else {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, lookPathErr))
 	}
 
-	return created
+	return created, nil
 }
 
 // Configure TLS for command queue manager
-func configTLSCommand(allAgentConfig string, credentialsDoc *xmldom.Document, cmdCredFilePath string) (bool, string) {
+func configTLSCommand(commandQMgr *config.QueueManager, credentialsDoc *xmldom.Document, cmdCredFilePath string) bool {
 	var created bool
 	// Create keystore using certificate provided if available.
 	cipherName, cipherSet := os.LookupEnv(MFT_CMD_QMGR_CIPHER)
@@ -158,10 +119,7 @@ func configTLSCommand(allAgentConfig string, credentialsDoc *xmldom.Document, cm
 			errCreateKeyStore := CreateKeyStore(KEYSTORES_PATH, CMD_QM_TRUSTSTORE, publicKeyCertPath, password)
 			if errCreateKeyStore == nil {
 				// Update coordination properties file
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionSslCipherSpec", cipherName)
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionSslTrustStore", filepath.Join(KEYSTORES_PATH, CMD_QM_TRUSTSTORE))
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionSslTrustStoreType", "pkcs12")
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionSslTrustStoreCredentialsFile", cmdCredFilePath)
+				setProperties(&commandQMgr.AdditionalProperties, tlsTrustStoreProperties(PROPERTY_PREFIX_COMMAND, cipherName, CMD_QM_TRUSTSTORE, cmdCredFilePath))
 				UpdateXmlWithKeyStoreCredentials(credentialsDoc, filepath.Join(KEYSTORES_PATH, CMD_QM_TRUSTSTORE), password)
 				created = true
 			} else {
@@ -174,10 +132,7 @@ func configTLSCommand(allAgentConfig string, credentialsDoc *xmldom.Document, cm
 		if len(privateKeyCertPath) > 0 {
 			errCreateSslStore := CreateKeyStore(KEYSTORES_PATH, CMD_QM_KEYSTORE, privateKeyCertPath, password)
 			if errCreateSslStore == nil {
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionSslCipherSpec", cipherName)
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionSslKeyStore", filepath.Join(KEYSTORES_PATH, CMD_QM_KEYSTORE))
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionSslKeyStoreType", "pkcs12")
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "commandQMgr.additionalProperties.connectionSslKeyStoreCredentialsFile", cmdCredFilePath)
+				setProperties(&commandQMgr.AdditionalProperties, tlsKeyStoreProperties(PROPERTY_PREFIX_COMMAND, cipherName, CMD_QM_KEYSTORE, cmdCredFilePath))
 				UpdateXmlWithKeyStoreCredentials(credentialsDoc, filepath.Join(KEYSTORES_PATH, CMD_QM_KEYSTORE), password)
 				created = true
 			} else {
@@ -189,21 +144,26 @@ func configTLSCommand(allAgentConfig string, credentialsDoc *xmldom.Document, cm
 		created = true
 	}
 
-	return created, allAgentConfig
+	return created
 }
 
 // Validate the configuration for required command qmgr attributes.
-func validateCommandAttributes(jsonData string) error {
+func validateCommandAttributes(agentConfiguration *config.Configuration) error {
 	// Commands queue manager is mandatory
-	if !gjson.Get(jsonData, "commandQMgr.name").Exists() {
+	if len(strings.TrimSpace(agentConfiguration.CommandQMgr.Name)) == 0 {
 		err := errors.New(utils.MFT_CONT_CFG_CMD_QM_NAME_MISSING_0017)
 		return err
 	}
-	// Coordination queue manager host is mandatory
-	if !gjson.Get(jsonData, "commandQMgr.host").Exists() {
+	// Command queue manager host is mandatory unless a connection name list or
+	// CCDT is specified
+	connection := commandConnection(&agentConfiguration.CommandQMgr)
+	if !connection.specified() {
 		err := errors.New(utils.MFT_CONT_CFG_CMD_QM_HOST_MISSING_0018)
 		return err
 	}
+	if err := connection.validate(); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_QMGR_CONNECTION_INVALID_0148, connection.role, connection.qmgrName, err)
+	}
 
 	return nil
 }
@@ -19,11 +19,11 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 )
 
 func TestValidateCommandAttributesValidProperties(t *testing.T) {
-	allAgentConfig, e := utils.ReadConfigurationDataFromFile("./data/test_agcfg.json")
+	allAgentConfig, e := config.Load("./data/test_agcfg.json")
 	if e != nil {
 		t.Fatal(e)
 	}
@@ -35,7 +35,10 @@ func TestValidateCommandAttributesValidProperties(t *testing.T) {
 }
 
 func TestValidateCommandAttributesMissingQmgrName(t *testing.T) {
-	agentConfig := "{\"waitTimeToStart\":20,\"commandQMgr\":{\"host\":\"10.254.16.17\",	\"port\":1414,\"channel\":\"QS_SVRCONN\",\"additionalProperties\": {}}}"
+	agentConfig, e := config.Parse("{\"waitTimeToStart\":20,\"commandQMgr\":{\"host\":\"10.254.16.17\",	\"port\":1414,\"channel\":\"QS_SVRCONN\",\"additionalProperties\": {}}}")
+	if e != nil {
+		t.Fatal(e)
+	}
 	validated := validateCommandAttributes(agentConfig)
 	if validated != nil {
 		t.Log("Command queue manager attributes validation failed.")
@@ -45,7 +48,10 @@ func TestValidateCommandAttributesMissingQmgrName(t *testing.T) {
 }
 
 func TestValidateCommandAttributesMissingQmgrHostName(t *testing.T) {
-	agentConfig := "{\"waitTimeToStart\":20,\"commandQMgr\":{\"name\":\"SECUREQM\",	\"port\":1414,\"channel\":\"QS_SVRCONN\",\"additionalProperties\": {}}}"
+	agentConfig, e := config.Parse("{\"waitTimeToStart\":20,\"commandQMgr\":{\"name\":\"SECUREQM\",	\"port\":1414,\"channel\":\"QS_SVRCONN\",\"additionalProperties\": {}}}")
+	if e != nil {
+		t.Fatal(e)
+	}
 	validated := validateCommandAttributes(agentConfig)
 	if validated != nil {
 		t.Log("Command queue manager attributes validation failed.")
@@ -55,7 +61,10 @@ func TestValidateCommandAttributesMissingQmgrHostName(t *testing.T) {
 }
 
 func TestValidateCommandAttributesMissingChannelName(t *testing.T) {
-	agentConfig := "{\"waitTimeToStart\":20,\"commandQMgr\":{\"name\":\"SECUREQM\",\"host\":\"10.254.16.17\",\"port\":1414,\"additionalProperties\": {}}}"
+	agentConfig, e := config.Parse("{\"waitTimeToStart\":20,\"commandQMgr\":{\"name\":\"SECUREQM\",\"host\":\"10.254.16.17\",\"port\":1414,\"additionalProperties\": {}}}")
+	if e != nil {
+		t.Fatal(e)
+	}
 	validated := validateCommandAttributes(agentConfig)
 	if validated == nil {
 		t.Log("Command queue manager attributes with missing channel name validation passed as expected.")
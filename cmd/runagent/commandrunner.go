@@ -0,0 +1,218 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Runs fte commands and keytool. Every command is run with a timeout and is
+* ended when the container is asked to stop. Tracing arguments are added to
+* fte commands when command tracing is enabled and the command line is logged
+* with secrets redacted. Tests replace the runner with a fake.
+ */
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/bfg"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Default time, in seconds, a command may run before it is ended
+const COMMAND_DEFAULT_TIMEOUT = 300
+
+// Time to wait for output of a command to be closed after it has been ended
+const COMMAND_WAIT_DELAY = 5 * time.Second
+
+// Command to be run
+type command struct {
+	name string
+	args []string
+	// Add tracing arguments when command tracing is enabled. Only fte
+	// commands accept them.
+	traceable bool
+	// Time the command may run. Timeout of the runner is used if zero.
+	timeout time.Duration
+	// Environment variables, in the form name=value, set for the command in
+	// addition to those of the container
+	env []string
+}
+
+// Result of running a command
+type commandResult struct {
+	// Command line with secrets redacted
+	commandLine string
+	stdout      string
+	stderr      string
+	exitCode    int
+	duration    time.Duration
+	// Ids of BFG messages found in the output, in order
+	messageIds []string
+	// Command was not found, did not complete in time, was cancelled or
+	// ended with a non-zero exit code
+	err error
+}
+
+// Runs commands. Replaced in dry-run mode and by tests.
+type commandRunner interface {
+	run(cmd *command) *commandResult
+}
+
+// Runner used for all commands
+var runner commandRunner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second)
+
+// Build an fte command. Tracing arguments are added when enabled.
+func fteCommand(name string, args ...string) *command {
+	return &command{name: name, args: args, traceable: true}
+}
+
+// Run an fte command using the current runner
+func runFteCommand(name string, args ...string) *commandResult {
+	return runner.run(fteCommand(name, args...))
+}
+
+// Arguments of the command, including tracing arguments if required
+func (cmd *command) arguments() []string {
+	args := append([]string{}, cmd.args...)
+	if cmd.traceable && commandTracingEnabled {
+		args = append(args, "-trace", "com.ibm.wmqfte=all")
+		cmdTracePath := GetCommandTracePath()
+		if len(cmdTracePath) > 0 {
+			args = append(args, "-tracePath", cmdTracePath)
+		}
+	}
+	return args
+}
+
+// Command line with values of secret arguments redacted
+func (cmd *command) commandLine(args []string) string {
+	return strings.Join(redactCommandArgs(append([]string{cmd.name}, args...)), " ")
+}
+
+// Determine if the command failed
+func (result *commandResult) failed() bool {
+	return result.err != nil
+}
+
+// Determine if the command failed because it was not found
+func (result *commandResult) notFound() bool {
+	return errors.Is(result.err, exec.ErrNotFound)
+}
+
+// Determine if the output contains the given BFG message id
+func (result *commandResult) hasMessage(messageId string) bool {
+	for _, id := range result.messageIds {
+		if bfg.Matches(id, messageId) {
+			return true
+		}
+	}
+	return false
+}
+
+// Log the reason of failure along with output of the command
+func (result *commandResult) logFailure() {
+	if result.notFound() {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, result.err))
+		return
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_FAILED_0107, result.commandLine, result.err))
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, result.stdout, result.stderr))
+
+	// Suggest what to do about known messages
+	for _, messageId := range result.messageIds {
+		if entry, found := bfg.Lookup(messageId); found && len(entry.Action) > 0 {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_MESSAGE_ACTION_0111, messageId, entry.Meaning, entry.Action))
+		}
+	}
+}
+
+// Runs commands as child processes
+type execCommandRunner struct {
+	// Cancelled when the container is asked to stop
+	ctx     context.Context
+	timeout time.Duration
+}
+
+func newExecCommandRunner(ctx context.Context, timeout time.Duration) *execCommandRunner {
+	return &execCommandRunner{ctx: ctx, timeout: timeout}
+}
+
+// Run the command and wait for it to end. The command and any processes it
+// started are killed if the timeout expires or the runner is cancelled.
+func (runner *execCommandRunner) run(cmd *command) *commandResult {
+	args := cmd.arguments()
+	result := &commandResult{commandLine: cmd.commandLine(args), exitCode: -1}
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_RUNNING_0106, result.commandLine))
+	}
+
+	cmdPath, lookPathErr := exec.LookPath(cmd.name)
+	if lookPathErr != nil {
+		result.err = lookPathErr
+		return result
+	}
+
+	timeout := cmd.timeout
+	if timeout == 0 {
+		timeout = runner.timeout
+	}
+	ctx, cancel := context.WithTimeout(runner.ctx, timeout)
+	defer cancel()
+
+	// #nosec G204
+	execCmd := exec.CommandContext(ctx, cmdPath, args...)
+	var outb, errb bytes.Buffer
+	execCmd.Stdout = &outb
+	execCmd.Stderr = &errb
+	if len(cmd.env) > 0 {
+		// Later values replace those of the container
+		execCmd.Env = append(os.Environ(), cmd.env...)
+	}
+	// fte commands are scripts that start a JVM, so kill the whole group
+	execCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	execCmd.Cancel = func() error {
+		return syscall.Kill(-execCmd.Process.Pid, syscall.SIGKILL)
+	}
+	execCmd.WaitDelay = COMMAND_WAIT_DELAY
+
+	start := time.Now()
+	err := execCmd.Run()
+	result.duration = time.Since(start)
+	result.stdout = outb.String()
+	result.stderr = errb.String()
+	if execCmd.ProcessState != nil {
+		result.exitCode = execCmd.ProcessState.ExitCode()
+	}
+	result.messageIds = bfg.Ids(result.stdout + result.stderr)
+
+	if runner.ctx.Err() != nil {
+		result.err = fmt.Errorf(utils.MFT_CONT_CMD_CANCELLED_0109, cmd.name)
+	} else if ctx.Err() == context.DeadlineExceeded {
+		result.err = fmt.Errorf(utils.MFT_CONT_CMD_TIMEOUT_0108, cmd.name, timeout)
+	} else {
+		result.err = err
+	}
+
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ENDED_0110, cmd.name, result.exitCode, result.duration.Round(time.Millisecond), strings.Join(result.messageIds, ", ")))
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Runner returning a fixed result and recording the commands run
+type fakeCommandRunner struct {
+	commands []*command
+	result   commandResult
+}
+
+func (runner *fakeCommandRunner) run(cmd *command) *commandResult {
+	runner.commands = append(runner.commands, cmd)
+	result := runner.result
+	return &result
+}
+
+func TestExecCommandRunner(t *testing.T) {
+	execRunner := newExecCommandRunner(context.Background(), 5*time.Second)
+
+	result := execRunner.run(&command{name: "sh", args: []string{"-c", "echo 'BFGCL0793I: The agent responded.'; echo BFGMQ1007I >&2; exit 3"}})
+	if !result.failed() || result.exitCode != 3 {
+		t.Errorf("Unexpected exit code %d, error %v", result.exitCode, result.err)
+	}
+	if !strings.Contains(result.stdout, "responded") || !strings.Contains(result.stderr, "BFGMQ1007I") {
+		t.Errorf("Output not captured: %q %q", result.stdout, result.stderr)
+	}
+	if !reflect.DeepEqual(result.messageIds, []string{"BFGCL0793I", "BFGMQ1007I"}) {
+		t.Errorf("Unexpected message ids %v", result.messageIds)
+	}
+
+	// Environment variables of the command are added to those of the container
+	t.Setenv("MFT_TEST_RUNNER_ENV", "container")
+	result = execRunner.run(&command{name: "sh", args: []string{"-c", "echo $MFT_TEST_RUNNER_ENV $PATH"}, env: []string{"MFT_TEST_RUNNER_ENV=command"}})
+	if result.failed() || !strings.HasPrefix(result.stdout, "command /") {
+		t.Errorf("Unexpected environment of command: %q %v", result.stdout, result.err)
+	}
+
+	// Command that does not complete in time
+	start := time.Now()
+	result = execRunner.run(&command{name: "sleep", args: []string{"10"}, timeout: 100 * time.Millisecond})
+	if !result.failed() || !strings.Contains(result.err.Error(), "did not complete") || time.Since(start) > 5*time.Second {
+		t.Errorf("Command not ended after timeout: %v after %v", result.err, time.Since(start))
+	}
+
+	// Runner cancelled as the container is stopping
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = newExecCommandRunner(ctx, 5*time.Second).run(&command{name: "sleep", args: []string{"10"}})
+	if !result.failed() || !strings.Contains(result.err.Error(), "stopping") {
+		t.Errorf("Command not ended when runner cancelled: %v", result.err)
+	}
+
+	result = execRunner.run(fteCommand("fteNoSuchCommand", "-p", "QM1"))
+	if !result.notFound() {
+		t.Errorf("Expected command not to be found: %v", result.err)
+	}
+}
+
+func TestCommandArguments(t *testing.T) {
+	defer func() { commandTracingEnabled = false }()
+	commandTracingEnabled = true
+	cmd := fteCommand("fteStartAgent", "-p", "QM1", "SRC")
+	args := cmd.arguments()
+	if len(args) < 5 || args[3] != "-trace" || args[4] != "com.ibm.wmqfte=all" {
+		t.Errorf("Trace arguments not added: %v", args)
+	}
+	keytool := &command{name: "keytool", args: []string{"-storepass", "Passw0rd"}}
+	if args := keytool.arguments(); len(args) != 2 {
+		t.Errorf("Trace arguments added to keytool: %v", args)
+	}
+	if commandLine := keytool.commandLine(keytool.arguments()); commandLine != "keytool -storepass "+utils.REDACTED_TEXT {
+		t.Errorf("Secret not redacted from command line: %s", commandLine)
+	}
+}
+
+func TestPingAgentWithFakeRunner(t *testing.T) {
+	fake := &fakeCommandRunner{result: commandResult{stdout: "BFGCL0793I: The agent SRC responded to the ping in 0.5 seconds.", messageIds: []string{"BFGCL0793I"}}}
+	runner = fake
+	defer func() { runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second) }()
+
+	if !PingAgent("QM1", "SRC", "5") {
+		t.Error("Agent not reported as ready")
+	}
+	if len(fake.commands) != 1 || fake.commands[0].name != "ftePingAgent" ||
+		!reflect.DeepEqual(fake.commands[0].args, []string{"-p", "QM1", "SRC", "-w", "5"}) {
+		t.Errorf("Unexpected commands run: %+v", fake.commands)
+	}
+
+	fake.result = commandResult{stdout: "BFGCL0214I: agent SRC didn't respond to ping after 5 seconds.", messageIds: []string{"BFGCL0214I"}}
+	if PingAgent("QM1", "SRC", "5") {
+		t.Error("Agent reported as ready when it did not respond")
+	}
+}
@@ -0,0 +1,486 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Contains the schema of the agent configuration JSON file and the methods
+* to validate a configuration against it. The whole file is validated before
+* any fte command is run so that typos, values of wrong type and unknown
+* attributes are reported upfront along with their JSON path.
+ */
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/tidwall/gjson"
+)
+
+// Describes an attribute, or a group of attributes, of the configuration file.
+type configSchema struct {
+	// One of the DATA_TYPE_* constants
+	dataType int
+	// Attributes of an object. Attributes not listed here are reported as unknown
+	// unless anyProperties is set.
+	properties map[string]*configSchema
+	// Attributes of an object that must be present
+	required []string
+	// Schema of the elements of an array
+	items *configSchema
+	// Allowed values of a string attribute. Compared ignoring case.
+	enum []string
+	// Object can contain attributes of any name with simple values. Used for
+	// additionalProperties groups that are written to properties files as is.
+	anyProperties bool
+}
+
+// Schema of the qmgrCredentials group
+var qmgrCredentialsSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"mqUserId":   {dataType: DATA_TYPE_STRING},
+		"mqPassword": {dataType: DATA_TYPE_STRING},
+	},
+}
+
+// Schema of additionalProperties groups
+var additionalPropertiesSchema = &configSchema{
+	dataType:      DATA_TYPE_OBJECT,
+	anyProperties: true,
+}
+
+// Schema of coordinationQMgr and commandQMgr groups
+var qmgrSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"name":                 {dataType: DATA_TYPE_STRING},
+		"host":                 {dataType: DATA_TYPE_STRING},
+		"port":                 {dataType: DATA_TYPE_INT},
+		"channel":              {dataType: DATA_TYPE_STRING},
+		"connectionNameList":   {dataType: DATA_TYPE_STRING},
+		"ccdtUrl":              {dataType: DATA_TYPE_STRING},
+		"qmgrCredentials":      qmgrCredentialsSchema,
+		"additionalProperties": additionalPropertiesSchema,
+	},
+	// Host is not required if a connection name list or CCDT is specified
+	required: []string{"name"},
+}
+
+// Schema of the retryPolicy group
+var retryPolicySchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"initialDelay": {dataType: DATA_TYPE_INT},
+		"maxDelay":     {dataType: DATA_TYPE_INT},
+		"deadline":     {dataType: DATA_TYPE_INT},
+	},
+}
+
+// Schema of an array of path or queue name patterns
+var patternListSchema = &configSchema{dataType: DATA_TYPE_ARRAY, items: &configSchema{dataType: DATA_TYPE_STRING}}
+
+// Schema of read and write groups of a sandbox
+var sandboxAccessSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"include":       patternListSchema,
+		"exclude":       patternListSchema,
+		"includeQueues": patternListSchema,
+		"excludeQueues": patternListSchema,
+	},
+}
+
+// Schema of an element of sandboxes array
+var sandboxSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"user":        {dataType: DATA_TYPE_STRING},
+		"userPattern": {dataType: DATA_TYPE_STRING, enum: []string{SANDBOX_USER_PATTERN_WILDCARD, SANDBOX_USER_PATTERN_REGEX}},
+		"read":        sandboxAccessSchema,
+		"write":       sandboxAccessSchema,
+	},
+	required: []string{"user"},
+}
+
+// Schema of the trigger group of a monitor
+var monitorTriggerSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"condition":   {dataType: DATA_TYPE_STRING, enum: append(append([]string{}, monitorDirectoryTriggers...), monitorQueueTriggers...)},
+		"pattern":     {dataType: DATA_TYPE_STRING},
+		"patternType": {dataType: DATA_TYPE_STRING, enum: monitorPatternTypes},
+		"size":        {dataType: DATA_TYPE_INT},
+		"sizeUnits":   {dataType: DATA_TYPE_STRING, enum: monitorSizeUnits},
+		"polls":       {dataType: DATA_TYPE_INT},
+	},
+	required: []string{"condition"},
+}
+
+// Schema of an element of monitors array
+var monitorSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"name":              {dataType: DATA_TYPE_STRING},
+		"description":       {dataType: DATA_TYPE_STRING},
+		"resourceType":      {dataType: DATA_TYPE_STRING, enum: monitorResourceTypes},
+		"resource":          {dataType: DATA_TYPE_STRING},
+		"recursionLevel":    {dataType: DATA_TYPE_INT},
+		"trigger":           monitorTriggerSchema,
+		"pollInterval":      {dataType: DATA_TYPE_INT},
+		"pollIntervalUnits": {dataType: DATA_TYPE_STRING, enum: monitorPollIntervalUnits},
+		"batchSize":         {dataType: DATA_TYPE_INT},
+		"destinationAgent":  {dataType: DATA_TYPE_STRING},
+		"destinationQMgr":   {dataType: DATA_TYPE_STRING},
+		"destinationType":   {dataType: DATA_TYPE_STRING, enum: monitorDestinationTypes},
+		"destinationPath":   {dataType: DATA_TYPE_STRING},
+		"overwrite":         {dataType: DATA_TYPE_BOOL},
+		"checksum":          {dataType: DATA_TYPE_STRING, enum: monitorChecksums},
+		"sourceDisposition": {dataType: DATA_TYPE_STRING, enum: monitorSourceDispositions},
+		"mode":              {dataType: DATA_TYPE_STRING, enum: monitorModes},
+	},
+	required: []string{"name", "resource", "destinationAgent", "destinationPath"},
+}
+
+// Attributes of templates and scheduled transfers describing the files
+// transferred
+var transferSpecProperties = map[string]*configSchema{
+	"sourceAgent":       {dataType: DATA_TYPE_STRING},
+	"sourceQMgr":        {dataType: DATA_TYPE_STRING},
+	"destinationAgent":  {dataType: DATA_TYPE_STRING},
+	"destinationQMgr":   {dataType: DATA_TYPE_STRING},
+	"sourceFiles":       patternListSchema,
+	"sourceDisposition": {dataType: DATA_TYPE_STRING, enum: transferSourceDispositions},
+	"recursive":         {dataType: DATA_TYPE_BOOL},
+	"destinationType":   {dataType: DATA_TYPE_STRING, enum: transferDestinationTypes},
+	"destinationPath":   {dataType: DATA_TYPE_STRING},
+	"overwrite":         {dataType: DATA_TYPE_BOOL},
+	"mode":              {dataType: DATA_TYPE_STRING, enum: transferModes},
+	"checksum":          {dataType: DATA_TYPE_STRING, enum: transferChecksums},
+	"priority":          {dataType: DATA_TYPE_INT},
+	"jobName":           {dataType: DATA_TYPE_STRING},
+}
+
+// Schema of an element of templates or schedules array. Adds the given
+// attributes to those describing the files transferred.
+func transferSchema(properties map[string]*configSchema, required ...string) *configSchema {
+	schema := &configSchema{
+		dataType:   DATA_TYPE_OBJECT,
+		properties: make(map[string]*configSchema),
+		required:   append([]string{"name", "destinationAgent", "sourceFiles", "destinationPath"}, required...),
+	}
+	for name, property := range transferSpecProperties {
+		schema.properties[name] = property
+	}
+	for name, property := range properties {
+		schema.properties[name] = property
+	}
+	return schema
+}
+
+// Schema of an element of templates array
+var templateSchema = transferSchema(map[string]*configSchema{
+	"name": {dataType: DATA_TYPE_STRING},
+})
+
+// Schema of the repeat group of a scheduled transfer
+var repeatSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"interval":  {dataType: DATA_TYPE_STRING, enum: scheduleRepeatIntervals},
+		"frequency": {dataType: DATA_TYPE_INT},
+		"count":     {dataType: DATA_TYPE_INT},
+		"end":       {dataType: DATA_TYPE_STRING},
+	},
+	required: []string{"interval"},
+}
+
+// Schema of an element of schedules array
+var scheduleSchema = transferSchema(map[string]*configSchema{
+	"name":     {dataType: DATA_TYPE_STRING},
+	"start":    {dataType: DATA_TYPE_STRING},
+	"timeBase": {dataType: DATA_TYPE_STRING, enum: scheduleTimeBases},
+	"repeat":   repeatSchema,
+}, "start")
+
+// Schema of an element of protocolServers array, built from the properties of
+// protocol servers
+var protocolServerSchema = bridgeServerSchema()
+
+// Schema of a reference to a secret held in a file or an environment variable
+var secretRefSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"file": {dataType: DATA_TYPE_STRING},
+		"env":  {dataType: DATA_TYPE_STRING},
+	},
+}
+
+// Schema of credentials group of a protocol server
+var protocolServerCredentialsSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"userId":     {dataType: DATA_TYPE_STRING},
+		"password":   secretRefSchema,
+		"privateKey": secretRefSchema,
+		"hostKey":    secretRefSchema,
+		"userMappings": {dataType: DATA_TYPE_ARRAY, items: &configSchema{
+			dataType: DATA_TYPE_OBJECT,
+			properties: map[string]*configSchema{
+				"requester":  {dataType: DATA_TYPE_STRING},
+				"userId":     {dataType: DATA_TYPE_STRING},
+				"password":   secretRefSchema,
+				"privateKey": secretRefSchema,
+			},
+			required: []string{"requester", "userId"},
+		}},
+	},
+}
+
+// Schema of an element of cdNodes array
+var cdNodeSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"name":     {dataType: DATA_TYPE_STRING},
+		"host":     {dataType: DATA_TYPE_STRING},
+		"port":     {dataType: DATA_TYPE_INT},
+		"platform": {dataType: DATA_TYPE_STRING, enum: cdNodePlatforms},
+		"credentials": {
+			dataType: DATA_TYPE_OBJECT,
+			properties: map[string]*configSchema{
+				"userId":   {dataType: DATA_TYPE_STRING},
+				"password": secretRefSchema,
+			},
+			required: []string{"userId", "password"},
+		},
+	},
+	required: []string{"name"},
+}
+
+// Schema of jvm group of an agent
+var jvmSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"maxHeap":          {dataType: DATA_TYPE_STRING},
+		"initialHeap":      {dataType: DATA_TYPE_STRING},
+		"heapPercentage":   {dataType: DATA_TYPE_INT},
+		"gcPolicy":         {dataType: DATA_TYPE_STRING, enum: jvmGCPolicies},
+		"systemProperties": additionalPropertiesSchema,
+		"verboseGC":        {dataType: DATA_TYPE_BOOL},
+		"dumpPath":         {dataType: DATA_TYPE_STRING},
+		"options":          {dataType: DATA_TYPE_ARRAY, items: &configSchema{dataType: DATA_TYPE_STRING}},
+	},
+}
+
+// Schema of an element of agents array
+var agentSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"name":                            {dataType: DATA_TYPE_STRING},
+		"type":                            {dataType: DATA_TYPE_STRING, enum: []string{AGENT_TYPE_STANDARD, AGENT_TYPE_BRIDGE, AGENT_TYPE_CD}},
+		"qmgrName":                        {dataType: DATA_TYPE_STRING},
+		"qmgrHost":                        {dataType: DATA_TYPE_STRING},
+		"qmgrPort":                        {dataType: DATA_TYPE_INT},
+		"qmgrChannel":                     {dataType: DATA_TYPE_STRING},
+		"qmgrConnectionNameList":          {dataType: DATA_TYPE_STRING},
+		"qmgrCcdtUrl":                     {dataType: DATA_TYPE_STRING},
+		"qmgrCredentials":                 qmgrCredentialsSchema,
+		"deleteOnTermination":             {dataType: DATA_TYPE_BOOL},
+		"cleanOnStart":                    {dataType: DATA_TYPE_STRING, enum: []string{"transfers", "monitors", "scheduledTransfers", "invalidMessages", "all"}},
+		"defaultServer":                   bridgeGlobalProperties["defaultServer"].schema(),
+		"maxActiveDestinationTransfers":   bridgeGlobalProperties["maxActiveDestinationTransfers"].schema(),
+		"failTransferWhenCapacityReached": bridgeGlobalProperties["failTransferWhenCapacityReached"].schema(),
+		"protocolServers":                 {dataType: DATA_TYPE_ARRAY, items: protocolServerSchema},
+		"cdNodes":                         {dataType: DATA_TYPE_ARRAY, items: cdNodeSchema},
+		"cdTmpDir":                        {dataType: DATA_TYPE_STRING},
+		"sandboxes":                       {dataType: DATA_TYPE_ARRAY, items: sandboxSchema},
+		"monitors":                        {dataType: DATA_TYPE_ARRAY, items: monitorSchema},
+		"templates":                       {dataType: DATA_TYPE_ARRAY, items: templateSchema},
+		"schedules":                       {dataType: DATA_TYPE_ARRAY, items: scheduleSchema},
+		"jvm":                             jvmSchema,
+		"additionalProperties":            additionalPropertiesSchema,
+	},
+	// Host is not required if a connection name list or CCDT is specified
+	required: []string{"name", "qmgrName"},
+}
+
+// Schema of the entire configuration file
+var agentConfigFileSchema = &configSchema{
+	dataType: DATA_TYPE_OBJECT,
+	properties: map[string]*configSchema{
+		"waitTimeToStart":  {dataType: DATA_TYPE_INT},
+		"retryPolicy":      retryPolicySchema,
+		"coordinationQMgr": qmgrSchema,
+		"commandQMgr":      qmgrSchema,
+		"agents":           {dataType: DATA_TYPE_ARRAY, items: agentSchema},
+	},
+	required: []string{"coordinationQMgr", "commandQMgr", "agents"},
+}
+
+// Validate the contents of agent configuration file against the schema. Returns
+// one error for every violation found, in the order attributes appear in the file.
+func validateConfigurationSchema(jsonData string) []error {
+	if !gjson.Valid(jsonData) {
+		return []error{errors.New(utils.MFT_CONT_CFG_SCHEMA_INVALID_JSON_0079)}
+	}
+	return agentConfigFileSchema.validate("", gjson.Parse(jsonData))
+}
+
+// Validate the given value against the schema. Path is the JSON path of the value
+// and is used in the errors returned.
+func (schema *configSchema) validate(path string, value gjson.Result) []error {
+	var errs []error
+	if !isSchemaTypeMatch(schema.dataType, value) {
+		errs = append(errs, fmt.Errorf(utils.MFT_CONT_CFG_SCHEMA_TYPE_MISMATCH_0080,
+			displayPath(path), schemaTypeName(schema.dataType), schemaFoundValue(path, value)))
+		return errs
+	}
+
+	switch schema.dataType {
+	case DATA_TYPE_OBJECT:
+		for _, name := range schema.required {
+			if !value.Get(name).Exists() {
+				errs = append(errs, fmt.Errorf(utils.MFT_CONT_CFG_SCHEMA_MISSING_0081,
+					joinPath(path, name), schemaTypeName(schema.properties[name].dataType)))
+			}
+		}
+		value.ForEach(func(key, item gjson.Result) bool {
+			childPath := joinPath(path, key.String())
+			if schema.anyProperties {
+				// Value of a free form attribute must be a simple value.
+				if item.IsObject() || item.IsArray() {
+					errs = append(errs, fmt.Errorf(utils.MFT_CONT_CFG_SCHEMA_TYPE_MISMATCH_0080,
+						childPath, "string, number or boolean", schemaFoundValue(childPath, item)))
+				}
+			} else if childSchema, ok := schema.properties[key.String()]; ok {
+				errs = append(errs, childSchema.validate(childPath, item)...)
+			} else {
+				errs = append(errs, fmt.Errorf(utils.MFT_CONT_CFG_SCHEMA_UNKNOWN_0082,
+					childPath, strings.Join(sortedKeys(schema.properties), ", ")))
+			}
+			return true
+		})
+	case DATA_TYPE_ARRAY:
+		for index, item := range value.Array() {
+			errs = append(errs, schema.items.validate(fmt.Sprintf("%s[%d]", path, index), item)...)
+		}
+	case DATA_TYPE_STRING:
+		if len(schema.enum) > 0 && !isEnumValue(schema.enum, value.String()) {
+			errs = append(errs, fmt.Errorf(utils.MFT_CONT_CFG_SCHEMA_ENUM_0083,
+				path, strings.Join(schema.enum, ", "), schemaFoundValue(path, value)))
+		}
+	}
+	return errs
+}
+
+// Determine if the JSON value is of the type expected. Numbers and booleans
+// supplied as strings, for example "1414" or "true", are accepted as existing
+// configuration files use them and they are written to properties files as text.
+func isSchemaTypeMatch(dataType int, value gjson.Result) bool {
+	switch dataType {
+	case DATA_TYPE_STRING:
+		return value.Type == gjson.String
+	case DATA_TYPE_INT:
+		if value.Type == gjson.Number {
+			return value.Num == float64(int64(value.Num))
+		}
+		if value.Type == gjson.String {
+			isNum, _ := utils.IsNumeric(strings.TrimSpace(value.Str))
+			return isNum
+		}
+		return false
+	case DATA_TYPE_BOOL:
+		if value.Type == gjson.True || value.Type == gjson.False {
+			return true
+		}
+		return value.Type == gjson.String &&
+			(strings.EqualFold(strings.TrimSpace(value.Str), "true") || strings.EqualFold(strings.TrimSpace(value.Str), "false"))
+	case DATA_TYPE_OBJECT:
+		return value.IsObject()
+	case DATA_TYPE_ARRAY:
+		return value.IsArray()
+	}
+	return false
+}
+
+// Determine if the value is one of the allowed values
+func isEnumValue(enum []string, value string) bool {
+	for _, allowed := range enum {
+		if strings.EqualFold(allowed, strings.TrimSpace(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Name of the data type as displayed in error messages
+func schemaTypeName(dataType int) string {
+	switch dataType {
+	case DATA_TYPE_STRING:
+		return "string"
+	case DATA_TYPE_INT:
+		return "integer"
+	case DATA_TYPE_BOOL:
+		return "boolean"
+	case DATA_TYPE_OBJECT:
+		return "object"
+	case DATA_TYPE_ARRAY:
+		return "array"
+	}
+	return "unknown"
+}
+
+// Value found in the configuration file as displayed in error messages. Values
+// of password attributes are not displayed.
+func schemaFoundValue(path string, value gjson.Result) string {
+	if strings.Contains(strings.ToLower(path), "password") {
+		return "********"
+	}
+	if value.IsObject() {
+		return "an object"
+	}
+	if value.IsArray() {
+		return "an array"
+	}
+	return value.Raw
+}
+
+// Build the JSON path of a child attribute
+func joinPath(path string, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+// JSON path of the root is displayed as $
+func displayPath(path string) string {
+	if len(path) == 0 {
+		return "$"
+	}
+	return path
+}
+
+// Names of the attributes in sorted order
+func sortedKeys(properties map[string]*configSchema) []string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
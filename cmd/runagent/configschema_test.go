@@ -0,0 +1,124 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Sample configuration files shipped with the tests must pass validation
+func TestValidateConfigurationSchemaSampleFiles(t *testing.T) {
+	for _, fileName := range []string{"./data/test_agcfg.json", "./data/ocpagcfg.json"} {
+		allAgentConfig, e := utils.ReadConfigurationDataFromFile(fileName)
+		if e != nil {
+			t.Fatal(e)
+		}
+		errs := validateConfigurationSchema(allAgentConfig)
+		if len(errs) > 0 {
+			t.Errorf("Validation of %s failed: %v", fileName, errs)
+		}
+	}
+}
+
+func TestValidateConfigurationSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name:     "invalid json",
+			config:   "{\"coordinationQMgr\":{",
+			expected: []string{utils.MFT_CONT_CFG_SCHEMA_INVALID_JSON_0079},
+		},
+		{
+			name:   "misspelt command queue manager",
+			config: "{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"commandsQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[]}",
+			expected: []string{
+				"Required attribute 'commandQMgr' of type object is missing.",
+				"Attribute 'commandsQMgr' is not a valid attribute.",
+			},
+		},
+		{
+			name:     "port not a number",
+			config:   "{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[{\"name\":\"SRC\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"qmgrPort\":\"abc\"}]}",
+			expected: []string{"Attribute 'agents[0].qmgrPort' must be of type integer but found \"abc\"."},
+		},
+		{
+			name:     "unknown protocol server attribute",
+			config:   "{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[{\"name\":\"PBA\",\"type\":\"BRIDGE\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"protocolServers\":[{\"name\":\"sftp\",\"type\":\"SFTP\",\"hostname\":\"sftp.ibm.com\"}]}]}",
+			expected: []string{"Attribute 'agents[0].protocolServers[0].hostname' is not a valid attribute."},
+		},
+		{
+			name:     "invalid enumeration value",
+			config:   "{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[{\"name\":\"SRC\",\"type\":\"SFTP\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\"}]}",
+			expected: []string{"Attribute 'agents[0].type' must be one of STANDARD, BRIDGE, CD but found \"SFTP\"."},
+		},
+		{
+			name:     "invalid jvm attributes",
+			config:   "{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[{\"name\":\"SRC\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"jvm\":{\"heapPercentage\":\"half\",\"options\":\"-Xshareclasses:none\"}}]}",
+			expected: []string{"Attribute 'agents[0].jvm.heapPercentage' must be of type integer but found \"half\".", "Attribute 'agents[0].jvm.options' must be of type array"},
+		},
+		{
+			name:     "password value not displayed",
+			config:   "{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\",\"qmgrCredentials\":{\"mqUserId\":\"app\",\"mqPassword\":12345}},\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[]}",
+			expected: []string{"Attribute 'coordinationQMgr.qmgrCredentials.mqPassword' must be of type string but found ********."},
+		},
+		{
+			name:     "nested additional properties",
+			config:   "{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[{\"name\":\"SRC\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"additionalProperties\":{\"trace\":{\"level\":\"all\"}}}]}",
+			expected: []string{"Attribute 'agents[0].additionalProperties.trace' must be of type string, number or boolean but found an object."},
+		},
+	}
+
+	for _, test := range tests {
+		errs := validateConfigurationSchema(test.config)
+		if len(errs) != len(test.expected) {
+			t.Errorf("%s: expected %d errors, got %d: %v", test.name, len(test.expected), len(errs), errs)
+			continue
+		}
+		for i, expected := range test.expected {
+			if !strings.HasPrefix(errs[i].Error(), expected) {
+				t.Errorf("%s: expected error starting with %q, got %q", test.name, expected, errs[i].Error())
+			}
+		}
+	}
+}
+
+// Configuration built from environment variables goes through the same validation
+func TestValidateConfigurationSchemaEnvironment(t *testing.T) {
+	configData, err := config.FromEnvironment([]string{"MFT_AGENT_NAME=SRC", "MFT_AGENT_QMGR_NAME=QM1", "MFT_AGENT_QMGR_HOST=localhost",
+		"MFT_AGENT_QMGR_PORT=1414", "MFT_AGENT_DELETE_ON_TERMINATION=false", "MFT_AGENT_PROP_trace=all",
+		"MFT_COORD_QMGR_NAME=QM1", "MFT_COORD_QMGR_HOST=localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := validateConfigurationSchema(configData); len(errs) > 0 {
+		t.Errorf("Validation of configuration built from environment variables failed: %v", errs)
+	}
+
+	configData, err = config.FromEnvironment([]string{"MFT_AGENT_NAME=SRC", "MFT_AGENT_QMGR_PORT=high", "MFT_COORD_QMGR_NAME=QM1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := validateConfigurationSchema(configData); len(errs) == 0 {
+		t.Error("Invalid port set by environment variable passed validation")
+	}
+}
@@ -20,16 +20,15 @@ package main
 * configuration.
  */
 import (
-	"bytes"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/user"
 	"strconv"
 	"strings"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
 	"github.com/subchen/go-xmldom"
 	"github.com/tidwall/gjson"
@@ -37,8 +36,8 @@ import (
 
 // Update coordination and command properties file with any additional properties specified in
 // configuration JSON file.
-func UpdateProperties(propertiesFile string, agentConfig string, sectionName string) error {
-	f, err := os.OpenFile(propertiesFile, os.O_APPEND|os.O_WRONLY, 0644)
+func UpdateProperties(propertiesFile string, properties config.Properties) error {
+	f, err := os.OpenFile(propertiesFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		errorMsg := fmt.Sprintf(utils.MFT_CONT_ERR_OPN_FILE_0067, propertiesFile, err)
 		return errors.New(errorMsg)
@@ -46,20 +45,18 @@ func UpdateProperties(propertiesFile string, agentConfig string, sectionName str
 	defer f.Close()
 
 	// Iterate throw the given attributes and updathe the specified properties file
-	if gjson.Get(agentConfig, sectionName).Exists() {
+	if len(properties) > 0 {
 		// Write a new line character before updating properties
-		result := gjson.Get(agentConfig, sectionName)
 		if _, err := f.WriteString("\n"); err != nil {
 			errorMsg := fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err)
 			return errors.New(errorMsg)
 		}
-		result.ForEach(func(key, value gjson.Result) bool {
-			if _, err := f.WriteString(key.String() + "=" + value.String() + "\n"); err != nil {
+		for _, property := range properties {
+			if _, err := f.WriteString(property.Name + "=" + property.Value + "\n"); err != nil {
 				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, propertiesFile, err))
-				return false // break if an error occurs.
+				break // break if an error occurs.
 			}
-			return true // keep iterating
-		})
+		}
 	}
 	return nil
 }
@@ -90,8 +87,9 @@ func GetCommandTracePath() string {
 	return TEXT_BLANK
 }
 
-// Setup userSandBox configuration to restrict access to file system
-func createUserSandbox(sandboxXmlFileName string) error {
+// Setup userSandBox configuration to restrict access to file system. The
+// default sandbox is written if no sandboxes are specified.
+func createUserSandbox(sandboxXmlFileName string, sandboxes []*config.Sandbox) error {
 	var errCusbox error = nil
 
 	// Open existing UserSandboxes.xml file
@@ -104,55 +102,15 @@ func createUserSandbox(sandboxXmlFileName string) error {
 	// defer the closing of our xml file so that we can parse it later on
 	defer userSandBoxXmlFile.Close()
 
-	// Set sandBoxRoot for standard agents to restrict the file system access. Use the value specified in
-	// MFT_MOUNT_PATH environment variable if available else use the default "/mountpath" folder.
-	// Agent will be able to read from or write to this folder and it will not have access to other parts
-	// of the file system.
-	var transferRootPath string = DEFAULT_MOUNT_PATH_FOR_TRANSFERS
-	mountPathEnv, mountPathEnvSet := os.LookupEnv(MFT_MOUNT_PATH)
-	if mountPathEnvSet {
-		mountPathEnv = strings.TrimSpace(mountPathEnv)
-		if len(mountPathEnv) > 0 {
-			//If the supplied path does not have /** suffix, then add it
-			if !strings.HasSuffix(mountPathEnv, "/**") {
-				if strings.HasSuffix(mountPathEnv, "/*") {
-					transferRootPath = mountPathEnv + "*"
-				} else if strings.HasSuffix(mountPathEnv, "/") {
-					transferRootPath = mountPathEnv + "**"
-				} else {
-					transferRootPath = mountPathEnv + "/**"
-				}
-			} else {
-				transferRootPath = mountPathEnv
-			}
-		}
+	// Agent will be able to read from or write to only the paths and queues
+	// included in sandboxes and it will not have access to other parts of the
+	// file system.
+	if len(sandboxes) == 0 {
+		sandboxes = defaultSandboxes()
 	}
+	sandBoxDoc := buildSandboxDocument(sandboxes)
 
-	sandBoxDoc := xmldom.NewDocument("tns:userSandboxes")
-	sandBoxDoc.Root.SetAttributeValue("xmlns:tns", "http://wmqfte.ibm.com/UserSandboxes")
-	sandBoxDoc.Root.SetAttributeValue("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
-	sandBoxDoc.Root.SetAttributeValue("xsi:schemaLocation", "http://wmqfte.ibm.com/UserSandboxes UserSandboxes.xsd")
-	agentNode := sandBoxDoc.Root.CreateNode("tns:agent")
-	sandBoxNode := agentNode.CreateNode("tns:sandbox")
-	// Override sandbox user name and pattern.
-	sandBoxNode.SetAttributeValue("user", "^[a-zA-Z0-9]*$")
-	sandBoxNode.SetAttributeValue("userPattern", "regex")
-	// Read Sandbox Attributes
-	readNode := sandBoxNode.CreateNode("tns:read")
-	includeNodeRead := readNode.CreateNode("tns:include")
-	includeNodeRead.SetAttributeValue("name", transferRootPath)
-	includeNodeReadQueue := readNode.CreateNode("tns:include")
-	includeNodeReadQueue.SetAttributeValue("name", "**")
-	includeNodeReadQueue.SetAttributeValue("type", "queue")
-	// Write Sandbox attributes
-	writeNode := sandBoxNode.CreateNode("tns:write")
-	includeWriteNode := writeNode.CreateNode("tns:include")
-	includeWriteNode.SetAttributeValue("name", transferRootPath)
-	includeWriteNodeQueue := writeNode.CreateNode("tns:include")
-	includeWriteNodeQueue.SetAttributeValue("name", "**")
-	includeWriteNodeQueue.SetAttributeValue("type", "queue")
-
-	if logLevel >= LOG_LEVEL_VERBOSE {
+	if logLevel >= LOG_LEVEL_VERBOSE || dryRunEnabled {
 		utils.PrintLog(sandBoxDoc.XMLPretty())
 	}
 
@@ -175,6 +133,11 @@ func createUserSandbox(sandboxXmlFileName string) error {
 * be in plain text while the password to be base64 encoded.
  */
 func setupCredentials(mqmftCredentialsXmlFileName string, bufferCred string) error {
+	// Secrets are not written to disk in dry-run mode
+	if dryRunEnabled {
+		bufferCred = utils.RedactSecrets(bufferCred)
+	}
+
 	// Create an empty credentials file, truncate if one exists
 	mqmftCredentialsXmlFile, err := os.OpenFile(mqmftCredentialsXmlFileName, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
 	// if we os.Open returns an error then handle it
@@ -201,7 +164,7 @@ func setupCredentials(mqmftCredentialsXmlFileName string, bufferCred string) err
 /**
 * Update XML data with credentials of queue manager
  */
-func UpdateXmlWithQmgrCredentials(xmlWriter *xmldom.Document, configData string, qmName string) error {
+func UpdateXmlWithQmgrCredentials(xmlWriter *xmldom.Document, credentials *config.Credentials, qmName string) error {
 	var mqUserId string
 	var mqPassword string
 	var err error
@@ -209,10 +172,9 @@ func UpdateXmlWithQmgrCredentials(xmlWriter *xmldom.Document, configData string,
 	var plainTextPassword string
 	var errReturn error = nil
 
-	if gjson.Get(configData, "mqUserId").Exists() &&
-		gjson.Get(configData, "mqPassword").Exists() {
-		mqUserId = strings.TrimSpace(gjson.Get(configData, "mqUserId").String())
-		mqPassword = strings.TrimSpace(gjson.Get(configData, "mqPassword").String())
+	if credentials != nil {
+		mqUserId = strings.TrimSpace(credentials.UserId)
+		mqPassword = strings.TrimSpace(credentials.Password)
 
 		if len(mqUserId) > 0 && len(mqPassword) > 0 {
 			// Decode the password from base64 format
@@ -226,6 +188,7 @@ func UpdateXmlWithQmgrCredentials(xmlWriter *xmldom.Document, configData string,
 			} else {
 				plainTextPassword = mqPassword
 			}
+			utils.AddSecretValue(mqPassword)
 
 			// Get the current process user id
 			user, err := user.Current()
@@ -259,6 +222,7 @@ func UpdateXmlWithQmgrCredentials(xmlWriter *xmldom.Document, configData string,
  */
 func UpdateXmlWithKeyStoreCredentials(xmlWriter *xmldom.Document, trustStore string, trustStorePassword string) {
 	if len(trustStore) > 0 && len(trustStorePassword) > 0 {
+		utils.AddSecretValue(trustStorePassword)
 		childNode := xmlWriter.Root.CreateNode("tns:file")
 		childNode.SetAttributeValue("path", trustStore)
 		childNode.SetAttributeValue("password", trustStorePassword)
@@ -282,39 +246,18 @@ func InitializeCredentialsDocumentWriter() *xmldom.Document {
 * Returns error if the method fails to encrypt the file.
  */
 func EncryptCredentialsFile(credentialsFile string) error {
-	var outb, errb bytes.Buffer
 	if logLevel >= LOG_LEVEL_VERBOSE {
 		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CRED_ENCRYPTING_0058, credentialsFile))
 	}
 
-	// Get the path of MFT fteObfuscate command.
-	cmdObfuscatePath, lookErr := exec.LookPath("fteObfuscate")
-	if lookErr != nil {
-		return lookErr
-	}
-
-	var cmdArgs []string
-	cmdArgs = append(cmdArgs, cmdObfuscatePath, "-f", credentialsFile)
-	if commandTracingEnabled {
-		cmdArgs = append(cmdArgs, "-trace", "com.ibm.wmqfte=all")
-		cmdTracePath := GetCommandTracePath()
-		if len(cmdTracePath) > 0 {
-			cmdArgs = append(cmdArgs, "-tracePath", cmdTracePath)
-		}
+	// Encrypt the credentials file with default key. Log an error in case of
+	// any error.
+	result := runFteCommand("fteObfuscate", "-f", credentialsFile)
+	if result.notFound() {
+		return result.err
 	}
-
-	// Encrypt the credentials file with default key
-	cmdObfucateCmd := &exec.Cmd{
-		Path: cmdObfuscatePath,
-		Args: cmdArgs,
-	}
-
-	// Reuse the same buffer
-	cmdObfucateCmd.Stdout = &outb
-	cmdObfucateCmd.Stderr = &errb
-	// Execute the fteObfuscate command. Log an error an exit in case of any error.
-	if err := cmdObfucateCmd.Run(); err != nil {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
+	if result.failed() {
+		result.logFailure()
 	} else {
 		if logLevel >= LOG_LEVEL_VERBOSE {
 			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CRED_ENCRYPTED_0059, credentialsFile))
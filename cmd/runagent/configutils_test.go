@@ -21,33 +21,31 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
-	"github.com/tidwall/gjson"
 )
 
 func TestUpdateAgentProperties(t *testing.T) {
-	allAgentConfig, e := utils.ReadConfigurationDataFromFile("./data/test_agcfg.json")
+	allAgentConfig, e := config.Load("./data/test_agcfg.json")
 	if e != nil {
 		t.Fatal(e)
 	}
 
-	propFileName := "./data/agent.properties"
-	singleAgentConfig := gjson.Get(allAgentConfig, "agents").Array()[0].String()
-	err := UpdateProperties(propFileName, singleAgentConfig, "additionalProperties")
+	propFileName := copyTestFile(t, "agent.properties")
+	additionalProperties := allAgentConfig.Agents[0].AdditionalProperties
+	err := UpdateProperties(propFileName, additionalProperties)
 	if err == nil {
 		updatedAgentPropFileContent, err := utils.ReadConfigurationDataFromFile(propFileName)
 		if err == nil {
-			result := gjson.Get(singleAgentConfig, "additionalProperties")
-			result.ForEach(func(key, value gjson.Result) bool {
-				searchToken := key.String() + "=" + value.String()
+			for _, property := range additionalProperties {
+				searchToken := property.Name + "=" + property.Value
 				if !strings.Contains(updatedAgentPropFileContent, searchToken) {
 					t.Log("TestUpdateAgentProperties - failed: Contents don't match.")
 					fmt.Printf("Updated agent properties: \n===\n%v\n===\n", updatedAgentPropFileContent)
 					t.Fail()
-					return false
+					break
 				}
-				return true // keep iterating
-			})
+			}
 			t.Log("Test TestUpdateAgentProperties passed as expected")
 		} else {
 			t.Log("TestUpdateAgentProperties - failed: Failed to read updated agent properties file.")
@@ -63,7 +61,7 @@ func TestUpdateAgentProperties(t *testing.T) {
 
 func TestCreateUserSandbox(t *testing.T) {
 	userSandboxFile := "UserSandbox.xml"
-	userSBoxErr := createUserSandbox(userSandboxFile)
+	userSBoxErr := createUserSandbox(userSandboxFile, nil)
 	if userSBoxErr == nil {
 		sandBoxContents, _ := utils.ReadConfigurationDataFromFile(userSandboxFile)
 		if strings.Contains(sandBoxContents, DEFAULT_MOUNT_PATH_FOR_TRANSFERS) {
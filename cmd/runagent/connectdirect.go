@@ -0,0 +1,211 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Connect:Direct bridge agents. The agent is created with fteCreateCDAgent
+* for the first node of the cdNodes section of the agent configuration. The
+* ConnectDirectNodeProperties.xml file of the agent lists the platform of
+* every node and ConnectDirectCredentials.xml file holds the user ids and
+* passwords of nodes. Passwords are secret references resolved when the
+* credentials file is written.
+ */
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/subchen/go-xmldom"
+)
+
+// Platforms of Connect:Direct nodes
+var cdNodePlatforms = []string{"windows", "unix", "zos"}
+
+// Append the parameters of fteCreateCDAgent describing the node the agent
+// connects to. Port defaults to the default port of fteCreateCDAgent.
+func updateCDAgentParameters(agent *config.Agent, params []string) []string {
+	node := agent.CDNodes[0]
+	params = append(params, "-cdNode", node.Name, "-cdNodeHost", node.Host)
+	if node.Port != nil {
+		params = append(params, "-cdNodePort", node.Port.String())
+	}
+	if len(agent.CDTmpDir) > 0 {
+		params = append(params, "-cdTmpDir", agent.CDTmpDir)
+	}
+	return params
+}
+
+// Build ConnectDirectNodeProperties.xml document. Nodes whose platform is not
+// specified are left to the defaults of the agent.
+func buildCDNodePropertiesDocument(nodes []*config.CDNode, credentialsFile string) *xmldom.Document {
+	nodesDoc := xmldom.NewDocument("tns:nodeProperties")
+	nodesDoc.Root.SetAttributeValue("xmlns:tns", "http://wmqfte.ibm.com/ConnectDirectNodeProperties")
+	nodesDoc.Root.SetAttributeValue("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
+	nodesDoc.Root.SetAttributeValue("xsi:schemaLocation", "http://wmqfte.ibm.com/ConnectDirectNodeProperties ConnectDirectNodeProperties.xsd")
+	nodesDoc.Root.CreateNode("tns:credentialsFile").SetAttributeValue("path", credentialsFile)
+	for _, node := range nodes {
+		if len(node.Platform) == 0 {
+			continue
+		}
+		nodeNode := nodesDoc.Root.CreateNode("tns:node")
+		nodeNode.SetAttributeValue("name", node.Name)
+		nodeNode.SetAttributeValue("pattern", "wildcard")
+		nodeNode.SetAttributeValue("type", strings.ToLower(node.Platform))
+	}
+	return nodesDoc
+}
+
+// Build ConnectDirectCredentials.xml document. Every user requesting a
+// transfer connects to the first node with its credentials and to remote
+// nodes with their credentials.
+func buildCDCredentialsDocument(agentName string, nodes []*config.CDNode) (*xmldom.Document, error) {
+	credentialsDoc := xmldom.NewDocument("tns:credentials")
+	credentialsDoc.Root.SetAttributeValue("xmlns:tns", "http://wmqfte.ibm.com/ConnectDirectCredentials")
+	credentialsDoc.Root.SetAttributeValue("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
+	credentialsDoc.Root.SetAttributeValue("xsi:schemaLocation", "http://wmqfte.ibm.com/ConnectDirectCredentials ConnectDirectCredentials.xsd")
+	agentNode := credentialsDoc.Root.CreateNode("tns:agent")
+	agentNode.SetAttributeValue("name", agentName)
+
+	bridgeNode := nodes[0]
+	password, err := resolveSecret(bridgeNode.Credentials.Password)
+	if err != nil {
+		return nil, fmt.Errorf("password of node %s: %v", bridgeNode.Name, err)
+	}
+	pnodeNode := agentNode.CreateNode("tns:pnode")
+	pnodeNode.SetAttributeValue("name", bridgeNode.Name)
+	pnodeNode.SetAttributeValue("pattern", "wildcard")
+	userNode := pnodeNode.CreateNode("tns:user")
+	userNode.SetAttributeValue("name", "*")
+	userNode.SetAttributeValue("pattern", "wildcard")
+	userNode.SetAttributeValue("cdUserId", bridgeNode.Credentials.UserId)
+	userNode.SetAttributeValue("cdPassword", password)
+
+	for _, node := range nodes[1:] {
+		if node.Credentials == nil {
+			continue
+		}
+		password, err := resolveSecret(node.Credentials.Password)
+		if err != nil {
+			return nil, fmt.Errorf("password of node %s: %v", node.Name, err)
+		}
+		snodeNode := userNode.CreateNode("tns:snode")
+		snodeNode.SetAttributeValue("name", node.Name)
+		snodeNode.SetAttributeValue("pattern", "wildcard")
+		snodeNode.SetAttributeValue("userId", node.Credentials.UserId)
+		snodeNode.SetAttributeValue("password", password)
+	}
+	return credentialsDoc, nil
+}
+
+// Write ConnectDirectNodeProperties.xml and ConnectDirectCredentials.xml files
+// in the configuration directory of the agent. The credentials file can be
+// read only by the container user and is encrypted.
+func createCDNodeFiles(agentPath string, agent *config.Agent) bool {
+	credentialsFile := agentPath + MFT_CD_CRED_SLASH
+	credentialsDoc, err := buildCDCredentialsDocument(agent.Name, agent.CDNodes)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CD_CONFIG_FAILED_0147, credentialsFile, err))
+		return false
+	}
+	credentialsXml := credentialsDoc.XMLPrettyEx("  ")
+	// Secrets are not written to disk in dry-run mode
+	if dryRunEnabled {
+		credentialsXml = utils.RedactSecrets(credentialsXml)
+	}
+	if err := utils.WriteSecretFile(credentialsFile, []byte(credentialsXml)); err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CD_CONFIG_FAILED_0147, credentialsFile, err))
+		return false
+	}
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(credentialsXml)
+	}
+	EncryptCredentialsFile(credentialsFile)
+
+	nodePropertiesFile := agentPath + MFT_CD_NODE_PROPS_SLASH
+	nodePropertiesXml := buildCDNodePropertiesDocument(agent.CDNodes, credentialsFile).XMLPrettyEx("  ")
+	if logLevel >= LOG_LEVEL_VERBOSE || dryRunEnabled {
+		utils.PrintLog(nodePropertiesXml)
+	}
+	if err := utils.WriteData(nodePropertiesFile, nodePropertiesXml); err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CD_CONFIG_FAILED_0147, nodePropertiesFile, err))
+		return false
+	}
+	return true
+}
+
+// Validate the Connect:Direct nodes of an agent. Nodes are required for
+// Connect:Direct bridge agents and not valid for other agents.
+func validateCDNodes(agent *config.Agent) error {
+	if !strings.EqualFold(agent.Type, AGENT_TYPE_CD) {
+		if len(agent.CDNodes) > 0 || len(agent.CDTmpDir) > 0 {
+			return fmt.Errorf("cdNodes and cdTmpDir are valid only for %s agents", AGENT_TYPE_CD)
+		}
+		return nil
+	}
+	if len(agent.CDNodes) == 0 {
+		return fmt.Errorf("cdNodes must specify the node the agent connects to")
+	}
+	names := make(map[string]bool)
+	for index, node := range agent.CDNodes {
+		if err := validateCDNode(node, index == 0); err != nil {
+			return fmt.Errorf("cdNodes[%d] is not valid, %v", index, err)
+		}
+		name := strings.ToUpper(node.Name)
+		if names[name] {
+			return fmt.Errorf("cdNodes[%d] is not valid, node %s is defined more than once", index, node.Name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// Validate a Connect:Direct node. The node the agent connects to must have a
+// host and credentials and its name must not contain wildcards.
+func validateCDNode(node *config.CDNode, bridgeNode bool) error {
+	if len(strings.TrimSpace(node.Name)) == 0 {
+		return fmt.Errorf("name must be specified")
+	}
+	if strings.ContainsAny(node.Name, " \t") {
+		return fmt.Errorf("name %q must not contain blanks", node.Name)
+	}
+	if bridgeNode {
+		if strings.ContainsAny(node.Name, "*?") {
+			return fmt.Errorf("name %q of the node the agent connects to must not contain wildcards", node.Name)
+		}
+		if len(strings.TrimSpace(node.Host)) == 0 {
+			return fmt.Errorf("host of the node the agent connects to must be specified")
+		}
+		if node.Credentials == nil {
+			return fmt.Errorf("credentials of the node the agent connects to must be specified")
+		}
+	}
+	if node.Port != nil && (int(*node.Port) < 1 || int(*node.Port) > 65535) {
+		return fmt.Errorf("port has value %d which is not within range 1 to 65535", int(*node.Port))
+	}
+	if len(node.Platform) > 0 && !isEnumValue(cdNodePlatforms, node.Platform) {
+		return fmt.Errorf("platform %q is not one of %s", node.Platform, strings.Join(cdNodePlatforms, ", "))
+	}
+	if node.Credentials != nil {
+		if len(strings.TrimSpace(node.Credentials.UserId)) == 0 {
+			return fmt.Errorf("credentials userId must be specified")
+		}
+		if err := validateSecretRef(node.Credentials.Password); err != nil {
+			return fmt.Errorf("credentials password %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+func TestUpdateCDAgentParameters(t *testing.T) {
+	agent := loadTestConfig(t, "test_cd.json").Agent("CDAGENT")
+	params := updateCDAgentParameters(agent, []string{"-p", "QM1"})
+	expected := []string{"-p", "QM1", "-cdNode", "CDBRIDGE", "-cdNodeHost", "cd.example.com", "-cdNodePort", "1364", "-cdTmpDir", "/mountpath/cdtmp"}
+	if !reflect.DeepEqual(params, expected) {
+		t.Errorf("Unexpected parameters %v", params)
+	}
+}
+
+func TestBuildCDDocuments(t *testing.T) {
+	t.Setenv("CD_PARTNER_PASSWORD", "PartnerPassw0rd")
+	agent := loadTestConfig(t, "test_cd.json").Agent("CDAGENT")
+	credentialsDoc, err := buildCDCredentialsDocument(agent.Name, agent.CDNodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentialsXml := credentialsDoc.XMLPretty()
+	for _, element := range []string{
+		`<tns:agent name="CDAGENT">`,
+		`<tns:pnode name="CDBRIDGE" pattern="wildcard">`,
+		`<tns:user name="*" pattern="wildcard" cdUserId="cduser" cdPassword="BridgePassw0rd">`,
+		`<tns:snode name="PARTNER*" pattern="wildcard" userId="partner" password="PartnerPassw0rd" />`,
+	} {
+		if !strings.Contains(credentialsXml, element) {
+			t.Errorf("Expected %s in credentials XML:\n%s", element, credentialsXml)
+		}
+	}
+	if strings.Contains(credentialsXml, "WINNODE") {
+		t.Errorf("Node without credentials written to credentials XML:\n%s", credentialsXml)
+	}
+
+	nodesXml := buildCDNodePropertiesDocument(agent.CDNodes, "/mnt/agent/ConnectDirectCredentials.xml").XMLPretty()
+	for _, element := range []string{
+		`<tns:credentialsFile path="/mnt/agent/ConnectDirectCredentials.xml" />`,
+		`<tns:node name="CDBRIDGE" pattern="wildcard" type="unix" />`,
+		`<tns:node name="PARTNER*" pattern="wildcard" type="zos" />`,
+		`<tns:node name="WINNODE" pattern="wildcard" type="windows" />`,
+	} {
+		if !strings.Contains(nodesXml, element) {
+			t.Errorf("Expected %s in node properties XML:\n%s", element, nodesXml)
+		}
+	}
+
+	os.Unsetenv("CD_PARTNER_PASSWORD")
+	if _, err := buildCDCredentialsDocument(agent.Name, agent.CDNodes); err == nil ||
+		err.Error() != "password of node PARTNER*: environment variable CD_PARTNER_PASSWORD is not set" {
+		t.Errorf("Unexpected error for missing secret: %v", err)
+	}
+}
+
+func TestValidateCDNodes(t *testing.T) {
+	if err := validateCDNodes(loadTestConfig(t, "test_cd.json").Agent("CDAGENT")); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	password := &config.SecretRef{File: "/etc/secrets/cd"}
+	credentials := &config.CDNodeCredentials{UserId: "cduser", Password: password}
+	port := config.Int(0)
+	tests := []struct {
+		agent *config.Agent
+		err   string
+	}{
+		{&config.Agent{Name: "STD", Type: AGENT_TYPE_STANDARD, CDNodes: []*config.CDNode{{Name: "N"}}},
+			"cdNodes and cdTmpDir are valid only for CD agents"},
+		{&config.Agent{Name: "CD", Type: AGENT_TYPE_CD},
+			"cdNodes must specify the node the agent connects to"},
+		{&config.Agent{Name: "CD", Type: AGENT_TYPE_CD, CDNodes: []*config.CDNode{{Name: "N*", Host: "h", Credentials: credentials}}},
+			"cdNodes[0] is not valid, name \"N*\" of the node the agent connects to must not contain wildcards"},
+		{&config.Agent{Name: "CD", Type: AGENT_TYPE_CD, CDNodes: []*config.CDNode{{Name: "N", Credentials: credentials}}},
+			"cdNodes[0] is not valid, host of the node the agent connects to must be specified"},
+		{&config.Agent{Name: "CD", Type: AGENT_TYPE_CD, CDNodes: []*config.CDNode{{Name: "N", Host: "h"}}},
+			"cdNodes[0] is not valid, credentials of the node the agent connects to must be specified"},
+		{&config.Agent{Name: "CD", Type: AGENT_TYPE_CD, CDNodes: []*config.CDNode{{Name: "N", Host: "h", Credentials: credentials, Port: &port}}},
+			"cdNodes[0] is not valid, port has value 0 which is not within range 1 to 65535"},
+		{&config.Agent{Name: "CD", Type: AGENT_TYPE_CD, CDNodes: []*config.CDNode{{Name: "N", Host: "h", Credentials: credentials, Platform: "os400"}}},
+			"cdNodes[0] is not valid, platform \"os400\" is not one of windows, unix, zos"},
+		{&config.Agent{Name: "CD", Type: AGENT_TYPE_CD, CDNodes: []*config.CDNode{{Name: "N", Host: "h",
+			Credentials: &config.CDNodeCredentials{UserId: "cduser", Password: &config.SecretRef{File: "/etc/secrets/cd", Env: "CD"}}}}},
+			"cdNodes[0] is not valid, credentials password must not specify both file and env"},
+		{&config.Agent{Name: "CD", Type: AGENT_TYPE_CD, CDNodes: []*config.CDNode{{Name: "N", Host: "h", Credentials: credentials}, {Name: "n"}}},
+			"cdNodes[1] is not valid, node n is defined more than once"},
+	}
+	for _, test := range tests {
+		err := validateCDNodes(test.agent)
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Expected error %q, found %v", test.err, err)
+		}
+	}
+}
+
+func TestDryRunSetupCDAgent(t *testing.T) {
+	dryRunEnabled = true
+	dryRunCommands = nil
+	runner = &dryRunCommandRunner{}
+	defer func() {
+		dryRunEnabled = false
+		dryRunCommands = nil
+		runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second)
+	}()
+
+	t.Setenv("CD_PARTNER_PASSWORD", "PartnerPassw0rd")
+	agent := loadTestConfig(t, "test_cd.json").Agent("CDAGENT")
+	outputDir := t.TempDir()
+	if err := createDryRunLayout(outputDir, "QM1", agent.Name); err != nil {
+		t.Fatal(err)
+	}
+	if created, _ := setupAgent(agent, outputDir, "QM1"); !created {
+		t.Fatal("Connect:Direct agent setup failed in dry-run mode")
+	}
+	if len(dryRunCommands) == 0 || !strings.HasPrefix(dryRunCommands[0], "fteCreateCDAgent -p QM1 -agentName CDAGENT") ||
+		!strings.HasSuffix(dryRunCommands[0], "-f -cdNode CDBRIDGE -cdNodeHost cd.example.com -cdNodePort 1364 -cdTmpDir /mountpath/cdtmp") {
+		t.Errorf("Unexpected commands recorded: %v", dryRunCommands)
+	}
+
+	agentDir := filepath.Join(outputDir, "mqft", "config", "QM1", "agents", "CDAGENT")
+	credentialsFile := filepath.Join(agentDir, "ConnectDirectCredentials.xml")
+	credentials, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(credentials), "BridgePassw0rd") || strings.Contains(string(credentials), "PartnerPassw0rd") {
+		t.Errorf("Credentials file contains password in dry-run mode: %s", credentials)
+	}
+	if info, err := os.Stat(credentialsFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Unexpected permissions of credentials file: %v %v", info.Mode(), err)
+	}
+	if _, err := os.Stat(filepath.Join(agentDir, "ConnectDirectNodeProperties.xml")); err != nil {
+		t.Errorf("Node properties not written: %v", err)
+	}
+	properties, err := os.ReadFile(filepath.Join(agentDir, "agent.properties"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(properties), "userSandboxes") || strings.Contains(string(properties), "protocolBridgeCredentialExitClasses") {
+		t.Errorf("Unexpected properties for Connect:Direct agent:\n%s", properties)
+	}
+}
@@ -0,0 +1,398 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Connections to coordination, command and agent queue managers. A queue
+* manager is connected to with a host and port, a connection name list such
+* as "host1(1414),host2(1414)" for multi-instance or HA queue managers, or a
+* client channel definition table (CCDT). MFT reads a connection name list
+* from a CCDT only, so a JSON CCDT is generated for a connection name list and
+* the *CcdtUrl property of the queue manager is set to the CCDT.
+ */
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Connection to the queue manager of a role
+type qmgrConnection struct {
+	// Use of the queue manager, for example "coordination" or "agent SRC"
+	role     string
+	qmgrName string
+	host     string
+	port     int
+	channel  string
+	// Comma separated list of host(port) entries
+	connectionNameList string
+	// Path or URL of a CCDT
+	ccdtUrl string
+	// Prefix of properties of the queue manager and names of fte command
+	// parameters, for example "coordination"
+	propertyPrefix string
+	cipherEnv      string
+	certPath       string
+}
+
+// Network address of a queue manager listener
+type qmgrAddress struct {
+	host string
+	port int
+}
+
+// Connection to the coordination queue manager
+func coordinationConnection(qmgr *config.QueueManager) *qmgrConnection {
+	return &qmgrConnection{
+		role:               "coordination",
+		qmgrName:           qmgr.Name,
+		host:               qmgr.Host,
+		port:               int(qmgr.Port),
+		channel:            qmgr.Channel,
+		connectionNameList: qmgr.ConnectionNameList,
+		ccdtUrl:            qmgr.CcdtUrl,
+		propertyPrefix:     PROPERTY_PREFIX_COORDINATION,
+		cipherEnv:          MFT_COORD_QMGR_CIPHER,
+		certPath:           coordinationQMCertPath,
+	}
+}
+
+// Connection to the command queue manager
+func commandConnection(qmgr *config.QueueManager) *qmgrConnection {
+	return &qmgrConnection{
+		role:               "command",
+		qmgrName:           qmgr.Name,
+		host:               qmgr.Host,
+		port:               int(qmgr.Port),
+		channel:            qmgr.Channel,
+		connectionNameList: qmgr.ConnectionNameList,
+		ccdtUrl:            qmgr.CcdtUrl,
+		propertyPrefix:     PROPERTY_PREFIX_COMMAND,
+		cipherEnv:          MFT_CMD_QMGR_CIPHER,
+		certPath:           commandQMCertPath,
+	}
+}
+
+// Connection to the queue manager of an agent
+func agentConnection(agent *config.Agent) *qmgrConnection {
+	return &qmgrConnection{
+		role:               "agent " + agent.Name,
+		qmgrName:           agent.QMgrName,
+		host:               agent.QMgrHost,
+		port:               int(agent.QMgrPort),
+		channel:            agent.QMgrChannel,
+		connectionNameList: agent.QMgrConnectionNameList,
+		ccdtUrl:            agent.QMgrCcdtUrl,
+		propertyPrefix:     PROPERTY_PREFIX_AGENT,
+		cipherEnv:          MFT_AGENT_QMGR_CIPHER,
+		certPath:           agentQMCertPath,
+	}
+}
+
+// Determine if the host, connection name list or CCDT of the queue manager
+// has been specified
+func (conn *qmgrConnection) specified() bool {
+	return len(strings.TrimSpace(conn.host)) > 0 || len(strings.TrimSpace(conn.connectionNameList)) > 0 ||
+		len(strings.TrimSpace(conn.ccdtUrl)) > 0
+}
+
+// Validate the connection name list or CCDT. Only one way of connecting to
+// the queue manager may be specified.
+func (conn *qmgrConnection) validate() error {
+	host := strings.TrimSpace(conn.host)
+	list := strings.TrimSpace(conn.connectionNameList)
+	ccdt := strings.TrimSpace(conn.ccdtUrl)
+	if len(list) > 0 && len(ccdt) > 0 {
+		return errors.New("connection name list and CCDT must not both be specified")
+	}
+	if len(host) > 0 && (len(list) > 0 || len(ccdt) > 0) {
+		return errors.New("host must not be specified with a connection name list or CCDT")
+	}
+	if len(list) > 0 {
+		if _, err := parseConnectionNameList(list); err != nil {
+			return err
+		}
+	}
+	if len(ccdt) > 0 {
+		path, remote, err := ccdtLocation(ccdt)
+		if err != nil {
+			return err
+		}
+		if !remote {
+			if _, err := readJSONCcdtAddresses(path, conn.qmgrName); err != nil && !errors.Is(err, errBinaryCcdt) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Parse a connection name list. Port of entries without one defaults to 1414
+// as it does for MQ.
+func parseConnectionNameList(list string) ([]qmgrAddress, error) {
+	var addresses []qmgrAddress
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			return nil, fmt.Errorf("connection name list %q contains an empty entry", list)
+		}
+		address := qmgrAddress{host: entry, port: config.DEFAULT_QMGR_PORT}
+		if open := strings.Index(entry, "("); open >= 0 {
+			if !strings.HasSuffix(entry, ")") {
+				return nil, fmt.Errorf("connection name %q is not of the form host(port)", entry)
+			}
+			port, err := strconv.Atoi(entry[open+1 : len(entry)-1])
+			if err != nil || port < 1 || port > 65535 {
+				return nil, fmt.Errorf("port of connection name %q is not within range 1 to 65535", entry)
+			}
+			address = qmgrAddress{host: strings.TrimSpace(entry[:open]), port: port}
+		}
+		if len(address.host) == 0 || strings.ContainsAny(address.host, " \t()") {
+			return nil, fmt.Errorf("connection name %q is not of the form host(port)", entry)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// Determine the location of a CCDT. Returns the path of a local CCDT or true
+// if the CCDT is read from a server.
+func ccdtLocation(ccdtUrl string) (string, bool, error) {
+	if filepath.IsAbs(ccdtUrl) {
+		return ccdtUrl, false, nil
+	}
+	location, err := url.Parse(ccdtUrl)
+	if err != nil {
+		return TEXT_BLANK, false, fmt.Errorf("CCDT %q is not a valid URL: %v", ccdtUrl, err)
+	}
+	switch strings.ToLower(location.Scheme) {
+	case "file":
+		if !filepath.IsAbs(location.Path) {
+			return TEXT_BLANK, false, fmt.Errorf("CCDT %q must name an absolute path", ccdtUrl)
+		}
+		return location.Path, false, nil
+	case "http", "https", "ftp":
+		if len(location.Host) == 0 {
+			return TEXT_BLANK, false, fmt.Errorf("CCDT %q must name a server", ccdtUrl)
+		}
+		return TEXT_BLANK, true, nil
+	}
+	return TEXT_BLANK, false, fmt.Errorf("CCDT %q must be an absolute path or a file, http, https or ftp URL", ccdtUrl)
+}
+
+// Binary CCDTs are accepted but their content is not known
+var errBinaryCcdt = errors.New("binary CCDT")
+
+// JSON CCDT as documented for MQ clients. Only attributes used for finding
+// the endpoints of a queue manager are listed.
+type jsonCcdt struct {
+	Channel []*jsonCcdtChannel `json:"channel"`
+}
+
+type jsonCcdtChannel struct {
+	Name             string `json:"name"`
+	Type             string `json:"type"`
+	ClientConnection struct {
+		Connection   []*jsonCcdtConnection `json:"connection"`
+		QueueManager string                `json:"queueManager"`
+	} `json:"clientConnection"`
+	TransmissionSecurity *jsonCcdtTransmissionSecurity `json:"transmissionSecurity,omitempty"`
+}
+
+type jsonCcdtConnection struct {
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"`
+}
+
+type jsonCcdtTransmissionSecurity struct {
+	CipherSpecification string `json:"cipherSpecification"`
+}
+
+// Read the addresses of the queue manager from the client connection channels
+// of a local JSON CCDT. Returns errBinaryCcdt if the file is not JSON.
+func readJSONCcdtAddresses(path string, qmgrName string) ([]qmgrAddress, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("CCDT %s could not be read: %v", path, err)
+	}
+	content := strings.TrimSpace(string(data))
+	if !strings.HasPrefix(content, "{") {
+		return nil, errBinaryCcdt
+	}
+	var ccdt jsonCcdt
+	if err := json.Unmarshal([]byte(content), &ccdt); err != nil {
+		return nil, fmt.Errorf("CCDT %s is not valid JSON: %v", path, err)
+	}
+	var addresses []qmgrAddress
+	for _, channel := range ccdt.Channel {
+		if channel == nil || !strings.EqualFold(channel.Type, "clientConnection") ||
+			!strings.EqualFold(strings.TrimSpace(channel.ClientConnection.QueueManager), strings.TrimSpace(qmgrName)) {
+			continue
+		}
+		for _, connection := range channel.ClientConnection.Connection {
+			if connection == nil || len(strings.TrimSpace(connection.Host)) == 0 {
+				continue
+			}
+			port := connection.Port
+			if port == 0 {
+				port = config.DEFAULT_QMGR_PORT
+			}
+			addresses = append(addresses, qmgrAddress{host: strings.TrimSpace(connection.Host), port: port})
+		}
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("CCDT %s has no client connection channel with a connection for queue manager %s", path, qmgrName)
+	}
+	return addresses, nil
+}
+
+// List the addresses of the queue manager. Returns the reason if the
+// addresses can not be determined, for example from a binary CCDT.
+func (conn *qmgrConnection) addresses() ([]qmgrAddress, string) {
+	if list := strings.TrimSpace(conn.connectionNameList); len(list) > 0 {
+		addresses, err := parseConnectionNameList(list)
+		if err != nil {
+			return nil, err.Error()
+		}
+		return addresses, TEXT_BLANK
+	}
+	if ccdt := strings.TrimSpace(conn.ccdtUrl); len(ccdt) > 0 {
+		path, remote, err := ccdtLocation(ccdt)
+		if err != nil {
+			return nil, err.Error()
+		}
+		if remote {
+			return nil, fmt.Sprintf("endpoints of CCDT %s on a server are not checked", ccdt)
+		}
+		addresses, err := readJSONCcdtAddresses(path, conn.qmgrName)
+		if errors.Is(err, errBinaryCcdt) {
+			return nil, fmt.Sprintf("endpoints of binary CCDT %s are not checked", ccdt)
+		} else if err != nil {
+			return nil, err.Error()
+		}
+		return addresses, TEXT_BLANK
+	}
+	return []qmgrAddress{{host: conn.host, port: conn.port}}, TEXT_BLANK
+}
+
+// Host, port and channel passed to the fte command that creates the
+// configuration and written to its properties file, for example
+// coordinationQMgrHost. The first entry of a connection name list is used.
+// None are used for a CCDT as the channel is defined in the CCDT.
+func (conn *qmgrConnection) hostProperties(name string) config.Properties {
+	if len(strings.TrimSpace(conn.ccdtUrl)) > 0 {
+		return config.Properties{}
+	}
+	host := conn.host
+	port := conn.port
+	if addresses, reason := conn.addresses(); len(reason) == 0 && len(addresses) > 0 {
+		host = addresses[0].host
+		port = addresses[0].port
+	}
+	return config.Properties{
+		{Name: name + "Host", Value: host},
+		{Name: name + "Port", Value: strconv.Itoa(port)},
+		{Name: name + "Channel", Value: conn.channel},
+	}
+}
+
+// Parameters of the fte command for the host, port and channel
+func (conn *qmgrConnection) commandArgs(name string) []string {
+	var args []string
+	for _, property := range conn.hostProperties(name) {
+		args = append(args, "-"+property.Name, property.Value)
+	}
+	return args
+}
+
+// Name of the property that holds the URL of the CCDT
+func ccdtUrlProperty(prefix string) string {
+	return prefix + "CcdtUrl"
+}
+
+// URL of the CCDT used by the queue manager, either the one specified or the
+// one generated in the given file for a connection name list. Returns blank
+// if neither has been specified.
+func (conn *qmgrConnection) ccdtPropertyValue(generatedFile string) string {
+	if ccdt := strings.TrimSpace(conn.ccdtUrl); len(ccdt) > 0 {
+		if filepath.IsAbs(ccdt) {
+			return "file://" + ccdt
+		}
+		return ccdt
+	}
+	if len(strings.TrimSpace(conn.connectionNameList)) > 0 {
+		return "file://" + generatedFile
+	}
+	return TEXT_BLANK
+}
+
+// Build a JSON CCDT with a client connection channel listing every entry of
+// the connection name list. The cipher of the queue manager, if any, is set
+// on the channel.
+func (conn *qmgrConnection) buildCcdt() (string, error) {
+	addresses, err := parseConnectionNameList(strings.TrimSpace(conn.connectionNameList))
+	if err != nil {
+		return TEXT_BLANK, err
+	}
+	channel := &jsonCcdtChannel{Name: conn.channel, Type: "clientConnection"}
+	channel.ClientConnection.QueueManager = conn.qmgrName
+	for _, address := range addresses {
+		channel.ClientConnection.Connection = append(channel.ClientConnection.Connection, &jsonCcdtConnection{Host: address.host, Port: address.port})
+	}
+	if cipherName := strings.TrimSpace(os.Getenv(conn.cipherEnv)); len(cipherName) > 0 {
+		channel.TransmissionSecurity = &jsonCcdtTransmissionSecurity{CipherSpecification: cipherName}
+	}
+	data, err := json.MarshalIndent(&jsonCcdt{Channel: []*jsonCcdtChannel{channel}}, "", "  ")
+	if err != nil {
+		return TEXT_BLANK, err
+	}
+	return string(data), nil
+}
+
+// Configure the queue manager to be connected to through a CCDT if a
+// connection name list or CCDT has been specified. A CCDT is written to the
+// given file for a connection name list. The *CcdtUrl property is set in the
+// given properties and replaces any value specified by the user.
+func configureCcdt(conn *qmgrConnection, generatedFile string, properties *config.Properties) bool {
+	ccdtUrl := conn.ccdtPropertyValue(generatedFile)
+	if len(ccdtUrl) == 0 {
+		return true
+	}
+	if len(strings.TrimSpace(conn.connectionNameList)) > 0 {
+		ccdt, err := conn.buildCcdt()
+		if err == nil {
+			err = utils.WriteData(generatedFile, ccdt)
+		}
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CCDT_WRITE_FAILED_0149, generatedFile, conn.role, conn.qmgrName, err))
+			return false
+		}
+		if logLevel >= LOG_LEVEL_VERBOSE {
+			utils.PrintLog(ccdt)
+		}
+	}
+	properties.Set(ccdtUrlProperty(conn.propertyPrefix), ccdtUrl)
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CCDT_CONFIGURED_0150, conn.role, conn.qmgrName, ccdtUrl))
+	return true
+}
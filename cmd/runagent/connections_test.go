@@ -0,0 +1,232 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+const testJSONCcdt = `{"channel":[
+{"name":"OTHER.SVRCONN","type":"clientConnection","clientConnection":{"connection":[{"host":"other.example.com","port":1420}],"queueManager":"QM2"}},
+{"name":"MFT.SVRCONN","type":"clientConnection","clientConnection":{"connection":[{"host":"qm1a.example.com","port":1414},{"host":"qm1b.example.com"}],"queueManager":"QM1"}}]}`
+
+// Write a JSON and a binary CCDT
+func writeTestCcdts(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	jsonCcdt := filepath.Join(dir, "ccdt.json")
+	if err := os.WriteFile(jsonCcdt, []byte(testJSONCcdt), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binaryCcdt := filepath.Join(dir, "AMQCLCHL.TAB")
+	if err := os.WriteFile(binaryCcdt, []byte("AMQR\x00\x01"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return jsonCcdt, binaryCcdt
+}
+
+func TestParseConnectionNameList(t *testing.T) {
+	addresses, err := parseConnectionNameList("qm1a.example.com(1414), qm1b.example.com(1415),10.0.0.1")
+	expected := []qmgrAddress{{"qm1a.example.com", 1414}, {"qm1b.example.com", 1415}, {"10.0.0.1", 1414}}
+	if err != nil || !reflect.DeepEqual(addresses, expected) {
+		t.Errorf("Unexpected addresses %v %v", addresses, err)
+	}
+
+	tests := []struct {
+		list string
+		err  string
+	}{
+		{"host1(1414),,host2(1414)", "connection name list \"host1(1414),,host2(1414)\" contains an empty entry"},
+		{"host1(1414", "connection name \"host1(1414\" is not of the form host(port)"},
+		{"host1(abc)", "port of connection name \"host1(abc)\" is not within range 1 to 65535"},
+		{"host1(70000)", "port of connection name \"host1(70000)\" is not within range 1 to 65535"},
+		{"(1414)", "connection name \"(1414)\" is not of the form host(port)"},
+		{"host 1(1414)", "connection name \"host 1(1414)\" is not of the form host(port)"},
+	}
+	for _, test := range tests {
+		if _, err := parseConnectionNameList(test.list); err == nil || err.Error() != test.err {
+			t.Errorf("Expected error %q, found %v", test.err, err)
+		}
+	}
+}
+
+func TestValidateQmgrConnection(t *testing.T) {
+	jsonCcdt, binaryCcdt := writeTestCcdts(t)
+	valid := []*qmgrConnection{
+		{qmgrName: "QM1", host: "localhost"},
+		{qmgrName: "QM1", connectionNameList: "qm1a(1414),qm1b(1414)"},
+		{qmgrName: "QM1", ccdtUrl: jsonCcdt},
+		{qmgrName: "QM1", ccdtUrl: "file://" + jsonCcdt},
+		{qmgrName: "QM1", ccdtUrl: binaryCcdt},
+		{qmgrName: "QM1", ccdtUrl: "https://ccdt.example.com/ccdt.json"},
+	}
+	for _, conn := range valid {
+		if err := conn.validate(); err != nil {
+			t.Errorf("Unexpected error for %+v: %v", conn, err)
+		}
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	tests := []struct {
+		conn *qmgrConnection
+		err  string
+	}{
+		{&qmgrConnection{qmgrName: "QM1", connectionNameList: "a(1414)", ccdtUrl: jsonCcdt},
+			"connection name list and CCDT must not both be specified"},
+		{&qmgrConnection{qmgrName: "QM1", host: "localhost", connectionNameList: "a(1414)"},
+			"host must not be specified with a connection name list or CCDT"},
+		{&qmgrConnection{qmgrName: "QM1", ccdtUrl: "ccdt.json"},
+			"CCDT \"ccdt.json\" must be an absolute path or a file, http, https or ftp URL"},
+		{&qmgrConnection{qmgrName: "QM1", ccdtUrl: "https:///ccdt.json"},
+			"CCDT \"https:///ccdt.json\" must name a server"},
+		{&qmgrConnection{qmgrName: "QM1", ccdtUrl: missing},
+			"CCDT " + missing + " could not be read"},
+		{&qmgrConnection{qmgrName: "QM3", ccdtUrl: jsonCcdt},
+			"CCDT " + jsonCcdt + " has no client connection channel with a connection for queue manager QM3"},
+	}
+	for _, test := range tests {
+		if err := test.conn.validate(); err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("Expected error %q, found %v", test.err, err)
+		}
+	}
+}
+
+func TestQmgrConnectionAddresses(t *testing.T) {
+	jsonCcdt, binaryCcdt := writeTestCcdts(t)
+	tests := []struct {
+		conn      *qmgrConnection
+		addresses []qmgrAddress
+		reason    string
+	}{
+		{&qmgrConnection{qmgrName: "QM1", host: "localhost", port: 1415}, []qmgrAddress{{"localhost", 1415}}, ""},
+		{&qmgrConnection{qmgrName: "QM1", connectionNameList: "qm1a(1414),qm1b(1416)"}, []qmgrAddress{{"qm1a", 1414}, {"qm1b", 1416}}, ""},
+		{&qmgrConnection{qmgrName: "qm1", ccdtUrl: jsonCcdt}, []qmgrAddress{{"qm1a.example.com", 1414}, {"qm1b.example.com", 1414}}, ""},
+		{&qmgrConnection{qmgrName: "QM1", ccdtUrl: binaryCcdt}, nil, "endpoints of binary CCDT " + binaryCcdt + " are not checked"},
+		{&qmgrConnection{qmgrName: "QM1", ccdtUrl: "http://ccdt.example.com/ccdt.json"}, nil,
+			"endpoints of CCDT http://ccdt.example.com/ccdt.json on a server are not checked"},
+	}
+	for _, test := range tests {
+		addresses, reason := test.conn.addresses()
+		if !reflect.DeepEqual(addresses, test.addresses) || reason != test.reason {
+			t.Errorf("Unexpected addresses %v %q for %+v", addresses, reason, test.conn)
+		}
+	}
+}
+
+func TestQmgrConnectionCommandArgs(t *testing.T) {
+	agentConfiguration, err := config.Parse(`{"coordinationQMgr":{"name":"QM1","host":"qm1.example.com","port":1415},
+		"commandQMgr":{"name":"QM1","connectionNameList":"qm1a(1414),qm1b(1414)","channel":"MFT.SVRCONN"},
+		"agents":[{"name":"SRC","qmgrName":"QM1","qmgrCcdtUrl":"https://ccdt.example.com/ccdt.json"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := coordinationConnection(&agentConfiguration.CoordinationQMgr).commandArgs("coordinationQMgr")
+	expected := []string{"-coordinationQMgrHost", "qm1.example.com", "-coordinationQMgrPort", "1415", "-coordinationQMgrChannel", "SYSTEM.DEF.SVRCONN"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Unexpected coordination arguments %v", args)
+	}
+	args = commandConnection(&agentConfiguration.CommandQMgr).commandArgs("connectionQMgr")
+	expected = []string{"-connectionQMgrHost", "qm1a", "-connectionQMgrPort", "1414", "-connectionQMgrChannel", "MFT.SVRCONN"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Unexpected command arguments %v", args)
+	}
+	if args = agentConnection(agentConfiguration.Agents[0]).commandArgs("agentQMgr"); len(args) != 0 {
+		t.Errorf("Unexpected agent arguments for a CCDT %v", args)
+	}
+}
+
+func TestConfigureCcdt(t *testing.T) {
+	t.Setenv(MFT_AGENT_QMGR_CIPHER, "ANY_TLS12_OR_HIGHER")
+	generatedFile := filepath.Join(t.TempDir(), "agentccdt.json")
+	conn := agentConnection(&config.Agent{Name: "SRC", QMgrName: "QM1", QMgrChannel: "MFT.SVRCONN", QMgrConnectionNameList: "qm1a(1414),qm1b(1415)"})
+	properties := config.Properties{{Name: "agentCcdtUrl", Value: "file:///mnt/old.json"}}
+	if !configureCcdt(conn, generatedFile, &properties) {
+		t.Fatal("CCDT not configured")
+	}
+	if len(properties) != 1 || properties[0].Value != "file://"+generatedFile {
+		t.Errorf("Unexpected properties %v", properties)
+	}
+	addresses, err := readJSONCcdtAddresses(generatedFile, "QM1")
+	if err != nil || !reflect.DeepEqual(addresses, []qmgrAddress{{"qm1a", 1414}, {"qm1b", 1415}}) {
+		t.Errorf("Unexpected addresses in generated CCDT %v %v", addresses, err)
+	}
+	ccdt, _ := os.ReadFile(generatedFile)
+	if !strings.Contains(string(ccdt), `"name": "MFT.SVRCONN"`) || !strings.Contains(string(ccdt), `"cipherSpecification": "ANY_TLS12_OR_HIGHER"`) {
+		t.Errorf("Unexpected generated CCDT %s", ccdt)
+	}
+
+	// CCDT specified by path is set as a file URL
+	conn = agentConnection(&config.Agent{Name: "SRC", QMgrName: "QM1", QMgrCcdtUrl: "/mnt/ccdt/ccdt.json"})
+	properties = config.Properties{}
+	if !configureCcdt(conn, generatedFile, &properties) || len(properties) != 1 ||
+		properties[0].Name != "agentCcdtUrl" || properties[0].Value != "file:///mnt/ccdt/ccdt.json" {
+		t.Errorf("Unexpected properties %v", properties)
+	}
+
+	// Nothing is set for a host
+	conn = agentConnection(&config.Agent{Name: "SRC", QMgrName: "QM1", QMgrHost: "localhost"})
+	properties = config.Properties{}
+	if !configureCcdt(conn, generatedFile, &properties) || len(properties) != 0 {
+		t.Errorf("Unexpected properties %v", properties)
+	}
+}
+
+func TestDryRunSetupCoordinationWithConnectionNameList(t *testing.T) {
+	dryRunEnabled = true
+	dryRunCommands = nil
+	runner = &dryRunCommandRunner{}
+	defer func() {
+		dryRunEnabled = false
+		dryRunCommands = nil
+		runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second)
+	}()
+
+	agentConfiguration, err := config.Parse(`{"coordinationQMgr":{"name":"QM1","connectionNameList":"qm1a(1414),qm1b(1414)"},
+		"commandQMgr":{"name":"QM1","host":"localhost"},"agents":[{"name":"SRC","qmgrName":"QM1","qmgrHost":"localhost"}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	if err := createDryRunLayout(outputDir, "QM1", "SRC"); err != nil {
+		t.Fatal(err)
+	}
+	if created, _ := setupCoordination(agentConfiguration, outputDir, "SRC"); !created {
+		t.Fatal("Coordination setup failed in dry-run mode")
+	}
+	if len(dryRunCommands) == 0 || dryRunCommands[0] != "fteSetupCoordination -coordinationQMgr QM1 -coordinationQMgrHost qm1a "+
+		"-coordinationQMgrPort 1414 -coordinationQMgrChannel SYSTEM.DEF.SVRCONN -f -default" {
+		t.Errorf("Unexpected commands recorded: %v", dryRunCommands)
+	}
+	coordinationPath := filepath.Join(outputDir, "mqft", "config", "QM1")
+	properties, err := os.ReadFile(filepath.Join(coordinationPath, "coordination.properties"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(properties), "coordinationCcdtUrl=file://"+filepath.Join(coordinationPath, "coordinationccdt.json")) {
+		t.Errorf("CCDT property not written:\n%s", properties)
+	}
+	if _, err := readJSONCcdtAddresses(filepath.Join(coordinationPath, "coordinationccdt.json"), "QM1"); err != nil {
+		t.Errorf("Generated CCDT not valid: %v", err)
+	}
+}
@@ -51,6 +51,7 @@ const LOG_LEVEL_VERBOSE = 2
 // Supported agent types
 const AGENT_TYPE_STANDARD = "STANDARD"
 const AGENT_TYPE_BRIDGE = "BRIDGE"
+const AGENT_TYPE_CD = "CD"
 
 // Supported log levels
 const LOG_LEVEL_INFO_TXT = "info"
@@ -97,6 +98,12 @@ const coordinationQMCertPath = "/etc/mqmft/pki/coordination"
 const commandQMCertPath = "/etc/mqmft/pki/command"
 const agentQMCertPath = "/etc/mqmft/pki/agent"
 
+// Certificates of FTPS servers of bridge agents are mounted in a directory,
+// named after the server, under this path. Stores built from them are named
+// with FTPS_SERVER_STORE_PREFIX followed by the name of the server.
+const bridgeServerCertPath = "/etc/mqmft/pki/bridge"
+const FTPS_SERVER_STORE_PREFIX = "ftps"
+
 // Blank
 const TEXT_BLANK = ""
 const TEXT_YES = "yes"
@@ -108,21 +115,36 @@ const MFT_CORD_CRED_SLASH = "/coordcredentials.xml"
 const MFT_AGENT_CRED_SLASH = "/agentcredentials.xml"
 const MFT_USER_SANDBOX_SLASH = "/UserSandboxes.xml"
 
+// CCDT file names generated for connection name lists
+const MFT_CORD_CCDT_SLASH = "/coordinationccdt.json"
+const MFT_CMD_CCDT_SLASH = "/commandccdt.json"
+const MFT_AGENT_CCDT_SLASH = "/agentccdt.json"
+
 // MFT config path
 const MFT_CONFIG_PATH_SUFFIX = "/mqft/config/"
 
 // MFT log path
 const MFT_LOG_PATH_SUFFIX = "/mqft/logs"
 
+// State of provisioned configuration
+const MFT_PROVISION_STATE_SLASH = "/mqft/provisionstate.json"
+
+// Host keys of SFTP servers pinned on first use
+const MFT_KNOWN_HOSTS_SLASH = "/mqft/known_hosts"
+
 // Agents
 const MFT_AGENTS_SLASH = "/agents/"
 const MFT_EXITS_SLASH = "/exits/"
+const MFT_MONITORS_SLASH = "/monitors/"
 
 // command properties
 const MFT_CMD_PROPS_SLASH = "/command.properties"
 const MFT_CORD_PROPS_SLASH = "/coordination.properties"
 const MFT_AGENT_PROPS_SLASH = "/agent.properties"
 const MFT_PBA_PROPS_SLASH = "/ProtocolBridgeProperties.xml"
+const MFT_CD_NODE_PROPS_SLASH = "/ConnectDirectNodeProperties.xml"
+const MFT_CD_CRED_SLASH = "/ConnectDirectCredentials.xml"
+const MFT_PBA_CRED_SLASH = "/ProtocolBridgeCredentials.json"
 
 // Error codes returned by runagent process
 const MFT_CONT_SUCCESS_CODE_0 = 0
@@ -150,8 +172,23 @@ const MFT_CONT_ERR_CODE_21 = 21
 const MFT_CONT_ERR_CODE_22 = 22
 const MFT_CONT_ERR_CODE_23 = 23
 const MFT_CONT_ERR_CODE_24 = 24
+const MFT_CONT_ERR_CODE_25 = 25
+const MFT_CONT_ERR_CODE_26 = 26
+const MFT_CONT_ERR_CODE_27 = 27
+const MFT_CONT_ERR_CODE_28 = 28
+const MFT_CONT_ERR_CODE_29 = 29
+const MFT_CONT_ERR_CODE_30 = 30
+const MFT_CONT_ERR_CODE_31 = 31
+const MFT_CONT_ERR_CODE_32 = 32
+
+// Interval, in seconds, at which the supervisor checks that agents are still running
+const AGENT_LIVENESS_CHECK_INTERVAL = 10
 
 // Data types used by ProtocolBridgeProperties.xml
 const DATA_TYPE_STRING = 1
 const DATA_TYPE_INT = 2
 const DATA_TYPE_BOOL = 3
+
+// Data types used by agent configuration file schema
+const DATA_TYPE_OBJECT = 4
+const DATA_TYPE_ARRAY = 5
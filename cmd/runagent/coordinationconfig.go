@@ -16,136 +16,94 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
 	"github.com/subchen/go-xmldom"
-	"github.com/tidwall/gjson"
-	"github.com/tidwall/sjson"
 )
 
-// Setup coordination configuration for agent.
-func setupCoordination(allAgentConfig string, bfgDataPath string, agentNameEnv string) bool {
-	// Variables for Stdout and Stderr
-	var outb, errb bytes.Buffer
+// Setup coordination configuration for the agents, whose names are only
+// logged. Returns the result of fteSetupCoordination if it failed.
+func setupCoordination(agentConfiguration *config.Configuration, bfgDataPath string, agentNames string) (bool, *commandResult) {
 	var created bool = false
-	coordinationQueueManagerName := gjson.Get(allAgentConfig, "coordinationQMgr.name").String()
+	coordinationQMgr := &agentConfiguration.CoordinationQMgr
+	coordinationQueueManagerName := coordinationQMgr.Name
 	// Setup coordination configuration
-	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_CORD_CONFIG_MSG_0024, agentNameEnv, coordinationQueueManagerName))
-
-	// Get the path of MFT fteSetupCoordination command.
-	cmdCoordPath, lookPathErr := exec.LookPath("fteSetupCoordination")
-	if lookPathErr == nil {
-		var port string
-		var channel string
-		var host string
-
-		if gjson.Get(allAgentConfig, "coordinationQMgr.host").Exists() {
-			host = gjson.Get(allAgentConfig, "coordinationQMgr.host").String()
-		} else {
-			host = "localhost"
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_CORD_CONFIG_MSG_0024, agentNames, coordinationQueueManagerName))
+
+	// Execute the fteSetupCoordination command. Log an error an exit in case of any error.
+	connection := coordinationConnection(coordinationQMgr)
+	params := append([]string{"-coordinationQMgr", coordinationQueueManagerName}, connection.commandArgs("coordinationQMgr")...)
+	result := runFteCommand("fteSetupCoordination", append(params, "-f", "-default")...)
+	if result.failed() {
+		result.logFailure()
+		return false, result
+	} else {
+		if logLevel >= LOG_LEVEL_VERBOSE {
+			utils.PrintLog(fmt.Sprintf("Command output: %s", result.stdout))
 		}
 
-		if gjson.Get(allAgentConfig, "coordinationQMgr.port").Exists() {
-			port = gjson.Get(allAgentConfig, "coordinationQMgr.port").String()
-		} else {
-			port = "1414"
-		}
+		// Update coordination properties file with additional attributes specified.
+		coordinationPropertiesFile := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQueueManagerName + MFT_CORD_PROPS_SLASH
+		// Coordination queue manager credentials file
+		var coordCredFilePath string = bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQueueManagerName + MFT_CORD_CRED_SLASH
 
-		if gjson.Get(allAgentConfig, "coordinationQMgr.channel").Exists() {
-			channel = gjson.Get(allAgentConfig, "coordinationQMgr.channel").String()
-		} else {
-			channel = "SYSTEM.DEF.SVRCONN"
+		// Start XML document for credentials file
+		credentialsDoc := InitializeCredentialsDocumentWriter()
+
+		// Configure TLS security
+		created = configTLSCoordination(coordinationQMgr, credentialsDoc, coordCredFilePath)
+		// Connection name list or CCDT of the coordination queue manager
+		if created {
+			created = configureCcdt(connection, bfgDataPath+MFT_CONFIG_PATH_SUFFIX+coordinationQueueManagerName+MFT_CORD_CCDT_SLASH,
+				&coordinationQMgr.AdditionalProperties)
 		}
 
-		var cmdArgs []string
-		cmdArgs = append(cmdArgs, cmdCoordPath,
-			"-coordinationQMgr", coordinationQueueManagerName,
-			"-coordinationQMgrHost", host,
-			"-coordinationQMgrPort", port, "-coordinationQMgrChannel", channel, "-f", "-default")
-		if commandTracingEnabled {
-			cmdArgs = append(cmdArgs, "-trace", "com.ibm.wmqfte=all")
-			cmdTracePath := GetCommandTracePath()
-			if len(cmdTracePath) > 0 {
-				cmdArgs = append(cmdArgs, "-tracePath", cmdTracePath)
+		if created {
+			// If a credentials file has been specified as environment variable, then set it here
+			if coordinationQMgr.Credentials != nil {
+				// Write coordination queue manager credentials
+				UpdateXmlWithQmgrCredentials(credentialsDoc, coordinationQMgr.Credentials, coordinationQueueManagerName)
 			}
-		}
 
-		cmdSetupCoord := &exec.Cmd{
-			Path: cmdCoordPath,
-			Args: cmdArgs,
-		}
+			errSetCred := setupCredentials(coordCredFilePath, credentialsDoc.XMLPretty())
+			if errSetCred == nil {
+				// Attempt to encrypt the credentials file with a fixed key
+				EncryptCredentialsFile(coordCredFilePath)
+				coordinationQMgr.AdditionalProperties.Set(credentialsFileProperty(PROPERTY_PREFIX_COORDINATION), coordCredFilePath)
+			} else {
+				utils.PrintLog(errSetCred.Error())
+			}
 
-		// Execute the fteSetupCoordination command. Log an error an exit in case of any error.
-		cmdSetupCoord.Stdout = &outb
-		cmdSetupCoord.Stderr = &errb
-		if err := cmdSetupCoord.Run(); err != nil {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_ERROR_0042, outb.String(), errb.String()))
-		} else {
 			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(fmt.Sprintf("Command output: %s", outb.String()))
+				utils.PrintLog(fmt.Sprintf(utils.MFT_UPDATED_CONFIGURATION, agentConfiguration))
 			}
 
-			// Update coordination properties file with additional attributes specified.
-			coordinationPropertiesFile := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQueueManagerName + MFT_CORD_PROPS_SLASH
-			// Coordination queue manager credentials file
-			var coordCredFilePath string = bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQueueManagerName + MFT_CORD_CRED_SLASH
-
-			// Start XML document for credentials file
-			credentialsDoc := InitializeCredentialsDocumentWriter()
-
-			// Configure TLS security
-			created, allAgentConfig = configTLSCoordination(allAgentConfig, credentialsDoc, coordCredFilePath)
-
-			if created {
-				// If a credentials file has been specified as environment variable, then set it here
-				if gjson.Get(allAgentConfig, "coordinationQMgr.qmgrCredentials").Exists() {
-					// Write coordination queue manager credentials
-					UpdateXmlWithQmgrCredentials(credentialsDoc, gjson.Get(allAgentConfig, "coordinationQMgr.qmgrCredentials").String(), coordinationQueueManagerName)
-				}
-
-				errSetCred := setupCredentials(coordCredFilePath, credentialsDoc.XMLPretty())
-				if errSetCred == nil {
-					// Attempt to encrypt the credentials file with a fixed key
-					EncryptCredentialsFile(coordCredFilePath)
-					allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationQMgrAuthenticationCredentialsFile", coordCredFilePath)
-				} else {
-					utils.PrintLog(errSetCred.Error())
-				}
-
-				if logLevel >= LOG_LEVEL_VERBOSE && len(allAgentConfig) > 0 {
-					utils.PrintLog(fmt.Sprintf(utils.MFT_UPDATED_CONFIGURATION, allAgentConfig))
-				}
-
-				// Update coordination properties file
-				err := UpdateProperties(coordinationPropertiesFile, allAgentConfig, "coordinationQMgr.additionalProperties")
-				if err != nil {
-					utils.PrintLog(err.Error())
-				} else {
-					if logLevel >= LOG_LEVEL_VERBOSE && len(coordCredFilePath) > 0 {
-						utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_CORD_CONFIG_CRED_PATH_0027, coordCredFilePath))
-					}
-					utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CORD_SETUP_COMP_0054, coordinationQueueManagerName))
-					created = true
+			// Update coordination properties file
+			err := UpdateProperties(coordinationPropertiesFile, coordinationQMgr.AdditionalProperties)
+			if err != nil {
+				utils.PrintLog(err.Error())
+			} else {
+				if logLevel >= LOG_LEVEL_VERBOSE && len(coordCredFilePath) > 0 {
+					utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_CORD_CONFIG_CRED_PATH_0027, coordCredFilePath))
 				}
+				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CORD_SETUP_COMP_0054, coordinationQueueManagerName))
+				created = true
 			}
 		}
-	} else {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, lookPathErr))
 	}
 
-	return created
+	return created, nil
 }
 
 // Create keystore using certificate provided if available. We need cipher name
 // at least public key environment variable to be set.
-func configTLSCoordination(allAgentConfig string, credentialsDoc *xmldom.Document, coordCredFilePath string) (bool, string) {
+func configTLSCoordination(coordinationQMgr *config.QueueManager, credentialsDoc *xmldom.Document, coordCredFilePath string) bool {
 	var created bool
 
 	cipherName, cipherSet := os.LookupEnv(MFT_COORD_QMGR_CIPHER)
@@ -159,10 +117,7 @@ func configTLSCoordination(allAgentConfig string, credentialsDoc *xmldom.Documen
 			errCreateKeyStore := CreateKeyStore(KEYSTORES_PATH, COORD_QM_TRUSTSTORE, publicKeyCertPath, password)
 			if errCreateKeyStore == nil {
 				// Update coordination properties file
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationSslCipherSpec", cipherName)
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationSslTrustStore", filepath.Join(KEYSTORES_PATH, COORD_QM_TRUSTSTORE))
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationSslTrustStoreType", "pkcs12")
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationSslTrustStoreCredentialsFile", coordCredFilePath)
+				setProperties(&coordinationQMgr.AdditionalProperties, tlsTrustStoreProperties(PROPERTY_PREFIX_COORDINATION, cipherName, COORD_QM_TRUSTSTORE, coordCredFilePath))
 				UpdateXmlWithKeyStoreCredentials(credentialsDoc, filepath.Join(KEYSTORES_PATH, COORD_QM_TRUSTSTORE), password)
 				created = true
 			} else {
@@ -176,10 +131,7 @@ func configTLSCoordination(allAgentConfig string, credentialsDoc *xmldom.Documen
 		if len(privateKeyCertPath) > 0 {
 			errCreateSslStore := CreateKeyStore(KEYSTORES_PATH, COORD_QM_KEYSTORE, privateKeyCertPath, password)
 			if errCreateSslStore == nil {
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationSslCipherSpec", cipherName)
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationSslKeyStore", filepath.Join(KEYSTORES_PATH, COORD_QM_KEYSTORE))
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationSslKeyStoreType", "pkcs12")
-				allAgentConfig, _ = sjson.Set(allAgentConfig, "coordinationQMgr.additionalProperties.coordinationSslKeyStoreCredentialsFile", coordCredFilePath)
+				setProperties(&coordinationQMgr.AdditionalProperties, tlsKeyStoreProperties(PROPERTY_PREFIX_COORDINATION, cipherName, COORD_QM_KEYSTORE, coordCredFilePath))
 				UpdateXmlWithKeyStoreCredentials(credentialsDoc, filepath.Join(KEYSTORES_PATH, COORD_QM_KEYSTORE), password)
 				created = true
 			} else {
@@ -194,23 +146,28 @@ func configTLSCoordination(allAgentConfig string, credentialsDoc *xmldom.Documen
 		// TLS not enabled.
 		created = true
 	}
-	return created, allAgentConfig
+	return created
 }
 
 // Validate attributes in JSON file.
 // Check if the configuration JSON contains all required attribtes
-func validateCoordinationAttributes(jsonData string) error {
+func validateCoordinationAttributes(agentConfiguration *config.Configuration) error {
 	// Coordination queue manager is mandatory
-	if !gjson.Get(jsonData, "coordinationQMgr.name").Exists() {
+	if len(strings.TrimSpace(agentConfiguration.CoordinationQMgr.Name)) == 0 {
 		err := errors.New(utils.MFT_CONT_CFG_CORD_QM_NAME_MISSING_0014)
 		return err
 	}
 
-	// Coordination queue manager host is mandatory
-	if !gjson.Get(jsonData, "coordinationQMgr.host").Exists() {
+	// Coordination queue manager host is mandatory unless a connection name
+	// list or CCDT is specified
+	connection := coordinationConnection(&agentConfiguration.CoordinationQMgr)
+	if !connection.specified() {
 		err := errors.New(utils.MFT_CONT_CFG_CORD_QM_HOST_MISSING_0015)
 		return err
 	}
+	if err := connection.validate(); err != nil {
+		return fmt.Errorf(utils.MFT_CONT_CFG_QMGR_CONNECTION_INVALID_0148, connection.role, connection.qmgrName, err)
+	}
 
 	return nil
 }
@@ -19,11 +19,11 @@ import (
 	"fmt"
 	"testing"
 
-	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 )
 
 func TestValidateCoordinationAttributesValidProperties(t *testing.T) {
-	allAgentConfig, e := utils.ReadConfigurationDataFromFile("./data/test_agcfg.json")
+	allAgentConfig, e := config.Load("./data/test_agcfg.json")
 	if e != nil {
 		t.Fatal(e)
 	}
@@ -35,7 +35,10 @@ func TestValidateCoordinationAttributesValidProperties(t *testing.T) {
 }
 
 func TestValidateCoordinationAttributesMissingQmgrName(t *testing.T) {
-	agentConfig := "{\"waitTimeToStart\":20,\"coordinationQMgr\":{\"host\":\"10.254.16.17\", \"port\":1414,\"channel\":\"QS_SVRCONN\",\"additionalProperties\": {}}}"
+	agentConfig, e := config.Parse("{\"waitTimeToStart\":20,\"coordinationQMgr\":{\"host\":\"10.254.16.17\", \"port\":1414,\"channel\":\"QS_SVRCONN\",\"additionalProperties\": {}}}")
+	if e != nil {
+		t.Fatal(e)
+	}
 
 	validated := validateCoordinationAttributes(agentConfig)
 	if validated != nil {
@@ -46,7 +49,10 @@ func TestValidateCoordinationAttributesMissingQmgrName(t *testing.T) {
 }
 
 func TestValidateCoordinationAttributesMissingQmgrHostName(t *testing.T) {
-	agentConfig := "{\"waitTimeToStart\":20,\"coordinationQMgr\":{\"name\":\"SECUREQM\", \"port\":1414,\"channel\":\"QS_SVRCONN\",\"additionalProperties\": {}}}"
+	agentConfig, e := config.Parse("{\"waitTimeToStart\":20,\"coordinationQMgr\":{\"name\":\"SECUREQM\", \"port\":1414,\"channel\":\"QS_SVRCONN\",\"additionalProperties\": {}}}")
+	if e != nil {
+		t.Fatal(e)
+	}
 
 	validated := validateCoordinationAttributes(agentConfig)
 	if validated != nil {
@@ -56,7 +62,10 @@ func TestValidateCoordinationAttributesMissingQmgrHostName(t *testing.T) {
 	}
 }
 func TestValidateCoordinationAttributesMissingChannelName(t *testing.T) {
-	agentConfig := "{\"waitTimeToStart\":20,\"coordinationQMgr\":{\"name\":\"SECUREQM\",\"host\":\"10.254.16.17\", \"port\":1414,\"additionalProperties\": {}}}"
+	agentConfig, e := config.Parse("{\"waitTimeToStart\":20,\"coordinationQMgr\":{\"name\":\"SECUREQM\",\"host\":\"10.254.16.17\", \"port\":1414,\"additionalProperties\": {}}}")
+	if e != nil {
+		t.Fatal(e)
+	}
 
 	validated := validateCoordinationAttributes(agentConfig)
 	if validated == nil {
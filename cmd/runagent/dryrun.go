@@ -0,0 +1,125 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Contains methods for running runagent in dry-run mode. In this mode all
+* configuration files are generated under the output directory specified on
+* command line but fte commands and keytool are not executed. Instead the
+* command lines are recorded, with secrets redacted, and written to a file
+* along with the generated configuration.
+ */
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Dry-run mode has been requested
+var dryRunEnabled bool = false
+
+// Command lines that would have been executed, in order
+var dryRunCommands []string
+
+// Result of each configuration step run in dry-run mode
+var dryRunSteps []dryRunStep
+
+// Name and outcome of a configuration step
+type dryRunStep struct {
+	name   string
+	passed bool
+}
+
+// Arguments whose following value must not be displayed
+var secretCommandArgs = []string{"-storepass", "-keypass", "-srcstorepass", "-deststorepass"}
+
+// Records command lines instead of running commands
+type dryRunCommandRunner struct{}
+
+// Record the command line, without the path of the command, and report
+// success.
+func (runner *dryRunCommandRunner) run(cmd *command) *commandResult {
+	commandLine := cmd.commandLine(cmd.arguments())
+	dryRunCommands = append(dryRunCommands, commandLine)
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_DRYRUN_CMD_0085, commandLine))
+	}
+	return &commandResult{commandLine: commandLine}
+}
+
+// Returns a copy of command arguments with values of secret arguments replaced
+func redactCommandArgs(args []string) []string {
+	redacted := make([]string, len(args))
+	copy(redacted, args)
+	for i := 0; i < len(redacted)-1; i++ {
+		for _, secretArg := range secretCommandArgs {
+			if redacted[i] == secretArg {
+				redacted[i+1] = utils.REDACTED_TEXT
+				i++
+				break
+			}
+		}
+	}
+	return redacted
+}
+
+// Record the outcome of a configuration step
+func recordDryRunStep(name string, passed bool) {
+	dryRunSteps = append(dryRunSteps, dryRunStep{name: name, passed: passed})
+}
+
+// Create directories that fte commands would have created, so that generated
+// configuration files can be written to them.
+func createDryRunLayout(bfgDataPath string, coordinationQMgr string, agentName string) error {
+	agentConfigPath := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQMgr + MFT_AGENTS_SLASH + agentName
+	return utils.CreatePath(agentConfigPath)
+}
+
+// Write the list of commands to output directory, display a summary of the
+// steps and end the process. Process ends with the given exit code if a step
+// failed and with zero otherwise.
+func completeDryRun(outputDir string, exitCode int) {
+	commandsFile := filepath.Join(outputDir, "commands.txt")
+	err := utils.WriteData(commandsFile, strings.Join(dryRunCommands, "\n")+"\n")
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_FAILED_WRITE_DATA, commandsFile, err))
+		recordDryRunStep("Write command list", false)
+	}
+
+	passed := true
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_DRYRUN_SUMMARY_0086, outputDir))
+	for _, step := range dryRunSteps {
+		result := "PASS"
+		if !step.passed {
+			result = "FAIL"
+			passed = false
+		}
+		utils.PrintLog(fmt.Sprintf("  %-40s %s", step.name, result))
+	}
+
+	if passed {
+		utils.PrintLog(utils.MFT_CONT_DRYRUN_PASSED_0087)
+		os.Exit(MFT_CONT_SUCCESS_CODE_0)
+	}
+	utils.PrintLog(utils.MFT_CONT_DRYRUN_FAILED_0088)
+	if exitCode == MFT_CONT_SUCCESS_CODE_0 {
+		exitCode = MFT_CONT_ERR_CODE_1
+	}
+	os.Exit(exitCode)
+}
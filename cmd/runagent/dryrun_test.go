@@ -0,0 +1,126 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+func TestRedactCommandArgs(t *testing.T) {
+	args := []string{"keytool", "-importcert", "-storepass", "Passw0rd", "-alias", "agentstore"}
+	redacted := redactCommandArgs(args)
+	if redacted[3] != utils.REDACTED_TEXT {
+		t.Errorf("Expected store password to be redacted, got %v", redacted)
+	}
+	if args[3] != "Passw0rd" {
+		t.Errorf("Original arguments must not be modified, got %v", args)
+	}
+}
+
+func TestRedactCredentials(t *testing.T) {
+	credentialsDoc := InitializeCredentialsDocumentWriter()
+	UpdateXmlWithKeyStoreCredentials(credentialsDoc, "/run/keystores/agenttruststore.p12", "Passw0rd")
+	UpdateXmlWithQmgrCredentials(credentialsDoc, &config.Credentials{UserId: "app", Password: "cGFzc3cwcmQ="}, "QM1")
+	redacted := utils.RedactSecrets(credentialsDoc.XMLPretty())
+	if strings.Contains(redacted, "Passw0rd") || strings.Contains(redacted, "cGFzc3cwcmQ=") {
+		t.Errorf("Credentials not redacted: %s", redacted)
+	}
+	if !strings.Contains(redacted, "mqUserId=\"app\"") {
+		t.Errorf("User id must not be redacted: %s", redacted)
+	}
+}
+
+func TestDryRunSetupAgent(t *testing.T) {
+	dryRunEnabled = true
+	dryRunCommands = nil
+	runner = &dryRunCommandRunner{}
+	defer func() {
+		dryRunEnabled = false
+		dryRunCommands = nil
+		runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second)
+	}()
+
+	allAgentConfig, e := config.Load("./data/ocpagcfg.json")
+	if e != nil {
+		t.Fatal(e)
+	}
+	outputDir := t.TempDir()
+	agentConfig := &config.Agent{Name: "SRCSTD", Type: config.AGENT_TYPE_STANDARD, QMgrName: "AGENTQM", QMgrHost: "10.254.12.41",
+		QMgrPort: config.DEFAULT_QMGR_PORT, QMgrChannel: config.DEFAULT_QMGR_CHANNEL,
+		Credentials: &config.Credentials{UserId: "app", Password: "cGFzc3cwcmQ="}}
+	if err := createDryRunLayout(outputDir, "SECUREQM", "SRCSTD"); err != nil {
+		t.Fatal(err)
+	}
+	if created, _ := setupCoordination(allAgentConfig, outputDir, "SRCSTD"); !created {
+		t.Fatal("Coordination setup failed in dry-run mode")
+	}
+	if created, _ := setupAgent(agentConfig, outputDir, "SECUREQM"); !created {
+		t.Fatal("Agent setup failed in dry-run mode")
+	}
+
+	if len(dryRunCommands) != 4 ||
+		!strings.HasPrefix(dryRunCommands[0], "fteSetupCoordination -coordinationQMgr SECUREQM") ||
+		!strings.HasPrefix(dryRunCommands[2], "fteCreateAgent -p SECUREQM -agentName SRCSTD") {
+		t.Errorf("Unexpected commands recorded: %v", dryRunCommands)
+	}
+
+	agentDir := filepath.Join(outputDir, "mqft", "config", "SECUREQM", "agents", "SRCSTD")
+	for _, fileName := range []string{"agent.properties", "UserSandboxes.xml", "agentcredentials.xml"} {
+		if !utils.DoesFileExist(filepath.Join(agentDir, fileName)) {
+			t.Errorf("Expected file %s was not generated", fileName)
+		}
+	}
+	credentials, err := os.ReadFile(filepath.Join(agentDir, "agentcredentials.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(credentials), "cGFzc3cwcmQ=") {
+		t.Errorf("Credentials file contains password in dry-run mode: %s", credentials)
+	}
+}
+
+func TestRunCommandRecordsInDryRun(t *testing.T) {
+	dryRunEnabled = true
+	dryRunCommands = nil
+	runner = &dryRunCommandRunner{}
+	defer func() {
+		dryRunEnabled = false
+		dryRunCommands = nil
+		runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second)
+	}()
+
+	result := runner.run(&command{name: "keytool", args: []string{"-importcert", "-storepass", "Passw0rd", "-file", "qm.crt"}})
+	if result.failed() {
+		t.Fatal(result.err)
+	}
+	result = runFteCommand("fteNoSuchCommand", "-p", "QM1")
+	if result.failed() {
+		t.Fatal(result.err)
+	}
+	expected := []string{"keytool -importcert -storepass " + utils.REDACTED_TEXT + " -file qm.crt", "fteNoSuchCommand -p QM1"}
+	if !reflect.DeepEqual(dryRunCommands, expected) {
+		t.Errorf("Unexpected commands recorded: %v", dryRunCommands)
+	}
+}
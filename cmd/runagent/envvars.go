@@ -35,12 +35,16 @@ const MFT_AGENT_CONFIG_FILE = "MFT_AGENT_CONFIG_FILE"
 // default path used for creating agent configuration and log files.
 const BFG_DATA = "BFG_DATA"
 
-// An agent might take some time to start after fteStartAgent command
-// is issued. This is the time, in seconds, the containeror will wait
-// for an agent to start. If an agent does not within the specified
-// wait time, the container will end.
+// Time, in seconds, ftePingAgent waits for an agent that is starting to
+// respond. Default is 10 seconds.
 const MFT_AGENT_START_WAIT_TIME = "MFT_AGENT_START_WAIT_TIME"
 
+// Optional. Interval, in seconds, between checks of an agent that is starting
+// and the time, in seconds, after which the container ends if the agent has
+// not become ready.
+const MFT_AGENT_START_POLL_INTERVAL = "MFT_AGENT_START_POLL_INTERVAL"
+const MFT_AGENT_START_DEADLINE = "MFT_AGENT_START_DEADLINE"
+
 // Enable agent tracing. "Yes" and "No" are the valid values with
 // "No" being default
 const MFT_AGENT_ENABLE_TRACE = "MFT_AGENT_ENABLE_TRACE"
@@ -70,3 +74,50 @@ const MFT_CMD_QMGR_CIPHER = "MFT_CMD_QMGR_CIPHER"
 
 // Agent queue manager cipherspec
 const MFT_AGENT_QMGR_CIPHER = "MFT_AGENT_QMGR_CIPHER"
+
+// Retrying of steps that fail because a queue manager is not available.
+// Initial and maximum delay between attempts and the overall time, in
+// seconds, after which a step is not retried. Override the values of
+// retryPolicy attribute of configuration file.
+const MFT_RETRY_INITIAL_DELAY = "MFT_RETRY_INITIAL_DELAY"
+const MFT_RETRY_MAX_DELAY = "MFT_RETRY_MAX_DELAY"
+const MFT_RETRY_DEADLINE = "MFT_RETRY_DEADLINE"
+
+// Optional. Timeout, in seconds, of each connectivity check made to queue
+// managers before configuration is created.
+const MFT_PREFLIGHT_TIMEOUT = "MFT_PREFLIGHT_TIMEOUT"
+
+// Optional. Time, in seconds, an fte command or keytool may run before it is
+// ended.
+const MFT_COMMAND_TIMEOUT = "MFT_COMMAND_TIMEOUT"
+
+// Optional. Regular expressions, one per line, matching secrets that must be
+// masked in console output and published logs in addition to the built-in
+// patterns.
+const MFT_REDACT_PATTERNS = "MFT_REDACT_PATTERNS"
+
+// Optional. Stop the container if protocol server definitions of bridge
+// agents are not valid. "Yes" and "No" are the supported values with "No"
+// being the default, in which case problems are reported as warnings.
+const MFT_BRIDGE_STRICT_VALIDATION = "MFT_BRIDGE_STRICT_VALIDATION"
+
+// Optional. Percentage of the container memory limit used for the heaps of
+// agents whose jvm section does not specify maxHeap or heapPercentage.
+// Default is 50.
+const MFT_JVM_HEAP_PERCENTAGE = "MFT_JVM_HEAP_PERCENTAGE"
+
+// Optional. Stop the container if additionalProperties contain properties
+// that are not known or whose values are not valid. "Yes" and "No" are the
+// supported values with "No" being the default, in which case problems are
+// reported as warnings.
+const MFT_PROPERTIES_STRICT_VALIDATION = "MFT_PROPERTIES_STRICT_VALIDATION"
+
+// Optional. Comma separated names of properties that are accepted without
+// being checked, such as properties read by user exits.
+const MFT_PROPERTIES_ALLOWED_NAMES = "MFT_PROPERTIES_ALLOWED_NAMES"
+
+// Optional. Read the host keys of SFTP servers of bridge agents when the
+// container starts. Keys seen for the first time are pinned in a known hosts
+// file on BFG_DATA and the agent is not started if a key changes. "Yes" and
+// "No" are the supported values with "No" being the default.
+const MFT_BRIDGE_HOST_KEY_DISCOVERY = "MFT_BRIDGE_HOST_KEY_DISCOVERY"
@@ -0,0 +1,269 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Contains the "runagent config explain" subcommand. It displays every
+* property that will be written to coordination.properties, command.properties
+* and agent.properties, its final value and where the value comes from: the
+* fte command that creates the file, defaults of the container, TLS setup,
+* credentials or additionalProperties of the configuration file. Properties
+* files are read by MFT with the last value of a property winning, so values
+* of additionalProperties that are overridden or specified twice are reported.
+* No command is run and no file is written.
+ */
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Explain subcommand has been requested
+var explainEnabled bool = false
+
+// Sources of property values
+const PROPERTY_SOURCE_USER = "additionalProperties"
+const PROPERTY_SOURCE_DEFAULT = "container default"
+const PROPERTY_SOURCE_TLS = "TLS setup"
+const PROPERTY_SOURCE_CREDENTIALS = "credentials"
+const PROPERTY_SOURCE_BRIDGE = "bridge agent setup"
+const PROPERTY_SOURCE_SANDBOX = "user sandbox setup"
+const PROPERTY_SOURCE_CCDT = "CCDT setup"
+
+// A property written to a properties file
+type explainedProperty struct {
+	name   string
+	value  string
+	source string
+}
+
+// Properties written to a properties file, in the order they are written
+type propertiesPlan struct {
+	path string
+	// Lines of the file. A later line overrides an earlier one.
+	lines []*explainedProperty
+	// Properties of additionalProperties, including values set by the
+	// container, before they are written
+	additional []*explainedProperty
+	warnings   []string
+}
+
+func newPropertiesPlan(path string) *propertiesPlan {
+	return &propertiesPlan{path: path}
+}
+
+// Write the properties to the file after the lines already written
+func (plan *propertiesPlan) write(properties config.Properties, source string) {
+	for _, property := range properties {
+		plan.writeLine(&explainedProperty{name: property.Name, value: property.Value, source: source})
+	}
+}
+
+// Write a line, reporting values of additionalProperties that it overrides
+func (plan *propertiesPlan) writeLine(line *explainedProperty) {
+	for _, earlier := range plan.lines {
+		if earlier.name != line.name || earlier.source == line.source || isUserSource(earlier.source) && isUserSource(line.source) {
+			continue
+		}
+		if isUserSource(earlier.source) {
+			plan.warnings = append(plan.warnings, fmt.Sprintf("value of %s in additionalProperties is overridden by %s", line.name, line.source))
+		} else if isUserSource(line.source) {
+			line = &explainedProperty{name: line.name, value: line.value, source: PROPERTY_SOURCE_USER + ", overrides " + earlier.source}
+		}
+	}
+	plan.lines = append(plan.lines, line)
+}
+
+// Determine if the value comes from additionalProperties of the configuration file
+func isUserSource(source string) bool {
+	return strings.HasPrefix(source, PROPERTY_SOURCE_USER)
+}
+
+// Add the additionalProperties of the configuration file, reporting
+// properties that are specified more than once.
+func (plan *propertiesPlan) addUserProperties(properties config.Properties) {
+	counts := make(map[string]int)
+	for _, property := range properties {
+		plan.additional = append(plan.additional, &explainedProperty{name: property.Name, value: property.Value, source: PROPERTY_SOURCE_USER})
+		counts[property.Name]++
+		if counts[property.Name] == 2 {
+			plan.warnings = append(plan.warnings, fmt.Sprintf("%s is specified more than once in additionalProperties, the last value is used", property.Name))
+		}
+	}
+}
+
+// Set properties of additionalProperties the way the container does while
+// creating configuration, replacing values specified by the user.
+func (plan *propertiesPlan) setProperties(properties config.Properties, source string) {
+	for _, property := range properties {
+		replaced := false
+		for _, existing := range plan.additional {
+			if existing.name != property.Name {
+				continue
+			}
+			if existing.source == PROPERTY_SOURCE_USER && existing.value != property.Value {
+				plan.warnings = append(plan.warnings, fmt.Sprintf("value of %s in additionalProperties is replaced by %s", property.Name, source))
+			}
+			existing.value = property.Value
+			existing.source = source
+			replaced = true
+			break
+		}
+		if !replaced {
+			plan.additional = append(plan.additional, &explainedProperty{name: property.Name, value: property.Value, source: source})
+		}
+	}
+}
+
+// Write additionalProperties to the file
+func (plan *propertiesPlan) writeAdditional() {
+	for _, property := range plan.additional {
+		plan.writeLine(property)
+	}
+}
+
+// Final value of every property, in the order properties first appear in the
+// file. Values of secret properties are redacted.
+func (plan *propertiesPlan) effective() []*explainedProperty {
+	var names []string
+	final := make(map[string]*explainedProperty)
+	for _, line := range plan.lines {
+		if _, found := final[line.name]; !found {
+			names = append(names, line.name)
+		}
+		final[line.name] = line
+	}
+	properties := make([]*explainedProperty, 0, len(names))
+	for _, name := range names {
+		property := *final[name]
+		if utils.IsSecretName(property.name) {
+			property.value = utils.REDACTED_TEXT
+		}
+		properties = append(properties, &property)
+	}
+	return properties
+}
+
+// Build the properties written for coordination and command queue managers
+// and each of the given agents.
+func explainProperties(agentConfiguration *config.Configuration, agentConfigs []*config.Agent, bfgDataPath string) []*propertiesPlan {
+	coordinationQMgr := agentConfiguration.CoordinationQMgr
+	commandQMgr := agentConfiguration.CommandQMgr
+	coordinationPath := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQMgr.Name
+
+	coordinationConn := coordinationConnection(&coordinationQMgr)
+	coordinationPlan := newPropertiesPlan(coordinationPath + MFT_CORD_PROPS_SLASH)
+	coordinationPlan.write(append(config.Properties{{Name: "coordinationQMgr", Value: coordinationQMgr.Name}},
+		coordinationConn.hostProperties("coordinationQMgr")...), "fteSetupCoordination")
+	coordinationPlan.addUserProperties(coordinationQMgr.AdditionalProperties)
+	explainTLSProperties(coordinationPlan, PROPERTY_PREFIX_COORDINATION, MFT_COORD_QMGR_CIPHER, coordinationQMCertPath,
+		COORD_QM_TRUSTSTORE, COORD_QM_KEYSTORE, coordinationPath+MFT_CORD_CRED_SLASH)
+	explainCcdtProperty(coordinationPlan, coordinationConn, coordinationPath+MFT_CORD_CCDT_SLASH)
+	coordinationPlan.writeAdditional()
+
+	commandConn := commandConnection(&commandQMgr)
+	commandPlan := newPropertiesPlan(coordinationPath + MFT_CMD_PROPS_SLASH)
+	commandPlan.write(append(config.Properties{{Name: "connectionQMgr", Value: commandQMgr.Name}},
+		commandConn.hostProperties("connectionQMgr")...), "fteSetupCommands")
+	commandPlan.addUserProperties(commandQMgr.AdditionalProperties)
+	explainTLSProperties(commandPlan, PROPERTY_PREFIX_COMMAND, MFT_CMD_QMGR_CIPHER, commandQMCertPath,
+		CMD_QM_TRUSTSTORE, CMD_QM_KEYSTORE, coordinationPath+MFT_CMD_CRED_SLASH)
+	explainCcdtProperty(commandPlan, commandConn, coordinationPath+MFT_CMD_CCDT_SLASH)
+	commandPlan.writeAdditional()
+
+	plans := []*propertiesPlan{coordinationPlan, commandPlan}
+	for _, agent := range agentConfigs {
+		agentPath := coordinationPath + MFT_AGENTS_SLASH + agent.Name
+		bridgeAgent := agent.Type == AGENT_TYPE_BRIDGE
+		cdAgent := agent.Type == AGENT_TYPE_CD
+		createCommand := "fteCreateAgent"
+		if bridgeAgent {
+			createCommand = "fteCreateBridgeAgent"
+		} else if cdAgent {
+			createCommand = "fteCreateCDAgent"
+		}
+
+		agentPlan := newPropertiesPlan(agentPath + MFT_AGENT_PROPS_SLASH)
+		agentConn := agentConnection(agent)
+		agentPlan.write(append(config.Properties{
+			{Name: "agentName", Value: agent.Name},
+			{Name: "agentQMgr", Value: agent.QMgrName},
+		}, agentConn.hostProperties("agentQMgr")...), createCommand)
+		agentPlan.write(agentDefaultProperties, PROPERTY_SOURCE_DEFAULT)
+		agentPlan.addUserProperties(agent.AdditionalProperties)
+		explainTLSProperties(agentPlan, PROPERTY_PREFIX_AGENT, MFT_AGENT_QMGR_CIPHER, agentQMCertPath,
+			AGENT_QM_TRUSTSTORE, AGENT_QM_KEYSTORE, agentPath+MFT_AGENT_CRED_SLASH)
+		explainCcdtProperty(agentPlan, agentConn, agentPath+MFT_AGENT_CCDT_SLASH)
+		if bridgeAgent && bridgeCredentialsSpecified(agent) {
+			agentPlan.setProperties(config.Properties{{Name: PROPERTY_BRIDGE_CREDENTIAL_CONFIGURATION, Value: agentPath + MFT_PBA_CRED_SLASH}},
+				PROPERTY_SOURCE_CREDENTIALS)
+		}
+		agentPlan.writeAdditional()
+		if bridgeAgent {
+			agentPlan.write(bridgeAgentProperties, PROPERTY_SOURCE_BRIDGE)
+		} else if !cdAgent && !userSandboxesSpecified(agent.AdditionalProperties) {
+			agentPlan.write(config.Properties{{Name: PROPERTY_USER_SANDBOXES, Value: "true"}}, PROPERTY_SOURCE_SANDBOX)
+		}
+		plans = append(plans, agentPlan)
+	}
+	return plans
+}
+
+// Set the properties that TLS setup and credentials add to additionalProperties
+// of a queue manager. TLS properties are added only if a cipher is specified
+// and the certificates for building the stores are available.
+func explainTLSProperties(plan *propertiesPlan, prefix string, cipherEnv string, certPath string, trustStore string, keyStore string, credentialsFile string) {
+	cipherName := strings.TrimSpace(os.Getenv(cipherEnv))
+	if len(cipherName) > 0 {
+		tlsSource := PROPERTY_SOURCE_TLS + " (" + cipherEnv + ")"
+		if len(getKeyFile(certPath, ".crt")) > 0 {
+			plan.setProperties(tlsTrustStoreProperties(prefix, cipherName, trustStore, credentialsFile), tlsSource)
+		}
+		if len(getKeyFile(certPath, ".key")) > 0 {
+			plan.setProperties(tlsKeyStoreProperties(prefix, cipherName, keyStore, credentialsFile), tlsSource)
+		}
+	}
+	plan.setProperties(config.Properties{{Name: credentialsFileProperty(prefix), Value: credentialsFile}}, PROPERTY_SOURCE_CREDENTIALS)
+}
+
+// Set the CCDT property of a queue manager connected to with a connection name
+// list or CCDT.
+func explainCcdtProperty(plan *propertiesPlan, connection *qmgrConnection, generatedFile string) {
+	if ccdtUrl := connection.ccdtPropertyValue(generatedFile); len(ccdtUrl) > 0 {
+		plan.setProperties(config.Properties{{Name: ccdtUrlProperty(connection.propertyPrefix), Value: ccdtUrl}}, PROPERTY_SOURCE_CCDT)
+	}
+}
+
+// Display the properties of each file followed by any warnings
+func printPropertiesExplanation(plans []*propertiesPlan) {
+	rowFormat := "%-45s %-50s %s"
+	warningCount := 0
+	for _, plan := range plans {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_EXPLAIN_FILE_0118, plan.path))
+		utils.PrintLog(strings.TrimSpace(fmt.Sprintf(rowFormat, "Property", "Value", "Source")))
+		for _, property := range plan.effective() {
+			utils.PrintLog(strings.TrimSpace(fmt.Sprintf(rowFormat, property.name, property.value, property.source)))
+		}
+		for _, warning := range plan.warnings {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_EXPLAIN_WARNING_0119, plan.path, warning))
+		}
+		warningCount += len(plan.warnings)
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_EXPLAIN_SUMMARY_0120, len(plans), warningCount))
+}
@@ -0,0 +1,119 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Find the effective value of the named property
+func findExplainedProperty(plan *propertiesPlan, name string) *explainedProperty {
+	for _, property := range plan.effective() {
+		if property.name == name {
+			return property
+		}
+	}
+	return nil
+}
+
+func TestExplainProperties(t *testing.T) {
+	agentConfiguration, err := config.Parse("{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"," +
+		"\"additionalProperties\":{\"coordinationQMgrAuthenticationCredentialsFile\":\"/mnt/creds.xml\"}}," +
+		"\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[" +
+		"{\"name\":\"SRC\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"additionalProperties\":" +
+		"{\"logCapture\":\"false\",\"trace\":\"all\",\"trace\":\"com.ibm.wmqfte=all\",\"agentSslKeyStorePassword\":\"passw0rd\"}}," +
+		"{\"name\":\"BRIDGE\",\"type\":\"BRIDGE\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"additionalProperties\":{\"enableQueueInputOutput\":\"true\"}}]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plans := explainProperties(agentConfiguration, agentConfiguration.Agents, "/mnt/mftdata")
+	if len(plans) != 4 || !strings.HasSuffix(plans[0].path, "/QM1/coordination.properties") || !strings.HasSuffix(plans[3].path, "/agents/BRIDGE/agent.properties") {
+		t.Fatalf("Unexpected properties files %v", plans)
+	}
+
+	// Credentials file set by the container replaces the value of the user
+	coordinationPlan := plans[0]
+	property := findExplainedProperty(coordinationPlan, "coordinationQMgrAuthenticationCredentialsFile")
+	if property == nil || property.source != PROPERTY_SOURCE_CREDENTIALS || property.value != "/mnt/mftdata/mqft/config/QM1/coordcredentials.xml" {
+		t.Errorf("Unexpected credentials file property %+v", property)
+	}
+	if len(coordinationPlan.warnings) != 1 || !strings.Contains(coordinationPlan.warnings[0], "replaced by credentials") {
+		t.Errorf("Unexpected coordination warnings %v", coordinationPlan.warnings)
+	}
+	if property = findExplainedProperty(coordinationPlan, "coordinationQMgrPort"); property == nil || property.value != "1414" || property.source != "fteSetupCoordination" {
+		t.Errorf("Unexpected coordination port property %+v", property)
+	}
+
+	agentPlan := plans[2]
+	if property = findExplainedProperty(agentPlan, "logCapture"); property == nil || property.value != "false" || property.source != "additionalProperties, overrides container default" {
+		t.Errorf("Unexpected logCapture property %+v", property)
+	}
+	if property = findExplainedProperty(agentPlan, "maxRestartCount"); property == nil || property.value != "0" || property.source != PROPERTY_SOURCE_DEFAULT {
+		t.Errorf("Unexpected maxRestartCount property %+v", property)
+	}
+	if property = findExplainedProperty(agentPlan, "trace"); property == nil || property.value != "com.ibm.wmqfte=all" {
+		t.Errorf("Last value of duplicated property not used %+v", property)
+	}
+	if property = findExplainedProperty(agentPlan, "agentSslKeyStorePassword"); property == nil || property.value != utils.REDACTED_TEXT {
+		t.Errorf("Secret not redacted %+v", property)
+	}
+	if len(agentPlan.warnings) != 1 || !strings.Contains(agentPlan.warnings[0], "trace is specified more than once") {
+		t.Errorf("Unexpected agent warnings %v", agentPlan.warnings)
+	}
+
+	bridgePlan := plans[3]
+	if property = findExplainedProperty(bridgePlan, "enableQueueInputOutput"); property == nil || property.value != "false" || property.source != PROPERTY_SOURCE_BRIDGE {
+		t.Errorf("Unexpected enableQueueInputOutput property %+v", property)
+	}
+	if property = findExplainedProperty(bridgePlan, "agentName"); property == nil || property.source != "fteCreateBridgeAgent" {
+		t.Errorf("Unexpected agentName property %+v", property)
+	}
+	if len(bridgePlan.warnings) != 1 || !strings.Contains(bridgePlan.warnings[0], "overridden by bridge agent setup") {
+		t.Errorf("Unexpected bridge agent warnings %v", bridgePlan.warnings)
+	}
+}
+
+func TestExplainCcdtProperties(t *testing.T) {
+	agentConfiguration, err := config.Parse("{\"coordinationQMgr\":{\"name\":\"QM1\",\"connectionNameList\":\"qm1a(1414),qm1b(1414)\"}," +
+		"\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[" +
+		"{\"name\":\"SRC\",\"qmgrName\":\"QM1\",\"qmgrCcdtUrl\":\"https://ccdt.example.com/ccdt.json\"}]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plans := explainProperties(agentConfiguration, agentConfiguration.Agents, "/mnt/mftdata")
+	coordinationPlan := plans[0]
+	if property := findExplainedProperty(coordinationPlan, "coordinationCcdtUrl"); property == nil ||
+		property.value != "file:///mnt/mftdata/mqft/config/QM1/coordinationccdt.json" || property.source != PROPERTY_SOURCE_CCDT {
+		t.Errorf("Unexpected coordination CCDT property %+v", property)
+	}
+	if property := findExplainedProperty(coordinationPlan, "coordinationQMgrHost"); property == nil || property.value != "qm1a" {
+		t.Errorf("Unexpected coordination host property %+v", property)
+	}
+	if property := findExplainedProperty(plans[1], "connectionCcdtUrl"); property != nil {
+		t.Errorf("Unexpected command CCDT property %+v", property)
+	}
+	agentPlan := plans[2]
+	if property := findExplainedProperty(agentPlan, "agentCcdtUrl"); property == nil || property.value != "https://ccdt.example.com/ccdt.json" {
+		t.Errorf("Unexpected agent CCDT property %+v", property)
+	}
+	if property := findExplainedProperty(agentPlan, "agentQMgrHost"); property != nil {
+		t.Errorf("Host property written for a CCDT %+v", property)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Discovery of the host keys of SFTP servers of bridge agents, trusted on first
+* use. When enabled, the container connects to every SFTP server before the
+* agent is started and reads the host key offered by the server, without
+* authenticating. A key not seen before is pinned in a known hosts file on
+* BFG_DATA and its fingerprint displayed, so that it can be set as the host key
+* of the server credentials. On later starts the key must match the pinned key,
+* otherwise the agent is not started.
+ */
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Default port of SFTP servers
+const SFTP_DEFAULT_PORT = "22"
+
+// Time, in seconds, allowed for reading the host key of a server that does not
+// specify connectionTimeout
+const HOST_KEY_DISCOVERY_TIMEOUT = 30
+
+// Returned by the host key callback to end the handshake once the key is read
+var errHostKeyRead = errors.New("host key read")
+
+// Determine if host keys of SFTP servers are discovered and pinned
+func isHostKeyDiscoveryEnabled() bool {
+	discovery, discoverySet := os.LookupEnv(MFT_BRIDGE_HOST_KEY_DISCOVERY)
+	return discoverySet && strings.EqualFold(strings.TrimSpace(discovery), TEXT_YES)
+}
+
+// Hash used for fingerprints of host keys of the server, MD5 if fingerprintHash
+// is not specified
+func serverFingerprintHash(server *config.ProtocolServer) string {
+	hash := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(server.FingerprintHash)), "-", "")
+	if len(hash) == 0 {
+		return FINGERPRINT_MD5
+	}
+	return hash
+}
+
+// Fingerprint of a host key in the format expected by fingerprintHash of the
+// server
+func hostKeyFingerprint(key ssh.PublicKey, server *config.ProtocolServer) string {
+	if serverFingerprintHash(server) == FINGERPRINT_SHA256 {
+		return ssh.FingerprintSHA256(key)
+	}
+	return ssh.FingerprintLegacyMD5(key)
+}
+
+// Read the host key of the SFTP server at the address. The handshake ends once
+// the key is received, the container never authenticates to the server.
+func readHostKey(address string, algorithms []string, timeout time.Duration) (ssh.PublicKey, net.Addr, error) {
+	var hostKey ssh.PublicKey
+	var remote net.Addr
+	clientConfig := &ssh.ClientConfig{
+		User: "mqmft",
+		HostKeyCallback: func(hostname string, remoteAddr net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			remote = remoteAddr
+			return errHostKeyRead
+		},
+		HostKeyAlgorithms: algorithms,
+		Timeout:           timeout,
+	}
+	client, err := ssh.Dial("tcp", address, clientConfig)
+	if client != nil {
+		client.Close()
+	}
+	if hostKey == nil {
+		if err == nil {
+			err = errors.New("the server did not offer a host key")
+		}
+		return nil, nil, err
+	}
+	return hostKey, remote, nil
+}
+
+// Discover the host keys of the SFTP servers of a bridge agent and check them
+// against the keys pinned in the known hosts file. Keys of servers seen for
+// the first time are pinned. Returns false if the key of a server has changed.
+// Servers that can not be reached are reported and do not stop the agent.
+func verifyBridgeHostKeys(agent *config.Agent, knownHostsFile string) bool {
+	if agent.Type != AGENT_TYPE_BRIDGE || !isHostKeyDiscoveryEnabled() {
+		return true
+	}
+	if dryRunEnabled {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_HOST_KEY_DRY_RUN_0171, agent.Name))
+		return true
+	}
+
+	// The known hosts file is created on first use
+	file, err := os.OpenFile(knownHostsFile, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_KNOWN_HOSTS_FAILED_0172, knownHostsFile, err))
+		return false
+	}
+	file.Close()
+
+	verified := true
+	for _, server := range agent.ProtocolServers {
+		host := strings.TrimSpace(server.Host)
+		if bridgeServerProtocol(server) != BRIDGE_PROTOCOL_SFTP || len(host) == 0 {
+			continue
+		}
+		port := SFTP_DEFAULT_PORT
+		if server.Port != nil {
+			port = server.Port.String()
+		}
+		address := net.JoinHostPort(host, port)
+		timeout := HOST_KEY_DISCOVERY_TIMEOUT * time.Second
+		if server.ConnectionTimeout != nil && *server.ConnectionTimeout > 0 {
+			timeout = time.Duration(*server.ConnectionTimeout) * time.Second
+		}
+		// Keys of the algorithms the bridge agent accepts are read
+		var algorithms []string
+		for _, algorithm := range strings.Split(server.HostKeyCipherList, ",") {
+			if algorithm = strings.TrimSpace(algorithm); len(algorithm) > 0 {
+				algorithms = append(algorithms, algorithm)
+			}
+		}
+
+		key, remote, err := readHostKey(address, algorithms, timeout)
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_HOST_KEY_UNREADABLE_0167, server.Name, agent.Name, address, err))
+			continue
+		}
+		if !verifyHostKey(server, address, remote, key, knownHostsFile) {
+			verified = false
+		}
+	}
+	return verified
+}
+
+// Check the host key of a server against the known hosts file, pinning it if
+// the server is not known
+func verifyHostKey(server *config.ProtocolServer, address string, remote net.Addr, key ssh.PublicKey, knownHostsFile string) bool {
+	fingerprint := hostKeyFingerprint(key, server)
+	// Known hosts file is read again as keys may have been pinned for other servers
+	callback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_KNOWN_HOSTS_FAILED_0172, knownHostsFile, err))
+		return false
+	}
+	err = callback(address, remote, key)
+	if err == nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_HOST_KEY_VERIFIED_0169, server.Name, address, knownHostsFile, fingerprint))
+		return true
+	}
+
+	var keyError *knownhosts.KeyError
+	if !errors.As(err, &keyError) || len(keyError.Want) > 0 {
+		// Key differs from the pinned key, or has been revoked
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_HOST_KEY_CHANGED_0170, server.Name, address, fingerprint, knownHostsFile, knownhosts.Normalize(address)))
+		return false
+	}
+
+	// Server seen for the first time
+	file, err := os.OpenFile(knownHostsFile, os.O_APPEND|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = file.WriteString(knownhosts.Line([]string{knownhosts.Normalize(address)}, key) + "\n")
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_KNOWN_HOSTS_FAILED_0172, knownHostsFile, err))
+		return false
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_HOST_KEY_PINNED_0168, server.Name, address, knownHostsFile, fingerprint,
+		base64.StdEncoding.EncodeToString([]byte(fingerprint))))
+	return true
+}
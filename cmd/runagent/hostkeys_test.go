@@ -0,0 +1,179 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// SFTP server that only offers its host key
+type testSSHServer struct {
+	listener net.Listener
+	mutex    sync.Mutex
+	signer   ssh.Signer
+}
+
+func newTestSSHServer(t *testing.T) *testSSHServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testSSHServer{listener: listener}
+	server.changeHostKey(t)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			serverConfig := &ssh.ServerConfig{NoClientAuth: true}
+			server.mutex.Lock()
+			serverConfig.AddHostKey(server.signer)
+			server.mutex.Unlock()
+			go func() {
+				// Handshake fails once the client has read the host key
+				ssh.NewServerConn(conn, serverConfig)
+				conn.Close()
+			}()
+		}
+	}()
+	return server
+}
+
+func (server *testSSHServer) changeHostKey(t *testing.T) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.mutex.Lock()
+	server.signer = signer
+	server.mutex.Unlock()
+}
+
+func (server *testSSHServer) agent(t *testing.T) *config.Agent {
+	t.Helper()
+	_, port, _ := net.SplitHostPort(server.listener.Addr().String())
+	configuration, err := config.Parse(`{"agents":[{"name":"PBA","type":"bridge","protocolServers":[
+		{"name":"sftp1","type":"SFTP","host":"127.0.0.1","port":` + port + `,"connectionTimeout":5},
+		{"name":"ftp1","host":"ftp.example.com"}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configuration.Agent("PBA")
+}
+
+func readKnownHosts(t *testing.T, knownHostsFile string) []string {
+	t.Helper()
+	data, err := os.ReadFile(knownHostsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestVerifyBridgeHostKeys(t *testing.T) {
+	server := newTestSSHServer(t)
+	agent := server.agent(t)
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+
+	// Host keys are not discovered unless asked to
+	if !verifyBridgeHostKeys(agent, knownHostsFile) {
+		t.Fatal("Agent not started when discovery is disabled")
+	}
+	if _, err := os.Stat(knownHostsFile); !os.IsNotExist(err) {
+		t.Fatalf("Known hosts file created when discovery is disabled: %v", err)
+	}
+
+	t.Setenv(MFT_BRIDGE_HOST_KEY_DISCOVERY, "Yes")
+	if !verifyBridgeHostKeys(agent, knownHostsFile) {
+		t.Fatal("Agent not started when host key is seen for the first time")
+	}
+	lines := readKnownHosts(t, knownHostsFile)
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "[127.0.0.1]:"+agent.ProtocolServers[0].Port.String()+" ssh-ed25519 ") {
+		t.Fatalf("Unexpected known hosts %q", lines)
+	}
+
+	// Pinned key is not added again
+	if !verifyBridgeHostKeys(agent, knownHostsFile) {
+		t.Fatal("Agent not started when host key matches the pinned key")
+	}
+	if lines := readKnownHosts(t, knownHostsFile); len(lines) != 1 {
+		t.Fatalf("Unexpected known hosts %q", lines)
+	}
+
+	server.changeHostKey(t)
+	if verifyBridgeHostKeys(agent, knownHostsFile) {
+		t.Fatal("Agent started when host key has changed")
+	}
+	if lines := readKnownHosts(t, knownHostsFile); len(lines) != 1 {
+		t.Fatalf("Changed key pinned %q", lines)
+	}
+}
+
+func TestVerifyBridgeHostKeysUnreachable(t *testing.T) {
+	server := newTestSSHServer(t)
+	agent := server.agent(t)
+	server.listener.Close()
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+
+	// Servers that can not be reached do not stop the agent
+	t.Setenv(MFT_BRIDGE_HOST_KEY_DISCOVERY, "yes")
+	if !verifyBridgeHostKeys(agent, knownHostsFile) {
+		t.Fatal("Agent not started when server can not be reached")
+	}
+	if data, err := os.ReadFile(knownHostsFile); err != nil || len(data) != 0 {
+		t.Fatalf("Unexpected known hosts %q %v", data, err)
+	}
+}
+
+func TestHostKeyFingerprint(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Fingerprints are accepted by the check of the credentials file
+	for hash, expected := range map[string]string{"": ssh.FingerprintLegacyMD5(key), "MD5": ssh.FingerprintLegacyMD5(key), "SHA-256": ssh.FingerprintSHA256(key)} {
+		server := &config.ProtocolServer{Name: "sftp1", FingerprintHash: hash}
+		fingerprint := hostKeyFingerprint(key, server)
+		if fingerprint != expected {
+			t.Errorf("Fingerprint %s for hash %q, expected %s", fingerprint, hash, expected)
+		}
+		if err := validateBridgeHostKey(encode(fingerprint), server); err != nil {
+			t.Errorf("Fingerprint %s for hash %q not valid: %v", fingerprint, hash, err)
+		}
+	}
+}
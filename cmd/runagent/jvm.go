@@ -0,0 +1,241 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* JVM options of agents. Options from the jvm section of an agent are added to
+* BFG_JVM_PROPERTIES of the fteStartAgent command, which starts the agent JVM.
+* When the maximum heap is not specified, -Xmx and -Xms are computed from the
+* memory limit of the container, read from cgroup v2 memory.max or cgroup v1
+* memory.limit_in_bytes, so that agents stay within the limit.
+ */
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Percentage of the container memory limit used for heaps by default
+const JVM_DEFAULT_HEAP_PERCENTAGE = 50
+
+// Smallest maximum heap, in MB, computed for an agent. Agents fail with out
+// of memory errors soon after starting with less.
+const JVM_MINIMUM_HEAP_MB = 128
+
+// Garbage collection policies of the IBM JVM shipped with MFT
+var jvmGCPolicies = []string{"gencon", "balanced", "optthruput", "optavgpause", "metronome", "nogc"}
+
+// Files holding the memory limit of the container. Replaced by tests.
+var cgroupV2MemoryMax = "/sys/fs/cgroup/memory.max"
+var cgroupV1MemoryLimit = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+
+// cgroup v1 reports a value close to the maximum int64 when there is no limit
+const cgroupV1Unlimited = int64(1) << 62
+
+// Sizes such as 512m or 2g as accepted by -Xmx and -Xms
+var jvmSizePattern = regexp.MustCompile(`^([0-9]+)([kKmMgG]?)$`)
+
+// Convert a JVM size to bytes
+func parseJVMSize(size string) (int64, error) {
+	match := jvmSizePattern.FindStringSubmatch(strings.TrimSpace(size))
+	if match == nil {
+		return 0, fmt.Errorf("size %q is not a number optionally followed by k, m or g", size)
+	}
+	value, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil || value == 0 {
+		return 0, fmt.Errorf("size %q is not a positive number", size)
+	}
+	switch strings.ToLower(match[2]) {
+	case "k":
+		value <<= 10
+	case "m":
+		value <<= 20
+	case "g":
+		value <<= 30
+	}
+	return value, nil
+}
+
+// Validate the jvm section of an agent. Values are passed to the JVM through
+// BFG_JVM_PROPERTIES, which is split on blanks, so they must not contain any.
+func validateJVMOptions(agent *config.Agent) error {
+	jvm := agent.JVM
+	if jvm == nil {
+		return nil
+	}
+	var maxHeap, initialHeap int64
+	var err error
+	if len(jvm.MaxHeap) > 0 {
+		if maxHeap, err = parseJVMSize(jvm.MaxHeap); err != nil {
+			return fmt.Errorf("maxHeap %v", err)
+		}
+	}
+	if len(jvm.InitialHeap) > 0 {
+		if initialHeap, err = parseJVMSize(jvm.InitialHeap); err != nil {
+			return fmt.Errorf("initialHeap %v", err)
+		}
+	}
+	if maxHeap > 0 && initialHeap > maxHeap {
+		return fmt.Errorf("initialHeap %s is larger than maxHeap %s", jvm.InitialHeap, jvm.MaxHeap)
+	}
+	if jvm.HeapPercentage != nil {
+		if len(jvm.MaxHeap) > 0 {
+			return errors.New("heapPercentage must not be specified with maxHeap")
+		}
+		if int(*jvm.HeapPercentage) < 1 || int(*jvm.HeapPercentage) > 100 {
+			return fmt.Errorf("heapPercentage has value %d which is not within range 1 to 100", int(*jvm.HeapPercentage))
+		}
+	}
+	if len(jvm.GCPolicy) > 0 && !isEnumValue(jvmGCPolicies, jvm.GCPolicy) {
+		return fmt.Errorf("gcPolicy %q is not one of %s", jvm.GCPolicy, strings.Join(jvmGCPolicies, ", "))
+	}
+	for _, property := range jvm.SystemProperties {
+		if len(property.Name) == 0 || strings.ContainsAny(property.Name, " \t\r\n=") {
+			return fmt.Errorf("system property name %q is not valid", property.Name)
+		}
+		if strings.ContainsAny(property.Value, " \t\r\n") {
+			return fmt.Errorf("value of system property %s must not contain blanks", property.Name)
+		}
+	}
+	if len(jvm.DumpPath) > 0 && (!filepath.IsAbs(jvm.DumpPath) || strings.ContainsAny(jvm.DumpPath, " \t\r\n")) {
+		return fmt.Errorf("dumpPath %q must be an absolute path without blanks", jvm.DumpPath)
+	}
+	for _, option := range jvm.Options {
+		if !strings.HasPrefix(option, "-") || strings.ContainsAny(option, " \t\r\n") {
+			return fmt.Errorf("option %q must start with - and must not contain blanks", option)
+		}
+	}
+	return nil
+}
+
+// Read the memory limit of the container in bytes. cgroup v2 is tried first.
+func containerMemoryLimit() (int64, error) {
+	if data, err := os.ReadFile(cgroupV2MemoryMax); err == nil {
+		value := strings.TrimSpace(string(data))
+		if value == "max" {
+			return 0, fmt.Errorf("%s is max", cgroupV2MemoryMax)
+		}
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit <= 0 {
+			return 0, fmt.Errorf("%s has value %q", cgroupV2MemoryMax, value)
+		}
+		return limit, nil
+	}
+	data, err := os.ReadFile(cgroupV1MemoryLimit)
+	if err != nil {
+		return 0, fmt.Errorf("neither %s nor %s could be read", cgroupV2MemoryMax, cgroupV1MemoryLimit)
+	}
+	value := strings.TrimSpace(string(data))
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("%s has value %q", cgroupV1MemoryLimit, value)
+	}
+	if limit >= cgroupV1Unlimited {
+		return 0, fmt.Errorf("%s is unlimited", cgroupV1MemoryLimit)
+	}
+	return limit, nil
+}
+
+// Percentage of the container memory limit used for the heap of an agent,
+// from the jvm section, MFT_JVM_HEAP_PERCENTAGE or the default.
+func heapPercentage(jvm *config.JVMOptions) int {
+	if jvm.HeapPercentage != nil {
+		return int(*jvm.HeapPercentage)
+	}
+	envValue, envSet := os.LookupEnv(MFT_JVM_HEAP_PERCENTAGE)
+	if !envSet || len(strings.TrimSpace(envValue)) == 0 {
+		return JVM_DEFAULT_HEAP_PERCENTAGE
+	}
+	percentage, err := strconv.Atoi(strings.TrimSpace(envValue))
+	if err != nil || percentage < 1 || percentage > 100 {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_JVM_ENV_INVALID_0155, envValue, MFT_JVM_HEAP_PERCENTAGE, JVM_DEFAULT_HEAP_PERCENTAGE))
+		return JVM_DEFAULT_HEAP_PERCENTAGE
+	}
+	return percentage
+}
+
+// Build the JVM options of an agent. When maxHeap is not specified, the
+// percentage of the container memory limit is shared equally by the agents
+// run by the container and -Xms, unless specified, is half of -Xmx. The heap
+// is not set if the share is below the minimum and -Xms never exceeds it.
+func agentJVMOptions(agent *config.Agent, agentCount int) []string {
+	jvm := agent.JVM
+	if jvm == nil {
+		jvm = &config.JVMOptions{}
+	}
+	maxHeap := strings.TrimSpace(jvm.MaxHeap)
+	initialHeap := strings.TrimSpace(jvm.InitialHeap)
+	if len(maxHeap) == 0 {
+		limit, err := containerMemoryLimit()
+		if err != nil {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_JVM_NO_MEMORY_LIMIT_0152, agent.Name, err))
+		} else {
+			if agentCount < 1 {
+				agentCount = 1
+			}
+			percentage := heapPercentage(jvm)
+			maxHeapBytes := limit * int64(percentage) / 100 / int64(agentCount)
+			if maxHeapBytes < JVM_MINIMUM_HEAP_MB<<20 {
+				// Raising the heap to the minimum would exceed the memory limit
+				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_JVM_HEAP_MINIMUM_0173, agent.Name, maxHeapBytes>>20, JVM_MINIMUM_HEAP_MB))
+			} else {
+				maxHeap = fmt.Sprintf("%dm", maxHeapBytes>>20)
+				if len(initialHeap) == 0 {
+					initialHeap = fmt.Sprintf("%dm", maxHeapBytes>>21)
+				} else if initialHeapBytes, err := parseJVMSize(initialHeap); err == nil && initialHeapBytes > maxHeapBytes {
+					// JVM does not start if initial heap is larger
+					utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_JVM_INITIAL_HEAP_REDUCED_0174, initialHeap, agent.Name, maxHeapBytes>>20))
+					initialHeap = maxHeap
+				}
+				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_JVM_HEAP_COMPUTED_0151, agent.Name, initialHeap, maxHeap, percentage, limit>>20, agentCount))
+			}
+		}
+	}
+
+	var options []string
+	if len(initialHeap) > 0 {
+		options = append(options, "-Xms"+initialHeap)
+	}
+	if len(maxHeap) > 0 {
+		options = append(options, "-Xmx"+maxHeap)
+	}
+	if len(jvm.GCPolicy) > 0 {
+		options = append(options, "-Xgcpolicy:"+strings.ToLower(strings.TrimSpace(jvm.GCPolicy)))
+	}
+	if jvm.VerboseGC != nil && bool(*jvm.VerboseGC) {
+		options = append(options, "-verbose:gc")
+	}
+	if len(jvm.DumpPath) > 0 {
+		options = append(options, "-Xdump:directory="+jvm.DumpPath)
+	}
+	for _, property := range jvm.SystemProperties {
+		options = append(options, "-D"+property.Name+"="+property.Value)
+	}
+	return append(options, jvm.Options...)
+}
+
+// Value of BFG_JVM_PROPERTIES for an agent, the options of the container
+// followed by the options of the agent so that the latter take precedence.
+func agentJVMProperties(options []string) string {
+	return strings.TrimSpace(os.Getenv(MFT_BFG_JVM_PROPERTIES) + " " + strings.Join(options, " "))
+}
@@ -0,0 +1,188 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+// Point the cgroup files at the given values. A blank value means the file
+// does not exist.
+func setTestMemoryLimit(t *testing.T, v2Value string, v1Value string) {
+	t.Helper()
+	dir := t.TempDir()
+	savedV2, savedV1 := cgroupV2MemoryMax, cgroupV1MemoryLimit
+	cgroupV2MemoryMax = filepath.Join(dir, "memory.max")
+	cgroupV1MemoryLimit = filepath.Join(dir, "memory.limit_in_bytes")
+	t.Cleanup(func() { cgroupV2MemoryMax, cgroupV1MemoryLimit = savedV2, savedV1 })
+	if len(v2Value) > 0 {
+		if err := os.WriteFile(cgroupV2MemoryMax, []byte(v2Value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(v1Value) > 0 {
+		if err := os.WriteFile(cgroupV1MemoryLimit, []byte(v1Value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParseJVMSize(t *testing.T) {
+	tests := map[string]int64{"1024": 1024, "64k": 64 << 10, "512m": 512 << 20, "2G": 2 << 30}
+	for size, expected := range tests {
+		if value, err := parseJVMSize(size); err != nil || value != expected {
+			t.Errorf("Unexpected value %d %v of %s", value, err, size)
+		}
+	}
+	for _, size := range []string{"", "0m", "1.5g", "512mb", "-1m"} {
+		if _, err := parseJVMSize(size); err == nil {
+			t.Errorf("Size %q accepted", size)
+		}
+	}
+}
+
+func TestValidateJVMOptions(t *testing.T) {
+	percentage := config.Int(75)
+	valid := &config.Agent{Name: "SRC", JVM: &config.JVMOptions{InitialHeap: "256m", HeapPercentage: &percentage, GCPolicy: "Gencon",
+		SystemProperties: config.Properties{{Name: "com.ibm.test", Value: "true"}}, DumpPath: "/mnt/dumps", Options: []string{"-Xshareclasses:none"}}}
+	if err := validateJVMOptions(valid); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := validateJVMOptions(&config.Agent{Name: "SRC"}); err != nil {
+		t.Errorf("Unexpected error without jvm section %v", err)
+	}
+
+	zero := config.Int(0)
+	tests := []struct {
+		jvm *config.JVMOptions
+		err string
+	}{
+		{&config.JVMOptions{MaxHeap: "1gb"}, "maxHeap size \"1gb\" is not a number optionally followed by k, m or g"},
+		{&config.JVMOptions{MaxHeap: "512m", InitialHeap: "1g"}, "initialHeap 1g is larger than maxHeap 512m"},
+		{&config.JVMOptions{MaxHeap: "512m", HeapPercentage: &percentage}, "heapPercentage must not be specified with maxHeap"},
+		{&config.JVMOptions{HeapPercentage: &zero}, "heapPercentage has value 0 which is not within range 1 to 100"},
+		{&config.JVMOptions{GCPolicy: "g1"}, "gcPolicy \"g1\" is not one of gencon, balanced, optthruput, optavgpause, metronome, nogc"},
+		{&config.JVMOptions{SystemProperties: config.Properties{{Name: "a b", Value: "1"}}}, "system property name \"a b\" is not valid"},
+		{&config.JVMOptions{SystemProperties: config.Properties{{Name: "a", Value: "1 2"}}}, "value of system property a must not contain blanks"},
+		{&config.JVMOptions{DumpPath: "dumps"}, "dumpPath \"dumps\" must be an absolute path without blanks"},
+		{&config.JVMOptions{Options: []string{"Xshareclasses:none"}}, "option \"Xshareclasses:none\" must start with - and must not contain blanks"},
+	}
+	for _, test := range tests {
+		if err := validateJVMOptions(&config.Agent{Name: "SRC", JVM: test.jvm}); err == nil || err.Error() != test.err {
+			t.Errorf("Expected error %q, found %v", test.err, err)
+		}
+	}
+}
+
+func TestContainerMemoryLimit(t *testing.T) {
+	tests := []struct {
+		v2, v1 string
+		limit  int64
+		err    string
+	}{
+		{"1073741824", "", 1 << 30, ""},
+		{"max", "", 0, "memory.max is max"},
+		{"", "536870912", 512 << 20, ""},
+		{"", "9223372036854771712", 0, "memory.limit_in_bytes is unlimited"},
+		{"", "", 0, "could be read"},
+	}
+	for _, test := range tests {
+		setTestMemoryLimit(t, test.v2, test.v1)
+		limit, err := containerMemoryLimit()
+		if len(test.err) == 0 {
+			if err != nil || limit != test.limit {
+				t.Errorf("Unexpected limit %d %v for %+v", limit, err, test)
+			}
+		} else if err == nil || !strings.HasSuffix(err.Error(), test.err) {
+			t.Errorf("Expected error ending with %q, found %v", test.err, err)
+		}
+	}
+}
+
+func TestAgentJVMOptions(t *testing.T) {
+	setTestMemoryLimit(t, "2147483648", "")
+	verbose := config.Bool(true)
+	agent := &config.Agent{Name: "SRC", JVM: &config.JVMOptions{GCPolicy: "Balanced", VerboseGC: &verbose, DumpPath: "/mnt/dumps",
+		SystemProperties: config.Properties{{Name: "com.ibm.test", Value: "true"}}, Options: []string{"-Xshareclasses:none"}}}
+	expected := []string{"-Xms512m", "-Xmx1024m", "-Xgcpolicy:balanced", "-verbose:gc", "-Xdump:directory=/mnt/dumps",
+		"-Dcom.ibm.test=true", "-Xshareclasses:none"}
+	if options := agentJVMOptions(agent, 1); !reflect.DeepEqual(options, expected) {
+		t.Errorf("Unexpected options %v", options)
+	}
+
+	// Heap percentage is shared by the agents of the container
+	t.Setenv(MFT_JVM_HEAP_PERCENTAGE, "75")
+	if options := agentJVMOptions(&config.Agent{Name: "SRC"}, 2); !reflect.DeepEqual(options, []string{"-Xms384m", "-Xmx768m"}) {
+		t.Errorf("Unexpected options for heap percentage %v", options)
+	}
+	t.Setenv(MFT_JVM_HEAP_PERCENTAGE, "150")
+	if options := agentJVMOptions(&config.Agent{Name: "SRC"}, 1); !reflect.DeepEqual(options, []string{"-Xms512m", "-Xmx1024m"}) {
+		t.Errorf("Unexpected options for invalid heap percentage %v", options)
+	}
+
+	// Initial heap larger than the computed maximum heap is reduced to it
+	agent = &config.Agent{Name: "SRC", JVM: &config.JVMOptions{InitialHeap: "2g"}}
+	if options := agentJVMOptions(agent, 1); !reflect.DeepEqual(options, []string{"-Xms1024m", "-Xmx1024m"}) {
+		t.Errorf("Unexpected options for initial heap %v", options)
+	}
+
+	// Heap specified in the jvm section is used as is
+	agent = &config.Agent{Name: "SRC", JVM: &config.JVMOptions{MaxHeap: "768m"}}
+	if options := agentJVMOptions(agent, 1); !reflect.DeepEqual(options, []string{"-Xmx768m"}) {
+		t.Errorf("Unexpected options for max heap %v", options)
+	}
+
+	// Heap is not set when the share is below the minimum
+	setTestMemoryLimit(t, "", "268435456")
+	if options := agentJVMOptions(&config.Agent{Name: "SRC"}, 4); len(options) != 0 {
+		t.Errorf("Unexpected options for small memory limit %v", options)
+	}
+	if options := agentJVMOptions(&config.Agent{Name: "SRC"}, 1); !reflect.DeepEqual(options, []string{"-Xms64m", "-Xmx128m"}) {
+		t.Errorf("Unexpected options for minimum heap %v", options)
+	}
+
+	// Nothing is set without a memory limit
+	setTestMemoryLimit(t, "max", "")
+	if options := agentJVMOptions(&config.Agent{Name: "SRC"}, 1); len(options) != 0 {
+		t.Errorf("Unexpected options without memory limit %v", options)
+	}
+}
+
+func TestStartAgentJVMProperties(t *testing.T) {
+	fake := &fakeCommandRunner{}
+	runner = fake
+	defer func() { runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second) }()
+	t.Setenv(MFT_BFG_JVM_PROPERTIES, "-Dcontainer=true")
+
+	if started, _ := StartAgent("SRC", "QM1", []string{"-Xmx512m", "-Dagent=true"}); !started {
+		t.Fatal("Agent not started")
+	}
+	if started, _ := StartAgent("DEST", "QM1", nil); !started {
+		t.Fatal("Agent not started")
+	}
+	if len(fake.commands) != 2 || !reflect.DeepEqual(fake.commands[0].env, []string{"BFG_JVM_PROPERTIES=-Dcontainer=true -Xmx512m -Dagent=true"}) ||
+		len(fake.commands[1].env) != 0 {
+		t.Errorf("Unexpected commands run: %+v", fake.commands)
+	}
+}
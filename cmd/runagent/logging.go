@@ -462,56 +462,46 @@ func getDebug() bool {
 
 // Setup logger to capture events.
 func configureLogger(name string, logUrl string, logKey string, logType string, logServerType int16) (mirrorFunc, error) {
-	var err error
 	//f := getLogFormat()
 	d := getDebug()
+	// Each mirror gets its own logger, as several agents may be mirrored at the
+	// same time. The most recent logger is also used for diagnostic messages.
+	agentLog, err := logger.NewLogger(os.Stdout, d, false, name, logUrl, logKey, logServerType)
+	if err != nil {
+		return nil, err
+	}
+	eventLog = agentLog
 	switch logType {
 	case "tlog":
-		eventLog, err = logger.NewLogger(os.Stdout, d, false, name, logUrl, logKey, logServerType)
-		if err != nil {
-			return nil, err
-		}
 		return func(msg string) bool {
-			eventLog.PushToLogToServer(msg)
+			agentLog.PushToLogToServer(msg)
 			return true
 		}, nil
 
 	case "json":
-		eventLog, err = logger.NewLogger(os.Stdout, d, false, name, logUrl, logKey, logServerType)
-		if err != nil {
-			return nil, err
-		}
 		return func(msg string) bool {
 			// Parse the JSON message, and print a simplified version
 			obj, err := processLogMessage(msg)
 			if err != nil {
-				eventLog.Printf("Failed to process log message - %v", err)
+				agentLog.Printf("Failed to process log message - %v", err)
 			} else {
-				eventLog.Printf(formatJSON(obj))
+				agentLog.Printf(formatJSON(obj))
 			}
 			return true
 		}, nil
 
 	case "console":
-		eventLog, err = logger.NewLogger(os.Stdout, d, false, name, logUrl, logKey, logServerType)
-		if err != nil {
-			return nil, err
-		}
 		return func(msg string) bool {
 			// Parse the message and print a simplified version
 			obj, err := processLogMessage(msg)
 			if err != nil {
-				eventLog.Printf("Failed to process log message - %v", err)
+				agentLog.Printf("Failed to process log message - %v", err)
 			} else {
-				eventLog.Printf(formatBasic(obj))
+				agentLog.Printf(formatBasic(obj))
 			}
 			return true
 		}, nil
 	default:
-		eventLog, err = logger.NewLogger(os.Stdout, d, false, name, logUrl, logKey, logServerType)
-		if err != nil {
-			return nil, err
-		}
 		return nil, fmt.Errorf("invalid value for LOG_FORMAT: %v", logType)
 	}
 }
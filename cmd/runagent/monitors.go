@@ -0,0 +1,514 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Resource monitors declared in the monitors section of an agent. Each monitor
+* is rendered to a Monitor XML definition. Once the agent is ready, monitors
+* of the agent listed by fteListMonitors are reconciled with the declared
+* ones: missing monitors are created, monitors whose definition has changed
+* since they were created are replaced and monitors that are not declared are
+* deleted. Monitors are not reconciled if the agent has no monitors section,
+* so that monitors created by other means, for example .mftc files, are kept.
+ */
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/subchen/go-xmldom"
+)
+
+// Types of resource monitored
+const MONITOR_RESOURCE_DIRECTORY = "directory"
+const MONITOR_RESOURCE_QUEUE = "queue"
+
+// Trigger conditions of directory monitors
+const MONITOR_TRIGGER_MATCH = "match"
+const MONITOR_TRIGGER_NO_MATCH = "noMatch"
+const MONITOR_TRIGGER_FILE_SIZE = "fileSize"
+const MONITOR_TRIGGER_SIZE_SAME = "sizeSame"
+
+// Trigger conditions of queue monitors
+const MONITOR_TRIGGER_QUEUE_NOT_EMPTY = "queueNotEmpty"
+const MONITOR_TRIGGER_COMPLETE_GROUPS = "completeGroups"
+
+// Supported values of monitor attributes
+var monitorResourceTypes = []string{MONITOR_RESOURCE_DIRECTORY, MONITOR_RESOURCE_QUEUE}
+var monitorDirectoryTriggers = []string{MONITOR_TRIGGER_MATCH, MONITOR_TRIGGER_NO_MATCH, MONITOR_TRIGGER_FILE_SIZE, MONITOR_TRIGGER_SIZE_SAME}
+var monitorQueueTriggers = []string{MONITOR_TRIGGER_QUEUE_NOT_EMPTY, MONITOR_TRIGGER_COMPLETE_GROUPS}
+var monitorPatternTypes = []string{"wildcard", "regex"}
+var monitorPollIntervalUnits = []string{"seconds", "minutes", "hours", "days"}
+var monitorSizeUnits = []string{"B", "KB", "MB", "GB"}
+var monitorDestinationTypes = transferDestinationTypes
+var monitorChecksums = transferChecksums
+var monitorSourceDispositions = transferSourceDispositions
+var monitorModes = transferModes
+
+// Characters that are not valid in monitor names
+var monitorNameInvalidChars = regexp.MustCompile(`[*%?\s]`)
+
+// Name of the provisioning section of a monitor
+func monitorSectionName(agentName string, monitorName string) string {
+	return "monitor " + agentName + "/" + strings.ToUpper(monitorName)
+}
+
+// Type of resource of the monitor, directory if not specified
+func monitorResourceType(monitor *config.Monitor) string {
+	if len(monitor.ResourceType) == 0 {
+		return MONITOR_RESOURCE_DIRECTORY
+	}
+	return strings.ToLower(monitor.ResourceType)
+}
+
+// Trigger condition of the monitor. Files matching * trigger directory
+// monitors and any message triggers queue monitors if not specified.
+func monitorTrigger(monitor *config.Monitor) *config.MonitorTrigger {
+	if monitor.Trigger != nil {
+		return monitor.Trigger
+	}
+	if monitorResourceType(monitor) == MONITOR_RESOURCE_QUEUE {
+		return &config.MonitorTrigger{Condition: MONITOR_TRIGGER_QUEUE_NOT_EMPTY}
+	}
+	return &config.MonitorTrigger{Condition: MONITOR_TRIGGER_MATCH, Pattern: "*"}
+}
+
+// Queue manager of the destination agent of a monitor
+func monitorDestinationQMgr(agentConfiguration *config.Configuration, agent *config.Agent, monitor *config.Monitor) string {
+	return agentQMgrName(agentConfiguration, monitor.DestinationAgent, monitor.DestinationQMgr, agent.QMgrName)
+}
+
+// Queue manager of the named agent. Use the queue manager specified, else
+// that of the agent if it is defined in the configuration file, else the
+// given default.
+func agentQMgrName(agentConfiguration *config.Configuration, agentName string, qmgrName string, defaultQMgr string) string {
+	if len(qmgrName) > 0 {
+		return qmgrName
+	}
+	if namedAgent := agentConfiguration.Agent(agentName); namedAgent != nil {
+		return namedAgent.QMgrName
+	}
+	return defaultQMgr
+}
+
+// Validate the monitors of an agent
+func validateMonitors(agent *config.Agent) error {
+	names := make(map[string]bool)
+	for index, monitor := range agent.Monitors {
+		if err := validateMonitor(monitor); err != nil {
+			return fmt.Errorf("monitors[%d] is not valid, %v", index, err)
+		}
+		name := strings.ToUpper(monitor.Name)
+		if names[name] {
+			return fmt.Errorf("monitors[%d] is not valid, monitor %s is defined more than once", index, monitor.Name)
+		}
+		names[name] = true
+	}
+	return nil
+}
+
+// Validate a monitor
+func validateMonitor(monitor *config.Monitor) error {
+	if len(strings.TrimSpace(monitor.Name)) == 0 {
+		return fmt.Errorf("name must be specified")
+	}
+	if monitorNameInvalidChars.MatchString(monitor.Name) {
+		return fmt.Errorf("name %q must not contain blanks or the characters *, %% and ?", monitor.Name)
+	}
+	resourceType := monitorResourceType(monitor)
+	if !isEnumValue(monitorResourceTypes, resourceType) {
+		return fmt.Errorf("resourceType %q is not one of %s", monitor.ResourceType, strings.Join(monitorResourceTypes, ", "))
+	}
+	if len(strings.TrimSpace(monitor.Resource)) == 0 {
+		return fmt.Errorf("resource must be specified")
+	}
+	if resourceType == MONITOR_RESOURCE_DIRECTORY && !strings.HasPrefix(monitor.Resource, "/") {
+		return fmt.Errorf("resource %q must be an absolute path", monitor.Resource)
+	}
+	if len(strings.TrimSpace(monitor.DestinationAgent)) == 0 {
+		return fmt.Errorf("destinationAgent must be specified")
+	}
+	if len(strings.TrimSpace(monitor.DestinationPath)) == 0 {
+		return fmt.Errorf("destinationPath must be specified")
+	}
+	if err := validateMonitorTrigger(resourceType, monitorTrigger(monitor)); err != nil {
+		return err
+	}
+	if err := validateMonitorNumber("recursionLevel", monitor.RecursionLevel, 0); err != nil {
+		return err
+	}
+	if err := validateMonitorNumber("pollInterval", monitor.PollInterval, 1); err != nil {
+		return err
+	}
+	if err := validateMonitorNumber("batchSize", monitor.BatchSize, 1); err != nil {
+		return err
+	}
+	enums := []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{"pollIntervalUnits", monitor.PollIntervalUnits, monitorPollIntervalUnits},
+		{"destinationType", monitor.DestinationType, monitorDestinationTypes},
+		{"checksum", monitor.Checksum, monitorChecksums},
+		{"sourceDisposition", monitor.SourceDisposition, monitorSourceDispositions},
+		{"mode", monitor.Mode, monitorModes},
+	}
+	for _, enum := range enums {
+		if len(enum.value) > 0 && !isEnumValue(enum.allowed, enum.value) {
+			return fmt.Errorf("%s %q is not one of %s", enum.name, enum.value, strings.Join(enum.allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// Validate the trigger condition of a monitor against the type of resource
+func validateMonitorTrigger(resourceType string, trigger *config.MonitorTrigger) error {
+	conditions := monitorDirectoryTriggers
+	if resourceType == MONITOR_RESOURCE_QUEUE {
+		conditions = monitorQueueTriggers
+	}
+	if !isEnumValue(conditions, trigger.Condition) {
+		return fmt.Errorf("trigger condition %q is not one of %s for %s monitors", trigger.Condition, strings.Join(conditions, ", "), resourceType)
+	}
+	if resourceType == MONITOR_RESOURCE_QUEUE {
+		return nil
+	}
+	if len(trigger.PatternType) > 0 && !isEnumValue(monitorPatternTypes, trigger.PatternType) {
+		return fmt.Errorf("trigger patternType %q is not one of %s", trigger.PatternType, strings.Join(monitorPatternTypes, ", "))
+	}
+	if strings.EqualFold(trigger.PatternType, "regex") {
+		if _, err := regexp.Compile(trigger.Pattern); err != nil {
+			return fmt.Errorf("trigger pattern %q is not a valid regular expression: %v", trigger.Pattern, err)
+		}
+	}
+	if strings.EqualFold(trigger.Condition, MONITOR_TRIGGER_FILE_SIZE) {
+		if trigger.Size == nil {
+			return fmt.Errorf("trigger size must be specified for %s condition", MONITOR_TRIGGER_FILE_SIZE)
+		}
+		if err := validateMonitorNumber("trigger size", trigger.Size, 1); err != nil {
+			return err
+		}
+		if len(trigger.SizeUnits) > 0 && !isEnumValue(monitorSizeUnits, trigger.SizeUnits) {
+			return fmt.Errorf("trigger sizeUnits %q is not one of %s", trigger.SizeUnits, strings.Join(monitorSizeUnits, ", "))
+		}
+	}
+	return validateMonitorNumber("trigger polls", trigger.Polls, 1)
+}
+
+// Validate that a number, if specified, is not less than the minimum
+func validateMonitorNumber(name string, value *config.Int, minimum int) error {
+	if value != nil && int(*value) < minimum {
+		return fmt.Errorf("%s has value %d which is less than %d", name, int(*value), minimum)
+	}
+	return nil
+}
+
+// Build the Monitor XML definition of a monitor of the given agent
+func getMonitorXml(agent *config.Agent, monitor *config.Monitor, destinationQMgr string) string {
+	monitorDoc := xmldom.NewDocument("monitor:monitor")
+	monitorDoc.Root.SetAttributeValue("version", "6.00")
+	monitorDoc.Root.SetAttributeValue("xmlns:monitor", "http://www.ibm.com/xmlns/wmqfte/7.0.1/MonitorDefinition")
+	monitorDoc.Root.SetAttributeValue("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
+	monitorDoc.Root.SetAttributeValue("xsi:schemaLocation", "http://www.ibm.com/xmlns/wmqfte/7.0.1/MonitorDefinition ./Monitor.xsd")
+	root := monitorDoc.Root
+
+	root.CreateNode("name").Text = strings.ToUpper(monitor.Name)
+	if len(monitor.Description) > 0 {
+		root.CreateNode("description").Text = monitor.Description
+	}
+	pollInterval := 1
+	if monitor.PollInterval != nil {
+		pollInterval = int(*monitor.PollInterval)
+	}
+	pollIntervalUnits := "minutes"
+	if len(monitor.PollIntervalUnits) > 0 {
+		pollIntervalUnits = strings.ToLower(monitor.PollIntervalUnits)
+	}
+	pollIntervalNode := root.CreateNode("pollInterval")
+	pollIntervalNode.SetAttributeValue("units", pollIntervalUnits)
+	pollIntervalNode.Text = strconv.Itoa(pollInterval)
+	if monitor.BatchSize != nil {
+		root.CreateNode("batch").SetAttributeValue("maxSize", monitor.BatchSize.String())
+	}
+	root.CreateNode("agent").Text = agent.Name
+
+	resourceType := monitorResourceType(monitor)
+	resourcesNode := root.CreateNode("resources")
+	if resourceType == MONITOR_RESOURCE_QUEUE {
+		resourcesNode.CreateNode("queue").Text = monitor.Resource
+	} else {
+		directoryNode := resourcesNode.CreateNode("directory")
+		recursionLevel := "0"
+		if monitor.RecursionLevel != nil {
+			recursionLevel = monitor.RecursionLevel.String()
+		}
+		directoryNode.SetAttributeValue("recursionLevel", recursionLevel)
+		directoryNode.Text = monitor.Resource
+	}
+
+	conditionNode := root.CreateNode("triggerMatch").CreateNode("conditions").CreateNode("allOf").CreateNode("condition")
+	addMonitorCondition(conditionNode, monitorTrigger(monitor))
+
+	transferNode := root.CreateNode("tasks").CreateNode("task")
+	transferNode.CreateNode("name")
+	addMonitorTransfer(transferNode.CreateNode("transfer"), agent, monitor, destinationQMgr)
+
+	addOriginator(root)
+	return monitorDoc.XMLPretty()
+}
+
+// Add the trigger condition of a monitor
+func addMonitorCondition(conditionNode *xmldom.Node, trigger *config.MonitorTrigger) {
+	var triggerNode *xmldom.Node
+	switch strings.ToLower(trigger.Condition) {
+	case strings.ToLower(MONITOR_TRIGGER_QUEUE_NOT_EMPTY):
+		conditionNode.CreateNode("queueNotEmpty")
+		return
+	case strings.ToLower(MONITOR_TRIGGER_COMPLETE_GROUPS):
+		conditionNode.CreateNode("completeGroups")
+		return
+	case strings.ToLower(MONITOR_TRIGGER_NO_MATCH):
+		triggerNode = conditionNode.CreateNode("fileNoMatch")
+	case strings.ToLower(MONITOR_TRIGGER_FILE_SIZE):
+		triggerNode = conditionNode.CreateNode("fileSize")
+		compareNode := triggerNode.CreateNode("compare")
+		compareNode.SetAttributeValue("operator", ">=")
+		sizeUnits := "MB"
+		if len(trigger.SizeUnits) > 0 {
+			sizeUnits = strings.ToUpper(trigger.SizeUnits)
+		}
+		compareNode.SetAttributeValue("units", sizeUnits)
+		compareNode.Text = trigger.Size.String()
+	case strings.ToLower(MONITOR_TRIGGER_SIZE_SAME):
+		triggerNode = conditionNode.CreateNode("fileSizeSame")
+		polls := "1"
+		if trigger.Polls != nil {
+			polls = trigger.Polls.String()
+		}
+		triggerNode.SetAttributeValue("polls", polls)
+	default:
+		triggerNode = conditionNode.CreateNode("fileMatch")
+	}
+	pattern := trigger.Pattern
+	if len(pattern) == 0 {
+		pattern = "*"
+	}
+	patternNode := triggerNode.CreateNode("pattern")
+	if len(trigger.PatternType) > 0 {
+		patternNode.SetAttributeValue("type", strings.ToLower(trigger.PatternType))
+	}
+	patternNode.Text = pattern
+}
+
+// Add the managed transfer request started when a monitor is triggered
+func addMonitorTransfer(transferNode *xmldom.Node, agent *config.Agent, monitor *config.Monitor, destinationQMgr string) {
+	requestNode := transferNode.CreateNode("request")
+	requestNode.SetAttributeValue("version", "6.00")
+	requestNode.SetAttributeValue("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
+	requestNode.SetAttributeValue("xsi:noNamespaceSchemaLocation", "FileTransfer.xsd")
+	managedTransferNode := requestNode.CreateNode("managedTransfer")
+	addOriginator(managedTransferNode)
+	sourceAgentNode := managedTransferNode.CreateNode("sourceAgent")
+	sourceAgentNode.SetAttributeValue("agent", agent.Name)
+	sourceAgentNode.SetAttributeValue("QMgr", agent.QMgrName)
+	destinationAgentNode := managedTransferNode.CreateNode("destinationAgent")
+	destinationAgentNode.SetAttributeValue("agent", monitor.DestinationAgent)
+	destinationAgentNode.SetAttributeValue("QMgr", destinationQMgr)
+
+	itemNode := managedTransferNode.CreateNode("transferSet").CreateNode("item")
+	checksum := "MD5"
+	if len(monitor.Checksum) > 0 && !strings.EqualFold(monitor.Checksum, "MD5") {
+		checksum = "none"
+	}
+	itemNode.SetAttributeValue("checksumMethod", checksum)
+	mode := "binary"
+	if len(monitor.Mode) > 0 {
+		mode = strings.ToLower(monitor.Mode)
+	}
+	itemNode.SetAttributeValue("mode", mode)
+
+	sourceNode := itemNode.CreateNode("source")
+	disposition := "leave"
+	if len(monitor.SourceDisposition) > 0 {
+		disposition = strings.ToLower(monitor.SourceDisposition)
+	}
+	sourceNode.SetAttributeValue("disposition", disposition)
+	if monitorResourceType(monitor) == MONITOR_RESOURCE_QUEUE {
+		sourceNode.SetAttributeValue("type", "queue")
+		sourceNode.CreateNode("queue").Text = monitor.Resource
+	} else {
+		sourceNode.SetAttributeValue("recursive", "false")
+		sourceNode.CreateNode("file").Text = "${FilePath}"
+	}
+
+	destinationNode := itemNode.CreateNode("destination")
+	destinationType := "directory"
+	if len(monitor.DestinationType) > 0 {
+		destinationType = strings.ToLower(monitor.DestinationType)
+	}
+	destinationNode.SetAttributeValue("type", destinationType)
+	if destinationType == "queue" {
+		destinationNode.CreateNode("queue").Text = monitor.DestinationPath
+	} else {
+		exist := "error"
+		if monitor.Overwrite != nil && bool(*monitor.Overwrite) {
+			exist = "overwrite"
+		}
+		destinationNode.SetAttributeValue("exist", exist)
+		destinationNode.CreateNode("file").Text = monitor.DestinationPath
+	}
+}
+
+// Add the host name and user that originated a monitor or a transfer
+func addOriginator(parentNode *xmldom.Node) {
+	originatorNode := parentNode.CreateNode("originator")
+	hostName, err := os.Hostname()
+	if err != nil {
+		hostName = TEXT_BLANK
+	}
+	originatorNode.CreateNode("hostName").Text = hostName
+	userId := TEXT_BLANK
+	if curUser, err := user.Current(); err == nil {
+		userId = curUser.Username
+	}
+	originatorNode.CreateNode("userID").Text = userId
+}
+
+// Names, upper cased, of the monitors of an agent in the output of
+// fteListMonitors. Each monitor is listed on a line that begins with the
+// name of the agent followed by the name of the monitor.
+func parseMonitorList(agentName string, output string) []string {
+	var names []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], agentName) || strings.HasSuffix(fields[1], ":") {
+			continue
+		}
+		names = append(names, strings.ToUpper(fields[1]))
+	}
+	return names
+}
+
+// List the monitors of an agent
+func listResourceMonitors(coordinationQMgr string, agentName string) ([]string, error) {
+	result := runFteCommand("fteListMonitors", "-p", coordinationQMgr, "-ma", agentName)
+	if result.failed() {
+		result.logFailure()
+		return nil, result.err
+	}
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
+	}
+	return parseMonitorList(agentName, result.stdout), nil
+}
+
+// Delete a monitor of an agent
+func deleteResourceMonitor(coordinationQMgr string, agentName string, agentQMgr string, monitorName string) bool {
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_RM_DELETE_0130, monitorName, agentName))
+	result := runFteCommand("fteDeleteMonitor", "-p", coordinationQMgr,
+		"-ma", agentName,
+		"-mm", agentQMgr,
+		"-mn", monitorName)
+	if result.failed() {
+		result.logFailure()
+		return false
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
+	return true
+}
+
+// Create, replace and delete monitors of the agent so that it has exactly the
+// monitors declared in its configuration. The definition of each monitor is
+// written to the monitors directory of the agent and the provisioning state
+// records the configuration each monitor was created with.
+func reconcileMonitors(agentConfiguration *config.Configuration, agent *config.Agent, coordinationQMgr string, agentPath string, provisioning *provisionState) {
+	if agent.Monitors == nil {
+		return
+	}
+	existingMonitors, err := listResourceMonitors(coordinationQMgr, agent.Name)
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_RM_LIST_FAILED_0128, agent.Name))
+		return
+	}
+	existing := make(map[string]bool, len(existingMonitors))
+	for _, name := range existingMonitors {
+		existing[name] = true
+	}
+
+	monitorsPath := agentPath + MFT_MONITORS_SLASH
+	if err := os.MkdirAll(monitorsPath, 0755); err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_OPN_FILE_0067, monitorsPath, err))
+		return
+	}
+
+	created, updated, deleted, unchanged, failed := 0, 0, 0, 0, 0
+	declared := make(map[string]bool, len(agent.Monitors))
+	for _, monitor := range agent.Monitors {
+		name := strings.ToUpper(monitor.Name)
+		declared[name] = true
+		destinationQMgr := monitorDestinationQMgr(agentConfiguration, agent, monitor)
+		sectionName := monitorSectionName(agent.Name, name)
+		section := provisioning.monitorSection(agent, monitor, destinationQMgr)
+		if existing[name] && provisioning.isProvisioned(sectionName, section) {
+			unchanged++
+			continue
+		}
+
+		monitorXml := getMonitorXml(agent, monitor, destinationQMgr)
+		if logLevel >= LOG_LEVEL_VERBOSE {
+			utils.PrintLog(monitorXml)
+		}
+		monitorFile := filepath.Join(monitorsPath, name+".xml")
+		if err := os.WriteFile(monitorFile, []byte(monitorXml), 0644); err != nil {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_ERR_UPDTING_FILE_0066, monitorFile, err))
+			failed++
+			continue
+		}
+		// Replace the monitor if it exists with a different definition
+		if !createResourceMonitor(coordinationQMgr, agent.Name, agent.QMgrName, name, monitorFile, existing[name]) {
+			provisioning.forget(sectionName)
+			failed++
+			continue
+		}
+		provisioning.provisioned(sectionName, section, nil)
+		if existing[name] {
+			updated++
+		} else {
+			created++
+		}
+	}
+
+	for _, name := range existingMonitors {
+		if declared[name] {
+			continue
+		}
+		if deleteResourceMonitor(coordinationQMgr, agent.Name, agent.QMgrName, name) {
+			provisioning.forget(monitorSectionName(agent.Name, name))
+			os.Remove(filepath.Join(monitorsPath, name+".xml"))
+			deleted++
+		} else {
+			failed++
+		}
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_RM_RECONCILED_0131, agent.Name, created, updated, deleted, unchanged, failed))
+}
@@ -0,0 +1,178 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+func TestGetMonitorXml(t *testing.T) {
+	configuration := loadTestConfig(t, "test_monitors.json")
+	agent := configuration.Agent("SRC")
+	expected := [][]string{
+		{
+			"<name>INVOICES</name>",
+			`<pollInterval units="seconds">30</pollInterval>`,
+			`<batch maxSize="5" />`,
+			"<agent>SRC</agent>",
+			`<directory recursionLevel="2">/mountpath/in</directory>`,
+			"<pattern type=\"wildcard\">*.csv</pattern>",
+			`<sourceAgent agent="SRC" QMgr="QM1" />`,
+			`<destinationAgent agent="DEST" QMgr="QM2" />`,
+			`<item checksumMethod="none" mode="binary">`,
+			`<source disposition="delete" recursive="false">`,
+			"<file>${FilePath}</file>",
+			`<destination type="directory" exist="overwrite">`,
+			"<file>/mountpath/out/&lt;b&gt;</file>",
+		},
+		{
+			`<pollInterval units="minutes">1</pollInterval>`,
+			"<queue>ORDERS.IN</queue>",
+			"<completeGroups />",
+			`<destinationAgent agent="REMOTE" QMgr="QM3" />`,
+			`<source disposition="leave" type="queue">`,
+			`<destination type="directory" exist="error">`,
+		},
+		{
+			`<compare operator="&gt;=" units="GB">10</compare>`,
+			"<pattern>*</pattern>",
+			`<destinationAgent agent="SRC" QMgr="QM1" />`,
+		},
+	}
+	for index, monitor := range agent.Monitors {
+		monitorXml := getMonitorXml(agent, monitor, monitorDestinationQMgr(configuration, agent, monitor))
+		for _, element := range expected[index] {
+			if !strings.Contains(monitorXml, element) {
+				t.Errorf("Expected %s in monitor XML:\n%s", element, monitorXml)
+			}
+		}
+	}
+}
+
+func TestValidateMonitors(t *testing.T) {
+	if err := validateMonitors(loadTestConfig(t, "test_monitors.json").Agent("SRC")); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	size := config.Int(0)
+	tests := []struct {
+		monitor *config.Monitor
+		err     string
+	}{
+		{&config.Monitor{Name: "MON*", Resource: "/in", DestinationAgent: "DEST", DestinationPath: "/out"},
+			"monitors[0] is not valid, name \"MON*\" must not contain blanks"},
+		{&config.Monitor{Name: "MON", Resource: "in", DestinationAgent: "DEST", DestinationPath: "/out"},
+			"monitors[0] is not valid, resource \"in\" must be an absolute path"},
+		{&config.Monitor{Name: "MON", Resource: "/in", DestinationPath: "/out"},
+			"monitors[0] is not valid, destinationAgent must be specified"},
+		{&config.Monitor{Name: "MON", ResourceType: "queue", Resource: "IN", DestinationAgent: "DEST", DestinationPath: "/out",
+			Trigger: &config.MonitorTrigger{Condition: "match"}},
+			"monitors[0] is not valid, trigger condition \"match\" is not one of queueNotEmpty, completeGroups for queue monitors"},
+		{&config.Monitor{Name: "MON", Resource: "/in", DestinationAgent: "DEST", DestinationPath: "/out",
+			Trigger: &config.MonitorTrigger{Condition: "fileSize", Size: &size}},
+			"monitors[0] is not valid, trigger size has value 0 which is less than 1"},
+		{&config.Monitor{Name: "MON", Resource: "/in", DestinationAgent: "DEST", DestinationPath: "/out", Checksum: "SHA1"},
+			"monitors[0] is not valid, checksum \"SHA1\" is not one of MD5, none"},
+	}
+	for _, test := range tests {
+		err := validateMonitors(&config.Agent{Name: "SRC", Monitors: []*config.Monitor{test.monitor}})
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("Expected error %q, found %v", test.err, err)
+		}
+	}
+	monitor := &config.Monitor{Name: "mon", Resource: "/in", DestinationAgent: "DEST", DestinationPath: "/out"}
+	duplicate := &config.Monitor{Name: "MON", Resource: "/in", DestinationAgent: "DEST", DestinationPath: "/out"}
+	if err := validateMonitors(&config.Agent{Monitors: []*config.Monitor{monitor, duplicate}}); err == nil {
+		t.Error("Duplicate monitor not reported")
+	}
+}
+
+func TestParseMonitorList(t *testing.T) {
+	output := "5724-H72 Copyright IBM Corp.  2008, 2024.  ALL RIGHTS RESERVED\n" +
+		"Agent Name:     Monitor Name:     Resource Type:     Resource:          Trigger Condition:\n" +
+		"SRC             INVOICES          directory          /mountpath/in      match(*.csv)\n" +
+		"SRC             OLD               queue              OLD.IN             queueNotEmpty\n" +
+		"SRC2            OTHER             directory          /mountpath/other   match(*)\n"
+	if names := parseMonitorList("src", output); !reflect.DeepEqual(names, []string{"INVOICES", "OLD"}) {
+		t.Errorf("Unexpected monitors %v", names)
+	}
+	if names := parseMonitorList("AGENT", "Agent Name:     Monitor Name:\n"); len(names) != 0 {
+		t.Errorf("Header parsed as monitor %v", names)
+	}
+}
+
+func TestReconcileMonitors(t *testing.T) {
+	configuration := loadTestConfig(t, "test_monitors.json")
+	agent := configuration.Agent("SRC")
+	bfgDataPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(bfgDataPath, "mqft"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	agentPath := filepath.Join(bfgDataPath, "agents", "SRC")
+
+	// INVOICES was created with the same definition, ORDERS with a different
+	// one and OLD is no longer defined. LARGE does not exist.
+	provisioning := loadProvisionState(bfgDataPath, false)
+	invoices := agent.Monitors[0]
+	provisioning.provisioned(monitorSectionName("SRC", "INVOICES"), provisioning.monitorSection(agent, invoices, "QM2"), nil)
+	provisioning.provisioned(monitorSectionName("SRC", "ORDERS"), provisioning.monitorSection(agent, invoices, "QM2"), nil)
+	fake := &fakeCommandRunner{result: commandResult{stdout: "SRC INVOICES directory /mountpath/in match(*.csv)\n" +
+		"SRC ORDERS queue ORDERS.IN completeGroups\nSRC OLD directory /mountpath/old match(*)\n"}}
+	runner = fake
+	defer func() { runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second) }()
+
+	reconcileMonitors(configuration, agent, "QMC", agentPath, provisioning)
+
+	var commands []string
+	for _, cmd := range fake.commands {
+		commands = append(commands, cmd.name+" "+strings.Join(cmd.args, " "))
+	}
+	monitorsPath := agentPath + MFT_MONITORS_SLASH
+	expected := []string{
+		"fteListMonitors -p QMC -ma SRC",
+		"fteCreateMonitor -p QMC -mm QM1 -ma SRC -mn ORDERS -ix " + filepath.Join(monitorsPath, "ORDERS.xml") + " -f",
+		"fteCreateMonitor -p QMC -mm QM1 -ma SRC -mn LARGE -ix " + filepath.Join(monitorsPath, "LARGE.xml"),
+		"fteDeleteMonitor -p QMC -ma SRC -mm QM1 -mn OLD",
+	}
+	if !reflect.DeepEqual(commands, expected) {
+		t.Errorf("Unexpected commands run:\n%s", strings.Join(commands, "\n"))
+	}
+	if _, err := os.Stat(filepath.Join(monitorsPath, "LARGE.xml")); err != nil {
+		t.Errorf("Monitor definition not written: %v", err)
+	}
+
+	// Nothing to do once monitors have been reconciled
+	fake.commands = nil
+	fake.result.stdout = "SRC INVOICES\nSRC ORDERS\nSRC LARGE\n"
+	reconcileMonitors(configuration, agent, "QMC", agentPath, loadProvisionState(bfgDataPath, false))
+	if len(fake.commands) != 1 {
+		t.Errorf("Unexpected commands run: %d", len(fake.commands))
+	}
+
+	// Monitors are left alone if none are declared
+	fake.commands = nil
+	reconcileMonitors(configuration, configuration.Agent("DEST"), "QMC", agentPath, provisioning)
+	if len(fake.commands) != 0 {
+		t.Errorf("Monitors reconciled for agent without monitors section: %d commands", len(fake.commands))
+	}
+}
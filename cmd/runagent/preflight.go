@@ -0,0 +1,324 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Pre-flight checks of the queue manager endpoints named in the configuration
+* file. Host names are resolved, a TCP connection is made to the listener and,
+* when a cipher is configured, a TLS handshake verifies the queue manager
+* certificate against the certificates used for building the truststore.
+* Every entry of a connection name list and every connection of a JSON CCDT
+* is checked. A queue manager is reachable when any of its endpoints passes,
+* as MQ tries each entry in turn. Endpoints that fail for a reachable queue
+* manager are reported as warnings. Problems are reported before any MFT
+* command is run.
+ */
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Default timeout, in seconds, of each check
+const PREFLIGHT_DEFAULT_TIMEOUT = 5
+
+// Status of a check
+const PREFLIGHT_OK = "OK"
+const PREFLIGHT_FAILED = "FAILED"
+const PREFLIGHT_WARNING = "WARNING"
+const PREFLIGHT_SKIPPED = "-"
+
+// Queue manager endpoint to check
+type preflightEndpoint struct {
+	// Use of the queue manager, for example "coordination" or "agent SRC"
+	role      string
+	qmgrName  string
+	host      string
+	port      int
+	cipherEnv string
+	certPath  string
+	// Reason the endpoint is not checked, for example as it is read from a
+	// binary CCDT
+	skipReason string
+}
+
+// Address of the endpoint for display
+func (endpoint *preflightEndpoint) address() string {
+	if len(endpoint.skipReason) > 0 {
+		return PREFLIGHT_SKIPPED
+	}
+	return net.JoinHostPort(endpoint.host, strconv.Itoa(endpoint.port))
+}
+
+// Result of checking an endpoint
+type preflightResult struct {
+	endpoint *preflightEndpoint
+	dns      string
+	tcp      string
+	tls      string
+	// Reason of a failure or warning
+	detail string
+	// Failure may be resolved by retrying, for example when the queue manager
+	// has not started yet
+	transient bool
+}
+
+// Determine if all checks of the endpoint passed
+func (result *preflightResult) passed() bool {
+	return result.dns != PREFLIGHT_FAILED && result.tcp != PREFLIGHT_FAILED && result.tls != PREFLIGHT_FAILED
+}
+
+// Report the failed checks of the endpoint as warnings, as another endpoint
+// of the queue manager passed
+func (result *preflightResult) downgrade() {
+	for _, status := range []*string{&result.dns, &result.tcp, &result.tls} {
+		if *status == PREFLIGHT_FAILED {
+			*status = PREFLIGHT_WARNING
+		}
+	}
+}
+
+// List the endpoints of coordination and command queue managers and of the
+// queue managers of the given agents. A queue manager has an endpoint for
+// each entry of its connection name list or CCDT.
+func preflightEndpoints(agentConfiguration *config.Configuration, agentConfigs []*config.Agent) []*preflightEndpoint {
+	connections := []*qmgrConnection{
+		coordinationConnection(&agentConfiguration.CoordinationQMgr),
+		commandConnection(&agentConfiguration.CommandQMgr),
+	}
+	for _, agent := range agentConfigs {
+		connections = append(connections, agentConnection(agent))
+	}
+
+	var endpoints []*preflightEndpoint
+	for _, connection := range connections {
+		addresses, reason := connection.addresses()
+		if len(reason) > 0 {
+			endpoints = append(endpoints, &preflightEndpoint{
+				role:       connection.role,
+				qmgrName:   connection.qmgrName,
+				cipherEnv:  connection.cipherEnv,
+				certPath:   connection.certPath,
+				skipReason: reason,
+			})
+			continue
+		}
+		for _, address := range addresses {
+			endpoints = append(endpoints, &preflightEndpoint{
+				role:      connection.role,
+				qmgrName:  connection.qmgrName,
+				host:      address.host,
+				port:      address.port,
+				cipherEnv: connection.cipherEnv,
+				certPath:  connection.certPath,
+			})
+		}
+	}
+	return endpoints
+}
+
+// Check all endpoints, retrying while the only failures are connections
+// refused by queue managers that may not have started yet. A queue manager
+// passes if any of its endpoints passes. Results of the last attempt are
+// displayed. Returns false if any queue manager can not be reached.
+func runPreflightChecks(endpoints []*preflightEndpoint, retry *retryPolicy) bool {
+	timeout := time.Duration(getRetrySeconds(MFT_PREFLIGHT_TIMEOUT, PREFLIGHT_DEFAULT_TIMEOUT)) * time.Second
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_PREFLIGHT_START_0102, len(endpoints)))
+
+	var results []*preflightResult
+	passed := retry.runStep("connect to queue managers", func() (bool, bool, string) {
+		results = make([]*preflightResult, 0, len(endpoints))
+		// Endpoints of a queue manager share its role
+		reachable := make(map[string]bool)
+		for _, endpoint := range endpoints {
+			result := checkEndpoint(endpoint, timeout)
+			results = append(results, result)
+			if result.passed() {
+				reachable[endpoint.role] = true
+			}
+		}
+		transient := true
+		var reasons []string
+		for _, result := range results {
+			if result.passed() {
+				continue
+			}
+			if reachable[result.endpoint.role] {
+				result.downgrade()
+				continue
+			}
+			transient = transient && result.transient
+			reasons = append(reasons, result.endpoint.role+": "+result.detail)
+		}
+		if len(reasons) == 0 {
+			return true, false, ""
+		}
+		if logLevel >= LOG_LEVEL_VERBOSE {
+			printPreflightResults(results)
+		}
+		return false, transient, strings.Join(reasons, "; ")
+	})
+
+	printPreflightResults(results)
+	if passed {
+		utils.PrintLog(utils.MFT_CONT_PREFLIGHT_PASSED_0104)
+	} else {
+		utils.PrintLog(utils.MFT_CONT_PREFLIGHT_FAILED_0103)
+	}
+	return passed
+}
+
+// Resolve the host name, connect to the listener and, if a cipher is
+// configured, verify the queue manager certificate.
+func checkEndpoint(endpoint *preflightEndpoint, timeout time.Duration) *preflightResult {
+	result := &preflightResult{endpoint: endpoint, dns: PREFLIGHT_SKIPPED, tcp: PREFLIGHT_SKIPPED, tls: PREFLIGHT_SKIPPED}
+	if len(endpoint.skipReason) > 0 {
+		result.detail = endpoint.skipReason
+		return result
+	}
+	if len(strings.TrimSpace(endpoint.host)) == 0 {
+		result.dns = PREFLIGHT_FAILED
+		result.detail = "host name not specified"
+		return result
+	}
+
+	// Addresses do not need resolving
+	if net.ParseIP(endpoint.host) == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		_, err := net.DefaultResolver.LookupHost(ctx, endpoint.host)
+		cancel()
+		if err != nil {
+			result.dns = PREFLIGHT_FAILED
+			result.detail = err.Error()
+			return result
+		}
+		result.dns = PREFLIGHT_OK
+	}
+
+	address := net.JoinHostPort(endpoint.host, strconv.Itoa(endpoint.port))
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		result.tcp = PREFLIGHT_FAILED
+		result.detail = err.Error()
+		// Listener may not have started yet
+		result.transient = true
+		return result
+	}
+	defer conn.Close()
+	result.tcp = PREFLIGHT_OK
+
+	if len(strings.TrimSpace(os.Getenv(endpoint.cipherEnv))) > 0 {
+		result.tls, result.detail = checkTLS(conn, endpoint.certPath, timeout)
+	}
+	return result
+}
+
+// Perform a TLS handshake over the connection and verify the queue manager
+// certificate against the .crt file used for building the truststore, or the
+// system certificates if there is none. Host names are not verified as MQ
+// does not verify them either. Returns the status and reason of failure.
+func checkTLS(conn net.Conn, certPath string, timeout time.Duration) (string, string) {
+	var roots *x509.CertPool
+	publicKeyCertPath := getKeyFile(certPath, ".crt")
+	if len(publicKeyCertPath) > 0 {
+		certData, err := os.ReadFile(publicKeyCertPath)
+		if err != nil {
+			return PREFLIGHT_FAILED, err.Error()
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(certData) {
+			return PREFLIGHT_FAILED, fmt.Sprintf("no certificates found in %s", publicKeyCertPath)
+		}
+	}
+
+	var verifyErr error
+	verified := false
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// Certificate is verified below without checking the host name
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			verifyErr = verifyServerCertificate(rawCerts, roots)
+			verified = verifyErr == nil
+			return verifyErr
+		},
+	}
+	tlsConn := tls.Client(conn, tlsConfig)
+	tlsConn.SetDeadline(time.Now().Add(timeout))
+	err := tlsConn.Handshake()
+	if err == nil {
+		return PREFLIGHT_OK, ""
+	}
+	if verifyErr != nil {
+		return PREFLIGHT_FAILED, "queue manager certificate is not trusted: " + verifyErr.Error()
+	}
+	if verified {
+		// Queue manager may require a client certificate, which is only
+		// available to MFT commands once the keystore has been created.
+		return PREFLIGHT_WARNING, "queue manager certificate is trusted but handshake ended: " + err.Error()
+	}
+	return PREFLIGHT_FAILED, "TLS handshake failed: " + err.Error()
+}
+
+// Verify the certificate chain presented by the queue manager
+func verifyServerCertificate(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return errors.New("no certificate presented")
+	}
+	var certs []*x509.Certificate
+	for _, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+// Display the results as a table followed by reasons of any failures.
+func printPreflightResults(results []*preflightResult) {
+	rowFormat := "%-20s %-20s %-30s %-7s %-7s %-7s"
+	utils.PrintLog(strings.TrimSpace(fmt.Sprintf(rowFormat, "Role", "Queue manager", "Endpoint", "DNS", "TCP", "TLS")))
+	for _, result := range results {
+		endpoint := result.endpoint
+		utils.PrintLog(strings.TrimSpace(fmt.Sprintf(rowFormat, endpoint.role, endpoint.qmgrName, endpoint.address(), result.dns, result.tcp, result.tls)))
+	}
+	for _, result := range results {
+		if len(result.detail) > 0 {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_PREFLIGHT_DETAIL_0105, result.endpoint.qmgrName, result.endpoint.role, result.detail))
+		}
+	}
+}
@@ -0,0 +1,219 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+func TestPreflightEndpoints(t *testing.T) {
+	agentConfiguration, err := config.Parse("{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"qm1.example.com\"}," +
+		"\"commandQMgr\":{\"name\":\"QM2\",\"host\":\"qm2.example.com\",\"port\":1415}," +
+		"\"agents\":[{\"name\":\"SRC\",\"qmgrName\":\"QM3\",\"qmgrHost\":\"10.0.0.1\",\"qmgrPort\":1416}]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := preflightEndpoints(agentConfiguration, agentConfiguration.Agents)
+	if len(endpoints) != 3 {
+		t.Fatalf("Expected 3 endpoints, found %d", len(endpoints))
+	}
+	if endpoints[0].qmgrName != "QM1" || endpoints[0].port != 1414 || endpoints[0].cipherEnv != MFT_COORD_QMGR_CIPHER {
+		t.Errorf("Unexpected coordination endpoint %+v", endpoints[0])
+	}
+	if endpoints[1].host != "qm2.example.com" || endpoints[1].port != 1415 || endpoints[1].certPath != commandQMCertPath {
+		t.Errorf("Unexpected command endpoint %+v", endpoints[1])
+	}
+	if endpoints[2].role != "agent SRC" || endpoints[2].host != "10.0.0.1" || endpoints[2].port != 1416 {
+		t.Errorf("Unexpected agent endpoint %+v", endpoints[2])
+	}
+
+	// Every entry of a connection name list is checked and endpoints of a
+	// binary CCDT are skipped
+	_, binaryCcdt := writeTestCcdts(t)
+	agentConfiguration, err = config.Parse("{\"coordinationQMgr\":{\"name\":\"QM1\",\"connectionNameList\":\"qm1a(1414),qm1b(1415)\"}," +
+		"\"commandQMgr\":{\"name\":\"QM1\",\"ccdtUrl\":\"" + binaryCcdt + "\"}," +
+		"\"agents\":[{\"name\":\"SRC\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\"}]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints = preflightEndpoints(agentConfiguration, agentConfiguration.Agents)
+	if len(endpoints) != 4 {
+		t.Fatalf("Expected 4 endpoints, found %d", len(endpoints))
+	}
+	if endpoints[0].host != "qm1a" || endpoints[1].role != "coordination" || endpoints[1].host != "qm1b" || endpoints[1].port != 1415 {
+		t.Errorf("Unexpected coordination endpoints %+v %+v", endpoints[0], endpoints[1])
+	}
+	if endpoints[2].role != "command" || len(endpoints[2].skipReason) == 0 || endpoints[2].address() != PREFLIGHT_SKIPPED {
+		t.Errorf("Unexpected command endpoint %+v", endpoints[2])
+	}
+	if result := checkEndpoint(endpoints[2], time.Second); !result.passed() || result.tcp != PREFLIGHT_SKIPPED || result.detail != endpoints[2].skipReason {
+		t.Errorf("Unexpected result of a skipped endpoint %+v", result)
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	// Nothing listening on the port
+	result := checkEndpoint(&preflightEndpoint{host: "127.0.0.1", port: port}, time.Second)
+	if result.passed() || result.dns != PREFLIGHT_SKIPPED || result.tcp != PREFLIGHT_FAILED || !result.transient {
+		t.Errorf("Unexpected result of connecting to a closed port %+v", result)
+	}
+
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port = listener.Addr().(*net.TCPAddr).Port
+	result = checkEndpoint(&preflightEndpoint{host: "127.0.0.1", port: port, cipherEnv: "MFT_TEST_PREFLIGHT_CIPHER"}, time.Second)
+	if !result.passed() || result.tcp != PREFLIGHT_OK || result.tls != PREFLIGHT_SKIPPED {
+		t.Errorf("Unexpected result of connecting to a listener %+v", result)
+	}
+
+	// Host names that can not be resolved are not retried
+	result = checkEndpoint(&preflightEndpoint{host: "qmgr.invalid", port: 1414}, time.Second)
+	if result.passed() || result.dns != PREFLIGHT_FAILED || result.transient {
+		t.Errorf("Unexpected result of resolving an invalid host name %+v", result)
+	}
+
+	result = checkEndpoint(&preflightEndpoint{host: " ", port: 1414}, time.Second)
+	if result.passed() || len(result.detail) == 0 {
+		t.Errorf("Unexpected result for a blank host name %+v", result)
+	}
+}
+
+func TestCheckEndpointTLS(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+	serverAddress := server.Listener.Addr().(*net.TCPAddr)
+
+	certPath := t.TempDir()
+	certData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(filepath.Join(certPath, "qmgr.crt"), certData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MFT_TEST_PREFLIGHT_CIPHER", "ANY_TLS12_OR_HIGHER")
+
+	endpoint := &preflightEndpoint{host: "127.0.0.1", port: serverAddress.Port, cipherEnv: "MFT_TEST_PREFLIGHT_CIPHER", certPath: certPath}
+	result := checkEndpoint(endpoint, 5*time.Second)
+	if !result.passed() || result.tls != PREFLIGHT_OK {
+		t.Errorf("Unexpected result of TLS handshake with trusted certificate %+v", result)
+	}
+
+	// Certificate not in the truststore
+	endpoint.certPath = t.TempDir()
+	if err := os.WriteFile(filepath.Join(endpoint.certPath, "other.crt"), []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result = checkEndpoint(endpoint, 5*time.Second)
+	if result.passed() || result.tls != PREFLIGHT_FAILED {
+		t.Errorf("Unexpected result of TLS handshake with invalid certificate file %+v", result)
+	}
+
+	endpoint.certPath = t.TempDir()
+	result = checkEndpoint(endpoint, 5*time.Second)
+	if result.passed() || result.tls != PREFLIGHT_FAILED || result.transient {
+		t.Errorf("Unexpected result of TLS handshake with untrusted certificate %+v", result)
+	}
+}
+
+func TestRunPreflightChecks(t *testing.T) {
+	retrySleep = func(delay time.Duration) {}
+	defer func() { retrySleep = time.Sleep }()
+	policy := &retryPolicy{initialDelay: time.Second, maxDelay: time.Second, deadline: 3 * time.Second}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+	endpoints := []*preflightEndpoint{{role: "coordination", qmgrName: "QM1", host: "127.0.0.1", port: port}}
+	if !runPreflightChecks(endpoints, policy) {
+		t.Error("Connectivity checks failed for a listening port")
+	}
+
+	endpoints = append(endpoints, &preflightEndpoint{role: "agent SRC", qmgrName: "QM2", host: "", port: 1414})
+	if runPreflightChecks(endpoints, policy) {
+		t.Error("Connectivity checks passed for an endpoint without host name")
+	}
+}
+
+func TestRunPreflightChecksConnectionNameList(t *testing.T) {
+	retrySleep = func(delay time.Duration) {}
+	defer func() { retrySleep = time.Sleep }()
+	policy := &retryPolicy{initialDelay: time.Second, maxDelay: time.Second, deadline: 3 * time.Second}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	// Port of a listener that has been closed refuses connections
+	refusing, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refusedPort := refusing.Addr().(*net.TCPAddr).Port
+	refusing.Close()
+
+	agentConfiguration, err := config.Parse(fmt.Sprintf(`{"coordinationQMgr":{"name":"QM1","connectionNameList":"127.0.0.1(%d),127.0.0.1(%d)"},
+		"commandQMgr":{"name":"QM1","host":"127.0.0.1","port":%d},"agents":[]}`, refusedPort, listener.Addr().(*net.TCPAddr).Port, listener.Addr().(*net.TCPAddr).Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := preflightEndpoints(agentConfiguration, nil)
+	if len(endpoints) != 3 {
+		t.Fatalf("Unexpected endpoints %v", endpoints)
+	}
+	if !runPreflightChecks(endpoints, policy) {
+		t.Error("Connectivity checks failed when one entry of the connection name list is reachable")
+	}
+	result := checkEndpoint(endpoints[0], time.Second)
+	if result.passed() || result.tcp != PREFLIGHT_FAILED {
+		t.Fatalf("Connection to refusing entry did not fail %+v", result)
+	}
+	result.downgrade()
+	if !result.passed() || result.tcp != PREFLIGHT_WARNING || result.dns != PREFLIGHT_SKIPPED {
+		t.Errorf("Failed entry not reported as warning %+v", result)
+	}
+
+	// Queue manager is not reachable if no entry is, checked without retrying
+	listener.Close()
+	if runPreflightChecks(endpoints, &retryPolicy{initialDelay: time.Second, maxDelay: time.Second}) {
+		t.Error("Connectivity checks passed when no entry of the connection name list is reachable")
+	}
+}
@@ -0,0 +1,401 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Catalog of the properties supported in agent.properties, coordination.properties
+* and command.properties. The additionalProperties groups of the configuration
+* file are written to these files as is, so a misspelt property is silently
+* ignored by the agent. Properties are checked against the catalog: unknown
+* properties and values of the wrong type are errors, deprecated properties
+* and properties the container sets itself are reported as warnings.
+ */
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/tidwall/gjson"
+)
+
+// Names of the properties files, as displayed in problems
+const PROPERTIES_FILE_AGENT = "agent.properties"
+const PROPERTIES_FILE_COORDINATION = "coordination.properties"
+const PROPERTIES_FILE_COMMAND = "command.properties"
+
+// A property of a properties file
+type catalogProperty struct {
+	// One of the DATA_TYPE_STRING, DATA_TYPE_INT or DATA_TYPE_BOOL constants
+	dataType int
+	// Range of an integer property
+	min int
+	max int
+	// Set if the property is deprecated, naming what should be used instead
+	deprecated string
+	// Set if the container sets the property itself, describing from what
+	managed string
+	// Value the container sets, if always the same. Specifying the same value
+	// is not an override.
+	managedValue string
+	// Agent types the property is managed for. All types if empty.
+	managedFor []string
+}
+
+func catalogString() *catalogProperty {
+	return &catalogProperty{dataType: DATA_TYPE_STRING}
+}
+
+func catalogBool() *catalogProperty {
+	return &catalogProperty{dataType: DATA_TYPE_BOOL}
+}
+
+// Integer property with the range of valid values
+func catalogInt(min int, max int) *catalogProperty {
+	return &catalogProperty{dataType: DATA_TYPE_INT, min: min, max: max}
+}
+
+// Property set by the container
+func catalogManaged(dataType int, managed string, agentTypes ...string) *catalogProperty {
+	return &catalogProperty{dataType: dataType, min: math.MinInt32, max: math.MaxInt32, managed: managed, managedFor: agentTypes}
+}
+
+// Property set by the container to a fixed value
+func catalogManagedValue(dataType int, value string, managed string, agentTypes ...string) *catalogProperty {
+	property := catalogManaged(dataType, managed, agentTypes...)
+	property.managedValue = value
+	return property
+}
+
+// Property that should no longer be used
+func catalogDeprecated(dataType int, replacement string) *catalogProperty {
+	return &catalogProperty{dataType: dataType, min: math.MinInt32, max: math.MaxInt32, deprecated: replacement}
+}
+
+// Properties connecting to a queue manager, common to the three files. Prefix
+// is one of the PROPERTY_PREFIX_* constants and source names the attributes
+// of the configuration file the connection is built from.
+func qmgrCatalogProperties(prefix string, source string) map[string]*catalogProperty {
+	return map[string]*catalogProperty{
+		prefix + "QMgr":                         catalogManaged(DATA_TYPE_STRING, "set from the queue manager attributes of "+source),
+		prefix + "QMgrHost":                     catalogManaged(DATA_TYPE_STRING, "set from the queue manager attributes of "+source),
+		prefix + "QMgrPort":                     catalogManaged(DATA_TYPE_INT, "set from the queue manager attributes of "+source),
+		prefix + "QMgrChannel":                  catalogManaged(DATA_TYPE_STRING, "set from the queue manager attributes of "+source),
+		prefix + "QMgrStandby":                  catalogString(),
+		ccdtUrlProperty(prefix):                 catalogManaged(DATA_TYPE_STRING, "set from the connection name list or CCDT of "+source),
+		credentialsFileProperty(prefix):         catalogManaged(DATA_TYPE_STRING, "set to the credentials file written by the container"),
+		prefix + "Ccsid":                        catalogInt(0, 65535),
+		prefix + "CcsidName":                    catalogString(),
+		prefix + "SslCipherSpec":                catalogManaged(DATA_TYPE_STRING, "set from the cipher environment variable of the queue manager"),
+		prefix + "SslCipherSuite":               catalogString(),
+		prefix + "SslPeerName":                  catalogString(),
+		prefix + "SslFipsRequired":              catalogBool(),
+		prefix + "SslTrustStore":                catalogManaged(DATA_TYPE_STRING, "set to the truststore created by the container"),
+		prefix + "SslTrustStoreType":            catalogManaged(DATA_TYPE_STRING, "set to the truststore created by the container"),
+		prefix + "SslTrustStoreCredentialsFile": catalogManaged(DATA_TYPE_STRING, "set to the credentials file written by the container"),
+		prefix + "SslTrustStorePassword":        catalogDeprecated(DATA_TYPE_STRING, prefix+"SslTrustStoreCredentialsFile"),
+		prefix + "SslKeyStore":                  catalogManaged(DATA_TYPE_STRING, "set to the keystore created by the container"),
+		prefix + "SslKeyStoreType":              catalogManaged(DATA_TYPE_STRING, "set to the keystore created by the container"),
+		prefix + "SslKeyStoreCredentialsFile":   catalogManaged(DATA_TYPE_STRING, "set to the credentials file written by the container"),
+		prefix + "SslKeyStorePassword":          catalogDeprecated(DATA_TYPE_STRING, prefix+"SslKeyStoreCredentialsFile"),
+	}
+}
+
+// Properties of coordination.properties
+var coordinationCatalog = mergeCatalog(qmgrCatalogProperties(PROPERTY_PREFIX_COORDINATION, "coordinationQMgr"), map[string]*catalogProperty{
+	"dynamicQueuePrefix": catalogString(),
+	"modelQueueName":     catalogString(),
+})
+
+// Properties of command.properties
+var commandCatalog = qmgrCatalogProperties(PROPERTY_PREFIX_COMMAND, "commandQMgr")
+
+// Properties of agent.properties
+var agentCatalog = mergeCatalog(qmgrCatalogProperties(PROPERTY_PREFIX_AGENT, "the agent"), map[string]*catalogProperty{
+	// Identity of the agent
+	"agentName": catalogManaged(DATA_TYPE_STRING, "set from the agent name"),
+	"agentType": catalogManaged(DATA_TYPE_STRING, "set from the agent type"),
+	"agentDesc": catalogString(),
+
+	// High availability, with an instance of the agent in each container
+	// sharing the agent queue manager
+	"highlyAvailable": catalogBool(),
+
+	// Transfers
+	"maxSourceTransfers":               catalogInt(1, math.MaxInt32),
+	"maxDestinationTransfers":          catalogInt(1, math.MaxInt32),
+	"maxQueuedTransfers":               catalogInt(1, math.MaxInt32),
+	"maxFilesForTransfer":              catalogInt(1, math.MaxInt32),
+	"maxInlineFileSize":                catalogInt(0, math.MaxInt32),
+	"maxCommandHandlerThreads":         catalogInt(1, math.MaxInt32),
+	"maxDelimitersPerMessage":          catalogInt(1, math.MaxInt32),
+	"agentChunkSize":                   catalogInt(1, math.MaxInt32),
+	"agentFrameSize":                   catalogInt(1, math.MaxInt32),
+	"agentWindowSize":                  catalogInt(1, math.MaxInt32),
+	"agentCheckpointInterval":          catalogInt(1, math.MaxInt32),
+	"agentMessageBatchSize":            catalogInt(1, math.MaxInt32),
+	"transferAckTimeout":               catalogInt(-1, math.MaxInt32),
+	"transferAckTimeoutRetries":        catalogInt(-1, math.MaxInt32),
+	"transferRecoveryTimeout":          catalogInt(-1, math.MaxInt32),
+	"senderTransferRetryInterval":      catalogInt(0, math.MaxInt32),
+	"recoverableTransferRetryInterval": catalogInt(0, math.MaxInt32),
+	"doNotUseTempOutputFile":           catalogBool(),
+	"deleteTmpFilesAfterTransfer":      catalogBool(),
+	"textReplacementCharacterSequence": catalogString(),
+	"enableQueueInputOutput":           catalogManagedValue(DATA_TYPE_BOOL, "false", "set to false as queues are not supported by bridge agents", AGENT_TYPE_BRIDGE),
+	"enableClusterQueueInputOutput":    catalogBool(),
+	"enableUserMetadataOptions":        catalogBool(),
+	"agentStatusPublishRateLimit":      catalogInt(0, math.MaxInt32),
+	"agentStatusPublishRateMin":        catalogInt(0, math.MaxInt32),
+	"failTransferWhenCapacityReached":  catalogBool(),
+	"failTransferOnFirstFailure":       catalogBool(),
+	"maxInputOutputMessageLength":      catalogInt(1, math.MaxInt32),
+	"enableMandatoryLocking":           catalogBool(),
+	"enableMemoryAllocationChecking":   catalogBool(),
+	"legacyXMLMessageMQMDFormat":       catalogBool(),
+	"ioIdleThreadTimeout":              catalogInt(1, math.MaxInt32),
+	"ioQueueDepth":                     catalogInt(1, math.MaxInt32),
+	"ioThreadPoolSize":                 catalogInt(1, math.MaxInt32),
+
+	// Resource monitors
+	"monitorMaxResourcesInPoll":      catalogInt(-1, math.MaxInt32),
+	"monitorGroupRetryLimit":         catalogInt(0, math.MaxInt32),
+	"monitorSilenceOnTriggerFailure": catalogInt(0, math.MaxInt32),
+	"monitorReportTriggerFail":       catalogBool(),
+
+	// Security
+	"authorityChecking":                 catalogBool(),
+	"userSandboxes":                     catalogBool(),
+	"additionalWildcardSandboxChecking": catalogBool(),
+	"commandPath":                       catalogString(),
+	"addCommandPathToSandbox":           catalogBool(),
+	"xmlConfigReloadInterval":           catalogInt(0, math.MaxInt32),
+	"sandboxRoot":                       catalogDeprecated(DATA_TYPE_STRING, "the sandboxes attribute of the agent, written to UserSandboxes.xml"),
+
+	// Exits
+	"exitClassPath":                       catalogString(),
+	"exitNativeLibraryPath":               catalogString(),
+	"sourceTransferStartExitClasses":      catalogString(),
+	"sourceTransferEndExitClasses":        catalogString(),
+	"destinationTransferStartExitClasses": catalogString(),
+	"destinationTransferEndExitClasses":   catalogString(),
+	"monitorExitClasses":                  catalogString(),
+	"IOExitClasses":                       catalogString(),
+	"protocolBridgeCredentialExitClasses": catalogManagedValue(DATA_TYPE_STRING, bridgeAgentProperties[0].Value, "set to the credential exit reading protocol server credentials", AGENT_TYPE_BRIDGE),
+	"cdCredentialExitClasses":             catalogString(),
+
+	// Protocol bridge and Connect:Direct bridge agents
+	"protocolBridgeCredentialConfiguration": catalogString(),
+	"protocolBridgeDataTimeout":             catalogInt(0, math.MaxInt32),
+	"cdNode":                                catalogManaged(DATA_TYPE_STRING, "set from the cdNodes attribute", AGENT_TYPE_CD),
+	"cdNodeHost":                            catalogManaged(DATA_TYPE_STRING, "set from the cdNodes attribute", AGENT_TYPE_CD),
+	"cdNodePort":                            catalogManaged(DATA_TYPE_INT, "set from the cdNodes attribute", AGENT_TYPE_CD),
+	"cdTmpDir":                              catalogManaged(DATA_TYPE_STRING, "set from the cdTmpDir attribute", AGENT_TYPE_CD),
+	"cdNodeProtocol":                        catalogString(),
+	"cdCustomNodePropertiesFile":            catalogString(),
+	"cdMaxConnectionRetries":                catalogInt(-1, math.MaxInt32),
+	"cdMaxPartnerConnectionRetries":         catalogInt(-1, math.MaxInt32),
+	"cdMaxWaitForProcessEndStats":           catalogInt(-1, math.MaxInt32),
+	"cdNodeLocalPortRange":                  catalogString(),
+	"cdNodeKeystore":                        catalogString(),
+	"cdNodeKeystoreType":                    catalogString(),
+	"cdNodeKeystoreCredentialsFile":         catalogString(),
+	"cdNodeTruststore":                      catalogString(),
+	"cdNodeTruststoreType":                  catalogString(),
+	"cdNodeTruststoreCredentialsFile":       catalogString(),
+
+	// Logging, trace and restarts
+	"logCapture":               catalogManagedValue(DATA_TYPE_BOOL, "true", "set to true so that the agent log is written to the container log"),
+	"agentLog":                 catalogString(),
+	"agentLogFiles":            catalogInt(1, math.MaxInt32),
+	"agentLogSize":             catalogInt(1, math.MaxInt32),
+	"logTransferRecovery":      catalogBool(),
+	"trace":                    catalogString(),
+	"traceFiles":               catalogInt(1, math.MaxInt32),
+	"traceSize":                catalogInt(1, math.MaxInt32),
+	"outputLogFiles":           catalogInt(1, math.MaxInt32),
+	"outputLogSize":            catalogInt(1, math.MaxInt32),
+	"javaCoreTriggerFile":      catalogString(),
+	"maxRestartCount":          catalogManagedValue(DATA_TYPE_INT, "0", "set to 0 so that the container ends when the agent fails"),
+	"maxRestartDelay":          catalogInt(-1, math.MaxInt32),
+	"maxRestartInterval":       catalogInt(0, math.MaxInt32),
+	"immediateShutdownTimeout": catalogInt(0, math.MaxInt32),
+})
+
+// Combine properties of two catalogs
+func mergeCatalog(catalog map[string]*catalogProperty, properties map[string]*catalogProperty) map[string]*catalogProperty {
+	for name, property := range properties {
+		catalog[name] = property
+	}
+	return catalog
+}
+
+// A problem with a property of a properties file. Warnings do not stop the
+// container even when validation is strict.
+type propertyProblem struct {
+	path    string
+	file    string
+	problem string
+	warning bool
+}
+
+func (problem propertyProblem) Error() string {
+	return fmt.Sprintf(utils.MFT_CONT_PROPERTY_INVALID_0156, problem.path, problem.file, problem.problem)
+}
+
+// Validate the additionalProperties groups of the configuration file against
+// the catalog of the properties file they are written to. The file must have
+// passed schema validation.
+func validatePropertiesCatalog(jsonData string) []propertyProblem {
+	var problems []propertyProblem
+	problems = append(problems, validateCatalogProperties(gjson.Get(jsonData, "coordinationQMgr.additionalProperties"),
+		"coordinationQMgr.additionalProperties", PROPERTIES_FILE_COORDINATION, coordinationCatalog, "")...)
+	problems = append(problems, validateCatalogProperties(gjson.Get(jsonData, "commandQMgr.additionalProperties"),
+		"commandQMgr.additionalProperties", PROPERTIES_FILE_COMMAND, commandCatalog, "")...)
+	for agentIndex, agent := range gjson.Get(jsonData, "agents").Array() {
+		agentType := strings.ToUpper(strings.TrimSpace(agent.Get("type").String()))
+		if len(agentType) == 0 {
+			agentType = AGENT_TYPE_STANDARD
+		}
+		problems = append(problems, validateCatalogProperties(agent.Get("additionalProperties"),
+			fmt.Sprintf("agents[%d].additionalProperties", agentIndex), PROPERTIES_FILE_AGENT, agentCatalog, agentType)...)
+	}
+	return problems
+}
+
+// Validate one additionalProperties group
+func validateCatalogProperties(properties gjson.Result, path string, file string, catalog map[string]*catalogProperty, agentType string) []propertyProblem {
+	var problems []propertyProblem
+	allowed := allowedPropertyNames()
+	properties.ForEach(func(key, value gjson.Result) bool {
+		name := key.String()
+		propertyPath := joinPath(path, name)
+		property, known := catalog[name]
+		if !known {
+			if !allowed[name] {
+				problem := "is not a known property"
+				if suggestion := suggestPropertyName(name, catalog); len(suggestion) > 0 {
+					problem += ", did you mean " + suggestion
+				}
+				problems = append(problems, propertyProblem{propertyPath, file, problem, false})
+			}
+			return true
+		}
+		if err := property.validateValue(value); err != nil {
+			problems = append(problems, propertyProblem{propertyPath, file, err.Error(), false})
+		}
+		if len(property.deprecated) > 0 {
+			problems = append(problems, propertyProblem{propertyPath, file, "is deprecated, use " + property.deprecated + " instead", true})
+		}
+		if property.overrides(value, agentType) {
+			problems = append(problems, propertyProblem{propertyPath, file, "overrides the value " + property.managed, true})
+		}
+		return true
+	})
+	return problems
+}
+
+// Determine if the value overrides the value set by the container for agents
+// of the given type
+func (property *catalogProperty) overrides(value gjson.Result, agentType string) bool {
+	if len(property.managed) == 0 || (len(property.managedFor) > 0 && !containsFold(property.managedFor, agentType)) {
+		return false
+	}
+	return len(property.managedValue) == 0 || !strings.EqualFold(strings.TrimSpace(value.String()), property.managedValue)
+}
+
+// Check that the value matches the type and range of the property
+func (property *catalogProperty) validateValue(value gjson.Result) error {
+	text := strings.TrimSpace(value.String())
+	switch property.dataType {
+	case DATA_TYPE_BOOL:
+		if value.Type != gjson.True && value.Type != gjson.False && !strings.EqualFold(text, "true") && !strings.EqualFold(text, "false") {
+			return fmt.Errorf("has value %s which is not true or false", value.Raw)
+		}
+	case DATA_TYPE_INT:
+		if value.Type == gjson.True || value.Type == gjson.False {
+			return fmt.Errorf("has value %s which is not an integer", value.Raw)
+		}
+		number, err := utils.ToNumber(text)
+		if err != nil {
+			return fmt.Errorf("has value %s which is not an integer", value.Raw)
+		}
+		if number < int64(property.min) || number > int64(property.max) {
+			return fmt.Errorf("has value %d which is not within range %d to %d", number, property.min, property.max)
+		}
+	}
+	return nil
+}
+
+// Name of the catalog property closest to the given name, if close enough to
+// be a likely misspelling. Blank if there is none.
+func suggestPropertyName(name string, catalog map[string]*catalogProperty) string {
+	names := make([]string, 0, len(catalog))
+	for candidate := range catalog {
+		names = append(names, candidate)
+	}
+	sort.Strings(names)
+
+	suggestion := ""
+	bestDistance := len(name)/5 + 2
+	for _, candidate := range names {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			suggestion = candidate
+			bestDistance = distance
+		}
+	}
+	return suggestion
+}
+
+// Levenshtein distance between two strings
+func editDistance(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// Names of properties accepted without checks, typically read by user exits
+func allowedPropertyNames() map[string]bool {
+	allowed := make(map[string]bool)
+	for _, name := range strings.Split(os.Getenv(MFT_PROPERTIES_ALLOWED_NAMES), ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			allowed[name] = true
+		}
+	}
+	return allowed
+}
+
+// Determine if unknown or invalid properties stop the container
+func isPropertiesValidationStrict() bool {
+	strict, strictSet := os.LookupEnv(MFT_PROPERTIES_STRICT_VALIDATION)
+	return strictSet && strings.EqualFold(strings.TrimSpace(strict), TEXT_YES)
+}
@@ -0,0 +1,118 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestValidatePropertiesCatalog(t *testing.T) {
+	// Sample configuration only sets properties to the values of the container
+	configData, err := os.ReadFile("./data/test_agcfg.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := validatePropertiesCatalog(string(configData)); len(problems) != 0 {
+		t.Errorf("Unexpected problems %v", problems)
+	}
+
+	configuration := "{\"coordinationQMgr\":{\"additionalProperties\":{\"coordinationSslPeerName\":\"CN=QM1\",\"coordinationQMgrHots\":\"qm1\"}}," +
+		"\"commandQMgr\":{\"additionalProperties\":{\"connectionQMgrAuthenticationCredentialsFile\":\"/mnt/cred.xml\"}}," +
+		"\"agents\":[{\"name\":\"SRC\",\"additionalProperties\":{\"maxSourceTransfer\":\"10\",\"maxDestinationTransfers\":\"ten\"," +
+		"\"maxQueuedTransfers\":0,\"userSandboxes\":\"yes\",\"logCapture\":false,\"maxRestartCount\":\"0\",\"enableQueueInputOutput\":true," +
+		"\"agentSslKeyStorePassword\":\"secret\",\"customExitSetting\":\"1\"}}," +
+		"{\"name\":\"PBA\",\"type\":\"bridge\",\"additionalProperties\":{\"enableQueueInputOutput\":\"true\",\"MaxSourceTransfers\":5}}]}"
+	expected := []propertyProblem{
+		{"coordinationQMgr.additionalProperties.coordinationQMgrHots", PROPERTIES_FILE_COORDINATION, "is not a known property, did you mean coordinationQMgrHost", false},
+		{"commandQMgr.additionalProperties.connectionQMgrAuthenticationCredentialsFile", PROPERTIES_FILE_COMMAND, "overrides the value set to the credentials file written by the container", true},
+		{"agents[0].additionalProperties.maxSourceTransfer", PROPERTIES_FILE_AGENT, "is not a known property, did you mean maxSourceTransfers", false},
+		{"agents[0].additionalProperties.maxDestinationTransfers", PROPERTIES_FILE_AGENT, "has value \"ten\" which is not an integer", false},
+		{"agents[0].additionalProperties.maxQueuedTransfers", PROPERTIES_FILE_AGENT, "has value 0 which is not within range 1 to 2147483647", false},
+		{"agents[0].additionalProperties.userSandboxes", PROPERTIES_FILE_AGENT, "has value \"yes\" which is not true or false", false},
+		{"agents[0].additionalProperties.logCapture", PROPERTIES_FILE_AGENT, "overrides the value set to true so that the agent log is written to the container log", true},
+		{"agents[0].additionalProperties.agentSslKeyStorePassword", PROPERTIES_FILE_AGENT, "is deprecated, use agentSslKeyStoreCredentialsFile instead", true},
+		{"agents[0].additionalProperties.customExitSetting", PROPERTIES_FILE_AGENT, "is not a known property", false},
+		{"agents[1].additionalProperties.enableQueueInputOutput", PROPERTIES_FILE_AGENT, "overrides the value set to false as queues are not supported by bridge agents", true},
+		{"agents[1].additionalProperties.MaxSourceTransfers", PROPERTIES_FILE_AGENT, "is not a known property, did you mean maxSourceTransfers", false},
+	}
+	assertPropertyProblems(t, validatePropertiesCatalog(configuration), expected)
+
+	// Properties read by user exits can be accepted
+	t.Setenv(MFT_PROPERTIES_ALLOWED_NAMES, "otherSetting, customExitSetting")
+	assertPropertyProblems(t, validatePropertiesCatalog(configuration), append(expected[:8:8], expected[9:]...))
+}
+
+func TestValidatePropertiesCatalogHighlyAvailable(t *testing.T) {
+	configData, err := os.ReadFile("./data/test_ha_agent.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Configuration of a highly available agent does not stop the container
+	// when validation is strict
+	t.Setenv(MFT_PROPERTIES_STRICT_VALIDATION, "yes")
+	for _, problem := range validatePropertiesCatalog(string(configData)) {
+		if !problem.warning && isPropertiesValidationStrict() {
+			t.Errorf("Unexpected problem %v", problem)
+		}
+	}
+}
+
+func TestPropertyProblemError(t *testing.T) {
+	problem := propertyProblem{"agents[0].additionalProperties.maxSourceTransfer", PROPERTIES_FILE_AGENT, "is not a known property, did you mean maxSourceTransfers", false}
+	expected := "Property 'agents[0].additionalProperties.maxSourceTransfer' of agent.properties is not a known property, did you mean maxSourceTransfers."
+	if problem.Error() != expected {
+		t.Errorf("Unexpected message %q", problem.Error())
+	}
+}
+
+func TestSuggestPropertyName(t *testing.T) {
+	tests := map[string]string{
+		"maxSourceTransfer":   "maxSourceTransfers",
+		"maxsourcetransfers":  "maxSourceTransfers",
+		"enableQueueIOutput":  "enableQueueInputOutput",
+		"agentSslTrustStor":   "agentSslTrustStore",
+		"traceLevel":          "",
+		"completelyUnrelated": "",
+	}
+	for name, expected := range tests {
+		if suggestion := suggestPropertyName(name, agentCatalog); suggestion != expected {
+			t.Errorf("Suggested %q for %s, expected %q", suggestion, name, expected)
+		}
+	}
+}
+
+func TestIsPropertiesValidationStrict(t *testing.T) {
+	if isPropertiesValidationStrict() {
+		t.Error("Validation is strict by default")
+	}
+	t.Setenv(MFT_PROPERTIES_STRICT_VALIDATION, "YES")
+	if !isPropertiesValidationStrict() {
+		t.Error("Strict validation not enabled")
+	}
+}
+
+func assertPropertyProblems(t *testing.T, problems []propertyProblem, expected []propertyProblem) {
+	t.Helper()
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %d problems, found %v", len(expected), problems)
+	}
+	for i, problem := range problems {
+		if problem != expected[i] {
+			t.Errorf("Expected problem %+v, found %+v", expected[i], problem)
+		}
+	}
+}
@@ -1,5 +1,5 @@
 /*
-© Copyright IBM Corporation 2022, 2022
+© Copyright IBM Corporation 2022, 2024
 
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
@@ -19,42 +19,243 @@ package main
 * Contains a list of properties as defined in ProtocolBridgeProperties.xml.
 * These properties are valid only for prototcol bridge agents. The methods
 * in this file validate the properties specified in agent configuration
-* JSON file.
+* JSON file. The schema of protocolServers elements is built from the same
+* list. Beyond the schema, a property must be valid for the protocol of the
+* server, integers must be within range, mandatory properties must be set and
+* the default server must be defined.
  */
-// HashMap of Protocol bridge property name and it's type
-// 1 - string
-// 2 - integer
-// 3 - boolean
-var bridgeProperies map[string]int = make(map[string]int)
-
-func BuildBridgePropertyList() {
-	bridgeProperies["defaultServer"] = DATA_TYPE_STRING
-	bridgeProperies["maxActiveDestinationTransfers"] = DATA_TYPE_INT
-	bridgeProperies["failTransferWhenCapacityReached"] = DATA_TYPE_BOOL
-	bridgeProperies["name"] = DATA_TYPE_STRING
-	bridgeProperies["type"] = DATA_TYPE_STRING
-	bridgeProperies["host"] = DATA_TYPE_STRING
-	bridgeProperies["port"] = DATA_TYPE_INT
-	bridgeProperies["platform"] = DATA_TYPE_STRING
-	bridgeProperies["timeZone"] = DATA_TYPE_STRING
-	bridgeProperies["locale"] = DATA_TYPE_STRING
-	bridgeProperies["fileEncoding"] = DATA_TYPE_STRING
-	bridgeProperies["listFormat"] = DATA_TYPE_STRING
-	bridgeProperies["listFileRecentDateFormat"] = DATA_TYPE_STRING
-	bridgeProperies["listFileOldDateFormat"] = DATA_TYPE_STRING
-	bridgeProperies["monthShortNames"] = DATA_TYPE_STRING
-	bridgeProperies["limitedWrite"] = DATA_TYPE_BOOL
-	bridgeProperies["maxListFileNames"] = DATA_TYPE_INT
-	bridgeProperies["maxListDirectoryLevels"] = DATA_TYPE_INT
-	bridgeProperies["maxSessions"] = DATA_TYPE_INT
-	bridgeProperies["socketTimeout"] = DATA_TYPE_INT
-	bridgeProperies["passiveMode"] = DATA_TYPE_BOOL
-	bridgeProperies["connectionTimeout"] = DATA_TYPE_INT
-	bridgeProperies["controlEncoding"] = DATA_TYPE_STRING
-}
-
-// Determine if the specified property is valid
-func ValidateBridgeProperty(propertyName string) (bool, int) {
-	typeV, ok := bridgeProperies[propertyName]
-	return ok, typeV
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/tidwall/gjson"
+)
+
+// Protocols of protocol servers
+const BRIDGE_PROTOCOL_FTP = "FTP"
+const BRIDGE_PROTOCOL_FTPS = "FTPS"
+const BRIDGE_PROTOCOL_SFTP = "SFTP"
+
+// Attribute of protocol servers holding their credentials, which is valid for
+// all protocols and is not a property of ProtocolBridgeProperties.xml
+const BRIDGE_SERVER_CREDENTIALS = "credentials"
+
+var allBridgeProtocols = []string{BRIDGE_PROTOCOL_FTP, BRIDGE_PROTOCOL_FTPS, BRIDGE_PROTOCOL_SFTP}
+var ftpBridgeProtocols = []string{BRIDGE_PROTOCOL_FTP, BRIDGE_PROTOCOL_FTPS}
+
+// A property of a protocol server or of the bridge agent
+type bridgeProperty struct {
+	// One of the DATA_TYPE_* constants
+	dataType int
+	// Allowed values of a string property. Compared ignoring case.
+	enum []string
+	// Range of an integer property
+	min int
+	max int
+	// Protocols the property is valid for
+	protocols []string
+	// Protocols for which the property must be specified
+	requiredFor []string
+}
+
+// Integer property with the range of valid values
+func bridgeIntProperty(min int, max int, protocols []string) *bridgeProperty {
+	return &bridgeProperty{dataType: DATA_TYPE_INT, min: min, max: max, protocols: protocols}
+}
+
+// Properties of protocol servers
+var bridgeServerProperties = map[string]*bridgeProperty{
+	"name":                          {dataType: DATA_TYPE_STRING, protocols: allBridgeProtocols},
+	"type":                          {dataType: DATA_TYPE_STRING, enum: allBridgeProtocols, protocols: allBridgeProtocols},
+	"host":                          {dataType: DATA_TYPE_STRING, protocols: allBridgeProtocols},
+	"port":                          bridgeIntProperty(1, 65535, allBridgeProtocols),
+	"platform":                      {dataType: DATA_TYPE_STRING, enum: []string{"UNIX", "WINDOWS", "OS400"}, protocols: allBridgeProtocols},
+	"timeZone":                      {dataType: DATA_TYPE_STRING, protocols: ftpBridgeProtocols, requiredFor: ftpBridgeProtocols},
+	"locale":                        {dataType: DATA_TYPE_STRING, protocols: ftpBridgeProtocols, requiredFor: ftpBridgeProtocols},
+	"fileEncoding":                  {dataType: DATA_TYPE_STRING, protocols: allBridgeProtocols, requiredFor: allBridgeProtocols},
+	"controlEncoding":               {dataType: DATA_TYPE_STRING, protocols: allBridgeProtocols},
+	"listFormat":                    {dataType: DATA_TYPE_STRING, enum: []string{"UNIX", "WINDOWS", "OS400IFS"}, protocols: ftpBridgeProtocols},
+	"listFileRecentDateFormat":      {dataType: DATA_TYPE_STRING, protocols: ftpBridgeProtocols},
+	"listFileOldDateFormat":         {dataType: DATA_TYPE_STRING, protocols: ftpBridgeProtocols},
+	"monthShortNames":               {dataType: DATA_TYPE_STRING, protocols: ftpBridgeProtocols},
+	"limitedWrite":                  {dataType: DATA_TYPE_BOOL, protocols: allBridgeProtocols},
+	"passiveMode":                   {dataType: DATA_TYPE_BOOL, protocols: ftpBridgeProtocols},
+	"trustStoreFile":                {dataType: DATA_TYPE_STRING, protocols: []string{BRIDGE_PROTOCOL_FTPS}},
+	"trustStoreType":                {dataType: DATA_TYPE_STRING, enum: []string{"jks", "pkcs12"}, protocols: []string{BRIDGE_PROTOCOL_FTPS}},
+	"keyStoreFile":                  {dataType: DATA_TYPE_STRING, protocols: []string{BRIDGE_PROTOCOL_FTPS}},
+	"keyStoreType":                  {dataType: DATA_TYPE_STRING, enum: []string{"jks", "pkcs12"}, protocols: []string{BRIDGE_PROTOCOL_FTPS}},
+	"ftpsType":                      {dataType: DATA_TYPE_STRING, enum: []string{"explicit", "implicit"}, protocols: []string{BRIDGE_PROTOCOL_FTPS}},
+	"protectionLevel":               {dataType: DATA_TYPE_STRING, enum: []string{"C", "P"}, protocols: []string{BRIDGE_PROTOCOL_FTPS}},
+	"cipherSuites":                  {dataType: DATA_TYPE_STRING, protocols: []string{BRIDGE_PROTOCOL_FTPS}},
+	"cipherList":                    {dataType: DATA_TYPE_STRING, protocols: []string{BRIDGE_PROTOCOL_SFTP}},
+	"hostKeyCipherList":             {dataType: DATA_TYPE_STRING, protocols: []string{BRIDGE_PROTOCOL_SFTP}},
+	"keyExchangeCipherList":         {dataType: DATA_TYPE_STRING, protocols: []string{BRIDGE_PROTOCOL_SFTP}},
+	"MACCipherList":                 {dataType: DATA_TYPE_STRING, protocols: []string{BRIDGE_PROTOCOL_SFTP}},
+	"fingerprintHash":               {dataType: DATA_TYPE_STRING, protocols: []string{BRIDGE_PROTOCOL_SFTP}},
+	"maxListFileNames":              bridgeIntProperty(1, math.MaxInt32, allBridgeProtocols),
+	"maxListDirectoryLevels":        bridgeIntProperty(0, math.MaxInt32, allBridgeProtocols),
+	"maxSessions":                   bridgeIntProperty(1, math.MaxInt32, allBridgeProtocols),
+	"socketTimeout":                 bridgeIntProperty(0, math.MaxInt32, allBridgeProtocols),
+	"connectionTimeout":             bridgeIntProperty(0, math.MaxInt32, allBridgeProtocols),
+	"maxActiveDestinationTransfers": bridgeIntProperty(1, math.MaxInt32, allBridgeProtocols),
+	// Applies to the bridge agent, accepted on servers as existing
+	// configuration files specify it there
+	"failTransferWhenCapacityReached": {dataType: DATA_TYPE_BOOL, protocols: allBridgeProtocols},
+}
+
+// Properties of the bridge agent, applying to all of its protocol servers
+var bridgeGlobalProperties = map[string]*bridgeProperty{
+	"defaultServer":                   {dataType: DATA_TYPE_STRING},
+	"maxActiveDestinationTransfers":   bridgeIntProperty(1, math.MaxInt32, nil),
+	"failTransferWhenCapacityReached": {dataType: DATA_TYPE_BOOL},
+}
+
+// Schema of the property, for validating configuration file
+func (property *bridgeProperty) schema() *configSchema {
+	return &configSchema{dataType: property.dataType, enum: property.enum}
+}
+
+// Schema of an element of protocolServers array, accepting the properties of
+// any protocol
+func bridgeServerSchema() *configSchema {
+	schema := &configSchema{
+		dataType:   DATA_TYPE_OBJECT,
+		properties: make(map[string]*configSchema),
+		required:   []string{"name"},
+	}
+	for name, property := range bridgeServerProperties {
+		schema.properties[name] = property.schema()
+	}
+	// Credentials are written to the credentials file of the agent
+	schema.properties[BRIDGE_SERVER_CREDENTIALS] = protocolServerCredentialsSchema
+	return schema
+}
+
+// Determine if the specified property is valid for servers of the protocol
+func ValidateBridgeProperty(protocol string, propertyName string) (*bridgeProperty, bool) {
+	property, ok := bridgeServerProperties[propertyName]
+	if !ok || !containsFold(property.protocols, protocol) {
+		return nil, false
+	}
+	return property, true
+}
+
+// Validate the protocol servers and bridge properties of every bridge agent in
+// the configuration file. The file must have passed schema validation. Returns
+// one error for every problem, naming the agent, the server and the property.
+func validateBridgeDefinitions(jsonData string) []error {
+	var errs []error
+	for agentIndex, agent := range gjson.Get(jsonData, "agents").Array() {
+		if !strings.EqualFold(agent.Get("type").String(), AGENT_TYPE_BRIDGE) {
+			continue
+		}
+		agentName := agent.Get("name").String()
+		agentPath := fmt.Sprintf("agents[%d]", agentIndex)
+		for name, property := range bridgeGlobalProperties {
+			if value := agent.Get(name); value.Exists() {
+				if err := property.validateRange(value); err != nil {
+					errs = append(errs, fmt.Errorf(utils.MFT_CONT_BRIDGE_INVALID_AGENT_0122, agentName, joinPath(agentPath, name), err))
+				}
+			}
+		}
+
+		serverNames := make(map[string]bool)
+		for serverIndex, server := range agent.Get("protocolServers").Array() {
+			serverName := server.Get("name").String()
+			serverPath := fmt.Sprintf("%s.protocolServers[%d]", agentPath, serverIndex)
+			if serverNames[serverName] {
+				errs = append(errs, fmt.Errorf(utils.MFT_CONT_BRIDGE_INVALID_SERVER_0123, serverName, agentName, joinPath(serverPath, "name"), "names a server that is defined more than once"))
+			}
+			serverNames[serverName] = true
+			for _, err := range validateBridgeServer(server, serverPath) {
+				errs = append(errs, fmt.Errorf(utils.MFT_CONT_BRIDGE_INVALID_SERVER_0123, serverName, agentName, err.path, err.problem))
+			}
+		}
+
+		if defaultServer := agent.Get("defaultServer"); defaultServer.Exists() && !serverNames[defaultServer.String()] {
+			errs = append(errs, fmt.Errorf(utils.MFT_CONT_BRIDGE_INVALID_AGENT_0122, agentName, joinPath(agentPath, "defaultServer"),
+				fmt.Sprintf("names server %s that is not defined in protocolServers", defaultServer.String())))
+		}
+	}
+	return errs
+}
+
+// A problem with a property of a protocol server
+type bridgeServerError struct {
+	path    string
+	problem string
+}
+
+// Validate the properties of a protocol server against the properties of its
+// protocol. Protocol defaults to FTP.
+func validateBridgeServer(server gjson.Result, serverPath string) []bridgeServerError {
+	var errs []bridgeServerError
+	protocol := strings.ToUpper(strings.TrimSpace(server.Get("type").String()))
+	if len(protocol) == 0 {
+		protocol = BRIDGE_PROTOCOL_FTP
+	}
+	server.ForEach(func(key, value gjson.Result) bool {
+		if key.String() == BRIDGE_SERVER_CREDENTIALS {
+			return true
+		}
+		property, valid := ValidateBridgeProperty(protocol, key.String())
+		if !valid {
+			errs = append(errs, bridgeServerError{joinPath(serverPath, key.String()), "is not valid for " + protocol + " servers"})
+		} else if err := property.validateRange(value); err != nil {
+			errs = append(errs, bridgeServerError{joinPath(serverPath, key.String()), err.Error()})
+		}
+		return true
+	})
+	for _, name := range sortedBridgePropertyNames() {
+		if containsFold(bridgeServerProperties[name].requiredFor, protocol) && len(strings.TrimSpace(server.Get(name).String())) == 0 {
+			errs = append(errs, bridgeServerError{joinPath(serverPath, name), "must be specified for " + protocol + " servers"})
+		}
+	}
+	return errs
+}
+
+// Check that an integer value is within the range of the property
+func (property *bridgeProperty) validateRange(value gjson.Result) error {
+	if property.dataType != DATA_TYPE_INT {
+		return nil
+	}
+	number, err := utils.ToNumber(strings.TrimSpace(value.String()))
+	if err != nil {
+		// Type has been checked by schema validation
+		return nil
+	}
+	if number < int64(property.min) || number > int64(property.max) {
+		return fmt.Errorf("has value %d which is not within range %d to %d", number, property.min, property.max)
+	}
+	return nil
+}
+
+// Names of server properties, sorted so that errors are reported in a stable order
+func sortedBridgePropertyNames() []string {
+	names := make([]string, 0, len(bridgeServerProperties))
+	for name := range bridgeServerProperties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Determine if the list contains the value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, item := range values {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Determine if invalid protocol server definitions stop the container
+func isBridgeValidationStrict() bool {
+	strict, strictSet := os.LookupEnv(MFT_BRIDGE_STRICT_VALIDATION)
+	return strictSet && strings.EqualFold(strings.TrimSpace(strict), TEXT_YES)
 }
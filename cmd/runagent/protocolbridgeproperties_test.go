@@ -0,0 +1,92 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestValidateBridgeProperty(t *testing.T) {
+	tests := []struct {
+		protocol string
+		name     string
+		valid    bool
+	}{
+		{"FTP", "timeZone", true},
+		{"ftps", "passiveMode", true},
+		{"FTPS", "ftpsType", true},
+		{"SFTP", "fingerprintHash", true},
+		{"SFTP", "timeZone", false},
+		{"FTP", "cipherList", false},
+		{"FTP", "trustStoreFile", false},
+		{"FTP", "hostname", false},
+	}
+	for _, test := range tests {
+		if _, valid := ValidateBridgeProperty(test.protocol, test.name); valid != test.valid {
+			t.Errorf("Property %s for %s servers reported as valid %v", test.name, test.protocol, valid)
+		}
+	}
+}
+
+func TestValidateBridgeDefinitions(t *testing.T) {
+	configData, err := os.ReadFile("./data/test_agcfg.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Protocol server newsftpserver of bridge agent BRIDGE: attribute 'agents[0].protocolServers[0].locale' is not valid for SFTP servers.",
+		"Protocol server newsftpserver of bridge agent BRIDGE: attribute 'agents[0].protocolServers[0].listFormat' is not valid for SFTP servers.",
+		"Protocol server mySFTPserver of bridge agent BRIDGE: attribute 'agents[0].protocolServers[2].timeZone' is not valid for SFTP servers.",
+		"Protocol server mySFTPserver of bridge agent BRIDGE: attribute 'agents[0].protocolServers[2].locale' is not valid for SFTP servers.",
+	}
+	assertBridgeErrors(t, validateBridgeDefinitions(string(configData)), expected)
+
+	configuration := "{\"agents\":[{\"name\":\"SRC\",\"protocolServers\":[{\"name\":\"ignored\",\"port\":0}]}," +
+		"{\"name\":\"PBA\",\"type\":\"bridge\",\"defaultServer\":\"missing\",\"maxActiveDestinationTransfers\":\"0\",\"protocolServers\":[" +
+		"{\"name\":\"ftp\",\"port\":70000,\"locale\":\"en_GB\",\"fileEncoding\":\"UTF-8\"}," +
+		"{\"name\":\"ftp\",\"type\":\"SFTP\",\"fileEncoding\":\"UTF-8\",\"maxSessions\":\"5\"}]}]}"
+	expected = []string{
+		"Bridge agent PBA: attribute 'agents[1].maxActiveDestinationTransfers' has value 0 which is not within range 1 to 2147483647.",
+		"Protocol server ftp of bridge agent PBA: attribute 'agents[1].protocolServers[0].port' has value 70000 which is not within range 1 to 65535.",
+		"Protocol server ftp of bridge agent PBA: attribute 'agents[1].protocolServers[0].timeZone' must be specified for FTP servers.",
+		"Protocol server ftp of bridge agent PBA: attribute 'agents[1].protocolServers[1].name' names a server that is defined more than once.",
+		"Bridge agent PBA: attribute 'agents[1].defaultServer' names server missing that is not defined in protocolServers.",
+	}
+	assertBridgeErrors(t, validateBridgeDefinitions(configuration), expected)
+}
+
+func TestIsBridgeValidationStrict(t *testing.T) {
+	if isBridgeValidationStrict() {
+		t.Error("Validation is strict by default")
+	}
+	t.Setenv(MFT_BRIDGE_STRICT_VALIDATION, " yes ")
+	if !isBridgeValidationStrict() {
+		t.Error("Strict validation not enabled")
+	}
+}
+
+func assertBridgeErrors(t *testing.T, errs []error, expected []string) {
+	t.Helper()
+	if len(errs) != len(expected) {
+		t.Fatalf("Expected %d errors, found %v", len(expected), errs)
+	}
+	for i, err := range errs {
+		if err.Error() != expected[i] {
+			t.Errorf("Expected error %q, found %q", expected[i], err.Error())
+		}
+	}
+}
@@ -0,0 +1,415 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Keeps track of the configuration that has been created in BFG_DATA, so that
+* coordination, command and agent configuration is created again only when
+* the configuration file, TLS environment variables or certificates change.
+* Values are recorded as salted hashes so that credentials are not written
+* to the state file.
+ */
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	"github.com/tidwall/gjson"
+)
+
+// Sections of provisioned configuration
+const PROVISION_COORDINATION = "coordination"
+const PROVISION_COMMAND = "command"
+
+// Configuration created when a section was last provisioned
+type provisionedSection struct {
+	// Hash of the entire section
+	Hash string `json:"hash"`
+	// Hash of each attribute, used for reporting what has changed
+	Attributes map[string]string `json:"attributes"`
+	// Files that must exist for the configuration to be reused
+	Files []string `json:"files,omitempty"`
+	// Identifier of the object created, for example of a scheduled transfer
+	Id string `json:"id,omitempty"`
+}
+
+// State of provisioned configuration kept in BFG_DATA
+type provisionState struct {
+	// Path of the state file
+	path string
+	// State is neither used nor updated, for example in dry-run mode
+	disabled bool
+	// Salt used for hashing values
+	Salt     string                         `json:"salt"`
+	Sections map[string]*provisionedSection `json:"sections"`
+}
+
+// Load the provisioning state from BFG_DATA. A missing or unreadable state
+// file results in all configuration being created.
+func loadProvisionState(bfgDataPath string, disabled bool) *provisionState {
+	state := &provisionState{
+		path:     bfgDataPath + MFT_PROVISION_STATE_SLASH,
+		disabled: disabled,
+		Sections: make(map[string]*provisionedSection),
+	}
+	if disabled {
+		return state
+	}
+
+	if utils.DoesFileExist(state.path) {
+		stateData, err := os.ReadFile(state.path)
+		if err == nil {
+			err = json.Unmarshal(stateData, state)
+		}
+		if err != nil {
+			if logLevel >= LOG_LEVEL_VERBOSE {
+				utils.PrintLog(fmt.Sprintf("Ignoring provisioning state in %s. %v", state.path, err))
+			}
+			state.Salt = ""
+			state.Sections = make(map[string]*provisionedSection)
+		}
+	}
+
+	if len(state.Salt) == 0 {
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err == nil {
+			state.Salt = hex.EncodeToString(salt)
+		}
+	}
+	return state
+}
+
+// Build a section from the given attributes, hashing each value.
+func (state *provisionState) newSection(attributes map[string]string) *provisionedSection {
+	section := &provisionedSection{Attributes: make(map[string]string, len(attributes))}
+	names := make([]string, 0, len(attributes))
+	for name, value := range attributes {
+		section.Attributes[name] = state.hash(name + "=" + value)
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sectionData strings.Builder
+	for _, name := range names {
+		sectionData.WriteString(name + "=" + section.Attributes[name] + "\n")
+	}
+	section.Hash = state.hash(sectionData.String())
+	return section
+}
+
+// Salted hash of the given text
+func (state *provisionState) hash(text string) string {
+	sum := sha256.Sum256([]byte(state.Salt + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// Determine if the named section has been provisioned with the same
+// configuration and all of its files are still available. The reason for
+// creating the configuration again is logged.
+func (state *provisionState) isProvisioned(name string, section *provisionedSection) bool {
+	if state.disabled {
+		return false
+	}
+	previous, found := state.Sections[name]
+	if !found {
+		return false
+	}
+
+	if previous.Hash != section.Hash {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_PROVISION_CHANGED_0095, name, strings.Join(section.changes(previous), ", ")))
+		return false
+	}
+
+	var missingFiles []string
+	for _, fileName := range previous.Files {
+		if !utils.DoesFileExist(fileName) {
+			missingFiles = append(missingFiles, fileName)
+		}
+	}
+	if len(missingFiles) > 0 {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_PROVISION_FILES_MISSING_0096, name, strings.Join(missingFiles, ", ")))
+		return false
+	}
+
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_PROVISION_UNCHANGED_0094, name))
+	return true
+}
+
+// List the attributes that have been added, removed or changed since the
+// section was previously provisioned.
+func (section *provisionedSection) changes(previous *provisionedSection) []string {
+	var changes []string
+	for name, hash := range section.Attributes {
+		previousHash, found := previous.Attributes[name]
+		if !found {
+			changes = append(changes, name+" added")
+		} else if previousHash != hash {
+			changes = append(changes, name+" changed")
+		}
+	}
+	for name := range previous.Attributes {
+		if _, found := section.Attributes[name]; !found {
+			changes = append(changes, name+" removed")
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// Record that the named section has been provisioned and save the state.
+func (state *provisionState) provisioned(name string, section *provisionedSection, files []string) {
+	if state.disabled {
+		return
+	}
+	section.Files = files
+	state.Sections[name] = section
+	state.save()
+}
+
+// Remove the named section so that it is provisioned again.
+func (state *provisionState) forget(name string) {
+	if state.disabled {
+		return
+	}
+	if _, found := state.Sections[name]; found {
+		delete(state.Sections, name)
+		state.save()
+	}
+}
+
+// Write the state to BFG_DATA. Failing to save the state is not fatal, the
+// configuration is created again on next start.
+func (state *provisionState) save() {
+	stateData, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = os.WriteFile(state.path, stateData, 0600)
+	}
+	if err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_PROVISION_STATE_ERROR_0097, state.path, err))
+	}
+}
+
+// Name of the section of an agent
+func agentSectionName(agentName string) string {
+	return "agent " + agentName
+}
+
+// Section describing the coordination queue manager configuration
+func (state *provisionState) coordinationSection(agentConfiguration *config.Configuration) *provisionedSection {
+	attributes := make(map[string]string)
+	flattenAttributes("", agentConfiguration.CoordinationQMgr, attributes)
+	addTLSAttributes(MFT_COORD_QMGR_CIPHER, coordinationQMCertPath, attributes)
+	return state.newSection(attributes)
+}
+
+// Section describing the command queue manager configuration
+func (state *provisionState) commandSection(agentConfiguration *config.Configuration) *provisionedSection {
+	attributes := make(map[string]string)
+	attributes["coordinationQMgr"] = agentConfiguration.CoordinationQMgr.Name
+	flattenAttributes("", agentConfiguration.CommandQMgr, attributes)
+	addTLSAttributes(MFT_CMD_QMGR_CIPHER, commandQMCertPath, attributes)
+	return state.newSection(attributes)
+}
+
+// Section describing the configuration of an agent
+func (state *provisionState) agentSection(agent *config.Agent, coordinationQMgr string) *provisionedSection {
+	attributes := make(map[string]string)
+	attributes["coordinationQMgr"] = coordinationQMgr
+	// Monitors, templates and schedules are reconciled separately once the
+	// agent has started and changing them does not require the agent to be
+	// created again. JVM options apply when the agent is started.
+	agentConfig := *agent
+	agentConfig.JVM = nil
+	agentConfig.Monitors = nil
+	agentConfig.Templates = nil
+	agentConfig.Schedules = nil
+	flattenAttributes("", &agentConfig, attributes)
+	addTLSAttributes(MFT_AGENT_QMGR_CIPHER, agentQMCertPath, attributes)
+	addCDSecretAttributes(agent, attributes)
+	addBridgeSecretAttributes(agent, attributes)
+	addFTPSCertificateAttributes(agent, bridgeServerCertPath, attributes)
+	return state.newSection(attributes)
+}
+
+// Section describing a transfer template or a scheduled transfer of an agent
+func (state *provisionState) transferSection(transfer interface{}, sourceQMgr string, destinationQMgr string) *provisionedSection {
+	attributes := make(map[string]string)
+	attributes["sourceQMgr"] = sourceQMgr
+	attributes["destinationQMgr"] = destinationQMgr
+	flattenAttributes("", transfer, attributes)
+	return state.newSection(attributes)
+}
+
+// Section describing a resource monitor of an agent
+func (state *provisionState) monitorSection(agent *config.Agent, monitor *config.Monitor, destinationQMgr string) *provisionedSection {
+	attributes := make(map[string]string)
+	attributes["agentQMgr"] = agent.QMgrName
+	attributes["destinationQMgr"] = destinationQMgr
+	flattenAttributes("", monitor, attributes)
+	return state.newSection(attributes)
+}
+
+// Add every attribute of the given value to the map, naming nested attributes
+// with their path in dot notation.
+func flattenAttributes(prefix string, value interface{}, attributes map[string]string) {
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	flattenResult(prefix, gjson.ParseBytes(jsonData), attributes)
+}
+
+func flattenResult(prefix string, result gjson.Result, attributes map[string]string) {
+	if result.IsObject() || result.IsArray() {
+		index := 0
+		result.ForEach(func(key, item gjson.Result) bool {
+			name := key.String()
+			if result.IsArray() {
+				name = fmt.Sprint(index)
+				index++
+			}
+			if len(prefix) > 0 {
+				name = prefix + "." + name
+			}
+			flattenResult(name, item, attributes)
+			return true
+		})
+	} else {
+		attributes[prefix] = result.String()
+	}
+}
+
+// Add the cipher environment variable and the certificates used for TLS
+// connections to the queue manager.
+func addTLSAttributes(cipherEnv string, certPath string, attributes map[string]string) {
+	attributes["env."+cipherEnv] = os.Getenv(cipherEnv)
+	addCertificateAttributes("certificates.", certPath, attributes)
+}
+
+// Add the certificates of FTPS servers of a bridge agent, from which the trust
+// stores and keystores of the servers are built
+func addFTPSCertificateAttributes(agent *config.Agent, certPath string, attributes map[string]string) {
+	for _, server := range agent.ProtocolServers {
+		if strings.EqualFold(server.Type, "FTPS") {
+			addCertificateAttributes("certificates.protocolServers."+server.Name+".", filepath.Join(certPath, server.Name), attributes)
+		}
+	}
+}
+
+// Add the contents of the files in the certificate directory, named with the
+// given prefix
+func addCertificateAttributes(prefix string, certPath string, attributes map[string]string) {
+	fileList, err := os.ReadDir(certPath)
+	if err != nil {
+		return
+	}
+	for _, fileInfo := range fileList {
+		if fileInfo.IsDir() {
+			continue
+		}
+		certData, err := os.ReadFile(filepath.Join(certPath, fileInfo.Name()))
+		if err == nil {
+			attributes[prefix+fileInfo.Name()] = string(certData)
+		}
+	}
+}
+
+// Add the passwords of Connect:Direct nodes so that the credentials file is
+// written again when a secret changes. Values are hashed like all attributes.
+func addCDSecretAttributes(agent *config.Agent, attributes map[string]string) {
+	for _, node := range agent.CDNodes {
+		if node.Credentials == nil {
+			continue
+		}
+		if password, err := resolveSecret(node.Credentials.Password); err == nil {
+			attributes["secrets.cdNodes."+node.Name+".password"] = password
+		}
+	}
+}
+
+// Add the secrets of protocol servers so that the credentials file is written
+// again when a secret changes
+func addBridgeSecretAttributes(agent *config.Agent, attributes map[string]string) {
+	addSecret := func(name string, ref *config.SecretRef) {
+		if ref == nil {
+			return
+		}
+		if value, err := resolveSecret(ref); err == nil {
+			attributes[name] = value
+		}
+	}
+	for _, server := range agent.ProtocolServers {
+		if server.Credentials == nil {
+			continue
+		}
+		prefix := "secrets.protocolServers." + server.Name
+		addSecret(prefix+".password", server.Credentials.Password)
+		addSecret(prefix+".privateKey", server.Credentials.PrivateKey)
+		addSecret(prefix+".hostKey", server.Credentials.HostKey)
+		for _, mapping := range server.Credentials.UserMappings {
+			addSecret(prefix+".userMappings."+mapping.Requester+".password", mapping.Password)
+			addSecret(prefix+".userMappings."+mapping.Requester+".privateKey", mapping.PrivateKey)
+		}
+	}
+}
+
+// Files created when provisioning, the properties file along with keystores,
+// credentials files and local CCDTs referred to by its properties.
+func provisionedFiles(propertiesFile string, properties config.Properties) []string {
+	files := []string{propertiesFile}
+	for _, property := range properties {
+		if strings.HasSuffix(property.Name, "TrustStore") ||
+			strings.HasSuffix(property.Name, "KeyStore") ||
+			strings.HasSuffix(property.Name, "CredentialsFile") {
+			files = append(files, property.Value)
+		} else if strings.HasSuffix(property.Name, "CcdtUrl") && strings.HasPrefix(property.Value, "file://") {
+			files = append(files, strings.TrimPrefix(property.Value, "file://"))
+		}
+	}
+	return files
+}
+
+// Files created when provisioning an agent, in the agent directory and the
+// stores built for FTPS servers of bridge agents. Stores under KEYSTORES_PATH
+// do not survive a restart of the container.
+func agentProvisionedFiles(agent *config.Agent, agentPath string) []string {
+	files := provisionedFiles(agentPath+MFT_AGENT_PROPS_SLASH, agent.AdditionalProperties)
+	if agent.Type == AGENT_TYPE_BRIDGE {
+		files = append(files, agentPath+MFT_PBA_PROPS_SLASH)
+		if bridgeCredentialsSpecified(agent) {
+			files = append(files, agentPath+MFT_PBA_CRED_SLASH)
+		}
+		for _, server := range agent.ProtocolServers {
+			if len(server.TrustStoreFile) > 0 {
+				files = append(files, server.TrustStoreFile)
+			}
+			if len(server.KeyStoreFile) > 0 {
+				files = append(files, server.KeyStoreFile)
+			}
+		}
+	} else if agent.Type == AGENT_TYPE_CD {
+		files = append(files, agentPath+MFT_CD_NODE_PROPS_SLASH, agentPath+MFT_CD_CRED_SLASH)
+	}
+	return files
+}
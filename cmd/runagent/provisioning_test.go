@@ -0,0 +1,154 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+func TestFlattenAttributes(t *testing.T) {
+	agentConfiguration, err := config.Parse("{\"agents\":[{\"name\":\"SRC\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\"," +
+		"\"protocolServers\":[{\"name\":\"SFTPSRV\",\"port\":22}],\"additionalProperties\":{\"trace\":\"all\"}}]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	attributes := make(map[string]string)
+	flattenAttributes("", agentConfiguration.Agents[0], attributes)
+	expected := map[string]string{
+		"qmgrHost":                   "localhost",
+		"qmgrPort":                   "1414",
+		"protocolServers.0.name":     "SFTPSRV",
+		"protocolServers.0.port":     "22",
+		"additionalProperties.trace": "all",
+		"qmgrChannel":                config.DEFAULT_QMGR_CHANNEL,
+	}
+	for name, value := range expected {
+		if attributes[name] != value {
+			t.Errorf("Attribute %s = %q, expected %q", name, attributes[name], value)
+		}
+	}
+}
+
+func TestProvisionState(t *testing.T) {
+	bfgDataPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(bfgDataPath, "mqft"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	propertiesFile := filepath.Join(bfgDataPath, "agent.properties")
+	if err := os.WriteFile(propertiesFile, []byte("logCapture=true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	agentConfiguration, err := config.Parse("{\"agents\":[{\"name\":\"SRC\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\"," +
+		"\"qmgrCredentials\":{\"mqUserId\":\"app\",\"mqPassword\":\"cGFzc3cwcmQ=\"}}]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := agentConfiguration.Agents[0]
+
+	// Nothing has been provisioned yet
+	state := loadProvisionState(bfgDataPath, false)
+	section := state.agentSection(agent, "QM1")
+	if state.isProvisioned(agentSectionName("SRC"), section) {
+		t.Fatal("Agent reported as provisioned before it was created")
+	}
+	state.provisioned(agentSectionName("SRC"), section, provisionedFiles(propertiesFile, agent.AdditionalProperties))
+
+	stateData, err := os.ReadFile(bfgDataPath + MFT_PROVISION_STATE_SLASH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(stateData), "cGFzc3cwcmQ=") {
+		t.Errorf("Provisioning state contains credentials: %s", stateData)
+	}
+
+	// Same configuration after a restart
+	state = loadProvisionState(bfgDataPath, false)
+	if !state.isProvisioned(agentSectionName("SRC"), state.agentSection(agent, "QM1")) {
+		t.Error("Unchanged agent configuration reported as changed")
+	}
+
+	// Changed configuration
+	agent.QMgrHost = "qm1.example.com"
+	agent.AdditionalProperties.Set("trace", "all")
+	changed := state.agentSection(agent, "QM1")
+	if state.isProvisioned(agentSectionName("SRC"), changed) {
+		t.Error("Changed agent configuration reported as unchanged")
+	}
+	changes := changed.changes(state.Sections[agentSectionName("SRC")])
+	if !reflect.DeepEqual(changes, []string{"additionalProperties.trace added", "qmgrHost changed"}) {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+
+	// Files created by the previous configuration have been removed
+	agent.QMgrHost = "localhost"
+	agent.AdditionalProperties = nil
+	os.Remove(propertiesFile)
+	if state.isProvisioned(agentSectionName("SRC"), state.agentSection(agent, "QM1")) {
+		t.Error("Agent reported as provisioned when its files are missing")
+	}
+
+	// State is ignored in dry-run mode
+	state = loadProvisionState(bfgDataPath, true)
+	if state.isProvisioned(agentSectionName("SRC"), state.agentSection(agent, "QM1")) {
+		t.Error("Provisioning state used in dry-run mode")
+	}
+}
+
+func TestAgentProvisionedFiles(t *testing.T) {
+	agentConfiguration, err := config.Parse(`{"agents":[{"name":"PBA","type":"bridge","qmgrName":"QM1","qmgrHost":"localhost",
+		"protocolServers":[{"name":"ftps1","type":"FTPS","host":"ftps.example.com"},{"name":"ftp1","host":"ftp.example.com"}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent := agentConfiguration.Agent("PBA")
+	// Stores built for the FTPS server when the agent was created
+	agent.ProtocolServers[0].TrustStoreFile = KEYSTORES_PATH + "/ftps1truststore.p12"
+	agent.ProtocolServers[0].KeyStoreFile = KEYSTORES_PATH + "/ftps1keystore.p12"
+	expected := []string{"/agents/PBA" + MFT_AGENT_PROPS_SLASH, "/agents/PBA" + MFT_PBA_PROPS_SLASH,
+		KEYSTORES_PATH + "/ftps1truststore.p12", KEYSTORES_PATH + "/ftps1keystore.p12"}
+	if files := agentProvisionedFiles(agent, "/agents/PBA"); !reflect.DeepEqual(files, expected) {
+		t.Errorf("Unexpected files %v", files)
+	}
+}
+
+func TestAddFTPSCertificateAttributes(t *testing.T) {
+	agentConfiguration, err := config.Parse(`{"agents":[{"name":"PBA","type":"bridge","qmgrName":"QM1","qmgrHost":"localhost",
+		"protocolServers":[{"name":"ftps1","type":"ftps","host":"ftps.example.com"},{"name":"ftp1","host":"ftp.example.com"}]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath := t.TempDir()
+	for _, server := range []string{"ftps1", "ftp1"} {
+		if err := os.MkdirAll(filepath.Join(certPath, server), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(certPath, server, "server.crt"), []byte(server+" certificate"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	attributes := make(map[string]string)
+	addFTPSCertificateAttributes(agentConfiguration.Agent("PBA"), certPath, attributes)
+	expected := map[string]string{"certificates.protocolServers.ftps1.server.crt": "ftps1 certificate"}
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("Unexpected attributes %v", attributes)
+	}
+}
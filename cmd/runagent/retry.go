@@ -0,0 +1,246 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Contains the retry policy used for steps that fail because a queue manager
+* is not available yet, for example when a queue manager and an agent are
+* deployed together. Output of the failed MFT command is searched for MQ
+* reason codes and BFG message ids to tell transient failures apart from the
+* ones that retrying will not resolve, such as authorization errors.
+ */
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/bfg"
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Default retry policy, in seconds
+const RETRY_DEFAULT_INITIAL_DELAY = 5
+const RETRY_DEFAULT_MAX_DELAY = 60
+const RETRY_DEFAULT_DEADLINE = 300
+
+// MQ reason codes returned when a queue manager is not available yet
+var transientReasonCodes = map[int]string{
+	2009: "MQRC_CONNECTION_BROKEN",
+	2059: "MQRC_Q_MGR_NOT_AVAILABLE",
+	2161: "MQRC_Q_MGR_QUIESCING",
+	2162: "MQRC_Q_MGR_STOPPING",
+	2202: "MQRC_CONNECTION_QUIESCING",
+	2203: "MQRC_CONNECTION_STOPPING",
+	2537: "MQRC_CHANNEL_NOT_AVAILABLE",
+	2538: "MQRC_HOST_NOT_AVAILABLE",
+}
+
+// MQ reason codes that retrying will not resolve
+var fatalReasonCodes = map[int]string{
+	2035: "MQRC_NOT_AUTHORIZED",
+	2058: "MQRC_Q_MGR_NAME_ERROR",
+	2063: "MQRC_SECURITY_ERROR",
+	2393: "MQRC_SSL_INITIALIZATION_ERROR",
+	2397: "MQRC_JSSE_ERROR",
+	2540: "MQRC_UNKNOWN_CHANNEL_NAME",
+}
+
+// MFT messages reported when a queue manager or an agent is not available yet
+var transientMessageIds = []string{
+	bfg.COORDINATION_QMGR_NOT_CONTACTED,
+	bfg.AGENT_PING_NO_RESPONSE,
+}
+
+// Reason codes in command output, for example "reason code was 2538",
+// "Reason '2059'" or "MQRC_HOST_NOT_AVAILABLE".
+var reasonCodeRegex = regexp.MustCompile(`(?i)reason(?: code)?(?: was| is| of)?:?\s*'?(\d{4})\b`)
+var reasonNameRegex = regexp.MustCompile(`MQRC_[A-Z_]+`)
+
+// Function used for waiting between attempts. Replaced by tests.
+var retrySleep = time.Sleep
+
+// Delays between attempts of a step
+type retryPolicy struct {
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	deadline     time.Duration
+}
+
+// Build the retry policy from retryPolicy attribute of the configuration
+// file. Environment variables override values from the configuration file.
+func newRetryPolicy(agentConfiguration *config.Configuration) *retryPolicy {
+	initialDelay := RETRY_DEFAULT_INITIAL_DELAY
+	maxDelay := RETRY_DEFAULT_MAX_DELAY
+	deadline := RETRY_DEFAULT_DEADLINE
+	if agentConfiguration != nil && agentConfiguration.RetryPolicy != nil {
+		configuredPolicy := agentConfiguration.RetryPolicy
+		if configuredPolicy.InitialDelay != nil {
+			initialDelay = int(*configuredPolicy.InitialDelay)
+		}
+		if configuredPolicy.MaxDelay != nil {
+			maxDelay = int(*configuredPolicy.MaxDelay)
+		}
+		if configuredPolicy.Deadline != nil {
+			deadline = int(*configuredPolicy.Deadline)
+		}
+	}
+	initialDelay = getRetrySeconds(MFT_RETRY_INITIAL_DELAY, initialDelay)
+	maxDelay = getRetrySeconds(MFT_RETRY_MAX_DELAY, maxDelay)
+	deadline = getRetrySeconds(MFT_RETRY_DEADLINE, deadline)
+
+	if initialDelay < 1 {
+		initialDelay = 1
+	}
+	if maxDelay < initialDelay {
+		maxDelay = initialDelay
+	}
+	return &retryPolicy{
+		initialDelay: time.Duration(initialDelay) * time.Second,
+		maxDelay:     time.Duration(maxDelay) * time.Second,
+		deadline:     time.Duration(deadline) * time.Second,
+	}
+}
+
+// Read number of seconds from the environment variable. Returns the given
+// value if the variable is not set or is not valid.
+func getRetrySeconds(envName string, value int) int {
+	envValue, envSet := os.LookupEnv(envName)
+	if !envSet || len(strings.TrimSpace(envValue)) == 0 {
+		return value
+	}
+	seconds, err := strconv.Atoi(strings.TrimSpace(envValue))
+	if err != nil || seconds < 0 {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_RETRY_ENV_INVALID_0101, envValue, envName, value))
+		return value
+	}
+	return seconds
+}
+
+// Run the step until it succeeds, fails with an error that retrying will not
+// resolve or the deadline is reached. Failures are classified using the output
+// of the MFT command returned by the step, nil if no command failed.
+func (policy *retryPolicy) run(stepName string, step func() (bool, *commandResult)) bool {
+	return policy.runStep(stepName, func() (bool, bool, string) {
+		done, result := step()
+		if done {
+			return true, false, ""
+		}
+		var commandOutput string
+		if result != nil {
+			commandOutput = result.stdout + result.stderr
+		}
+		transient, reason := classifyCommandFailure(commandOutput)
+		return false, transient, reason
+	})
+}
+
+// Run the step until it succeeds, reports a failure that is not transient or
+// the deadline is reached. The step returns whether it succeeded, whether the
+// failure is transient and the reason of failure. The delay between attempts
+// doubles after every attempt up to the maximum delay.
+func (policy *retryPolicy) runStep(stepName string, step func() (bool, bool, string)) bool {
+	start := time.Now()
+	delay := policy.initialDelay
+	for attempt := 1; ; attempt++ {
+		done, transient, reason := step()
+		if done {
+			return true
+		}
+		if !transient {
+			if len(reason) > 0 {
+				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_RETRY_FATAL_0099, stepName, reason))
+			}
+			return false
+		}
+		if time.Since(start)+delay > policy.deadline {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_RETRY_DEADLINE_0100, stepName, attempt, policy.deadline))
+			return false
+		}
+
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_RETRY_ATTEMPT_0098, attempt, stepName, reason, delay))
+		retrySleep(delay)
+		delay *= 2
+		if delay > policy.maxDelay {
+			delay = policy.maxDelay
+		}
+	}
+}
+
+// Determine if a command failed because a queue manager or an agent is not
+// available yet. Returns the reason codes or message ids found in the output.
+// Failures without a known transient reason are not retried.
+func classifyCommandFailure(commandOutput string) (bool, string) {
+	var reasonCodes []int
+	for _, match := range reasonCodeRegex.FindAllStringSubmatch(commandOutput, -1) {
+		reasonCode, _ := strconv.Atoi(match[1])
+		reasonCodes = append(reasonCodes, reasonCode)
+	}
+	for _, reasonName := range reasonNameRegex.FindAllString(commandOutput, -1) {
+		reasonCodes = append(reasonCodes, reasonCodeByName(reasonName))
+	}
+
+	var transientReasons []string
+	var fatalReasons []string
+	for _, reasonCode := range reasonCodes {
+		if reasonName, found := fatalReasonCodes[reasonCode]; found {
+			fatalReasons = appendReason(fatalReasons, fmt.Sprintf("%d %s", reasonCode, reasonName))
+		} else if reasonName, found := transientReasonCodes[reasonCode]; found {
+			transientReasons = appendReason(transientReasons, fmt.Sprintf("%d %s", reasonCode, reasonName))
+		}
+	}
+	if len(fatalReasons) > 0 {
+		return false, strings.Join(fatalReasons, ", ")
+	}
+
+	for _, messageId := range transientMessageIds {
+		if bfg.Contains(commandOutput, messageId) {
+			transientReasons = appendReason(transientReasons, messageId)
+		}
+	}
+	if len(transientReasons) > 0 {
+		return true, strings.Join(transientReasons, ", ")
+	}
+	return false, ""
+}
+
+// Get the reason code of a known MQRC_* name. Returns 0 if the name is not known.
+func reasonCodeByName(reasonName string) int {
+	for reasonCode, name := range transientReasonCodes {
+		if name == reasonName {
+			return reasonCode
+		}
+	}
+	for reasonCode, name := range fatalReasonCodes {
+		if name == reasonName {
+			return reasonCode
+		}
+	}
+	return 0
+}
+
+// Append the reason if it is not already in the list
+func appendReason(reasons []string, reason string) []string {
+	for _, existing := range reasons {
+		if existing == reason {
+			return reasons
+		}
+	}
+	return append(reasons, reason)
+}
@@ -0,0 +1,126 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+func TestClassifyCommandFailure(t *testing.T) {
+	tests := []struct {
+		output    string
+		transient bool
+		reason    string
+	}{
+		{"BFGMQ1007I: The coordination queue manager cannot be contacted or has refused a connection attempt. The WebSphere MQ reason code was 2538.", true, "2538 MQRC_HOST_NOT_AVAILABLE, BFGMQ1007I"},
+		{"MQJE001: Completion Code '2', Reason '2059'.", true, "2059 MQRC_Q_MGR_NOT_AVAILABLE"},
+		{"BFGCL0251E: The connection failed with MQRC_CHANNEL_NOT_AVAILABLE.", true, "2537 MQRC_CHANNEL_NOT_AVAILABLE"},
+		{"BFGCL0214I: agent SRC didn't respond to ping after 10 seconds.", true, "BFGCL0214I"},
+		{"BFGMQ1007I: The coordination queue manager cannot be contacted or has refused a connection attempt. The WebSphere MQ reason code was 2035.", false, "2035 MQRC_NOT_AUTHORIZED"},
+		{"Reason '2059'. Reason '2393'.", false, "2393 MQRC_SSL_INITIALIZATION_ERROR"},
+		{"BFGCL0014E: Agent configuration already exists. Created on 2024-01-01.", false, ""},
+		{"", false, ""},
+	}
+	for _, test := range tests {
+		transient, reason := classifyCommandFailure(test.output)
+		if transient != test.transient || reason != test.reason {
+			t.Errorf("classifyCommandFailure(%q) = %v, %q; expected %v, %q", test.output, transient, reason, test.transient, test.reason)
+		}
+	}
+}
+
+func TestRetryPolicyRun(t *testing.T) {
+	var delays []time.Duration
+	retrySleep = func(delay time.Duration) { delays = append(delays, delay) }
+	defer func() { retrySleep = time.Sleep }()
+
+	policy := &retryPolicy{initialDelay: time.Second, maxDelay: 3 * time.Second, deadline: time.Minute}
+
+	// Step succeeds once the queue manager becomes available
+	attempts := 0
+	done := policy.run("setup coordination configuration", func() (bool, *commandResult) {
+		attempts++
+		if attempts < 5 {
+			return false, &commandResult{stderr: "The WebSphere MQ reason code was 2538."}
+		}
+		return true, nil
+	})
+	if !done || attempts != 5 {
+		t.Errorf("Step not retried until success, done %v after %d attempts", done, attempts)
+	}
+	if !reflect.DeepEqual(delays, []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second}) {
+		t.Errorf("Unexpected delays between attempts %v", delays)
+	}
+
+	// Authorization failures are not retried
+	attempts = 0
+	done = policy.run("create agent SRC", func() (bool, *commandResult) {
+		attempts++
+		return false, &commandResult{stdout: "MQRC_NOT_AUTHORIZED"}
+	})
+	if done || attempts != 1 {
+		t.Errorf("Fatal failure retried, done %v after %d attempts", done, attempts)
+	}
+
+	// Failures that are not reported by a command are not retried
+	attempts = 0
+	done = policy.run("setup command configuration", func() (bool, *commandResult) {
+		attempts++
+		return false, nil
+	})
+	if done || attempts != 1 {
+		t.Errorf("Failure without command output retried, done %v after %d attempts", done, attempts)
+	}
+
+	// Deadline is reached
+	policy.deadline = 0
+	attempts = 0
+	done = policy.run("start agent SRC", func() (bool, *commandResult) {
+		attempts++
+		return false, &commandResult{stdout: "Reason '2059'"}
+	})
+	if done || attempts != 1 {
+		t.Errorf("Step retried beyond deadline, done %v after %d attempts", done, attempts)
+	}
+}
+
+func TestNewRetryPolicy(t *testing.T) {
+	agentConfiguration, err := config.Parse("{\"retryPolicy\":{\"initialDelay\":2,\"maxDelay\":\"30\",\"deadline\":120}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := newRetryPolicy(agentConfiguration)
+	if policy.initialDelay != 2*time.Second || policy.maxDelay != 30*time.Second || policy.deadline != 2*time.Minute {
+		t.Errorf("Retry policy not taken from configuration: %+v", policy)
+	}
+
+	t.Setenv(MFT_RETRY_DEADLINE, "600")
+	t.Setenv(MFT_RETRY_MAX_DELAY, "abc")
+	policy = newRetryPolicy(agentConfiguration)
+	if policy.deadline != 10*time.Minute || policy.maxDelay != 30*time.Second {
+		t.Errorf("Retry policy not overridden by environment: %+v", policy)
+	}
+
+	t.Setenv(MFT_RETRY_DEADLINE, "")
+	policy = newRetryPolicy(nil)
+	if policy.initialDelay != RETRY_DEFAULT_INITIAL_DELAY*time.Second || policy.deadline != RETRY_DEFAULT_DEADLINE*time.Second {
+		t.Errorf("Default retry policy not used: %+v", policy)
+	}
+}
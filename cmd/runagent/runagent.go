@@ -19,14 +19,19 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"os/user"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+	flag "github.com/spf13/pflag"
 	"github.com/tidwall/gjson"
 )
 
@@ -53,6 +58,28 @@ func main() {
 	var bfgDataPath string
 	var allAgentConfig string
 	var e error
+	var dryRunOutputDir string
+
+	flag.BoolVar(&dryRunEnabled, "dry-run", false, "Generate agent configuration without running any commands")
+	flag.StringVar(&dryRunOutputDir, "output-dir", "", "Directory to write configuration generated in dry-run mode")
+	flag.Parse()
+
+	// Load patterns of secrets configured by the user before anything is
+	// logged. Secrets could be displayed if any of the patterns were ignored.
+	if err := utils.SetRedactPatterns(os.Getenv(MFT_REDACT_PATTERNS)); err != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_REDACT_PATTERNS_INVALID_0121, MFT_REDACT_PATTERNS, err))
+		os.Exit(MFT_CONT_ERR_CODE_29)
+	}
+
+	// "config explain" is the only subcommand
+	if args := flag.Args(); len(args) > 0 {
+		if len(args) == 2 && args[0] == "config" && args[1] == "explain" {
+			explainEnabled = true
+		} else {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_UNKNOWN_SUBCOMMAND_0117, strings.Join(args, " ")))
+			os.Exit(MFT_CONT_ERR_CODE_28)
+		}
+	}
 
 	// By default minimal logging is enabled.
 	logLevel = LOG_LEVEL_INFO
@@ -80,11 +107,14 @@ func main() {
 	// Print container image details
 	printImageInfo()
 
-	// There should only be one instance of this process.
-	singleErr := verifySingleProcess()
-	if singleErr != nil {
-		utils.PrintLog(singleErr.Error())
-		os.Exit(MFT_CONT_ERR_CODE_24)
+	// There should only be one instance of this process. A dry run or explaining
+	// the configuration does not start an agent and hence can be run alongside.
+	if !dryRunEnabled && !explainEnabled {
+		singleErr := verifySingleProcess()
+		if singleErr != nil {
+			utils.PrintLog(singleErr.Error())
+			os.Exit(MFT_CONT_ERR_CODE_24)
+		}
 	}
 
 	// First check if license is accepted or not.
@@ -113,33 +143,41 @@ func main() {
 	// Copy the name of agent
 	agentNameGlobal = agentNameEnv
 
-	// Time to wait for agent to start. Default wait time is 10 seconds
-	delayTimeStatusCheck := time.Duration(10) * time.Second
-	timeWaitForAgentStartStr, timeWaitForAgentStartSet := os.LookupEnv(MFT_AGENT_START_WAIT_TIME)
-	// Value is numeric and above 0, then use it.
-	if timeWaitForAgentStartSet {
-		isNum, _ := utils.IsNumeric(timeWaitForAgentStartStr)
-		if isNum {
-			waitTime, _ := utils.ToNumber(timeWaitForAgentStartStr)
-			if waitTime > 0 {
-				delayTimeStatusCheck = time.Duration(waitTime) * time.Second
-			}
-		} else {
-			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(utils.MFT_CONT_ENV_AGENT_START_TIME_0008)
-			}
-		}
-	}
+	// Time to wait for agents to start
+	startPolicy := newAgentStartPolicy()
 
 	// Check if MFT command tracing is enabled
 	commandTracingEnabled = IsCommandTracingEnabled()
 
-	// Create directory for file transfers
-	errVol := utils.CreatePath(MOUNT_PATH_TRANSFERS)
-	if errVol != nil {
-		utils.PrintLog(errVol.Error())
+	// Commands are ended if they run for longer than the timeout or if the
+	// container is asked to stop while agents are being configured. Command
+	// lines are only recorded in dry-run mode.
+	commandTimeout := time.Duration(getRetrySeconds(MFT_COMMAND_TIMEOUT, COMMAND_DEFAULT_TIMEOUT)) * time.Second
+	setupContext, stopSetup := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	if dryRunEnabled {
+		runner = &dryRunCommandRunner{}
 	} else {
-		utils.PrintLog(fmt.Sprintf("Transfer root directory '%s' created", MOUNT_PATH_TRANSFERS))
+		runner = newExecCommandRunner(setupContext, commandTimeout)
+	}
+
+	if dryRunEnabled {
+		// All configuration is written to the output directory in dry-run mode.
+		dryRunOutputDir = strings.TrimSpace(dryRunOutputDir)
+		if len(dryRunOutputDir) == 0 {
+			utils.PrintLog(utils.MFT_CONT_DRYRUN_OUTPUT_DIR_0090)
+			os.Exit(MFT_CONT_ERR_CODE_5)
+		}
+		dryRunOutputDir, _ = filepath.Abs(dryRunOutputDir)
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_DRYRUN_ENABLED_0089, dryRunOutputDir))
+		os.Setenv(BFG_DATA, dryRunOutputDir)
+	} else if !explainEnabled {
+		// Create directory for file transfers
+		errVol := utils.CreatePath(MOUNT_PATH_TRANSFERS)
+		if errVol != nil {
+			utils.PrintLog(errVol.Error())
+		} else {
+			utils.PrintLog(fmt.Sprintf("Transfer root directory '%s' created", MOUNT_PATH_TRANSFERS))
+		}
 	}
 
 	// See if we have been given mount point for creating agent configuration and log directory.
@@ -182,21 +220,13 @@ func main() {
 	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CONFIG_PATH_0010, bfgDataPath))
 
 	// Read agent configuration data from file specified in the environment
-	// variable MFT_AGENT_CONFIG_FILE.
-	bfgConfigFilePath, configFileSet := os.LookupEnv(MFT_AGENT_CONFIG_FILE)
-	if !configFileSet {
-		// MFT_AGENT_CONFIG_FILE environment variable not specified. Looking for
-		// config.json file in /run/mqmft directory.
-		msg := fmt.Sprintf(utils.MFT_ENV_AGNT_CFG_FILE_NOT_SPECIFIED, utils.MFT_DEFAULT_CONFIG_JSON)
-		utils.PrintLog(msg)
-		// Assign the default config filename, so that rest of the processing goes on.
-		bfgConfigFilePath = utils.MFT_DEFAULT_CONFIG_JSON
-	} else {
-		bfgConfigFilePath = strings.TrimSpace(bfgConfigFilePath)
-		if bfgConfigFilePath == TEXT_BLANK {
-			utils.PrintLog(utils.MFT_CONT_ENV_AGNT_CFG_FILE_BLANK_0012)
-			os.Exit(MFT_CONT_ERR_CODE_9)
-		}
+	// variable MFT_AGENT_CONFIG_FILE. If it is not set, config.json file in
+	// /run/mqmft directory is used or, if that does not exist either, the
+	// configuration is built from environment variables.
+	bfgConfigFilePath := config.Source()
+	if bfgConfigFilePath == TEXT_BLANK {
+		utils.PrintLog(utils.MFT_CONT_ENV_AGNT_CFG_FILE_BLANK_0012)
+		os.Exit(MFT_CONT_ERR_CODE_9)
 	}
 
 	// Copy the JSON configuration file path
@@ -204,16 +234,65 @@ func main() {
 	// Read the entire agent configuration data from JSON file. The configuration file
 	// may contain data for multiple agents. We will choose data for matching agent name
 	// specified in MFT_AGENT_NAME environment variable
-	allAgentConfig, e = utils.ReadConfigurationDataFromFile(bfgConfigFilePath)
+	allAgentConfig, e = config.ReadData(bfgConfigFilePath)
 	if e != nil {
 		// Exit if we had any error when reading configuration file
 		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_FILE_READ_0013, bfgConfigFilePath, e))
 		os.Exit(MFT_CONT_ERR_CODE_10)
 	}
 
+	// Validate the entire configuration against the schema before running any command.
+	// Report every violation found so that all of them can be fixed in one go.
+	schemaErrors := validateConfigurationSchema(allAgentConfig)
+	if len(schemaErrors) > 0 {
+		for _, schemaError := range schemaErrors {
+			utils.PrintLog(schemaError.Error())
+		}
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_SCHEMA_FAILED_0084, bfgConfigFilePath, len(schemaErrors)))
+		os.Exit(MFT_CONT_ERR_CODE_25)
+	}
+
+	// Protocol servers of bridge agents are validated against the properties of
+	// their protocol. Problems stop the container only if asked to.
+	if bridgeErrors := validateBridgeDefinitions(allAgentConfig); len(bridgeErrors) > 0 {
+		for _, bridgeError := range bridgeErrors {
+			utils.PrintLog(bridgeError.Error())
+		}
+		if isBridgeValidationStrict() {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_VALIDATION_FAILED_0124, bfgConfigFilePath, len(bridgeErrors)))
+			os.Exit(MFT_CONT_ERR_CODE_30)
+		}
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_BRIDGE_VALIDATION_WARNING_0125, bfgConfigFilePath, len(bridgeErrors), MFT_BRIDGE_STRICT_VALIDATION))
+	}
+
+	// Additional properties are checked against the properties supported in
+	// each properties file. Only unknown and invalid properties stop the
+	// container, and only if asked to.
+	if problems := validatePropertiesCatalog(allAgentConfig); len(problems) > 0 {
+		errorCount := 0
+		for _, problem := range problems {
+			utils.PrintLog(problem.Error())
+			if !problem.warning {
+				errorCount++
+			}
+		}
+		if errorCount > 0 && isPropertiesValidationStrict() {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_PROPERTIES_VALIDATION_FAILED_0157, bfgConfigFilePath, errorCount))
+			os.Exit(MFT_CONT_ERR_CODE_31)
+		}
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_PROPERTIES_VALIDATION_WARNING_0158, bfgConfigFilePath, len(problems), MFT_PROPERTIES_STRICT_VALIDATION))
+	}
+
+	// Decode the configuration into typed attributes and apply default values.
+	agentConfiguration, e := config.Parse(allAgentConfig)
+	if e != nil {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_FILE_READ_0013, bfgConfigFilePath, e))
+		os.Exit(MFT_CONT_ERR_CODE_10)
+	}
+
 	// Validate coordination queue manager attributes. Throw an error if minimum attributes
 	// are not available
-	errorCrd := validateCoordinationAttributes(allAgentConfig)
+	errorCrd := validateCoordinationAttributes(agentConfiguration)
 	if errorCrd != nil {
 		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_MISSING_ATTRIBS_0016, bfgConfigFilePath, errorCrd))
 		os.Exit(MFT_CONT_ERR_CODE_11)
@@ -221,56 +300,53 @@ func main() {
 
 	// Validate command queue manager attributes. Throw an error if minimum attributes are
 	// not available
-	errorCmd := validateCommandAttributes(allAgentConfig)
+	errorCmd := validateCommandAttributes(agentConfiguration)
 	if errorCmd != nil {
 		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_MISSING_ATTRIBS_0016, bfgConfigFilePath, errorCmd))
 		os.Exit(MFT_CONT_ERR_CODE_12)
 	}
 	if logLevel >= LOG_LEVEL_VERBOSE {
-		utils.PrintLog(fmt.Sprintf("All configurations in %s file: %v", bfgConfigFilePath, allAgentConfig))
+		utils.PrintLog(fmt.Sprintf("All configurations in %s file: %v", bfgConfigFilePath, agentConfiguration))
 	}
 
-	// We may have multiple agent configurations defined in the JSON file. Iterate through all
-	// definitions and pick the one that has matching agent name specified in environment variable
-	var singleAgentConfig string
-	configurationFound := false
-	agentsJson := gjson.Get(allAgentConfig, "agents").Array()
 	// Return an error if no agent configuration is supplied
-	if len(agentsJson) == 0 {
+	if len(agentConfiguration.Agents) == 0 {
 		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_NO_AGENT_CONFIG_SUPPLIED, bfgConfigFilePath))
 		os.Exit(MFT_CONT_ERR_CODE_23)
 	}
 
-	// Loop through the supplied JSON and identify configuration for agent name supplied
-	// via environment variable MFT_AGENT_NAME
-	for i := 0; i < len(agentsJson); i++ {
-		singleAgentConfig = agentsJson[i].String()
+	// MFT_AGENT_NAME environment variable may name several agents, or all agents
+	// defined in the JSON file. Pick the configuration of each of them.
+	agentNames := agentConfiguration.AgentNames(agentNameEnv)
+	if len(agentNames) == 0 {
+		utils.PrintLog(utils.MFT_CONT_ENV_AGENT_NAME_BLANK_0007)
+		os.Exit(MFT_CONT_ERR_CODE_4)
+	}
+	agentConfigs := make([]*config.Agent, len(agentNames))
+	for i, agentName := range agentNames {
+		singleAgentConfig := agentConfiguration.Agent(agentName)
+		// Exit if we did not find the configuration for specified agent
+		if singleAgentConfig == nil {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_AGENT_CONFIG_MISSING_0019, agentName, bfgConfigFilePath))
+			os.Exit(MFT_CONT_ERR_CODE_13)
+		}
 		if logLevel >= LOG_LEVEL_VERBOSE {
 			utils.PrintLog(fmt.Sprintf(utils.MFT_AGENT_JSON_CONFIG, singleAgentConfig))
 		}
-		if gjson.Get(singleAgentConfig, "name").Exists() {
-			agentNameConfig := gjson.Get(singleAgentConfig, "name").String()
-			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_AGENT_NAME_CONFIG_FILE, agentNameConfig))
-			}
-			agentNameConfig = strings.TrimSpace(agentNameConfig)
-			if strings.EqualFold(agentNameConfig, agentNameEnv) {
-				configurationFound = true
-				break
-			}
-		}
-	}
-
-	// Exit if we did not find the configuration for specified agent
-	if !configurationFound {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_AGENT_CONFIG_MISSING_0019, agentNameEnv, bfgConfigFilePath))
-		os.Exit(MFT_CONT_ERR_CODE_13)
-	} else {
 		err := ValidateAgentAttributes(singleAgentConfig)
 		if err != nil {
 			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CFG_AGENT_CONFIG_ERROR_0023, bfgConfigFilePath, err))
 			os.Exit(MFT_CONT_ERR_CODE_14)
 		}
+		agentConfigs[i] = singleAgentConfig
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_SUPERVISE_AGENTS_0091, len(agentNames), strings.Join(agentNames, ", ")))
+
+	// Display the properties that would be written and end without creating
+	// any configuration.
+	if explainEnabled {
+		printPropertiesExplanation(explainProperties(agentConfiguration, agentConfigs, bfgDataPath))
+		os.Exit(MFT_CONT_SUCCESS_CODE_0)
 	}
 
 	// Get any JVM properties specified in environment variable BFG_JVM_PROPERTIES and append the default
@@ -286,122 +362,190 @@ func main() {
 	}
 
 	// Cache the coordination queue manager name
-	coordinationQMgr := gjson.Get(allAgentConfig, "coordinationQMgr.name").String()
+	coordinationQMgr := agentConfiguration.CoordinationQMgr.Name
 
-	// Setup coordination configuration
-	coordinationCreated := setupCoordination(allAgentConfig, bfgDataPath, agentNameEnv)
-	if !coordinationCreated {
-		utils.PrintLog(utils.MFT_CONT_CORD_CFG_FAILED_0029)
-		os.Exit(MFT_CONT_ERR_CODE_15)
-	}
+	// Steps that fail because a queue manager is not available yet are retried
+	retry := newRetryPolicy(agentConfiguration)
 
-	// Setup command configuration
-	commandsCreated := setupCommands(allAgentConfig, bfgDataPath, agentNameEnv)
-	if !commandsCreated {
-		utils.PrintLog(utils.MFT_CONT_CMD_CFG_FAILED_0030)
-		os.Exit(MFT_CONT_ERR_CODE_16)
+	// Verify that queue managers can be reached before running any command.
+	// Not done in dry-run mode as queue managers are not used.
+	if !dryRunEnabled {
+		if !runPreflightChecks(preflightEndpoints(agentConfiguration, agentConfigs), retry) {
+			os.Exit(MFT_CONT_ERR_CODE_27)
+		}
 	}
 
-	// Create the specified agent configuration
-	setupAgentDone := setupAgent(singleAgentConfig, bfgDataPath, coordinationQMgr)
-	if !setupAgentDone {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_CFG_FAILED_0031, agentNameEnv))
-		os.Exit(MFT_CONT_ERR_CODE_17)
+	// Commands that would normally create the configuration directories are not
+	// run in dry-run mode, so create them here.
+	if dryRunEnabled {
+		for _, agentName := range agentNames {
+			errLayout := createDryRunLayout(bfgDataPath, coordinationQMgr, agentName)
+			if errLayout != nil {
+				utils.PrintLog(errLayout.Error())
+				os.Exit(MFT_CONT_ERR_CODE_5)
+			}
+		}
 	}
 
-	// Clean agent if asked for before starting the agent
-	cleanAgent(singleAgentConfig, coordinationQMgr, agentNameEnv)
+	// Configuration that has not changed since it was last created in BFG_DATA
+	// is used as is. Every step is run in dry-run mode.
+	provisioning := loadProvisionState(bfgDataPath, dryRunEnabled)
+	coordinationPath := bfgDataPath + MFT_CONFIG_PATH_SUFFIX + coordinationQMgr
 
-	// Submit request to start the agent.
-	startAgentDone := StartAgent(agentNameEnv, coordinationQMgr)
-	if !startAgentDone {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_START_FAILED_0032, agentNameEnv))
-		os.Exit(MFT_CONT_ERR_CODE_18)
+	// Setup coordination configuration
+	coordinationSection := provisioning.coordinationSection(agentConfiguration)
+	if !provisioning.isProvisioned(PROVISION_COORDINATION, coordinationSection) {
+		coordinationCreated := retry.run("setup coordination configuration", func() (bool, *commandResult) {
+			return setupCoordination(agentConfiguration, bfgDataPath, strings.Join(agentNames, ", "))
+		})
+		if dryRunEnabled {
+			recordDryRunStep("Coordination configuration", coordinationCreated)
+		}
+		if !coordinationCreated {
+			utils.PrintLog(utils.MFT_CONT_CORD_CFG_FAILED_0029)
+			if dryRunEnabled {
+				completeDryRun(dryRunOutputDir, MFT_CONT_ERR_CODE_15)
+			}
+			os.Exit(MFT_CONT_ERR_CODE_15)
+		}
+		provisioning.provisioned(PROVISION_COORDINATION, coordinationSection,
+			provisionedFiles(coordinationPath+MFT_CORD_PROPS_SLASH, agentConfiguration.CoordinationQMgr.AdditionalProperties))
+		// Setting up coordination overwrites command configuration as well
+		provisioning.forget(PROVISION_COMMAND)
 	}
 
-	// Setup agent log mirroring.
-	var wg sync.WaitGroup
-	defer func() {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_WAIT_MIRROR_CMP_0035, agentNameEnv))
-		wg.Wait()
-	}()
-
-	ctxAgentLog, cancelMirrorAgentLog := context.WithCancel(context.Background())
-	defer func() {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_WAIT_MIRROR_STOP_0036, agentNameEnv))
-		cancelMirrorAgentLog()
-	}()
+	// Setup command configuration
+	commandSection := provisioning.commandSection(agentConfiguration)
+	if !provisioning.isProvisioned(PROVISION_COMMAND, commandSection) {
+		commandsCreated := retry.run("setup command configuration", func() (bool, *commandResult) {
+			return setupCommands(agentConfiguration, bfgDataPath, strings.Join(agentNames, ", "))
+		})
+		if dryRunEnabled {
+			recordDryRunStep("Command configuration", commandsCreated)
+		}
+		if !commandsCreated {
+			utils.PrintLog(utils.MFT_CONT_CMD_CFG_FAILED_0030)
+			if dryRunEnabled {
+				completeDryRun(dryRunOutputDir, MFT_CONT_ERR_CODE_16)
+			}
+			os.Exit(MFT_CONT_ERR_CODE_16)
+		}
+		provisioning.provisioned(PROVISION_COMMAND, commandSection,
+			provisionedFiles(coordinationPath+MFT_CMD_PROPS_SLASH, agentConfiguration.CommandQMgr.AdditionalProperties))
+	}
+
+	// Create, clean and start each of the agents.
+	supervisor := newAgentSupervisor(bfgDataPath, coordinationQMgr, startPolicy)
+	for i, agentName := range agentNames {
+		// Create the specified agent configuration
+		agentSection := provisioning.agentSection(agentConfigs[i], coordinationQMgr)
+		if !provisioning.isProvisioned(agentSectionName(agentName), agentSection) {
+			setupAgentDone := retry.run("create agent "+agentName, func() (bool, *commandResult) {
+				return setupAgent(agentConfigs[i], bfgDataPath, coordinationQMgr)
+			})
+			if dryRunEnabled {
+				recordDryRunStep(fmt.Sprintf("Agent %s configuration", agentName), setupAgentDone)
+			}
+			if !setupAgentDone {
+				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_CFG_FAILED_0031, agentName))
+				if dryRunEnabled {
+					completeDryRun(dryRunOutputDir, MFT_CONT_ERR_CODE_17)
+				}
+				// Stop agents that have already been started
+				supervisor.stopAgents()
+				os.Exit(MFT_CONT_ERR_CODE_17)
+			}
+			agentPath := coordinationPath + MFT_AGENTS_SLASH + agentName
+			provisioning.provisioned(agentSectionName(agentName), agentSection, agentProvisionedFiles(agentConfigs[i], agentPath))
+		}
 
-	// Display the contents of agent's output0.log file on the console.
-	if logLevel >= LOG_LEVEL_VERBOSE {
-		agentLogPath := bfgDataPath + DIR_AGENT_LOGS + coordinationQMgr + DIR_AGENTS + agentNameEnv + "/logs/output0.log"
-		mirrorAgentLogs(ctxAgentLog, &wg, agentNameEnv, agentLogPath, "", "", LOG_TYPE_CONSOLE, -1)
-	}
-
-	// Verify that agent is ready to accept to requests
-	pingWaitTime := strconv.Itoa((int)(delayTimeStatusCheck / time.Second))
-	agentReady := PingAgent(coordinationQMgr, agentNameEnv, pingWaitTime)
-	if !agentReady {
-		//if agent not started yet, wait for some time and then reissue fteListAgents commad
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_NOT_STARTED_0033, agentNameEnv, delayTimeStatusCheck/time.Second))
-		time.Sleep(delayTimeStatusCheck)
-		agentReady = PingAgent(coordinationQMgr, agentNameEnv, pingWaitTime)
-		// Agent has not started, exit.
-		if !agentReady {
-			if logLevel >= LOG_LEVEL_INFO {
-				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_FAILED_TO_START_0034, agentNameEnv))
+		// Mistakes in the credentials file of a bridge agent otherwise show
+		// only when transfers fail
+		if !checkBridgeCredentialsFile(agentConfigs[i], coordinationPath+MFT_AGENTS_SLASH+agentName) {
+			if dryRunEnabled {
+				completeDryRun(dryRunOutputDir, MFT_CONT_ERR_CODE_30)
 			}
-			os.Exit(MFT_CONT_ERR_CODE_21)
+			supervisor.stopAgents()
+			os.Exit(MFT_CONT_ERR_CODE_30)
 		}
-	}
 
-	// Check if the agent is ready, by searching for BFGAG0059 event ID
-	// output0.log file
-	isReady, readyError := utils.IsAgentReady(bfgDataPath, agentNameEnv, coordinationQMgr)
-	if readyError != nil || !isReady {
-		if readyError != nil {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_NOT_READY_ERROR, agentNameEnv, readyError))
+		// Host keys of SFTP servers are trusted on first use if asked to
+		if !verifyBridgeHostKeys(agentConfigs[i], bfgDataPath+MFT_KNOWN_HOSTS_SLASH) {
+			supervisor.stopAgents()
+			os.Exit(MFT_CONT_ERR_CODE_32)
 		}
-		// There was an error or agent is not ready, then exit
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_NOT_READY, agentNameEnv))
-		os.Exit(MFT_CONT_ERR_CODE_21)
-	}
 
-	// Mirror contents of capture log on the console
-	setupMirrorCaptureLogs(ctxAgentLog, &wg, bfgDataPath, coordinationQMgr, agentNameEnv)
+		// Clean agent if asked for before starting the agent
+		cleanAgent(agentConfigs[i], coordinationQMgr, agentName)
 
-	// Mirror contents trace files to console
-	setupMirrorTraceLogs(ctxAgentLog, &wg, bfgDataPath, coordinationQMgr, agentNameEnv)
+		// Submit request to start the agent. Events already in output0.log
+		// are from an earlier run and are ignored while waiting for the agent.
+		startLogOffset := outputLogSize(bfgDataPath + DIR_AGENT_LOGS + coordinationQMgr + DIR_AGENTS + agentName)
+		// Heap is sized for the number of agents sharing the container
+		jvmOptions := agentJVMOptions(agentConfigs[i], len(agentNames))
+		if len(jvmOptions) > 0 {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_JVM_OPTIONS_0153, agentName, strings.Join(jvmOptions, " ")))
+		}
+		startAgentDone := retry.run("start agent "+agentName, func() (bool, *commandResult) {
+			return StartAgent(agentName, coordinationQMgr, jvmOptions)
+		})
+		if !startAgentDone {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_START_FAILED_0032, agentName))
+			supervisor.stopAgents()
+			os.Exit(MFT_CONT_ERR_CODE_18)
+		}
+		agent := supervisor.addAgent(agentName, agentConfigs[i])
+		agent.startLogOffset = startLogOffset
+	}
 
-	// Push transfer logs to specified server
-	setupMirrorTransferLogs(ctxAgentLog, &wg, bfgDataPath, coordinationQMgr, agentNameEnv)
+	// Nothing more to do in dry-run mode as agents have not been started.
+	if dryRunEnabled {
+		completeDryRun(dryRunOutputDir, MFT_CONT_SUCCESS_CODE_0)
+	}
 
-	// If agent status is READY or ACTIVE, then we are good.
-	if agentReady {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_STARTED_0038, agentNameEnv))
-		// Execute any commands provided in the cmds file
-		postInit()
+	// Wait for every agent to become ready and setup mirroring of its logs.
+	for _, agent := range supervisor.agents {
+		if !supervisor.waitForAgentReady(agent) {
+			supervisor.stopAgents()
+			supervisor.stopMirrors()
+			os.Exit(MFT_CONT_ERR_CODE_21)
+		}
+	}
 
-		// Setup a siganl handle and wait for till container is stopped.
-		signalControl := signalHandler(agentNameEnv, coordinationQMgr)
-		<-signalControl
-		cancelMirrorAgentLog()
+	// Create, replace or delete resource monitors, transfer templates and
+	// scheduled transfers as declared in the configuration of each agent.
+	for _, agent := range supervisor.agents {
+		reconcileMonitors(agentConfiguration, agent.config, coordinationQMgr, coordinationPath+MFT_AGENTS_SLASH+agent.name, provisioning)
+		reconcileTemplates(agentConfiguration, agent.config, coordinationQMgr, provisioning)
+		reconcileSchedules(agentConfiguration, agent.config, coordinationQMgr, provisioning)
+	}
 
-		// Delete agent configuration on exit
-		deleteAgentOnExit := gjson.Get(singleAgentConfig, "deleteOnTermination").Bool()
-		// Delete agent configuration if asked for
-		if deleteAgentOnExit {
-			deleteAgent(coordinationQMgr, agentNameEnv)
-		} else {
-			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_CFG_DELETED_0039, agentNameEnv))
-		}
+	// Execute any commands provided in the cmds file
+	postInit()
 
-		// Agent has ended. Return success
-		os.Exit(MFT_CONT_SUCCESS_CODE_0)
-	} else {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_START_FAILED_0040, agentNameEnv))
-		os.Exit(MFT_CONT_ERR_CODE_22)
+	// Agents are configured. Commands run from now on, such as the one that
+	// stops agents when the container is asked to stop, are not cancelled.
+	stopSetup()
+	runner = newExecCommandRunner(context.Background(), commandTimeout)
+
+	// Setup a siganl handle and wait till container is stopped or an agent ends.
+	signalControl := signalHandler(supervisor)
+	supervisor.monitorAgents()
+	select {
+	case <-signalControl:
+	case agentName := <-supervisor.agentEnded:
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_ENDED_0092, agentName))
+		supervisor.stopAgents()
+		supervisor.stopMirrors()
+		os.Exit(MFT_CONT_ERR_CODE_26)
 	}
+	supervisor.stopMirrors()
+
+	// Delete agent configuration if asked for
+	supervisor.deleteAgentsOnExit()
+
+	// Agents have ended. Return success
+	os.Exit(MFT_CONT_SUCCESS_CODE_0)
 }
 
 // Mirror trace file contents to console if we have been asked
@@ -467,6 +611,7 @@ func setupMirrorTransferLogs(ctxTransferLog context.Context, wg *sync.WaitGroup,
 							gjson.Get(serverLogData, KEY_INJESTION_DNA).Exists() {
 							logDNAUrl := gjson.Get(serverLogData, KEY_URL_DNA).String()
 							logDNAKey := gjson.Get(serverLogData, KEY_INJESTION_DNA).String()
+							utils.AddSecretValue(logDNAKey)
 							transferLogPath := bfgDataPath + DIR_AGENT_LOGS + coordinationQMgr + DIR_AGENTS + agentNameEnv + "/logs/transferlog0.json"
 							mirrorAgentLogs(ctxTransferLog, wg, "IBMMQMFT Agent "+agentNameEnv, transferLogPath, logDNAUrl, logDNAKey, LOG_TYPE_TRANSFER,
 								LOG_SERVER_TYPE_DNA_NUM)
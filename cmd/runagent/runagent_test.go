@@ -87,11 +87,10 @@ func TestReadConfigurationDataFromFile(t *testing.T) {
 	}
 }
 
-// Test updating of agent properties file
-func TestupdateAgentProperties(t *testing.T) {
-	configDataValid := "{\"dataPath\":\"/mqmft/mftdata\",\"monitoringInterval\":300,\"displayAgentLogs\":true,\"displayLineCount\":50,\"waitTimeToStart\":10,\"coordinationQMgr\":{\"name\":\"QUICKSTART\",\"host\":\"10.254.0.4\",\"port\":1414,\"channel\":\"MFT_HA_CHN\"},\"commandsQMgr\":{\"name\":\"QUICKSTART\",\"host\":\"10.254.0.4\",\"port\":1414,\"channel\":\"MFT_HA_CHN\"},\"agent\":{\"name\":\"KXAGNT\",\"type\":\"STANDARD\",\"qmgrName\":\"QUICKSTART\",\"qmgrHost\":\"10.254.0.4\",\"qmgrPort\":1414,\"qmgrChannel\":\"MFT_HA_CHN\",\"credentialsFile\":\"/usr/local/bin/MQMFTCredentials.xml\",\"protocolBridge\":{\"credentialsFile\":\"/usr/local/bin/ProtocolBridgeCredentials.xml\",\"serverType\":\"SFTP\",\"serverHost\":\"9.199.144.110\",\"serverTimezone\":\"\",\"serverPlatform\":\"UNIX\",\"serverLocale\":\"en-US\",\"serverFileEncoding\":\"UTF-8\",\"serverPort\":22,\"serverTrustStoreFile\":\"\",\"serverLimitedWrite\":\"\",\"serverListFormat\":\"\",\"serverUserId\":\"root\",\"serverPassword\":\"Kitt@n0or\"},\"additionalProperties\":{\"enableQueueInputOutput\":\"true\"}}"
+// Test updating of agent properties file when the configuration has no additional properties
+func TestUpdateAgentPropertiesDefaults(t *testing.T) {
 	initialProps := "agentQMgr=MFTQM\nagentQMgrPort=1414\nagentDesc=\nagentQMgrHost=localhost\nagentQMgrChannel=MFT_CHN\nagentName=SRC\ntrace=com.ibm.wmqfte=all"
-	compareTemplate := "agentQMgr=MFTQM\nagentQMgrPort=1414\nagentDesc=\nagentQMgrHost=localhost\nagentQMgrChannel=MFT_CHN\nagentName=SRC\ntrace=com.ibm.wmqfte=all\nenableQueueInputOutput=true"
+	compareTemplate := "agentQMgr=MFTQM\nagentQMgrPort=1414\nagentDesc=\nagentQMgrHost=localhost\nagentQMgrChannel=MFT_CHN\nagentName=SRC\ntrace=com.ibm.wmqfte=all\nlogCapture=true\nmaxRestartCount=0\nuserSandboxes=true\n"
 
 	agentProps, err := ioutil.TempFile("", t.Name())
 	if err != nil {
@@ -108,7 +107,7 @@ func TestupdateAgentProperties(t *testing.T) {
 	agentPropsF.Close()
 
 	// Update the agent.properties file with data from configuration file
-	updateAgentProperties(agentProps.Name(), configDataValid, "additionalProperties", false)
+	updateAgentProperties(agentProps.Name(), nil, AGENT_TYPE_STANDARD)
 
 	content, err := ioutil.ReadFile(agentProps.Name())
 	if err != nil {
@@ -0,0 +1,216 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Contains the user sandboxes of standard agents. Sandboxes are described in
+* the sandboxes section of the agent configuration and written to the
+* UserSandboxes.xml file of the agent. If no sandbox is described, a single
+* sandbox gives every user read and write access to the transfer mount path
+* and all queues. Sandboxes are not written if userSandboxes property is set
+* to false in additionalProperties.
+ */
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/subchen/go-xmldom"
+)
+
+// Ways of matching user names of a sandbox
+const SANDBOX_USER_PATTERN_WILDCARD = "wildcard"
+const SANDBOX_USER_PATTERN_REGEX = "regex"
+
+// Name of the agent property enabling user sandboxes
+const PROPERTY_USER_SANDBOXES = "userSandboxes"
+
+// Queue names, optionally followed by a queue manager name, with wildcards
+var sandboxQueuePattern = regexp.MustCompile(`^[A-Za-z0-9._/%*?]+(@[A-Za-z0-9._/%*?]+)?$`)
+
+// Path, with a /** suffix, of the directory transfers read from and write to.
+// Use the value specified in MFT_MOUNT_PATH environment variable if available
+// else use the default "/mountpath" folder.
+func transferRootPath() string {
+	var rootPath string = DEFAULT_MOUNT_PATH_FOR_TRANSFERS
+	mountPathEnv, mountPathEnvSet := os.LookupEnv(MFT_MOUNT_PATH)
+	if mountPathEnvSet {
+		mountPathEnv = strings.TrimSpace(mountPathEnv)
+		if len(mountPathEnv) > 0 {
+			//If the supplied path does not have /** suffix, then add it
+			if !strings.HasSuffix(mountPathEnv, "/**") {
+				if strings.HasSuffix(mountPathEnv, "/*") {
+					rootPath = mountPathEnv + "*"
+				} else if strings.HasSuffix(mountPathEnv, "/") {
+					rootPath = mountPathEnv + "**"
+				} else {
+					rootPath = mountPathEnv + "/**"
+				}
+			} else {
+				rootPath = mountPathEnv
+			}
+		}
+	}
+	return rootPath
+}
+
+// Sandbox used when none is described in the configuration. Any alphanumeric
+// user can read from and write to the transfer root path and all queues.
+func defaultSandboxes() []*config.Sandbox {
+	rootPath := transferRootPath()
+	return []*config.Sandbox{{
+		User:        "^[a-zA-Z0-9]*$",
+		UserPattern: SANDBOX_USER_PATTERN_REGEX,
+		Read:        &config.SandboxAccess{Include: []string{rootPath}, IncludeQueues: []string{"**"}},
+		Write:       &config.SandboxAccess{Include: []string{rootPath}, IncludeQueues: []string{"**"}},
+	}}
+}
+
+// Determine if userSandboxes property is specified in additionalProperties
+func userSandboxesSpecified(properties config.Properties) bool {
+	for _, property := range properties {
+		if strings.EqualFold(property.Name, PROPERTY_USER_SANDBOXES) {
+			return true
+		}
+	}
+	return false
+}
+
+// Determine if user sandboxes have been turned off in additionalProperties
+func userSandboxesDisabled(properties config.Properties) bool {
+	for _, property := range properties {
+		if strings.EqualFold(property.Name, PROPERTY_USER_SANDBOXES) && strings.EqualFold(strings.TrimSpace(property.Value), "false") {
+			return true
+		}
+	}
+	return false
+}
+
+// Build UserSandboxes.xml document from the given sandboxes
+func buildSandboxDocument(sandboxes []*config.Sandbox) *xmldom.Document {
+	sandBoxDoc := xmldom.NewDocument("tns:userSandboxes")
+	sandBoxDoc.Root.SetAttributeValue("xmlns:tns", "http://wmqfte.ibm.com/UserSandboxes")
+	sandBoxDoc.Root.SetAttributeValue("xmlns:xsi", "http://www.w3.org/2001/XMLSchema-instance")
+	sandBoxDoc.Root.SetAttributeValue("xsi:schemaLocation", "http://wmqfte.ibm.com/UserSandboxes UserSandboxes.xsd")
+	agentNode := sandBoxDoc.Root.CreateNode("tns:agent")
+	for _, sandbox := range sandboxes {
+		sandBoxNode := agentNode.CreateNode("tns:sandbox")
+		sandBoxNode.SetAttributeValue("user", sandbox.User)
+		if len(sandbox.UserPattern) > 0 {
+			sandBoxNode.SetAttributeValue("userPattern", strings.ToLower(sandbox.UserPattern))
+		}
+		addSandboxAccess(sandBoxNode, "tns:read", sandbox.Read)
+		addSandboxAccess(sandBoxNode, "tns:write", sandbox.Write)
+	}
+	return sandBoxDoc
+}
+
+// Add read or write section of a sandbox. Includes are written before
+// excludes as required by the schema of UserSandboxes.xml.
+func addSandboxAccess(sandBoxNode *xmldom.Node, name string, access *config.SandboxAccess) {
+	if access == nil {
+		return
+	}
+	accessNode := sandBoxNode.CreateNode(name)
+	for _, path := range access.Include {
+		accessNode.CreateNode("tns:include").SetAttributeValue("name", path)
+	}
+	for _, queue := range access.IncludeQueues {
+		includeNode := accessNode.CreateNode("tns:include")
+		includeNode.SetAttributeValue("name", queue)
+		includeNode.SetAttributeValue("type", "queue")
+	}
+	for _, path := range access.Exclude {
+		accessNode.CreateNode("tns:exclude").SetAttributeValue("name", path)
+	}
+	for _, queue := range access.ExcludeQueues {
+		excludeNode := accessNode.CreateNode("tns:exclude")
+		excludeNode.SetAttributeValue("name", queue)
+		excludeNode.SetAttributeValue("type", "queue")
+	}
+}
+
+// Validate the sandboxes of an agent. Sandboxes are valid only for standard
+// agents that have not turned off user sandboxes.
+func validateSandboxes(agent *config.Agent) error {
+	if len(agent.Sandboxes) == 0 {
+		return nil
+	}
+	if strings.EqualFold(agent.Type, AGENT_TYPE_BRIDGE) || strings.EqualFold(agent.Type, AGENT_TYPE_CD) {
+		return fmt.Errorf("sandboxes are valid only for %s agents", AGENT_TYPE_STANDARD)
+	}
+	if userSandboxesDisabled(agent.AdditionalProperties) {
+		return fmt.Errorf("sandboxes are specified but %s property is set to false", PROPERTY_USER_SANDBOXES)
+	}
+	for index, sandbox := range agent.Sandboxes {
+		if err := validateSandbox(sandbox); err != nil {
+			return fmt.Errorf("sandboxes[%d] is not valid, %v", index, err)
+		}
+	}
+	return nil
+}
+
+// Validate a sandbox
+func validateSandbox(sandbox *config.Sandbox) error {
+	if len(strings.TrimSpace(sandbox.User)) == 0 {
+		return fmt.Errorf("user must be specified")
+	}
+	if strings.EqualFold(sandbox.UserPattern, SANDBOX_USER_PATTERN_REGEX) {
+		if _, err := regexp.Compile(sandbox.User); err != nil {
+			return fmt.Errorf("user %q is not a valid regular expression: %v", sandbox.User, err)
+		}
+	}
+	if sandbox.Read == nil && sandbox.Write == nil {
+		return fmt.Errorf("read or write access must be specified")
+	}
+	if err := validateSandboxAccess("read", sandbox.Read); err != nil {
+		return err
+	}
+	return validateSandboxAccess("write", sandbox.Write)
+}
+
+// Validate read or write section of a sandbox. Paths must be absolute and
+// queue names must be valid queue names that can contain wildcards.
+func validateSandboxAccess(name string, access *config.SandboxAccess) error {
+	if access == nil {
+		return nil
+	}
+	if len(access.Include) == 0 && len(access.IncludeQueues) == 0 {
+		return fmt.Errorf("%s must include at least one path or queue", name)
+	}
+	for _, paths := range [][]string{access.Include, access.Exclude} {
+		for _, path := range paths {
+			if !strings.HasPrefix(path, "/") {
+				return fmt.Errorf("%s path %q must be an absolute path", name, path)
+			}
+			for _, element := range strings.Split(path, "/") {
+				if element == ".." {
+					return fmt.Errorf("%s path %q must not contain '..'", name, path)
+				}
+			}
+		}
+	}
+	for _, queues := range [][]string{access.IncludeQueues, access.ExcludeQueues} {
+		for _, queue := range queues {
+			if !sandboxQueuePattern.MatchString(queue) {
+				return fmt.Errorf("%s queue %q is not a valid queue name pattern", name, queue)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+func TestCreateUserSandboxFromConfiguration(t *testing.T) {
+	sandboxes := []*config.Sandbox{
+		{
+			User:        "app*",
+			UserPattern: "Wildcard",
+			Read: &config.SandboxAccess{
+				Include:       []string{"/mountpath/in/**", "/mountpath/shared/**"},
+				Exclude:       []string{"/mountpath/in/private/**"},
+				IncludeQueues: []string{"APP.*@QM1"},
+			},
+			Write: &config.SandboxAccess{
+				Include:       []string{"/mountpath/out/**"},
+				ExcludeQueues: []string{"SYSTEM.**"},
+			},
+		},
+		{User: "admin", Read: &config.SandboxAccess{Include: []string{"/mountpath/**"}}},
+	}
+	sandboxFile := filepath.Join(t.TempDir(), "UserSandboxes.xml")
+	if err := createUserSandbox(sandboxFile, sandboxes); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(sandboxFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sandboxXml := string(content)
+	expected := []string{
+		`<tns:sandbox user="app*" userPattern="wildcard">`,
+		`<tns:include name="/mountpath/shared/**" />`,
+		`<tns:include name="APP.*@QM1" type="queue" />`,
+		`<tns:exclude name="/mountpath/in/private/**" />`,
+		`<tns:exclude name="SYSTEM.**" type="queue" />`,
+		`<tns:sandbox user="admin">`,
+	}
+	for _, element := range expected {
+		if !strings.Contains(sandboxXml, element) {
+			t.Errorf("Expected %s in sandbox XML:\n%s", element, sandboxXml)
+		}
+	}
+	// Includes must precede excludes
+	if strings.Index(sandboxXml, "APP.*@QM1") > strings.Index(sandboxXml, "/mountpath/in/private/**") {
+		t.Errorf("Queue include written after exclude:\n%s", sandboxXml)
+	}
+	if strings.Contains(sandboxXml, "^[a-zA-Z0-9]*$") {
+		t.Errorf("Default sandbox written with configured sandboxes:\n%s", sandboxXml)
+	}
+}
+
+func TestDefaultSandboxesMountPath(t *testing.T) {
+	t.Setenv(MFT_MOUNT_PATH, "/data/transfers/")
+	sandboxes := defaultSandboxes()
+	if len(sandboxes) != 1 || sandboxes[0].Read.Include[0] != "/data/transfers/**" || sandboxes[0].Write.Include[0] != "/data/transfers/**" {
+		t.Errorf("Unexpected default sandbox %+v", sandboxes[0])
+	}
+}
+
+func TestValidateSandboxes(t *testing.T) {
+	readAll := &config.SandboxAccess{Include: []string{"/mountpath/**"}}
+	tests := []struct {
+		agent *config.Agent
+		err   string
+	}{
+		{&config.Agent{Name: "SRC", Sandboxes: []*config.Sandbox{{User: "app", Read: readAll}}}, ""},
+		{&config.Agent{Name: "SRC"}, ""},
+		{&config.Agent{Name: "PBA", Type: "bridge", Sandboxes: []*config.Sandbox{{User: "app", Read: readAll}}},
+			"sandboxes are valid only for STANDARD agents"},
+		{&config.Agent{Name: "SRC", AdditionalProperties: config.Properties{{Name: "userSandboxes", Value: "False"}},
+			Sandboxes: []*config.Sandbox{{User: "app", Read: readAll}}},
+			"sandboxes are specified but userSandboxes property is set to false"},
+		{&config.Agent{Name: "SRC", Sandboxes: []*config.Sandbox{{User: " ", Read: readAll}}},
+			"sandboxes[0] is not valid, user must be specified"},
+		{&config.Agent{Name: "SRC", Sandboxes: []*config.Sandbox{{User: "app", Read: readAll}, {User: "(app", UserPattern: "regex", Read: readAll}}},
+			"sandboxes[1] is not valid, user \"(app\" is not a valid regular expression"},
+		{&config.Agent{Name: "SRC", Sandboxes: []*config.Sandbox{{User: "app"}}},
+			"sandboxes[0] is not valid, read or write access must be specified"},
+		{&config.Agent{Name: "SRC", Sandboxes: []*config.Sandbox{{User: "app", Write: &config.SandboxAccess{Exclude: []string{"/tmp"}}}}},
+			"sandboxes[0] is not valid, write must include at least one path or queue"},
+		{&config.Agent{Name: "SRC", Sandboxes: []*config.Sandbox{{User: "app", Read: &config.SandboxAccess{Include: []string{"mountpath/**"}}}}},
+			"sandboxes[0] is not valid, read path \"mountpath/**\" must be an absolute path"},
+		{&config.Agent{Name: "SRC", Sandboxes: []*config.Sandbox{{User: "app", Read: &config.SandboxAccess{Include: []string{"/mountpath/../etc/**"}}}}},
+			"sandboxes[0] is not valid, read path \"/mountpath/../etc/**\" must not contain '..'"},
+		{&config.Agent{Name: "SRC", Sandboxes: []*config.Sandbox{{User: "app", Write: &config.SandboxAccess{IncludeQueues: []string{"APP QUEUE"}}}}},
+			"sandboxes[0] is not valid, write queue \"APP QUEUE\" is not a valid queue name pattern"},
+	}
+	for _, test := range tests {
+		err := validateSandboxes(test.agent)
+		if len(test.err) == 0 {
+			if err != nil {
+				t.Errorf("Unexpected error for agent %s: %v", test.agent.Name, err)
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("Expected error %q, found %v", test.err, err)
+		}
+	}
+}
+
+func TestUpdateAgentPropertiesUserSandboxesOverride(t *testing.T) {
+	propertiesFile := filepath.Join(t.TempDir(), "agent.properties")
+	updateAgentProperties(propertiesFile, config.Properties{{Name: "userSandboxes", Value: "false"}}, AGENT_TYPE_STANDARD)
+	content, err := os.ReadFile(propertiesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(content), "userSandboxes=") != 1 || !strings.Contains(string(content), "userSandboxes=false\n") {
+		t.Errorf("userSandboxes not overridden:\n%s", content)
+	}
+}
@@ -0,0 +1,80 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Secret references in the configuration file. A secret is read from a file,
+* typically mounted from a Kubernetes secret, or from an environment variable
+* when a configuration file is written, so that secrets are never held in the
+* configuration file itself. Values read are registered as secrets so that
+* they are redacted from all output.
+ */
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Check that a secret reference names exactly one source of the secret
+func validateSecretRef(ref *config.SecretRef) error {
+	if ref == nil {
+		return fmt.Errorf("must be specified")
+	}
+	file := strings.TrimSpace(ref.File)
+	env := strings.TrimSpace(ref.Env)
+	if len(file) == 0 && len(env) == 0 {
+		return fmt.Errorf("must specify file or env")
+	}
+	if len(file) > 0 && len(env) > 0 {
+		return fmt.Errorf("must not specify both file and env")
+	}
+	if len(file) > 0 && !filepath.IsAbs(file) {
+		return fmt.Errorf("file %q must be an absolute path", ref.File)
+	}
+	return nil
+}
+
+// Read the value of a secret. Line endings at the end of a file are removed
+// as editors and kubectl often add them.
+func resolveSecret(ref *config.SecretRef) (string, error) {
+	if err := validateSecretRef(ref); err != nil {
+		return TEXT_BLANK, err
+	}
+	var value string
+	if file := strings.TrimSpace(ref.File); len(file) > 0 {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return TEXT_BLANK, fmt.Errorf("failed to read secret file %s: %v", file, err)
+		}
+		value = strings.TrimRight(string(data), "\r\n")
+		if len(value) == 0 {
+			return TEXT_BLANK, fmt.Errorf("secret file %s is empty", file)
+		}
+	} else {
+		env := strings.TrimSpace(ref.Env)
+		envValue, envSet := os.LookupEnv(env)
+		if !envSet || len(envValue) == 0 {
+			return TEXT_BLANK, fmt.Errorf("environment variable %s is not set", env)
+		}
+		value = envValue
+	}
+	utils.AddSecretValue(value)
+	return value, nil
+}
@@ -0,0 +1,61 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+func TestResolveSecret(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secretFile, []byte("FileS3cret\r\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET_ENV", "EnvS3cret")
+	tests := []struct {
+		ref   *config.SecretRef
+		value string
+		err   string
+	}{
+		{&config.SecretRef{File: secretFile}, "FileS3cret", ""},
+		{&config.SecretRef{Env: "TEST_SECRET_ENV"}, "EnvS3cret", ""},
+		{nil, "", "must be specified"},
+		{&config.SecretRef{}, "", "must specify file or env"},
+		{&config.SecretRef{File: "secret"}, "", "file \"secret\" must be an absolute path"},
+		{&config.SecretRef{File: filepath.Join(t.TempDir(), "missing")}, "", "failed to read secret file"},
+		{&config.SecretRef{Env: "TEST_SECRET_UNSET"}, "", "environment variable TEST_SECRET_UNSET is not set"},
+	}
+	for _, test := range tests {
+		value, err := resolveSecret(test.ref)
+		if len(test.err) == 0 {
+			if err != nil || value != test.value {
+				t.Errorf("Expected %q, found %q %v", test.value, value, err)
+			}
+		} else if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("Expected error %q, found %v", test.err, err)
+		}
+	}
+	// Values read are redacted from output
+	if redacted := utils.RedactSecrets("user FileS3cret EnvS3cret"); strings.Contains(redacted, "S3cret") {
+		t.Errorf("Secrets not redacted: %s", redacted)
+	}
+}
@@ -16,10 +16,8 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"os"
-	"os/exec"
 	"os/signal"
 	"syscall"
 
@@ -33,12 +31,14 @@ const (
 	reapNow      = iota
 )
 
-func signalHandler(agentName string, coordinationQMgr string) chan int {
+// Handle signals sent to the container. All agents run by the supervisor are
+// stopped when the container is asked to stop.
+func signalHandler(supervisor *agentSupervisor) chan int {
 	control := make(chan int)
 	// Use separate channels for the signals, to avoid SIGCHLD signals swamping
 	// the buffer, and preventing other signals.
-	stopSignals := make(chan os.Signal)
-	reapSignals := make(chan os.Signal)
+	stopSignals := make(chan os.Signal, 1)
+	reapSignals := make(chan os.Signal, 1)
 	signal.Notify(stopSignals, syscall.SIGTERM, syscall.SIGINT)
 	go func() {
 		for {
@@ -48,7 +48,7 @@ func signalHandler(agentName string, coordinationQMgr string) chan int {
 				signal.Stop(reapSignals)
 				signal.Stop(stopSignals)
 				// #nosec G104
-				stopAgent(agentName, coordinationQMgr)
+				supervisor.stopAgents()
 				// One final reap
 				reapZombies()
 				close(control)
@@ -94,27 +94,15 @@ func reapZombies() {
 
 // Stops an agent when container stop is issued.
 func stopAgent(agentName string, coordinationQMgr string) {
-	var outb, errb bytes.Buffer
-	// Get the path of MFT fteStopAgent command.
-	cmdStopAgntPath, lookPathErr := exec.LookPath("fteStopAgent")
-	if lookPathErr != nil {
-		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, lookPathErr))
+	result := runFteCommand("fteStopAgent", "-p", coordinationQMgr, agentName, "-i")
+	if result.notFound() {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, result.err))
 		os.Exit(1)
 	}
-	cmdStopAgnt := &exec.Cmd{
-		Path: cmdStopAgntPath,
-		Args: []string{cmdStopAgntPath, "-p", coordinationQMgr, agentName, "-i"},
-	}
-
-	outb.Reset()
-	errb.Reset()
-	cmdStopAgnt.Stdout = &outb
-	cmdStopAgnt.Stderr = &errb
-	err := cmdStopAgnt.Run()
-	if err != nil {
-		utils.PrintLog(fmt.Sprintf("An error occured when running fteStopAgent command. The error is: %s", err.Error()))
-		utils.PrintLog(fmt.Sprintf("Command: %s\n", outb.String()))
-		utils.PrintLog(fmt.Sprintf("Error %s\n", errb.String()))
+	if result.failed() {
+		utils.PrintLog(fmt.Sprintf("An error occured when running fteStopAgent command. The error is: %s", result.err.Error()))
+		utils.PrintLog(fmt.Sprintf("Command: %s\n", result.stdout))
+		utils.PrintLog(fmt.Sprintf("Error %s\n", result.stderr))
 	} else {
 		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGENT_STOPPED_0068, agentName))
 	}
@@ -0,0 +1,199 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Contains the supervisor that runs one or more agents in a container. Every
+* agent has its own log mirrors, readiness check and liveness monitor. If any
+* agent ends while the container is running, the remaining agents are stopped
+* and the container ends with an error.
+ */
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// An agent run by the supervisor
+type managedAgent struct {
+	// Name of the agent
+	name string
+	// Configuration of the agent from the configuration file
+	config *config.Agent
+	// Size of output0.log before the agent was started
+	startLogOffset int64
+	// Agent process has ended unexpectedly
+	ended atomic.Bool
+	// Cancels the log mirrors of the agent
+	ctxMirror    context.Context
+	cancelMirror context.CancelFunc
+	// Tracks the log mirrors of the agent
+	wgMirror sync.WaitGroup
+}
+
+// Runs the agents configured in the container
+type agentSupervisor struct {
+	bfgDataPath      string
+	coordinationQMgr string
+	start            *agentStartPolicy
+	agents           []*managedAgent
+	// Set once agents are being stopped, so that an agent ending is not
+	// reported as a failure
+	stopping atomic.Bool
+	// Receives the name of an agent that has ended unexpectedly
+	agentEnded chan string
+}
+
+// Create a supervisor for agents of the given coordination queue manager
+func newAgentSupervisor(bfgDataPath string, coordinationQMgr string, start *agentStartPolicy) *agentSupervisor {
+	return &agentSupervisor{
+		bfgDataPath:      bfgDataPath,
+		coordinationQMgr: coordinationQMgr,
+		start:            start,
+	}
+}
+
+// Add an agent to the list of agents run by the supervisor
+func (supervisor *agentSupervisor) addAgent(name string, agentConfig *config.Agent) *managedAgent {
+	ctx, cancel := context.WithCancel(context.Background())
+	agent := &managedAgent{
+		name:         name,
+		config:       agentConfig,
+		ctxMirror:    ctx,
+		cancelMirror: cancel,
+	}
+	supervisor.agents = append(supervisor.agents, agent)
+	return agent
+}
+
+// Path of the agent's log directory
+func (supervisor *agentSupervisor) agentLogPath(agent *managedAgent) string {
+	return supervisor.bfgDataPath + DIR_AGENT_LOGS + supervisor.coordinationQMgr + DIR_AGENTS + agent.name
+}
+
+// Wait for the agent to become ready and then start mirroring its logs. Returns
+// false if the agent did not become ready.
+func (supervisor *agentSupervisor) waitForAgentReady(agent *managedAgent) bool {
+	// Display the contents of agent's output0.log file on the console.
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		agentLogPath := supervisor.agentLogPath(agent) + "/logs/output0.log"
+		mirrorAgentLogs(agent.ctxMirror, &agent.wgMirror, agent.name, agentLogPath, "", "", LOG_TYPE_CONSOLE, -1)
+	}
+
+	// Poll the agent until it is ready to accept requests
+	watch := newAgentStartWatch(supervisor.agentLogPath(agent), agent.startLogOffset)
+	if !supervisor.start.waitForAgent(agent.name, supervisor.coordinationQMgr, watch) {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_NOT_READY, agent.name))
+		return false
+	}
+
+	// Mirror contents of capture log on the console
+	setupMirrorCaptureLogs(agent.ctxMirror, &agent.wgMirror, supervisor.bfgDataPath, supervisor.coordinationQMgr, agent.name)
+
+	// Mirror contents trace files to console
+	setupMirrorTraceLogs(agent.ctxMirror, &agent.wgMirror, supervisor.bfgDataPath, supervisor.coordinationQMgr, agent.name)
+
+	// Push transfer logs to specified server
+	setupMirrorTransferLogs(agent.ctxMirror, &agent.wgMirror, supervisor.bfgDataPath, supervisor.coordinationQMgr, agent.name)
+
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_STARTED_0038, agent.name))
+	return true
+}
+
+// Start monitoring the processes of all agents. The name of an agent that ends
+// is sent to the agentEnded channel.
+func (supervisor *agentSupervisor) monitorAgents() {
+	supervisor.agentEnded = make(chan string, len(supervisor.agents))
+	for _, agent := range supervisor.agents {
+		go supervisor.monitorAgent(agent)
+	}
+}
+
+// Periodically check that the agent process is still running.
+func (supervisor *agentSupervisor) monitorAgent(agent *managedAgent) {
+	agentPidPath := supervisor.agentLogPath(agent) + "/agent.pid"
+	ticker := time.NewTicker(time.Duration(AGENT_LIVENESS_CHECK_INTERVAL) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-agent.ctxMirror.Done():
+			return
+		case <-ticker.C:
+			if supervisor.stopping.Load() {
+				return
+			}
+			if !isAgentProcessRunning(agentPidPath) {
+				if !supervisor.stopping.Load() {
+					agent.ended.Store(true)
+					supervisor.agentEnded <- agent.name
+				}
+				return
+			}
+		}
+	}
+}
+
+// Determine if the process whose id is recorded in the given pid file is running
+func isAgentProcessRunning(agentPidPath string) bool {
+	agentPid, _ := utils.GetAgentPid(agentPidPath)
+	if agentPid <= 1 {
+		return false
+	}
+	agentRunning, err := utils.IsAgentRunning(agentPid)
+	return err == nil && agentRunning
+}
+
+// Stop all agents that are still running. Only the first call stops agents,
+// later calls return immediately.
+func (supervisor *agentSupervisor) stopAgents() {
+	if supervisor.stopping.Swap(true) {
+		return
+	}
+	utils.PrintLog(utils.MFT_CONT_AGNT_STOPPING_ALL_0093)
+	for _, agent := range supervisor.agents {
+		if !agent.ended.Load() {
+			stopAgent(agent.name, supervisor.coordinationQMgr)
+		}
+	}
+}
+
+// Stop log mirroring of all agents and wait for it to complete
+func (supervisor *agentSupervisor) stopMirrors() {
+	for _, agent := range supervisor.agents {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_WAIT_MIRROR_STOP_0036, agent.name))
+		agent.cancelMirror()
+	}
+	for _, agent := range supervisor.agents {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_WAIT_MIRROR_CMP_0035, agent.name))
+		agent.wgMirror.Wait()
+	}
+}
+
+// Delete configuration of agents that have deleteOnTermination attribute set
+func (supervisor *agentSupervisor) deleteAgentsOnExit() {
+	for _, agent := range supervisor.agents {
+		if bool(agent.config.DeleteOnTermination) {
+			deleteAgent(supervisor.coordinationQMgr, agent.name)
+		} else {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_AGNT_CFG_DELETED_0039, agent.name))
+		}
+	}
+}
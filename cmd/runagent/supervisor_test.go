@@ -0,0 +1,47 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+const supervisorTestConfig = "{\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"},\"agents\":[" +
+	"{\"name\":\"SRC1\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\"}," +
+	"{\"name\":\"SRC2\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"deleteOnTermination\":true}," +
+	"{\"name\":\"DEST\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\"}]}"
+
+func TestSupervisorAddAgent(t *testing.T) {
+	agentConfiguration, err := config.Parse(supervisorTestConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	supervisor := newAgentSupervisor("/mnt/mftdata", "QM1", newAgentStartPolicy())
+	for _, agentName := range agentConfiguration.AgentNames("SRC1,SRC2") {
+		supervisor.addAgent(agentName, agentConfiguration.Agent(agentName))
+	}
+	if len(supervisor.agents) != 2 || supervisor.agents[1].config.Name != "SRC2" {
+		t.Fatalf("Agents not added to supervisor: %v", supervisor.agents)
+	}
+	if bool(supervisor.agents[0].config.DeleteOnTermination) || !bool(supervisor.agents[1].config.DeleteOnTermination) {
+		t.Error("deleteOnTermination attribute not available to supervisor")
+	}
+	if path := supervisor.agentLogPath(supervisor.agents[0]); path != "/mnt/mftdata"+DIR_AGENT_LOGS+"QM1"+DIR_AGENTS+"SRC1" {
+		t.Errorf("Unexpected agent log path %s", path)
+	}
+}
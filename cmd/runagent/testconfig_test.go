@@ -0,0 +1,63 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Placeholder in test configuration files for the absolute path of the data
+// directory, as secret files must be named by absolute paths
+const TEST_DATA_DIR = "${TEST_DATA_DIR}"
+
+// Load a test configuration file from the data directory
+func loadTestConfig(t *testing.T, fileName string) *config.Configuration {
+	t.Helper()
+	dataDir, err := filepath.Abs("data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configData, err := utils.ReadConfigurationDataFromFile(filepath.Join(dataDir, fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration, err := config.Parse(strings.ReplaceAll(configData, TEST_DATA_DIR, dataDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return configuration
+}
+
+// Copy a file of the data directory to a temporary directory, so that tests
+// updating it leave the committed file unchanged
+func copyTestFile(t *testing.T, fileName string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("data", fileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	copyPath := filepath.Join(t.TempDir(), fileName)
+	if err := os.WriteFile(copyPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return copyPath
+}
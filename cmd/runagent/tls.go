@@ -16,19 +16,57 @@ limitations under the License.
 package main
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"math/rand"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
 )
 
+// Prefix of the properties used for connecting to each queue manager
+const PROPERTY_PREFIX_COORDINATION = "coordination"
+const PROPERTY_PREFIX_COMMAND = "connection"
+const PROPERTY_PREFIX_AGENT = "agent"
+
+// Properties that configure the truststore used for connecting to a queue
+// manager. Prefix is one of the PROPERTY_PREFIX_* constants.
+func tlsTrustStoreProperties(prefix string, cipherName string, trustStore string, credentialsFile string) config.Properties {
+	return config.Properties{
+		{Name: prefix + "SslCipherSpec", Value: cipherName},
+		{Name: prefix + "SslTrustStore", Value: filepath.Join(KEYSTORES_PATH, trustStore)},
+		{Name: prefix + "SslTrustStoreType", Value: "pkcs12"},
+		{Name: prefix + "SslTrustStoreCredentialsFile", Value: credentialsFile},
+	}
+}
+
+// Properties that configure the keystore used for connecting to a queue
+// manager. Prefix is one of the PROPERTY_PREFIX_* constants.
+func tlsKeyStoreProperties(prefix string, cipherName string, keyStore string, credentialsFile string) config.Properties {
+	return config.Properties{
+		{Name: prefix + "SslCipherSpec", Value: cipherName},
+		{Name: prefix + "SslKeyStore", Value: filepath.Join(KEYSTORES_PATH, keyStore)},
+		{Name: prefix + "SslKeyStoreType", Value: "pkcs12"},
+		{Name: prefix + "SslKeyStoreCredentialsFile", Value: credentialsFile},
+	}
+}
+
+// Name of the property naming the credentials file of a queue manager
+func credentialsFileProperty(prefix string) string {
+	return prefix + "QMgrAuthenticationCredentialsFile"
+}
+
+// Set the given properties, replacing values that have already been set
+func setProperties(properties *config.Properties, values config.Properties) {
+	for _, value := range values {
+		properties.Set(value.Name, value.Value)
+	}
+}
+
 /**
 * Create keystore and include user specified certificates
  */
@@ -40,70 +78,68 @@ func CreateKeyStore(keyStoreDir string, keyStoreFile string, certFilePath string
 	}
 
 	keyStorePathFinal := filepath.Join(keyStoreDir, keyStoreFile)
-	// Delete if keystore already exists
-	finfo, err := os.Lstat(keyStoreDir)
-	if err == nil {
-		if logLevel >= LOG_LEVEL_VERBOSE {
-			utils.PrintLog(fmt.Sprintf("Keystore path %v exists.", finfo.Name()))
-		}
-		certInfo, err := os.Lstat(keyStorePathFinal)
+	// Existing keystores are left untouched in dry-run mode, only the command is recorded.
+	if !dryRunEnabled {
+		// Delete if keystore already exists
+		finfo, err := os.Lstat(keyStoreDir)
 		if err == nil {
 			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(fmt.Sprintf("Keystore %v already exists. Deleting it...", certInfo.Name()))
+				utils.PrintLog(fmt.Sprintf("Keystore path %v exists.", finfo.Name()))
 			}
-			delErr := os.Remove(keyStorePathFinal)
-			if delErr == nil {
+			certInfo, err := os.Lstat(keyStorePathFinal)
+			if err == nil {
 				if logLevel >= LOG_LEVEL_VERBOSE {
-					utils.PrintLog(fmt.Sprintf("Existing keystore %v deleted", keyStorePathFinal))
+					utils.PrintLog(fmt.Sprintf("Keystore %v already exists. Deleting it...", certInfo.Name()))
+				}
+				delErr := os.Remove(keyStorePathFinal)
+				if delErr == nil {
+					if logLevel >= LOG_LEVEL_VERBOSE {
+						utils.PrintLog(fmt.Sprintf("Existing keystore %v deleted", keyStorePathFinal))
+					}
+				} else {
+					errorMsg := fmt.Sprintf("An error occurred while deleting keystore %v. The error is: %v", keyStorePathFinal, delErr)
+					return errors.New(errorMsg)
 				}
-			} else {
-				errorMsg := fmt.Sprintf("An error occurred while deleting keystore %v. The error is: %v", keyStorePathFinal, delErr)
-				return errors.New(errorMsg)
 			}
 		}
-	}
 
-	// Create directory
-	errCreateDataPath := utils.CreatePath(keyStoreDir)
-	if errCreateDataPath != nil {
-		return errCreateDataPath
+		// Create directory
+		errCreateDataPath := utils.CreatePath(keyStoreDir)
+		if errCreateDataPath != nil {
+			return errCreateDataPath
+		}
 	}
 
 	// A new keystore will be created if it does not exist.
-	var outb, errb bytes.Buffer
-	cmdKeyToolPath, lookPathErr := exec.LookPath("keytool")
-	if lookPathErr == nil {
-		var cmdArgs []string
-		cmdArgs = append(cmdArgs, cmdKeyToolPath,
-			"-importcert",
-			"-trustcacerts",
-			"-keystore", keyStorePathFinal,
-			"-storetype", "pkcs12",
-			"-storepass", certStorePassword,
-			"-noprompt",
-			"-v",
-			"-alias", "agentstore",
-			"-file", certFilePath)
-
-		cmdKeyTool := &exec.Cmd{
-			Path: cmdKeyToolPath,
-			Args: cmdArgs,
+	result := runner.run(&command{name: "keytool", args: []string{
+		"-importcert",
+		"-trustcacerts",
+		"-keystore", keyStorePathFinal,
+		"-storetype", "pkcs12",
+		"-storepass", certStorePassword,
+		"-noprompt",
+		"-v",
+		"-alias", "agentstore",
+		"-file", certFilePath}})
+	if result.notFound() {
+		if logLevel >= LOG_LEVEL_VERBOSE {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_NOT_FOUND_0028, result.err))
 		}
-
-		cmdKeyTool.Stdout = &outb
-		cmdKeyTool.Stderr = &errb
-		// Execute the keytool command. Log an error an exit in case of any error.
-		if err := cmdKeyTool.Run(); err != nil {
-			errorMsg := fmt.Sprintf("Error occurred while creating keystore. Command Output: %v Error Output: %vError: %v",
-				outb.String(), errb.String(), err.Error())
-			return errors.New(errorMsg)
-		} else {
-			if logLevel >= LOG_LEVEL_VERBOSE {
-				utils.PrintLog(fmt.Sprintf("Created keystore. Output: %v %v", outb.String(), errb.String()))
-			}
+		return nil
+	}
+	// Log an error an exit in case of any error.
+	if result.failed() {
+		errorMsg := fmt.Sprintf("Error occurred while creating keystore. Command Output: %v Error Output: %vError: %v",
+			result.stdout, result.stderr, result.err.Error())
+		return errors.New(errorMsg)
+	} else {
+		if logLevel >= LOG_LEVEL_VERBOSE {
+			utils.PrintLog(fmt.Sprintf("Created keystore. Output: %v %v", result.stdout, result.stderr))
 		}
+	}
 
-		// Change the permisions on the keystore
+	// Change the permisions on the keystore
+	if !dryRunEnabled {
 		err := os.Chmod(keyStorePathFinal, 0600)
 		if err != nil {
 			errorMsg := fmt.Sprintf(utils.MFT_FAILED_PERMISSION_KEYSTORE, keyStorePathFinal, err)
@@ -113,7 +149,8 @@ func CreateKeyStore(keyStoreDir string, keyStoreFile string, certFilePath string
 	return nil
 }
 
-// Generates a random 12 character password from the characters a-z, A-Z, 0-9
+// Generates a random 12 character password from the characters a-z, A-Z, 0-9.
+// The password is registered as a secret so that it is never displayed.
 func generateRandomPassword() string {
 	rand.Seed(time.Now().Unix())
 	validChars := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
@@ -122,10 +159,67 @@ func generateRandomPassword() string {
 	for i := 0; i < 12; i++ {
 		password = password + string(validcharArray[rand.Intn(len(validcharArray))])
 	}
-
+	utils.AddSecretValue(password)
 	return password
 }
 
+// A store built for a FTPS server and its password
+type ftpsStore struct {
+	path     string
+	password string
+}
+
+// Build trust stores and keystores of FTPS servers from certificates mounted
+// in a directory named after each server under certPath. Stores are not built
+// for servers that name a trust store or keystore file in the configuration.
+// The servers are updated to use the stores that are built. Returns the stores
+// and false if any of them could not be built.
+func createFTPSStores(servers []*config.ProtocolServer, certPath string) ([]ftpsStore, bool) {
+	var stores []ftpsStore
+	created := true
+	for _, server := range servers {
+		if !strings.EqualFold(server.Type, "FTPS") {
+			continue
+		}
+		serverCertPath := filepath.Join(certPath, server.Name)
+		password := ""
+
+		publicKeyFile := getKeyFile(serverCertPath, ".crt")
+		if len(server.TrustStoreFile) == 0 && len(publicKeyFile) > 0 {
+			password = generateRandomPassword()
+			trustStore := FTPS_SERVER_STORE_PREFIX + server.Name + "truststore.p12"
+			errCreateKeyStore := CreateKeyStore(KEYSTORES_PATH, trustStore, publicKeyFile, password)
+			if errCreateKeyStore == nil {
+				server.TrustStoreFile = filepath.Join(KEYSTORES_PATH, trustStore)
+				server.TrustStoreType = "pkcs12"
+				stores = append(stores, ftpsStore{path: server.TrustStoreFile, password: password})
+			} else {
+				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_KEYSTORE_CREATE_FAILED, trustStore, errCreateKeyStore.Error()))
+				created = false
+			}
+		}
+
+		// Private key for client authentication
+		privateKeyCertPath := getKeyFile(serverCertPath, ".key")
+		if len(server.KeyStoreFile) == 0 && len(privateKeyCertPath) > 0 {
+			if len(password) == 0 {
+				password = generateRandomPassword()
+			}
+			keyStore := FTPS_SERVER_STORE_PREFIX + server.Name + "keystore.p12"
+			errCreateSslStore := CreateKeyStore(KEYSTORES_PATH, keyStore, privateKeyCertPath, password)
+			if errCreateSslStore == nil {
+				server.KeyStoreFile = filepath.Join(KEYSTORES_PATH, keyStore)
+				server.KeyStoreType = "pkcs12"
+				stores = append(stores, ftpsStore{path: server.KeyStoreFile, password: password})
+			} else {
+				utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_KEYSTORE_CREATE_FAILED, keyStore, errCreateSslStore.Error()))
+				created = false
+			}
+		}
+	}
+	return stores, created
+}
+
 // Search the specified directory for certificate files
 func getKeyFile(keysDir string, fileType string) string {
 	fileList, err := os.ReadDir(keysDir)
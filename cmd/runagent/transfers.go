@@ -0,0 +1,529 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+/*
+* Transfer templates and scheduled transfers declared in the templates and
+* schedules sections of an agent. Once the agent is ready, they are compared
+* with the output of fteListTemplates and fteListScheduledTransfers. Missing
+* ones are created, changed ones are replaced and those of the agent that are
+* not declared are deleted. Commands are run with their arguments as is, so
+* file names need no quoting.
+*
+* Scheduled transfers have no name, only an identifier assigned by the agent.
+* The identifier of each scheduled transfer created is found by listing the
+* scheduled transfers of the agent and is kept in the provisioning state.
+ */
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Supported values of transfer attributes
+var transferDestinationTypes = []string{"directory", "file", "queue"}
+var transferChecksums = []string{"MD5", "none"}
+var transferSourceDispositions = []string{"leave", "delete"}
+var transferModes = []string{"binary", "text"}
+var scheduleTimeBases = []string{"admin", "source", "UTC"}
+var scheduleRepeatIntervals = []string{"minutes", "hours", "days", "weeks", "months", "years"}
+
+// Format of start and end times of scheduled transfers
+const SCHEDULE_TIME_FORMAT = "2006-01-02T15:04"
+
+// Number of times, a second apart, scheduled transfers are listed to find
+// the identifiers of those that have been created
+const SCHEDULE_LIST_ATTEMPTS = 10
+
+// Replaced by tests
+var scheduleListSleep = time.Sleep
+
+// Name of the provisioning section of a transfer template
+func templateSectionName(agentName string, templateName string) string {
+	return "template " + agentName + "/" + templateName
+}
+
+// Name of the provisioning section of a scheduled transfer
+func scheduleSectionName(agentName string, scheduleName string) string {
+	return "schedule " + agentName + "/" + scheduleName
+}
+
+// Source agent and queue manager of a transfer. The agent that declares the
+// transfer is the source agent unless another one is named.
+func transferSource(agentConfiguration *config.Configuration, agent *config.Agent, spec *config.TransferSpec) (string, string) {
+	if len(spec.SourceAgent) == 0 || strings.EqualFold(spec.SourceAgent, agent.Name) {
+		return agent.Name, agentQMgrName(agentConfiguration, agent.Name, spec.SourceQMgr, agent.QMgrName)
+	}
+	return spec.SourceAgent, agentQMgrName(agentConfiguration, spec.SourceAgent, spec.SourceQMgr, agent.QMgrName)
+}
+
+// Arguments of fteCreateTemplate and fteCreateTransfer describing the agents
+// and the files transferred. Source files are the last arguments.
+func transferArgs(spec *config.TransferSpec, sourceAgent string, sourceQMgr string, destinationQMgr string) []string {
+	args := []string{"-sa", sourceAgent, "-sm", sourceQMgr, "-da", spec.DestinationAgent, "-dm", destinationQMgr}
+	if len(spec.Mode) > 0 {
+		args = append(args, "-t", strings.ToLower(spec.Mode))
+	}
+	if len(spec.Checksum) > 0 {
+		checksum := "none"
+		if strings.EqualFold(spec.Checksum, "MD5") {
+			checksum = "MD5"
+		}
+		args = append(args, "-cs", checksum)
+	}
+	if spec.Overwrite != nil {
+		exist := "error"
+		if bool(*spec.Overwrite) {
+			exist = "overwrite"
+		}
+		args = append(args, "-de", exist)
+	}
+	if len(spec.SourceDisposition) > 0 {
+		args = append(args, "-sd", strings.ToLower(spec.SourceDisposition))
+	}
+	if spec.Recursive != nil && bool(*spec.Recursive) {
+		args = append(args, "-r")
+	}
+	if spec.Priority != nil {
+		args = append(args, "-pr", spec.Priority.String())
+	}
+	if len(spec.JobName) > 0 {
+		args = append(args, "-jn", spec.JobName)
+	}
+	switch strings.ToLower(spec.DestinationType) {
+	case "file":
+		args = append(args, "-df", spec.DestinationPath)
+	case "queue":
+		args = append(args, "-dq", spec.DestinationPath)
+	default:
+		args = append(args, "-dd", spec.DestinationPath)
+	}
+	return append(args, spec.SourceFiles...)
+}
+
+// Arguments of fteCreateTemplate for a template
+func templateArgs(coordinationQMgr string, template *config.Template, sourceAgent string, sourceQMgr string, destinationQMgr string) []string {
+	args := []string{"-p", coordinationQMgr, "-tn", template.Name}
+	return append(args, transferArgs(&template.TransferSpec, sourceAgent, sourceQMgr, destinationQMgr)...)
+}
+
+// Arguments of fteCreateTransfer for a scheduled transfer
+func scheduleArgs(coordinationQMgr string, schedule *config.Schedule, sourceAgent string, sourceQMgr string, destinationQMgr string) []string {
+	timeBase := "admin"
+	if strings.EqualFold(schedule.TimeBase, "UTC") {
+		timeBase = "UTC"
+	} else if len(schedule.TimeBase) > 0 {
+		timeBase = strings.ToLower(schedule.TimeBase)
+	}
+	args := []string{"-p", coordinationQMgr, "-tb", timeBase, "-ss", schedule.Start}
+	if schedule.Repeat != nil {
+		args = append(args, "-oi", strings.ToLower(schedule.Repeat.Interval))
+		if schedule.Repeat.Frequency != nil {
+			args = append(args, "-of", schedule.Repeat.Frequency.String())
+		}
+		if schedule.Repeat.Count != nil {
+			args = append(args, "-oc", schedule.Repeat.Count.String())
+		} else if len(schedule.Repeat.End) > 0 {
+			args = append(args, "-oe", schedule.Repeat.End)
+		}
+	}
+	return append(args, transferArgs(&schedule.TransferSpec, sourceAgent, sourceQMgr, destinationQMgr)...)
+}
+
+// Validate the transfer templates of an agent
+func validateTemplates(agent *config.Agent) error {
+	names := make(map[string]bool)
+	for index, template := range agent.Templates {
+		if len(strings.TrimSpace(template.Name)) == 0 {
+			return fmt.Errorf("templates[%d] is not valid, name must be specified", index)
+		}
+		if names[template.Name] {
+			return fmt.Errorf("templates[%d] is not valid, template %s is defined more than once", index, template.Name)
+		}
+		names[template.Name] = true
+		if err := validateTransferSpec(&template.TransferSpec); err != nil {
+			return fmt.Errorf("templates[%d] is not valid, %v", index, err)
+		}
+	}
+	return nil
+}
+
+// Validate the scheduled transfers of an agent
+func validateSchedules(agent *config.Agent) error {
+	names := make(map[string]bool)
+	for index, schedule := range agent.Schedules {
+		if err := validateSchedule(agent, schedule); err != nil {
+			return fmt.Errorf("schedules[%d] is not valid, %v", index, err)
+		}
+		if names[schedule.Name] {
+			return fmt.Errorf("schedules[%d] is not valid, schedule %s is defined more than once", index, schedule.Name)
+		}
+		names[schedule.Name] = true
+	}
+	return nil
+}
+
+// Validate a scheduled transfer
+func validateSchedule(agent *config.Agent, schedule *config.Schedule) error {
+	if len(strings.TrimSpace(schedule.Name)) == 0 {
+		return fmt.Errorf("name must be specified")
+	}
+	if len(schedule.SourceAgent) > 0 && !strings.EqualFold(schedule.SourceAgent, agent.Name) {
+		return fmt.Errorf("sourceAgent %s must be agent %s that runs the schedule", schedule.SourceAgent, agent.Name)
+	}
+	if _, err := time.Parse(SCHEDULE_TIME_FORMAT, schedule.Start); err != nil {
+		return fmt.Errorf("start %q is not in the format yyyy-MM-ddThh:mm", schedule.Start)
+	}
+	if len(schedule.TimeBase) > 0 && !isEnumValue(scheduleTimeBases, schedule.TimeBase) {
+		return fmt.Errorf("timeBase %q is not one of %s", schedule.TimeBase, strings.Join(scheduleTimeBases, ", "))
+	}
+	if repeat := schedule.Repeat; repeat != nil {
+		if !isEnumValue(scheduleRepeatIntervals, repeat.Interval) {
+			return fmt.Errorf("repeat interval %q is not one of %s", repeat.Interval, strings.Join(scheduleRepeatIntervals, ", "))
+		}
+		if err := validateMonitorNumber("repeat frequency", repeat.Frequency, 1); err != nil {
+			return err
+		}
+		if err := validateMonitorNumber("repeat count", repeat.Count, 1); err != nil {
+			return err
+		}
+		if repeat.Count != nil && len(repeat.End) > 0 {
+			return fmt.Errorf("repeat count and end must not both be specified")
+		}
+		if len(repeat.End) > 0 {
+			if _, err := time.Parse(SCHEDULE_TIME_FORMAT, repeat.End); err != nil {
+				return fmt.Errorf("repeat end %q is not in the format yyyy-MM-ddThh:mm", repeat.End)
+			}
+		}
+	}
+	return validateTransferSpec(&schedule.TransferSpec)
+}
+
+// Validate the files transferred by a template or a scheduled transfer
+func validateTransferSpec(spec *config.TransferSpec) error {
+	if len(strings.TrimSpace(spec.DestinationAgent)) == 0 {
+		return fmt.Errorf("destinationAgent must be specified")
+	}
+	if len(spec.SourceFiles) == 0 {
+		return fmt.Errorf("sourceFiles must be specified")
+	}
+	for _, sourceFile := range spec.SourceFiles {
+		if len(strings.TrimSpace(sourceFile)) == 0 {
+			return fmt.Errorf("sourceFiles must not contain blank file names")
+		}
+	}
+	if len(strings.TrimSpace(spec.DestinationPath)) == 0 {
+		return fmt.Errorf("destinationPath must be specified")
+	}
+	if spec.Priority != nil && (int(*spec.Priority) < 0 || int(*spec.Priority) > 9) {
+		return fmt.Errorf("priority has value %d which is not within range 0 to 9", int(*spec.Priority))
+	}
+	enums := []struct {
+		name    string
+		value   string
+		allowed []string
+	}{
+		{"destinationType", spec.DestinationType, transferDestinationTypes},
+		{"checksum", spec.Checksum, transferChecksums},
+		{"sourceDisposition", spec.SourceDisposition, transferSourceDispositions},
+		{"mode", spec.Mode, transferModes},
+	}
+	for _, enum := range enums {
+		if len(enum.value) > 0 && !isEnumValue(enum.allowed, enum.value) {
+			return fmt.Errorf("%s %q is not one of %s", enum.name, enum.value, strings.Join(enum.allowed, ", "))
+		}
+	}
+	return nil
+}
+
+// Value of a "Name: value" line of list command output
+func listValue(line string, label string) (string, bool) {
+	if !strings.HasPrefix(line, label) {
+		return TEXT_BLANK, false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, label)), true
+}
+
+// Templates in the output of fteListTemplates -v, mapped to the name of
+// their source agent. The source agent is blank if it is not listed.
+func parseTemplateList(output string) map[string]string {
+	templates := make(map[string]string)
+	current := TEXT_BLANK
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if name, found := listValue(line, "Template Name:"); found {
+			current = name
+			templates[current] = TEXT_BLANK
+		} else if sourceAgent, found := listValue(line, "Source Agent Name:"); found && len(current) > 0 {
+			templates[current] = sourceAgent
+		}
+	}
+	return templates
+}
+
+// Identifiers of the scheduled transfers of an agent in the output of
+// fteListScheduledTransfers
+func parseScheduleList(agentName string, output string) []string {
+	var ids []string
+	current := TEXT_BLANK
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if id, found := listValue(line, "Schedule Identifier:"); found {
+			current = id
+		} else if sourceAgent, found := listValue(line, "Source Agent Name:"); found && len(current) > 0 {
+			if strings.EqualFold(sourceAgent, agentName) {
+				ids = append(ids, current)
+			}
+			current = TEXT_BLANK
+		}
+	}
+	return ids
+}
+
+// Run an fte command, logging its output. Returns false if it failed.
+func runReconcileCommand(name string, args ...string) (*commandResult, bool) {
+	result := runFteCommand(name, args...)
+	if result.failed() {
+		result.logFailure()
+		return result, false
+	}
+	if logLevel >= LOG_LEVEL_VERBOSE {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_CMD_INFO_0043, result.stdout))
+	}
+	return result, true
+}
+
+// Create, replace and delete transfer templates so that templates whose
+// source agent is the given agent are exactly those declared.
+func reconcileTemplates(agentConfiguration *config.Configuration, agent *config.Agent, coordinationQMgr string, provisioning *provisionState) {
+	if agent.Templates == nil {
+		return
+	}
+	result, listed := runReconcileCommand("fteListTemplates", "-p", coordinationQMgr, "-v")
+	if !listed {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_TEMPLATE_LIST_FAILED_0134, agent.Name))
+		return
+	}
+	existing := parseTemplateList(result.stdout)
+
+	// Templates declared by any agent are kept
+	declared := make(map[string]bool)
+	for _, configuredAgent := range agentConfiguration.Agents {
+		for _, template := range configuredAgent.Templates {
+			declared[template.Name] = true
+		}
+	}
+
+	created, replaced, deleted, unchanged, failed := 0, 0, 0, 0, 0
+	for _, template := range agent.Templates {
+		sourceAgent, sourceQMgr := transferSource(agentConfiguration, agent, &template.TransferSpec)
+		destinationQMgr := agentQMgrName(agentConfiguration, template.DestinationAgent, template.DestinationQMgr, agent.QMgrName)
+		sectionName := templateSectionName(agent.Name, template.Name)
+		section := provisioning.transferSection(template, sourceQMgr, destinationQMgr)
+		_, exists := existing[template.Name]
+		if exists && provisioning.isProvisioned(sectionName, section) {
+			unchanged++
+			continue
+		}
+		if exists {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_TEMPLATE_REPLACE_0137, template.Name))
+			if _, ok := runReconcileCommand("fteDeleteTemplates", "-p", coordinationQMgr, template.Name); !ok {
+				failed++
+				continue
+			}
+		} else {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_TEMPLATE_CREATE_0136, template.Name))
+		}
+		if _, ok := runReconcileCommand("fteCreateTemplate", templateArgs(coordinationQMgr, template, sourceAgent, sourceQMgr, destinationQMgr)...); !ok {
+			provisioning.forget(sectionName)
+			failed++
+			continue
+		}
+		provisioning.provisioned(sectionName, section, nil)
+		if exists {
+			replaced++
+		} else {
+			created++
+		}
+	}
+
+	names := make([]string, 0, len(existing))
+	for name := range existing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if declared[name] || !strings.EqualFold(existing[name], agent.Name) {
+			continue
+		}
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_TEMPLATE_DELETE_0138, name, agent.Name))
+		if _, ok := runReconcileCommand("fteDeleteTemplates", "-p", coordinationQMgr, name); ok {
+			provisioning.forget(templateSectionName(agent.Name, name))
+			deleted++
+		} else {
+			failed++
+		}
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_TEMPLATE_RECONCILED_0143, agent.Name, created, replaced, deleted, unchanged, failed))
+}
+
+// List identifiers of the scheduled transfers of an agent
+func listScheduledTransfers(coordinationQMgr string, agentName string) ([]string, bool) {
+	result, ok := runReconcileCommand("fteListScheduledTransfers", "-p", coordinationQMgr)
+	if !ok {
+		return nil, false
+	}
+	return parseScheduleList(agentName, result.stdout), true
+}
+
+// Delete a scheduled transfer of an agent
+func deleteScheduledTransfer(coordinationQMgr string, agentName string, id string) bool {
+	_, ok := runReconcileCommand("fteDeleteScheduledTransfer", "-p", coordinationQMgr, "-sa", agentName, id)
+	return ok
+}
+
+// Scheduled transfer that has been created and whose identifier is not known
+type createdSchedule struct {
+	name        string
+	sectionName string
+	section     *provisionedSection
+}
+
+// Create, replace and delete scheduled transfers of the agent so that it has
+// exactly the scheduled transfers declared. A scheduled transfer that has
+// run all its occurrences is not created again unless its definition changes.
+func reconcileSchedules(agentConfiguration *config.Configuration, agent *config.Agent, coordinationQMgr string, provisioning *provisionState) {
+	if agent.Schedules == nil {
+		return
+	}
+	existingIds, listed := listScheduledTransfers(coordinationQMgr, agent.Name)
+	if !listed {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_SCHEDULE_LIST_FAILED_0135, agent.Name))
+		return
+	}
+	existing := make(map[string]bool, len(existingIds))
+	for _, id := range existingIds {
+		existing[id] = true
+	}
+
+	created, replaced, deleted, unchanged, failed := 0, 0, 0, 0, 0
+	owned := make(map[string]bool)
+	var pending []*createdSchedule
+	for _, schedule := range agent.Schedules {
+		sourceAgent, sourceQMgr := transferSource(agentConfiguration, agent, &schedule.TransferSpec)
+		destinationQMgr := agentQMgrName(agentConfiguration, schedule.DestinationAgent, schedule.DestinationQMgr, agent.QMgrName)
+		sectionName := scheduleSectionName(agent.Name, schedule.Name)
+		section := provisioning.transferSection(schedule, sourceQMgr, destinationQMgr)
+		previousId := TEXT_BLANK
+		if previous, found := provisioning.Sections[sectionName]; found {
+			previousId = previous.Id
+		}
+		if len(previousId) > 0 && provisioning.isProvisioned(sectionName, section) {
+			// Kept even if it is no longer listed as it has run all its occurrences
+			owned[previousId] = true
+			unchanged++
+			continue
+		}
+		replace := existing[previousId]
+		if replace {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_SCHEDULE_REPLACE_0140, schedule.Name, agent.Name, previousId))
+			// Not deleted again as a scheduled transfer that is not declared
+			owned[previousId] = true
+			if !deleteScheduledTransfer(coordinationQMgr, agent.Name, previousId) {
+				failed++
+				continue
+			}
+		} else {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_SCHEDULE_CREATE_0139, schedule.Name, agent.Name))
+		}
+		provisioning.forget(sectionName)
+		if _, ok := runReconcileCommand("fteCreateTransfer", scheduleArgs(coordinationQMgr, schedule, sourceAgent, sourceQMgr, destinationQMgr)...); !ok {
+			failed++
+			continue
+		}
+		if replace {
+			replaced++
+		} else {
+			created++
+		}
+		pending = append(pending, &createdSchedule{name: schedule.Name, sectionName: sectionName, section: section})
+	}
+
+	for _, id := range existingIds {
+		if owned[id] {
+			continue
+		}
+		utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_SCHEDULE_DELETE_0141, id, agent.Name))
+		if deleteScheduledTransfer(coordinationQMgr, agent.Name, id) {
+			deleted++
+		} else {
+			failed++
+		}
+	}
+
+	recordScheduleIds(coordinationQMgr, agent.Name, existingIds, pending, provisioning)
+	utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_SCHEDULE_RECONCILED_0144, agent.Name, created, replaced, deleted, unchanged, failed))
+}
+
+// Find the identifiers of the scheduled transfers that have been created,
+// which are those listed that were not listed before, and record them in the
+// provisioning state. The agent assigns identifiers in ascending order, so
+// they are matched with scheduled transfers in the order they were created.
+func recordScheduleIds(coordinationQMgr string, agentName string, previousIds []string, pending []*createdSchedule, provisioning *provisionState) {
+	if len(pending) == 0 {
+		return
+	}
+	previous := make(map[string]bool, len(previousIds))
+	for _, id := range previousIds {
+		previous[id] = true
+	}
+	var newIds []string
+	for attempt := 0; attempt < SCHEDULE_LIST_ATTEMPTS && len(newIds) < len(pending); attempt++ {
+		// Scheduled transfers are listed once the agent has processed the requests
+		scheduleListSleep(time.Second)
+		ids, listed := listScheduledTransfers(coordinationQMgr, agentName)
+		if !listed {
+			continue
+		}
+		newIds = nil
+		for _, id := range ids {
+			if !previous[id] {
+				newIds = append(newIds, id)
+			}
+		}
+	}
+	sort.Slice(newIds, func(i, j int) bool {
+		first, firstErr := strconv.Atoi(newIds[i])
+		second, secondErr := strconv.Atoi(newIds[j])
+		if firstErr != nil || secondErr != nil {
+			return newIds[i] < newIds[j]
+		}
+		return first < second
+	})
+	for index, schedule := range pending {
+		if index >= len(newIds) {
+			utils.PrintLog(fmt.Sprintf(utils.MFT_CONT_SCHEDULE_ID_MISSING_0142, schedule.name, agentName))
+			continue
+		}
+		schedule.section.Id = newIds[index]
+		provisioning.provisioned(schedule.sectionName, schedule.section, nil)
+	}
+}
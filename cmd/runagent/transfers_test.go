@@ -0,0 +1,222 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/config"
+)
+
+// Runner returning, for each command, the next of the outputs given for it
+type scriptedCommandRunner struct {
+	commands []string
+	outputs  map[string][]string
+}
+
+func (runner *scriptedCommandRunner) run(cmd *command) *commandResult {
+	runner.commands = append(runner.commands, cmd.name+" "+strings.Join(cmd.args, " "))
+	result := &commandResult{}
+	if outputs := runner.outputs[cmd.name]; len(outputs) > 0 {
+		result.stdout = outputs[0]
+		runner.outputs[cmd.name] = outputs[1:]
+	}
+	return result
+}
+
+func TestTransferCommandArgs(t *testing.T) {
+	configuration := loadTestConfig(t, "test_transfers.json")
+	agent := configuration.Agent("SRC")
+	tests := []struct {
+		args     []string
+		expected []string
+	}{
+		{templateArgs("QMC", agent.Templates[0], "SRC", "QM1", "QM2"),
+			[]string{"-p", "QMC", "-tn", "T1", "-sa", "SRC", "-sm", "QM1", "-da", "DEST", "-dm", "QM2", "-dd", "/mountpath/out", "/mountpath/in/*.csv"}},
+		// File names with blanks are passed as a single argument
+		{templateArgs("QMC", agent.Templates[1], "SRC", "QM1", "QM2"),
+			[]string{"-p", "QMC", "-tn", "T2", "-sa", "SRC", "-sm", "QM1", "-da", "DEST", "-dm", "QM2", "-t", "text", "-cs", "none",
+				"-de", "overwrite", "-sd", "delete", "-r", "-pr", "5", "-jn", "daily", "-df", "/mountpath/out/b.txt", "/mountpath/in/a file.txt"}},
+		{scheduleArgs("QMC", agent.Schedules[0], "SRC", "QM1", "QM2"),
+			[]string{"-p", "QMC", "-tb", "UTC", "-ss", "2024-10-01T23:00", "-oi", "days", "-of", "1", "-oc", "30",
+				"-sa", "SRC", "-sm", "QM1", "-da", "DEST", "-dm", "QM2", "-dd", "/mountpath/out", "/mountpath/in/*"}},
+		{scheduleArgs("QMC", agent.Schedules[1], "SRC", "QM1", "QM2"),
+			[]string{"-p", "QMC", "-tb", "admin", "-ss", "2024-10-01T08:00", "-oi", "hours", "-oe", "2024-12-31T00:00",
+				"-sa", "SRC", "-sm", "QM1", "-da", "DEST", "-dm", "QM2", "-dd", "/mountpath/logs", "/mountpath/in/*.log"}},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.args, test.expected) {
+			t.Errorf("Unexpected arguments %q, expected %q", test.args, test.expected)
+		}
+	}
+
+	sourceAgent, sourceQMgr := transferSource(configuration, agent, &agent.Templates[2].TransferSpec)
+	if sourceAgent != "OTHER" || sourceQMgr != "QM9" {
+		t.Errorf("Unexpected source %s %s", sourceAgent, sourceQMgr)
+	}
+	if destinationQMgr := agentQMgrName(configuration, "DEST", "", "QM1"); destinationQMgr != "QM2" {
+		t.Errorf("Unexpected destination queue manager %s", destinationQMgr)
+	}
+}
+
+func TestValidateTemplatesAndSchedules(t *testing.T) {
+	agent := loadTestConfig(t, "test_transfers.json").Agent("SRC")
+	if err := validateTemplates(agent); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := validateSchedules(agent); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	spec := config.TransferSpec{DestinationAgent: "DEST", SourceFiles: []string{"/in"}, DestinationPath: "/out"}
+	count := config.Int(3)
+	tests := []struct {
+		schedule *config.Schedule
+		err      string
+	}{
+		{&config.Schedule{Name: "S", Start: "2024-10-01 08:00", TransferSpec: spec},
+			"schedules[0] is not valid, start \"2024-10-01 08:00\" is not in the format yyyy-MM-ddThh:mm"},
+		{&config.Schedule{Name: "S", Start: "2024-10-01T08:00", Repeat: &config.Repeat{Interval: "seconds"}, TransferSpec: spec},
+			"schedules[0] is not valid, repeat interval \"seconds\" is not one of minutes, hours, days, weeks, months, years"},
+		{&config.Schedule{Name: "S", Start: "2024-10-01T08:00", Repeat: &config.Repeat{Interval: "days", Count: &count, End: "2024-10-09T08:00"}, TransferSpec: spec},
+			"schedules[0] is not valid, repeat count and end must not both be specified"},
+		{&config.Schedule{Name: "S", Start: "2024-10-01T08:00", TransferSpec: config.TransferSpec{SourceAgent: "OTHER", DestinationAgent: "DEST", SourceFiles: []string{"/in"}, DestinationPath: "/out"}},
+			"schedules[0] is not valid, sourceAgent OTHER must be agent SRC that runs the schedule"},
+		{&config.Schedule{Name: "S", Start: "2024-10-01T08:00", TransferSpec: config.TransferSpec{DestinationAgent: "DEST", DestinationPath: "/out"}},
+			"schedules[0] is not valid, sourceFiles must be specified"},
+	}
+	for _, test := range tests {
+		err := validateSchedules(&config.Agent{Name: "SRC", Schedules: []*config.Schedule{test.schedule}})
+		if err == nil || err.Error() != test.err {
+			t.Errorf("Expected error %q, found %v", test.err, err)
+		}
+	}
+	duplicate := &config.Template{Name: "T1", TransferSpec: spec}
+	if err := validateTemplates(&config.Agent{Templates: []*config.Template{duplicate, duplicate}}); err == nil {
+		t.Error("Duplicate template not reported")
+	}
+}
+
+func TestParseTemplateAndScheduleLists(t *testing.T) {
+	templates := parseTemplateList("Template Name:  T1\n  Source Agent Name:  SRC\n  Destination Agent Name:  DEST\n" +
+		"Template Name:  T2\nTemplate Name:  T3\n  Source Agent Name:  OTHER\n")
+	if !reflect.DeepEqual(templates, map[string]string{"T1": "SRC", "T2": "", "T3": "OTHER"}) {
+		t.Errorf("Unexpected templates %v", templates)
+	}
+	ids := parseScheduleList("src", "Schedule Identifier:  3\nSource Agent Name:  SRC\n  Source File Name:  /in/*\n"+
+		"Schedule Identifier:  4\nSource Agent Name:  OTHER\nSchedule Identifier:  12\nSource Agent Name:  SRC\n")
+	if !reflect.DeepEqual(ids, []string{"3", "12"}) {
+		t.Errorf("Unexpected schedule identifiers %v", ids)
+	}
+}
+
+func newTransfersTestState(t *testing.T) *provisionState {
+	t.Helper()
+	bfgDataPath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(bfgDataPath, "mqft"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return loadProvisionState(bfgDataPath, false)
+}
+
+func TestReconcileTemplates(t *testing.T) {
+	configuration := loadTestConfig(t, "test_transfers.json")
+	agent := configuration.Agent("SRC")
+	provisioning := newTransfersTestState(t)
+	provisioning.provisioned(templateSectionName("SRC", "T1"), provisioning.transferSection(agent.Templates[0], "QM1", "QM2"), nil)
+	fake := &scriptedCommandRunner{outputs: map[string][]string{"fteListTemplates": {
+		"Template Name: T1\nSource Agent Name: SRC\nTemplate Name: T2\nSource Agent Name: SRC\n" +
+			"Template Name: OLD\nSource Agent Name: SRC\nTemplate Name: KEEP\nSource Agent Name: OTHER\n"}}}
+	runner = fake
+	defer func() { runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second) }()
+
+	reconcileTemplates(configuration, agent, "QMC", provisioning)
+	expected := []string{
+		"fteListTemplates -p QMC -v",
+		"fteDeleteTemplates -p QMC T2",
+		"fteCreateTemplate -p QMC -tn T2 -sa SRC -sm QM1 -da DEST -dm QM2 -t text -cs none -de overwrite -sd delete -r -pr 5 -jn daily -df /mountpath/out/b.txt /mountpath/in/a file.txt",
+		"fteCreateTemplate -p QMC -tn T3 -sa OTHER -sm QM9 -da DEST -dm QM8 -dd /out /in",
+		"fteDeleteTemplates -p QMC OLD",
+	}
+	if !reflect.DeepEqual(fake.commands, expected) {
+		t.Errorf("Unexpected commands run:\n%s", strings.Join(fake.commands, "\n"))
+	}
+	if _, found := provisioning.Sections[templateSectionName("SRC", "T3")]; !found {
+		t.Error("Created template not recorded")
+	}
+}
+
+func TestReconcileSchedules(t *testing.T) {
+	scheduleListSleep = func(time.Duration) {}
+	defer func() { scheduleListSleep = time.Sleep }()
+	configuration := loadTestConfig(t, "test_transfers.json")
+	agent := configuration.Agent("SRC")
+	provisioning := newTransfersTestState(t)
+
+	// NIGHTLY is unchanged, HOURLY has changed and ONCE has not been created.
+	// Scheduled transfer 7 is not declared.
+	nightly := provisioning.transferSection(agent.Schedules[0], "QM1", "QM2")
+	nightly.Id = "3"
+	provisioning.provisioned(scheduleSectionName("SRC", "NIGHTLY"), nightly, nil)
+	hourly := provisioning.transferSection(agent.Schedules[0], "QM1", "QM2")
+	hourly.Id = "4"
+	provisioning.provisioned(scheduleSectionName("SRC", "HOURLY"), hourly, nil)
+	listed := func(ids ...string) string {
+		var output strings.Builder
+		for _, id := range ids {
+			output.WriteString("Schedule Identifier: " + id + "\nSource Agent Name: SRC\n")
+		}
+		return output.String()
+	}
+	fake := &scriptedCommandRunner{outputs: map[string][]string{"fteListScheduledTransfers": {
+		listed("3", "4", "7"), listed("3"), listed("3", "8"), listed("3", "9", "8")}}}
+	runner = fake
+	defer func() { runner = newExecCommandRunner(context.Background(), COMMAND_DEFAULT_TIMEOUT*time.Second) }()
+
+	reconcileSchedules(configuration, agent, "QMC", provisioning)
+	expected := []string{
+		"fteListScheduledTransfers -p QMC",
+		"fteDeleteScheduledTransfer -p QMC -sa SRC 4",
+		"fteCreateTransfer -p QMC -tb admin -ss 2024-10-01T08:00 -oi hours -oe 2024-12-31T00:00 -sa SRC -sm QM1 -da DEST -dm QM2 -dd /mountpath/logs /mountpath/in/*.log",
+		"fteCreateTransfer -p QMC -tb source -ss 2024-10-02T08:00 -sa SRC -sm QM1 -da DEST -dm QM2 -dd /mountpath/once /mountpath/in/once",
+		"fteDeleteScheduledTransfer -p QMC -sa SRC 7",
+		"fteListScheduledTransfers -p QMC",
+		"fteListScheduledTransfers -p QMC",
+		"fteListScheduledTransfers -p QMC",
+	}
+	if !reflect.DeepEqual(fake.commands, expected) {
+		t.Errorf("Unexpected commands run:\n%s", strings.Join(fake.commands, "\n"))
+	}
+	for name, id := range map[string]string{"NIGHTLY": "3", "HOURLY": "8", "ONCE": "9"} {
+		if section := provisioning.Sections[scheduleSectionName("SRC", name)]; section == nil || section.Id != id {
+			t.Errorf("Schedule %s not recorded with identifier %s: %+v", name, id, section)
+		}
+	}
+
+	// NIGHTLY has run all its occurrences and is not created again
+	fake.commands = nil
+	fake.outputs["fteListScheduledTransfers"] = []string{listed("8", "9")}
+	reconcileSchedules(configuration, agent, "QMC", provisioning)
+	if len(fake.commands) != 1 {
+		t.Errorf("Unexpected commands run:\n%s", strings.Join(fake.commands, "\n"))
+	}
+}
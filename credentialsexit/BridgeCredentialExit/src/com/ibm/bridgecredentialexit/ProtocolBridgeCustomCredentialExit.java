@@ -32,7 +32,6 @@ import java.util.Map;
 import java.util.Properties;
 import java.util.Set;
 import java.util.StringTokenizer;
-import java.util.regex.Matcher;
 import java.util.regex.Pattern;
 
 import com.ibm.wmqfte.exitroutine.api.CredentialExitResult;
@@ -74,8 +73,10 @@ public class ProtocolBridgeCustomCredentialExit implements ProtocolBridgeCredent
 		}
 	}
 
-	// The map that holds mq user ID to serverUserId and serverPassword mappings
-	final private Map<String, CredentialsExt> credentialsMap = new HashMap<String, CredentialsExt>();
+	// The map that holds server host names to serverUserId and serverPassword
+	// mappings. A server can have credentials for several transfer requesters,
+	// kept in the order they were read.
+	final private Map<String, List<CredentialsExt>> credentialsMap = new HashMap<String, List<CredentialsExt>>();
 	final private int ENCODED_PLAIN_TEXT = 0;
 	final private int ENCODED_BASE64 = 1;
 	private boolean enableDebugLogs = false;
@@ -257,9 +258,8 @@ public class ProtocolBridgeCustomCredentialExit implements ProtocolBridgeCredent
 		if (serverType != null) {
 			if (serverType.equalsIgnoreCase("SFTP")) {
 				parsed = processV2SFTPCredentials(jsonObj);
-			} else if (serverType.equalsIgnoreCase("FTPS")) {
-
-			} else if (serverType.equalsIgnoreCase("FTP")) {
+			} else if (serverType.equalsIgnoreCase("FTP") || serverType.equalsIgnoreCase("FTPS")) {
+				// FTPS servers use user id and password like FTP servers
 				parsed = processV2FTPCredential(jsonObj);
 			}
 		} else {
@@ -376,7 +376,7 @@ public class ProtocolBridgeCustomCredentialExit implements ProtocolBridgeCredent
 		credentials = new Credentials(new CredentialUserId(serverUserId), privateKeys,
 				new CredentialHostKey(serverHostKey));
 		// Add it to the list of credentials
-		credentialsMap.put(serverHostName, new CredentialsExt(requesterUserId, credentials));
+		addCredentials(serverHostName, new CredentialsExt(requesterUserId, credentials));
 		writeLog("Credential information for host - " + serverHostName + " processed successfully.");
 
 		return true;
@@ -418,7 +418,7 @@ public class ProtocolBridgeCustomCredentialExit implements ProtocolBridgeCredent
 				final Credentials credentials = new Credentials(new CredentialUserId(serverUserId),
 						new CredentialPassword(serverPassword));
 				// Insert the credentials into the map
-				credentialsMap.put(serverHostName, new CredentialsExt(requesterUserId, credentials));
+				addCredentials(serverHostName, new CredentialsExt(requesterUserId, credentials));
 			} catch (Exception ex) {
 				writeLog("Failed to create credentials due to exception: " + ex);
 				ex.printStackTrace();
@@ -476,7 +476,7 @@ public class ProtocolBridgeCustomCredentialExit implements ProtocolBridgeCredent
 				final Credentials credentials = new Credentials(new CredentialUserId(serverUserId),
 						new CredentialPassword(serverPassword));
 				// Insert the credentials into the map
-				credentialsMap.put(serverHostName, new CredentialsExt(".*", credentials));
+				addCredentials(serverHostName, new CredentialsExt(".*", credentials));
 				parsed = true;
 			} catch (UnsupportedEncodingException e) {
 				writeLog(e.getMessage());
@@ -488,7 +488,7 @@ public class ProtocolBridgeCustomCredentialExit implements ProtocolBridgeCredent
 			final Credentials credentials = new Credentials(new CredentialUserId(serverUserId),
 					new CredentialPassword(serverPassword));
 			// Insert the credentials into the map
-			credentialsMap.put(serverHostName, new CredentialsExt(".*", credentials));
+			addCredentials(serverHostName, new CredentialsExt(".*", credentials));
 			parsed = true;
 		} else {
 			// Unknown encoding, don't do anything.
@@ -509,7 +509,8 @@ public class ProtocolBridgeCustomCredentialExit implements ProtocolBridgeCredent
 	public CredentialExitResult mapMQUserId(String mqUserId) {
 		CredentialExitResult result = null;
 		// Attempt to get the server credentials for the given mq user id
-		final CredentialsExt credentials = credentialsMap.get(mqUserId.trim());
+		final List<CredentialsExt> credentialsList = credentialsMap.get(mqUserId.trim());
+		final CredentialsExt credentials = credentialsList == null ? null : credentialsList.get(0);
 		writeDebugLog("mapMQUserId" + mqUserId);
 		if (credentials == null) {
 			// No entry has been found so return no mapping found with no credentials
@@ -560,66 +561,102 @@ public class ProtocolBridgeCustomCredentialExit implements ProtocolBridgeCredent
 			}
 		}
 
-		// Attempt to get the server credentials for the given mq user id
-		final CredentialsExt credentials = credentialsMap.get(endPointAddress.getHost().trim());
-		if (credentials == null) {
+		// Attempt to get the server credentials for the given mq user id. Credentials
+		// of a matching requester are used before credentials of any requester.
+		final List<CredentialsExt> credentialsList = credentialsMap.get(endPointAddress.getHost().trim());
+		if (credentialsList == null) {
 			// No entry has been found so return no mapping found with no credentials
 			writeLog("Credentials for server " + endPointAddress.getHost() + " not found.");
 			result = new CredentialExitResult(CredentialExitResultCode.NO_MAPPING_FOUND, null);
 		} else {
-			writeDebugLog("Requested Uid " + credentials.getRequesterId());
-			// We may need to match the given userId. So do that now.
-			if (mqUserId != null && credentials.getRequesterId() != null) {
-				// We may have wild cards.
-				try {
-					String requesterIdCred = credentials.getRequesterId();
-					Pattern p = null;
-					Matcher m = null;
-					try {
-						requesterIdCred = requesterIdCred.replaceAll("\\*", "\\\\*");
-						p = Pattern.compile(requesterIdCred);
-						m = p.matcher(mqUserId);
-					} catch (Exception ex) {
-						writeLog("Failed to parse transfer requester user id.");
-					}
-					if (m.matches()) {
-						writeDebugLog("Matching Uid found ");
-						result = new CredentialExitResult(CredentialExitResultCode.USER_SUCCESSFULLY_MAPPED,
-								credentials.getCredential());
-					} else {
-						// If the requester id is *, then match every id that is supplied
-						writeDebugLog("Matching Uid not found. Determining credentials for generic userId *");
-						if (credentials.getRequesterId().equalsIgnoreCase("*")
-								|| credentials.getRequesterId().equalsIgnoreCase(".*")) {
-							Credentials crd = credentials.getCredential();
-							List<CredentialPrivateKey> pkey = crd.getPrivateKey();
-							String strPkey = pkey.get(0).getKey();
-							result = new CredentialExitResult(CredentialExitResultCode.USER_SUCCESSFULLY_MAPPED,
-									credentials.getCredential());
-
-							writeDebugLog("Returning credentials for a generic user *");
-						} else {
-							writeLog("Credentials for user " + mqUserId + " not found.");
-							result = new CredentialExitResult(CredentialExitResultCode.NO_MAPPING_FOUND, null);
-						}
+			CredentialsExt matched = null;
+			for (CredentialsExt credentials : credentialsList) {
+				writeDebugLog("Requested Uid " + credentials.getRequesterId());
+				if (requesterMatches(credentials, mqUserId)) {
+					writeDebugLog("Matching Uid found ");
+					matched = credentials;
+					break;
+				}
+			}
+			if (matched == null) {
+				// If the requester id is *, then match every id that is supplied
+				writeDebugLog("Matching Uid not found. Determining credentials for generic userId *");
+				for (CredentialsExt credentials : credentialsList) {
+					if (isGenericRequester(credentials)) {
+						writeDebugLog("Returning credentials for a generic user *");
+						matched = credentials;
+						break;
 					}
-				} catch (Exception ex) {
-					writeLog("An error occurred while processing credential information. The exception is: "
-							+ ex.toString());
-					result = new CredentialExitResult(CredentialExitResultCode.NO_MAPPING_FOUND, null);
 				}
-			} else {
-				// writeDebugLog("Requester id and mq userid not matching");
-				// Some credentials have been found so return success to the user along with the
-				// credentials
+			}
+			if (matched != null) {
 				result = new CredentialExitResult(CredentialExitResultCode.USER_SUCCESSFULLY_MAPPED,
-						credentials.getCredential());
+						matched.getCredential());
+			} else {
+				writeLog("Credentials for user " + mqUserId + " not found.");
+				result = new CredentialExitResult(CredentialExitResultCode.NO_MAPPING_FOUND, null);
 			}
 		}
 		writeDebugLog("mapMQUserId - exit");
 		return result;
 	}
 
+	/**
+	 * Add credentials of a server. Credentials of a server that has already been
+	 * read for the same requester are replaced.
+	 * 
+	 * @param serverHostName
+	 * @param credentials
+	 */
+	private void addCredentials(String serverHostName, CredentialsExt credentials) {
+		List<CredentialsExt> credentialsList = credentialsMap.get(serverHostName);
+		if (credentialsList == null) {
+			credentialsList = new ArrayList<CredentialsExt>();
+			credentialsMap.put(serverHostName, credentialsList);
+		}
+		for (int index = 0; index < credentialsList.size(); index++) {
+			String requesterId = credentialsList.get(index).getRequesterId();
+			if (requesterId != null && requesterId.equals(credentials.getRequesterId())) {
+				credentialsList.set(index, credentials);
+				return;
+			}
+		}
+		credentialsList.add(credentials);
+	}
+
+	/**
+	 * Determine if the requester id of credentials matches the given user id.
+	 * Requester id can be a regular expression.
+	 * 
+	 * @param credentials
+	 * @param mqUserId
+	 * @return
+	 */
+	private boolean requesterMatches(CredentialsExt credentials, String mqUserId) {
+		String requesterIdCred = credentials.getRequesterId();
+		if (mqUserId == null || requesterIdCred == null) {
+			return true;
+		}
+		try {
+			requesterIdCred = requesterIdCred.replaceAll("\\*", "\\\\*");
+			return Pattern.compile(requesterIdCred).matcher(mqUserId).matches();
+		} catch (Exception ex) {
+			writeLog("Failed to parse transfer requester user id " + credentials.getRequesterId());
+			return false;
+		}
+	}
+
+	/**
+	 * Determine if credentials apply to any requester
+	 * 
+	 * @param credentials
+	 * @return
+	 */
+	private boolean isGenericRequester(CredentialsExt credentials) {
+		String requesterId = credentials.getRequesterId();
+		return requesterId != null && (requesterId.equalsIgnoreCase("*") || requesterId.equalsIgnoreCase(".*"));
+	}
+
 	private void writeLog(String log) {
 		System.out.println("PBACUSTEXIT: " + log);
 	}
@@ -183,5 +183,28 @@ public class TestPBAExit {
 		CredentialExitResult cer = exit.mapMQUserId(pse, "nomquserid");
 		assertEquals(cer.getResultCode(), CredentialExitResultCode.NO_MAPPING_FOUND);
 	}
+
+	@Test
+	public void testMapUseridMultipleRequestersOneHost() {
+		ProtocolBridgeCustomCredentialExit exit = new ProtocolBridgeCustomCredentialExit();
+		Map <String, String> props = new HashMap<String, String>();
+		URL url = getClass().getResource("ProtocolBridgeCredsMultiRequester.json");
+		File file = new File(url.getPath());
+		props.put("protocolBridgeCredentialConfiguration", file.getAbsolutePath());
+		assertTrue(exit.initialize(props));
+		ProtocolServerEndPoint pse = new ProtocolServerEndPoint("shared", "FTP", "9.122.123.124", 21);
+
+		// Requester with its own entry gets it, the last entry of a requester wins
+		CredentialExitResult cer = exit.mapMQUserId(pse, "mquserid");
+		assertEquals(cer.getResultCode(), CredentialExitResultCode.USER_SUCCESSFULLY_MAPPED);
+		assertEquals(cer.getCredentials().getUserId().get(), "mqftp");
+		assertEquals(cer.getCredentials().getPassword().get(), "MqPassw0rd");
+
+		// Other requesters get the generic entry, even though it comes first
+		CredentialExitResult cer1 = exit.mapMQUserId(pse, "otheruser");
+		assertEquals(cer1.getResultCode(), CredentialExitResultCode.USER_SUCCESSFULLY_MAPPED);
+		assertEquals(cer1.getCredentials().getUserId().get(), "anyuser");
+		assertEquals(cer1.getCredentials().getPassword().get(), "AnyPassw0rd");
+	}
 	
 }
@@ -13,6 +13,7 @@ require (
 	github.com/syndtr/gocapability v0.0.0-20200815063812-42c35b437635
 	github.com/tidwall/gjson v1.14.1
 	github.com/tidwall/sjson v1.2.4
+	golang.org/x/crypto v0.23.0
 	golang.org/x/sys v0.20.0
 )
 
@@ -0,0 +1,125 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bfg
+
+/*
+* Catalog of messages that the container acts upon, with their meaning and
+* what can be done when they are reported.
+ */
+
+// Agent has started and is ready to process transfers
+const AGENT_STARTED = "BFGAG0059I"
+
+// Agent responded to ftePingAgent
+const AGENT_PING_RESPONDED = "BFGCL0793I"
+
+// Agent did not respond to ftePingAgent
+const AGENT_PING_NO_RESPONSE = "BFGCL0214I"
+
+// Coordination queue manager cannot be contacted
+const COORDINATION_QMGR_NOT_CONTACTED = "BFGMQ1007I"
+
+// Result of a completed transfer, in the supplement of its transfer log
+const TRANSFER_SUCCESSFUL = "BFGRP0032I"
+const TRANSFER_PARTIALLY_SUCCESSFUL = "BFGRP0033I"
+const TRANSFER_FAILED = "BFGRP0034I"
+const TRANSFER_NO_FILES_TRANSFERRED = "BFGRP0036I"
+const TRANSFER_NOT_COMPLETED = "BFGRP0037I"
+
+// Transfer log messages reporting that a transfer is being resynchronized
+// after a recovery. Severity varies between releases.
+var TRANSFER_RESYNCHRONIZING = []string{
+	"BFGTL0015", "BFGTL0017", "BFGTL0019", "BFGTL0021",
+	"BFGTL0032", "BFGTL0033", "BFGTL0034", "BFGTL0035",
+}
+
+// Messages reported by an agent that fails to start. Other errors reported
+// while the agent starts, for example about a single transfer or monitor, do
+// not stop it from becoming ready.
+var AGENT_START_FAILED = []string{
+	"BFGAG0061",
+}
+
+// Meaning of a message and the action to take when it is reported
+type CatalogEntry struct {
+	Meaning string
+	// Blank if no action is needed
+	Action string
+}
+
+// Known messages, by id without severity
+var catalog = map[string]CatalogEntry{
+	AGENT_STARTED[:BASE_ID_LENGTH]: {
+		Meaning: "The agent has started and is ready to process transfers.",
+	},
+	AGENT_PING_RESPONDED[:BASE_ID_LENGTH]: {
+		Meaning: "The agent responded to a ping request.",
+	},
+	AGENT_PING_NO_RESPONSE[:BASE_ID_LENGTH]: {
+		Meaning: "The agent did not respond to a ping request in time.",
+		Action:  "Verify that the agent is running and that it is connected to its queue manager. Agent logs are in the output0.log file of the agent.",
+	},
+	COORDINATION_QMGR_NOT_CONTACTED[:BASE_ID_LENGTH]: {
+		Meaning: "The coordination queue manager cannot be contacted or refused the connection.",
+		Action:  "Verify the host, port and channel of the coordination queue manager, that the queue manager is running and that the user is authorized to connect. The MQ reason code in the message tells the cause.",
+	},
+	AGENT_START_FAILED[0]: {
+		Meaning: "The agent ended abnormally.",
+		Action:  "Check the output0.log file of the agent for the errors reported before it ended. An FFDC file may have been written to the logs/ffdc directory of the agent.",
+	},
+	TRANSFER_SUCCESSFUL[:BASE_ID_LENGTH]: {
+		Meaning: "The transfer completed successfully.",
+	},
+	TRANSFER_PARTIALLY_SUCCESSFUL[:BASE_ID_LENGTH]: {
+		Meaning: "The transfer completed with partial success. Some files were not transferred.",
+		Action:  "Check the transfer log of the transfer for the files that failed.",
+	},
+	TRANSFER_FAILED[:BASE_ID_LENGTH]: {
+		Meaning: "The transfer failed.",
+		Action:  "Check the transfer log of the transfer for the reason of failure.",
+	},
+	TRANSFER_NO_FILES_TRANSFERRED[:BASE_ID_LENGTH]: {
+		Meaning: "The transfer completed but no files were transferred.",
+		Action:  "Verify that the source files exist and that the agent is permitted to read them.",
+	},
+	TRANSFER_NOT_COMPLETED[:BASE_ID_LENGTH]: {
+		Meaning: "The transfer failed.",
+		Action:  "Check the transfer log of the transfer for the reason of failure.",
+	},
+}
+
+func init() {
+	for _, id := range TRANSFER_RESYNCHRONIZING {
+		catalog[id] = CatalogEntry{
+			Meaning: "The transfer is being resynchronized after a recovery.",
+			Action:  "No action is needed unless the transfer does not complete.",
+		}
+	}
+}
+
+// Look up a message id, with or without severity, in the catalog
+func Lookup(id string) (CatalogEntry, bool) {
+	if len(id) < BASE_ID_LENGTH {
+		return CatalogEntry{}, false
+	}
+	entry, found := catalog[id[:BASE_ID_LENGTH]]
+	return entry, found
+}
+
+// Catalog entry of the message
+func (message Message) Lookup() (CatalogEntry, bool) {
+	return Lookup(message.Id)
+}
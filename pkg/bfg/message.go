@@ -0,0 +1,176 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bfg
+
+/*
+* Finds IBM MQ Managed File Transfer messages in command output, agent logs
+* and transfer logs. A message id such as BFGCL0793I is made of the BFG
+* prefix, a two letter component, a four digit number and a severity of I
+* (information), W (warning) or E (error).
+ */
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Severity of a message, the last character of its id
+type Severity string
+
+const SEVERITY_INFO Severity = "I"
+const SEVERITY_WARNING Severity = "W"
+const SEVERITY_ERROR Severity = "E"
+
+// Length of a message id without the severity, for example BFGCL0793
+const BASE_ID_LENGTH = 9
+
+// Message ids, with or without severity
+var messageIdRegex = regexp.MustCompile(`\bBFG([A-Z]{2})(\d{4})([IWE]?)\b`)
+
+// Names of components that report messages
+var componentNames = map[string]string{
+	"AG": "agent",
+	"CL": "command",
+	"DB": "database logger",
+	"IO": "file system",
+	"MQ": "queue manager connection",
+	"PR": "protocol bridge",
+	"RP": "transfer report",
+	"TL": "transfer log",
+}
+
+// A message found in text
+type Message struct {
+	// Full id, for example BFGCL0793I. Severity is blank if the id was
+	// found without one.
+	Id        string
+	Component string
+	Number    int
+	Severity  Severity
+	// Text following the id up to the end of the line
+	Text string
+}
+
+// Find all messages in the text, in order of appearance
+func Parse(text string) []Message {
+	var messages []Message
+	for _, match := range messageIdRegex.FindAllStringSubmatchIndex(text, -1) {
+		number, _ := strconv.Atoi(text[match[4]:match[5]])
+		message := Message{
+			Id:        text[match[0]:match[1]],
+			Component: text[match[2]:match[3]],
+			Number:    number,
+			Severity:  Severity(text[match[6]:match[7]]),
+		}
+		lineEnd := strings.IndexByte(text[match[1]:], '\n')
+		if lineEnd < 0 {
+			lineEnd = len(text) - match[1]
+		}
+		message.Text = strings.TrimSpace(strings.TrimPrefix(text[match[1]:match[1]+lineEnd], ":"))
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// Ids of messages in the text, in order of first appearance
+func Ids(text string) []string {
+	var ids []string
+	for _, message := range Parse(text) {
+		if !containsId(ids, message.Id) {
+			ids = append(ids, message.Id)
+		}
+	}
+	return ids
+}
+
+// Determine if the text contains any of the given message ids. Ids given
+// without severity match messages of any severity.
+func Contains(text string, ids ...string) bool {
+	for _, message := range Parse(text) {
+		for _, id := range ids {
+			if Matches(message.Id, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Determine if the message id found matches the given id. Severity is
+// ignored if either id does not have one.
+func Matches(foundId string, id string) bool {
+	if foundId == id {
+		return true
+	}
+	return len(foundId) >= BASE_ID_LENGTH && len(id) >= BASE_ID_LENGTH &&
+		(len(foundId) == BASE_ID_LENGTH || len(id) == BASE_ID_LENGTH) &&
+		foundId[:BASE_ID_LENGTH] == id[:BASE_ID_LENGTH]
+}
+
+// Highest severity of messages in the text. Blank if there are none.
+func HighestSeverity(text string) Severity {
+	var highest Severity
+	for _, message := range Parse(text) {
+		if severityRank(message.Severity) > severityRank(highest) {
+			highest = message.Severity
+		}
+	}
+	return highest
+}
+
+// Name of the component that reported the message
+func (message Message) ComponentName() string {
+	if name, found := componentNames[message.Component]; found {
+		return name
+	}
+	return message.Component
+}
+
+// Id without severity, for example BFGCL0793
+func (message Message) BaseId() string {
+	return message.Id[:BASE_ID_LENGTH]
+}
+
+// Determine if the message reports an error
+func (message Message) IsError() bool {
+	return message.Severity == SEVERITY_ERROR
+}
+
+// Determine if the message reports a warning
+func (message Message) IsWarning() bool {
+	return message.Severity == SEVERITY_WARNING
+}
+
+func severityRank(severity Severity) int {
+	switch severity {
+	case SEVERITY_ERROR:
+		return 3
+	case SEVERITY_WARNING:
+		return 2
+	case SEVERITY_INFO:
+		return 1
+	}
+	return 0
+}
+
+func containsId(ids []string, id string) bool {
+	for _, existing := range ids {
+		if existing == id {
+			return true
+		}
+	}
+	return false
+}
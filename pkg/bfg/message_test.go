@@ -0,0 +1,113 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package bfg
+
+import (
+	"reflect"
+	"testing"
+)
+
+const commandOutput = "5724-H72 Copyright IBM Corp.  2008, 2024.  ALL RIGHTS RESERVED\n" +
+	"BFGCL0212I: Issuing ping request to agent SRC\n" +
+	"BFGCL0214I: agent SRC didn't respond to ping after 5 seconds.\n" +
+	"BFGMQ1007I: The coordination queue manager cannot be contacted. The WebSphere MQ reason code was 2538.\n" +
+	"BFGCL0214I: agent SRC didn't respond to ping after 5 seconds."
+
+func TestParse(t *testing.T) {
+	messages := Parse(commandOutput)
+	if len(messages) != 4 {
+		t.Fatalf("Expected 4 messages, found %d: %v", len(messages), messages)
+	}
+	expected := Message{Id: "BFGCL0214I", Component: "CL", Number: 214, Severity: SEVERITY_INFO,
+		Text: "agent SRC didn't respond to ping after 5 seconds."}
+	if messages[1] != expected {
+		t.Errorf("Message = %+v, expected %+v", messages[1], expected)
+	}
+	if messages[2].ComponentName() != "queue manager connection" || messages[2].BaseId() != "BFGMQ1007" {
+		t.Errorf("Unexpected component %s or base id %s", messages[2].ComponentName(), messages[2].BaseId())
+	}
+	if messages[3].Text != "agent SRC didn't respond to ping after 5 seconds." {
+		t.Errorf("Text of message at end of output not parsed: %q", messages[3].Text)
+	}
+
+	if messages := Parse("Failed with BFGIO0011E and BFGTL0015"); len(messages) != 2 ||
+		!messages[0].IsError() || messages[1].Severity != "" {
+		t.Errorf("Unexpected messages %+v", messages)
+	}
+	if messages := Parse("XBFGCL0793I BFGCL07931 BFGCL0793X"); len(messages) != 0 {
+		t.Errorf("Text that is not a message id parsed as messages %+v", messages)
+	}
+}
+
+func TestIds(t *testing.T) {
+	ids := Ids(commandOutput)
+	if !reflect.DeepEqual(ids, []string{"BFGCL0212I", AGENT_PING_NO_RESPONSE, COORDINATION_QMGR_NOT_CONTACTED}) {
+		t.Errorf("Unexpected ids %v", ids)
+	}
+	if ids := Ids("no messages"); ids != nil {
+		t.Errorf("Unexpected ids %v", ids)
+	}
+}
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		text     string
+		ids      []string
+		expected bool
+	}{
+		{commandOutput, []string{AGENT_PING_RESPONDED, COORDINATION_QMGR_NOT_CONTACTED}, true},
+		{commandOutput, []string{AGENT_PING_RESPONDED}, false},
+		{"BFGTL0017W: Resynchronizing transfer", TRANSFER_RESYNCHRONIZING, true},
+		{"BFGTL0017", []string{"BFGTL0017I"}, true},
+		{"BFGTL0017W", []string{"BFGTL0017I"}, false},
+		{"[17/10/2026] BFGAG0059I: The agent has successfully initialized.", []string{AGENT_STARTED}, true},
+	}
+	for _, test := range tests {
+		if Contains(test.text, test.ids...) != test.expected {
+			t.Errorf("Contains(%q, %v) expected %v", test.text, test.ids, test.expected)
+		}
+	}
+}
+
+func TestHighestSeverity(t *testing.T) {
+	tests := map[string]Severity{
+		commandOutput:                          SEVERITY_INFO,
+		"BFGIO0011E: failed. BFGTL0017W: warn": SEVERITY_ERROR,
+		"BFGTL0017W: warn BFGCL0793I":          SEVERITY_WARNING,
+		"Transfer completed":                   "",
+	}
+	for text, expected := range tests {
+		if severity := HighestSeverity(text); severity != expected {
+			t.Errorf("HighestSeverity(%q) = %q, expected %q", text, severity, expected)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	entry, found := Lookup(COORDINATION_QMGR_NOT_CONTACTED)
+	if !found || len(entry.Meaning) == 0 || len(entry.Action) == 0 {
+		t.Errorf("Catalog entry not found for %s: %+v", COORDINATION_QMGR_NOT_CONTACTED, entry)
+	}
+	if _, found := Lookup("BFGTL0033W"); !found {
+		t.Error("Catalog entry not found for resynchronization message with severity")
+	}
+	if _, found := (Message{Id: "BFGCL0212I"}).Lookup(); found {
+		t.Error("Catalog entry found for unknown message")
+	}
+	if _, found := Lookup("BFG"); found {
+		t.Error("Catalog entry found for invalid id")
+	}
+}
@@ -0,0 +1,449 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+* Package config contains the model of the agent configuration JSON file.
+* The file is decoded once into the types of this package and default values
+* are applied, so that programs get typed access to the configuration instead
+* of looking up attributes in the JSON text.
+ */
+package config
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Default port of queue managers
+const DEFAULT_QMGR_PORT = 1414
+
+// Default channel used for connecting to queue managers
+const DEFAULT_QMGR_CHANNEL = "SYSTEM.DEF.SVRCONN"
+
+// Types of agent
+const AGENT_TYPE_STANDARD = "STANDARD"
+const AGENT_TYPE_BRIDGE = "BRIDGE"
+const AGENT_TYPE_CD = "CD"
+
+// Value of MFT_AGENT_NAME environment variable that selects all agents
+// defined in the configuration file
+const ALL_AGENTS = "*"
+
+// Entire contents of the agent configuration file
+type Configuration struct {
+	WaitTimeToStart  *Int         `json:"waitTimeToStart,omitempty"`
+	RetryPolicy      *RetryPolicy `json:"retryPolicy,omitempty"`
+	CoordinationQMgr QueueManager `json:"coordinationQMgr"`
+	CommandQMgr      QueueManager `json:"commandQMgr"`
+	Agents           []*Agent     `json:"agents"`
+}
+
+// Retrying of steps that fail because a queue manager is not available yet.
+// All values are in seconds.
+type RetryPolicy struct {
+	InitialDelay *Int `json:"initialDelay,omitempty"`
+	MaxDelay     *Int `json:"maxDelay,omitempty"`
+	Deadline     *Int `json:"deadline,omitempty"`
+}
+
+// Coordination or command queue manager. The queue manager is connected to
+// with host and port, a connection name list or a CCDT.
+type QueueManager struct {
+	Name                 string       `json:"name"`
+	Host                 string       `json:"host"`
+	Port                 Int          `json:"port"`
+	Channel              string       `json:"channel"`
+	ConnectionNameList   string       `json:"connectionNameList,omitempty"`
+	CcdtUrl              string       `json:"ccdtUrl,omitempty"`
+	Credentials          *Credentials `json:"qmgrCredentials,omitempty"`
+	AdditionalProperties Properties   `json:"additionalProperties,omitempty"`
+}
+
+// Credentials used for connecting to a queue manager
+type Credentials struct {
+	UserId   string `json:"mqUserId"`
+	Password string `json:"mqPassword"`
+}
+
+// An agent and its queue manager
+type Agent struct {
+	Name                            string            `json:"name"`
+	Type                            string            `json:"type"`
+	QMgrName                        string            `json:"qmgrName"`
+	QMgrHost                        string            `json:"qmgrHost"`
+	QMgrPort                        Int               `json:"qmgrPort"`
+	QMgrChannel                     string            `json:"qmgrChannel"`
+	QMgrConnectionNameList          string            `json:"qmgrConnectionNameList,omitempty"`
+	QMgrCcdtUrl                     string            `json:"qmgrCcdtUrl,omitempty"`
+	Credentials                     *Credentials      `json:"qmgrCredentials,omitempty"`
+	DeleteOnTermination             Bool              `json:"deleteOnTermination,omitempty"`
+	CleanOnStart                    string            `json:"cleanOnStart,omitempty"`
+	DefaultServer                   string            `json:"defaultServer,omitempty"`
+	MaxActiveDestinationTransfers   *Int              `json:"maxActiveDestinationTransfers,omitempty"`
+	FailTransferWhenCapacityReached *Bool             `json:"failTransferWhenCapacityReached,omitempty"`
+	ProtocolServers                 []*ProtocolServer `json:"protocolServers,omitempty"`
+	CDNodes                         []*CDNode         `json:"cdNodes,omitempty"`
+	CDTmpDir                        string            `json:"cdTmpDir,omitempty"`
+	Sandboxes                       []*Sandbox        `json:"sandboxes,omitempty"`
+	Monitors                        []*Monitor        `json:"monitors,omitempty"`
+	Templates                       []*Template       `json:"templates,omitempty"`
+	Schedules                       []*Schedule       `json:"schedules,omitempty"`
+	JVM                             *JVMOptions       `json:"jvm,omitempty"`
+	AdditionalProperties            Properties        `json:"additionalProperties,omitempty"`
+}
+
+// A user sandbox of a standard agent, written to UserSandboxes.xml file. The
+// sandbox applies to users whose name matches user, which is a wildcard
+// pattern unless userPattern is "regex".
+type Sandbox struct {
+	User        string         `json:"user"`
+	UserPattern string         `json:"userPattern,omitempty"`
+	Read        *SandboxAccess `json:"read,omitempty"`
+	Write       *SandboxAccess `json:"write,omitempty"`
+}
+
+// Paths and queues that can be read from or written to in a sandbox. Names
+// can contain wildcards.
+type SandboxAccess struct {
+	Include       []string `json:"include,omitempty"`
+	Exclude       []string `json:"exclude,omitempty"`
+	IncludeQueues []string `json:"includeQueues,omitempty"`
+	ExcludeQueues []string `json:"excludeQueues,omitempty"`
+}
+
+// A resource monitor of an agent. The monitor polls a directory or a queue
+// and, when the trigger condition is met, transfers the matching files or
+// messages to the destination agent. Attributes that are not specified take
+// the defaults of fteCreateMonitor.
+type Monitor struct {
+	Name              string          `json:"name"`
+	Description       string          `json:"description,omitempty"`
+	ResourceType      string          `json:"resourceType,omitempty"`
+	Resource          string          `json:"resource"`
+	RecursionLevel    *Int            `json:"recursionLevel,omitempty"`
+	Trigger           *MonitorTrigger `json:"trigger,omitempty"`
+	PollInterval      *Int            `json:"pollInterval,omitempty"`
+	PollIntervalUnits string          `json:"pollIntervalUnits,omitempty"`
+	BatchSize         *Int            `json:"batchSize,omitempty"`
+	DestinationAgent  string          `json:"destinationAgent"`
+	DestinationQMgr   string          `json:"destinationQMgr,omitempty"`
+	DestinationType   string          `json:"destinationType,omitempty"`
+	DestinationPath   string          `json:"destinationPath"`
+	Overwrite         *Bool           `json:"overwrite,omitempty"`
+	Checksum          string          `json:"checksum,omitempty"`
+	SourceDisposition string          `json:"sourceDisposition,omitempty"`
+	Mode              string          `json:"mode,omitempty"`
+}
+
+// Condition that triggers a transfer of a resource monitor
+type MonitorTrigger struct {
+	Condition   string `json:"condition"`
+	Pattern     string `json:"pattern,omitempty"`
+	PatternType string `json:"patternType,omitempty"`
+	Size        *Int   `json:"size,omitempty"`
+	SizeUnits   string `json:"sizeUnits,omitempty"`
+	Polls       *Int   `json:"polls,omitempty"`
+}
+
+// Files transferred, and how, by a transfer template or a scheduled transfer
+type TransferSpec struct {
+	SourceAgent       string   `json:"sourceAgent,omitempty"`
+	SourceQMgr        string   `json:"sourceQMgr,omitempty"`
+	DestinationAgent  string   `json:"destinationAgent"`
+	DestinationQMgr   string   `json:"destinationQMgr,omitempty"`
+	SourceFiles       []string `json:"sourceFiles"`
+	SourceDisposition string   `json:"sourceDisposition,omitempty"`
+	Recursive         *Bool    `json:"recursive,omitempty"`
+	DestinationType   string   `json:"destinationType,omitempty"`
+	DestinationPath   string   `json:"destinationPath"`
+	Overwrite         *Bool    `json:"overwrite,omitempty"`
+	Mode              string   `json:"mode,omitempty"`
+	Checksum          string   `json:"checksum,omitempty"`
+	Priority          *Int     `json:"priority,omitempty"`
+	JobName           string   `json:"jobName,omitempty"`
+}
+
+// A transfer template created with fteCreateTemplate
+type Template struct {
+	Name string `json:"name"`
+	TransferSpec
+}
+
+// A scheduled transfer of an agent created with fteCreateTransfer. The agent
+// is the source agent of the transfer.
+type Schedule struct {
+	Name     string  `json:"name"`
+	Start    string  `json:"start"`
+	TimeBase string  `json:"timeBase,omitempty"`
+	Repeat   *Repeat `json:"repeat,omitempty"`
+	TransferSpec
+}
+
+// Repetition of a scheduled transfer. The transfer is repeated every
+// frequency intervals, count times or until end if specified.
+type Repeat struct {
+	Interval  string `json:"interval"`
+	Frequency *Int   `json:"frequency,omitempty"`
+	Count     *Int   `json:"count,omitempty"`
+	End       string `json:"end,omitempty"`
+}
+
+// A Connect:Direct node of a Connect:Direct bridge agent. The first node is
+// the node the agent connects to, other nodes are remote nodes files are
+// transferred to or from.
+type CDNode struct {
+	Name        string             `json:"name"`
+	Host        string             `json:"host,omitempty"`
+	Port        *Int               `json:"port,omitempty"`
+	Platform    string             `json:"platform,omitempty"`
+	Credentials *CDNodeCredentials `json:"credentials,omitempty"`
+}
+
+// User id and password used for connecting to a Connect:Direct node
+type CDNodeCredentials struct {
+	UserId   string     `json:"userId"`
+	Password *SecretRef `json:"password"`
+}
+
+// Reference to a secret that is not held in the configuration file, either
+// a file, typically mounted from a Kubernetes secret, or an environment
+// variable. Exactly one of them must be specified.
+type SecretRef struct {
+	File string `json:"file,omitempty"`
+	Env  string `json:"env,omitempty"`
+}
+
+// Options of the JVM of an agent, added to BFG_JVM_PROPERTIES when the agent
+// is started. Heap sizes are JVM sizes such as "512m". When maxHeap is not
+// specified the heap is sized from the memory limit of the container.
+type JVMOptions struct {
+	MaxHeap          string     `json:"maxHeap,omitempty"`
+	InitialHeap      string     `json:"initialHeap,omitempty"`
+	HeapPercentage   *Int       `json:"heapPercentage,omitempty"`
+	GCPolicy         string     `json:"gcPolicy,omitempty"`
+	SystemProperties Properties `json:"systemProperties,omitempty"`
+	VerboseGC        *Bool      `json:"verboseGC,omitempty"`
+	DumpPath         string     `json:"dumpPath,omitempty"`
+	Options          []string   `json:"options,omitempty"`
+}
+
+// A protocol server of a bridge agent. Attributes that are not specified are
+// nil or blank and are not written to ProtocolBridgeProperties.xml file.
+// Attributes specified with a blank value are written, so that a value set
+// earlier can be cleared.
+type ProtocolServer struct {
+	Name                            string                     `json:"name"`
+	Type                            string                     `json:"type,omitempty"`
+	Host                            string                     `json:"host,omitempty"`
+	Port                            *Int                       `json:"port,omitempty"`
+	Platform                        string                     `json:"platform,omitempty"`
+	TimeZone                        string                     `json:"timeZone,omitempty"`
+	Locale                          string                     `json:"locale,omitempty"`
+	FileEncoding                    string                     `json:"fileEncoding,omitempty"`
+	ControlEncoding                 string                     `json:"controlEncoding,omitempty"`
+	ListFormat                      string                     `json:"listFormat,omitempty"`
+	ListFileRecentDateFormat        string                     `json:"listFileRecentDateFormat,omitempty"`
+	ListFileOldDateFormat           string                     `json:"listFileOldDateFormat,omitempty"`
+	MonthShortNames                 string                     `json:"monthShortNames,omitempty"`
+	LimitedWrite                    *Bool                      `json:"limitedWrite,omitempty"`
+	PassiveMode                     *Bool                      `json:"passiveMode,omitempty"`
+	TrustStoreFile                  string                     `json:"trustStoreFile,omitempty"`
+	TrustStoreType                  string                     `json:"trustStoreType,omitempty"`
+	KeyStoreFile                    string                     `json:"keyStoreFile,omitempty"`
+	KeyStoreType                    string                     `json:"keyStoreType,omitempty"`
+	FtpsType                        string                     `json:"ftpsType,omitempty"`
+	ProtectionLevel                 string                     `json:"protectionLevel,omitempty"`
+	CipherSuites                    string                     `json:"cipherSuites,omitempty"`
+	MaxListFileNames                *Int                       `json:"maxListFileNames,omitempty"`
+	MaxListDirectoryLevels          *Int                       `json:"maxListDirectoryLevels,omitempty"`
+	MaxSessions                     *Int                       `json:"maxSessions,omitempty"`
+	SocketTimeout                   *Int                       `json:"socketTimeout,omitempty"`
+	ConnectionTimeout               *Int                       `json:"connectionTimeout,omitempty"`
+	MaxActiveDestinationTransfers   *Int                       `json:"maxActiveDestinationTransfers,omitempty"`
+	FailTransferWhenCapacityReached *Bool                      `json:"failTransferWhenCapacityReached,omitempty"`
+	CipherList                      string                     `json:"cipherList,omitempty"`
+	HostKeyCipherList               string                     `json:"hostKeyCipherList,omitempty"`
+	KeyExchangeCipherList           string                     `json:"keyExchangeCipherList,omitempty"`
+	MACCipherList                   string                     `json:"MACCipherList,omitempty"`
+	FingerprintHash                 string                     `json:"fingerprintHash,omitempty"`
+	Credentials                     *ProtocolServerCredentials `json:"credentials,omitempty"`
+	// Names of the attributes specified in the configuration file, blank
+	// ones included
+	specified map[string]bool
+}
+
+// Decode a protocol server, recording which attributes are specified
+func (server *ProtocolServer) UnmarshalJSON(data []byte) error {
+	type protocolServer ProtocolServer
+	if err := json.Unmarshal(data, (*protocolServer)(server)); err != nil {
+		return err
+	}
+	server.specified = make(map[string]bool)
+	gjson.ParseBytes(data).ForEach(func(key, _ gjson.Result) bool {
+		server.specified[key.String()] = true
+		return true
+	})
+	return nil
+}
+
+// Determine if the attribute is specified in the configuration file, even if
+// its value is blank
+func (server *ProtocolServer) IsSpecified(attribute string) bool {
+	return server.specified[attribute]
+}
+
+// Credentials used by a bridge agent for connecting to a protocol server.
+// The user id and secrets apply to transfers of any requester unless a user
+// mapping of the requester is specified.
+type ProtocolServerCredentials struct {
+	UserId       string                       `json:"userId,omitempty"`
+	Password     *SecretRef                   `json:"password,omitempty"`
+	PrivateKey   *SecretRef                   `json:"privateKey,omitempty"`
+	HostKey      *SecretRef                   `json:"hostKey,omitempty"`
+	UserMappings []*ProtocolServerUserMapping `json:"userMappings,omitempty"`
+}
+
+// Credentials used for transfers requested by MQ users matching requester
+type ProtocolServerUserMapping struct {
+	Requester  string     `json:"requester"`
+	UserId     string     `json:"userId"`
+	Password   *SecretRef `json:"password,omitempty"`
+	PrivateKey *SecretRef `json:"privateKey,omitempty"`
+}
+
+// Read the configuration from the given JSON file, or build it from
+// environment variables if the source is ENVIRONMENT_SOURCE
+func Load(configFile string) (*Configuration, error) {
+	configData, err := ReadData(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(configData)
+}
+
+// Decode the configuration from JSON text and apply default values
+func Parse(configData string) (*Configuration, error) {
+	var configuration Configuration
+	if err := json.Unmarshal([]byte(configData), &configuration); err != nil {
+		return nil, err
+	}
+	configuration.applyDefaults()
+	return &configuration, nil
+}
+
+// Set default values of attributes that have not been specified
+func (configuration *Configuration) applyDefaults() {
+	configuration.CoordinationQMgr.applyDefaults()
+	configuration.CommandQMgr.applyDefaults()
+	for _, agent := range configuration.Agents {
+		agent.applyDefaults()
+	}
+}
+
+// Set default values of queue manager attributes
+func (qmgr *QueueManager) applyDefaults() {
+	if qmgr.Port == 0 {
+		qmgr.Port = DEFAULT_QMGR_PORT
+	}
+	if len(strings.TrimSpace(qmgr.Channel)) == 0 {
+		qmgr.Channel = DEFAULT_QMGR_CHANNEL
+	}
+}
+
+// Set default values of agent attributes. Type of agent is upper cased so that
+// it can be compared with the AGENT_TYPE_* constants.
+func (agent *Agent) applyDefaults() {
+	agent.Type = strings.ToUpper(strings.TrimSpace(agent.Type))
+	if len(agent.Type) == 0 {
+		agent.Type = AGENT_TYPE_STANDARD
+	}
+	if agent.QMgrPort == 0 {
+		agent.QMgrPort = DEFAULT_QMGR_PORT
+	}
+	if len(strings.TrimSpace(agent.QMgrChannel)) == 0 {
+		agent.QMgrChannel = DEFAULT_QMGR_CHANNEL
+	}
+}
+
+// Get the configuration of the named agent. Agent names are compared ignoring
+// case. Returns nil if agent is not defined.
+func (configuration *Configuration) Agent(agentName string) *Agent {
+	for _, agent := range configuration.Agents {
+		if strings.EqualFold(strings.TrimSpace(agent.Name), strings.TrimSpace(agentName)) {
+			return agent
+		}
+	}
+	return nil
+}
+
+// Get the names of agents from the value of MFT_AGENT_NAME environment variable.
+// The value can be a single agent name, a comma separated list of names or * to
+// select all agents defined in the configuration. Blank and duplicate names
+// are ignored.
+func (configuration *Configuration) AgentNames(agentNameList string) []string {
+	var names []string
+	if strings.TrimSpace(agentNameList) == ALL_AGENTS {
+		for _, agent := range configuration.Agents {
+			names = appendAgentName(names, agent.Name)
+		}
+	} else {
+		for _, name := range strings.Split(agentNameList, ",") {
+			names = appendAgentName(names, name)
+		}
+	}
+	return names
+}
+
+// Append agent name to the list if it is not blank and not already in the list.
+func appendAgentName(names []string, name string) []string {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return names
+	}
+	for _, existing := range names {
+		if strings.EqualFold(existing, name) {
+			return names
+		}
+	}
+	return append(names, name)
+}
+
+// Returns the configuration as JSON text
+func (configuration *Configuration) String() string {
+	return toJSON(configuration)
+}
+
+// Returns the agent configuration as JSON text
+func (agent *Agent) String() string {
+	return toJSON(agent)
+}
+
+// Returns the protocol server configuration as JSON text
+func (server *ProtocolServer) String() string {
+	return toJSON(server)
+}
+
+// Encode the given value as JSON text. Returns a blank string if the value
+// can not be encoded.
+func toJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
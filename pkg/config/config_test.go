@@ -0,0 +1,167 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testConfig = "{\"waitTimeToStart\":\"20\",\"coordinationQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\"}," +
+	"\"commandQMgr\":{\"name\":\"QM1\",\"host\":\"localhost\",\"port\":\"1415\",\"channel\":\"MFT.SVRCONN\"},\"agents\":[" +
+	"{\"name\":\"SRC1\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"additionalProperties\":{\"trace\":\"all\",\"enableQueueInputOutput\":true,\"maxQueuedTransfers\":500}}," +
+	"{\"name\":\"SRC2\",\"type\":\"bridge\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\",\"deleteOnTermination\":\"true\"," +
+	"\"protocolServers\":[{\"name\":\"SFTPSRV\",\"type\":\"SFTP\",\"port\":\"22\",\"limitedWrite\":false}]}," +
+	"{\"name\":\"DEST\",\"qmgrName\":\"QM1\",\"qmgrHost\":\"localhost\"}]}"
+
+func TestParseDefaults(t *testing.T) {
+	configuration, err := Parse(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configuration.WaitTimeToStart == nil || *configuration.WaitTimeToStart != 20 {
+		t.Errorf("waitTimeToStart not decoded from string: %v", configuration.WaitTimeToStart)
+	}
+	coordination := configuration.CoordinationQMgr
+	if coordination.Port != DEFAULT_QMGR_PORT || coordination.Channel != DEFAULT_QMGR_CHANNEL {
+		t.Errorf("Defaults not applied to coordination queue manager: %v", coordination)
+	}
+	command := configuration.CommandQMgr
+	if command.Port != 1415 || command.Channel != "MFT.SVRCONN" {
+		t.Errorf("Command queue manager attributes overridden by defaults: %v", command)
+	}
+
+	agent := configuration.Agents[0]
+	if agent.Type != AGENT_TYPE_STANDARD || agent.QMgrPort != DEFAULT_QMGR_PORT || agent.QMgrChannel != DEFAULT_QMGR_CHANNEL {
+		t.Errorf("Defaults not applied to agent: %v", agent)
+	}
+	bridge := configuration.Agents[1]
+	if bridge.Type != AGENT_TYPE_BRIDGE || !bool(bridge.DeleteOnTermination) {
+		t.Errorf("Bridge agent attributes not decoded: %v", bridge)
+	}
+	server := bridge.ProtocolServers[0]
+	if server.Port == nil || *server.Port != 22 || server.LimitedWrite == nil || bool(*server.LimitedWrite) || server.MaxSessions != nil {
+		t.Errorf("Protocol server attributes not decoded: %v", server)
+	}
+}
+
+func TestProtocolServerSpecified(t *testing.T) {
+	configuration, err := Parse("{\"agents\":[{\"name\":\"SRC\",\"type\":\"bridge\"," +
+		"\"protocolServers\":[{\"name\":\"FTPSRV\",\"type\":\"FTP\",\"monthShortNames\":\"\"}]}]}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := configuration.Agents[0].ProtocolServers[0]
+	if !server.IsSpecified("monthShortNames") || !server.IsSpecified("name") {
+		t.Errorf("Specified attributes not recorded: %v", server)
+	}
+	if server.IsSpecified("locale") {
+		t.Errorf("Attribute locale reported as specified: %v", server)
+	}
+}
+
+func TestParseInvalidValues(t *testing.T) {
+	tests := []string{
+		"{\"coordinationQMgr\":{\"name\":\"QM1\",\"port\":\"abc\"}}",
+		"{\"agents\":[{\"name\":\"SRC\",\"deleteOnTermination\":\"yes please\"}]}",
+		"{\"agents\":[{\"name\":\"SRC\",\"additionalProperties\":[\"trace\"]}]}",
+	}
+	for _, test := range tests {
+		if _, err := Parse(test); err == nil {
+			t.Errorf("Expected error parsing %s", test)
+		}
+	}
+}
+
+func TestProperties(t *testing.T) {
+	configuration, err := Parse(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties := configuration.Agents[0].AdditionalProperties
+	expected := Properties{{"trace", "all"}, {"enableQueueInputOutput", "true"}, {"maxQueuedTransfers", "500"}}
+	if !reflect.DeepEqual(properties, expected) {
+		t.Fatalf("Properties = %v, expected %v", properties, expected)
+	}
+
+	properties.Set("trace", "com.ibm.wmqfte=all")
+	properties.Set("agentSslKeyStoreType", "pkcs12")
+	if value, found := properties.Get("trace"); !found || value != "com.ibm.wmqfte=all" {
+		t.Errorf("Property not replaced: %v", properties)
+	}
+	if len(properties) != 4 || properties[3].Name != "agentSslKeyStoreType" {
+		t.Errorf("Property not appended: %v", properties)
+	}
+
+	data, err := properties.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedJSON := "{\"trace\":\"com.ibm.wmqfte=all\",\"enableQueueInputOutput\":\"true\",\"maxQueuedTransfers\":\"500\",\"agentSslKeyStoreType\":\"pkcs12\"}"
+	if string(data) != expectedJSON {
+		t.Errorf("MarshalJSON = %s, expected %s", data, expectedJSON)
+	}
+}
+
+func TestAgentNames(t *testing.T) {
+	configuration, err := Parse(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		agentNameList string
+		expected      []string
+	}{
+		{"SRC1", []string{"SRC1"}},
+		{" SRC1 , SRC2 ", []string{"SRC1", "SRC2"}},
+		{"SRC1,,src1,DEST", []string{"SRC1", "DEST"}},
+		{"*", []string{"SRC1", "SRC2", "DEST"}},
+		{" , ", nil},
+	}
+	for _, test := range tests {
+		names := configuration.AgentNames(test.agentNameList)
+		if !reflect.DeepEqual(names, test.expected) {
+			t.Errorf("AgentNames(%q) = %v, expected %v", test.agentNameList, names, test.expected)
+		}
+	}
+}
+
+func TestAgent(t *testing.T) {
+	configuration, err := Parse(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if agent := configuration.Agent(" src2 "); agent == nil || agent.Name != "SRC2" {
+		t.Errorf("Configuration of agent SRC2 not found: %v", agent)
+	}
+	if agent := configuration.Agent("SRC3"); agent != nil {
+		t.Errorf("Configuration found for agent SRC3 that is not defined: %v", agent)
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	configuration, err := Parse(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := Parse(configuration.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(configuration, reparsed) {
+		t.Errorf("Configuration changed when serialized:\n%v\n%v", configuration, reparsed)
+	}
+}
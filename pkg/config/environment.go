@@ -0,0 +1,289 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+/*
+* Builds the configuration from environment variables when no configuration
+* file has been mounted. The result is JSON text in the same form as the
+* configuration file, so that it goes through the same validation. Only a
+* single standard agent, named by MFT_AGENT_NAME, can be configured this way.
+* Queue manager passwords can be read from files, for example mounted
+* Kubernetes secrets, so that they do not appear in the environment.
+ */
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+// Source of the configuration when it is built from environment variables.
+// Used in place of the path of the configuration file.
+const ENVIRONMENT_SOURCE = "environment variables"
+
+// Environment variables naming the configuration file and the agent
+const ENV_CONFIG_FILE = "MFT_AGENT_CONFIG_FILE"
+const ENV_AGENT_NAME = "MFT_AGENT_NAME"
+
+// Environment variables of the coordination queue manager. The configuration
+// is built from environment variables only if the name is set.
+const ENV_COORD_QMGR_NAME = "MFT_COORD_QMGR_NAME"
+const ENV_COORD_QMGR_HOST = "MFT_COORD_QMGR_HOST"
+const ENV_COORD_QMGR_PORT = "MFT_COORD_QMGR_PORT"
+const ENV_COORD_QMGR_CHANNEL = "MFT_COORD_QMGR_CHANNEL"
+const ENV_COORD_QMGR_CONNECTION_NAME_LIST = "MFT_COORD_QMGR_CONNECTION_NAME_LIST"
+const ENV_COORD_QMGR_CCDT_URL = "MFT_COORD_QMGR_CCDT_URL"
+const ENV_COORD_QMGR_PROP_PREFIX = "MFT_COORD_QMGR_PROP_"
+const ENV_COORD_QMGR_USER_ID = "MFT_COORD_QMGR_USER_ID"
+const ENV_COORD_QMGR_PASSWORD = "MFT_COORD_QMGR_PASSWORD"
+const ENV_COORD_QMGR_PASSWORD_FILE = "MFT_COORD_QMGR_PASSWORD_FILE"
+
+// Environment variables of the command queue manager. The coordination queue
+// manager is used if none of them is set.
+const ENV_CMD_QMGR_NAME = "MFT_CMD_QMGR_NAME"
+const ENV_CMD_QMGR_HOST = "MFT_CMD_QMGR_HOST"
+const ENV_CMD_QMGR_PORT = "MFT_CMD_QMGR_PORT"
+const ENV_CMD_QMGR_CHANNEL = "MFT_CMD_QMGR_CHANNEL"
+const ENV_CMD_QMGR_CONNECTION_NAME_LIST = "MFT_CMD_QMGR_CONNECTION_NAME_LIST"
+const ENV_CMD_QMGR_CCDT_URL = "MFT_CMD_QMGR_CCDT_URL"
+const ENV_CMD_QMGR_PROP_PREFIX = "MFT_CMD_QMGR_PROP_"
+const ENV_CMD_QMGR_USER_ID = "MFT_CMD_QMGR_USER_ID"
+const ENV_CMD_QMGR_PASSWORD = "MFT_CMD_QMGR_PASSWORD"
+const ENV_CMD_QMGR_PASSWORD_FILE = "MFT_CMD_QMGR_PASSWORD_FILE"
+
+// Environment variables of the agent
+const ENV_AGENT_TYPE = "MFT_AGENT_TYPE"
+const ENV_AGENT_QMGR_NAME = "MFT_AGENT_QMGR_NAME"
+const ENV_AGENT_QMGR_HOST = "MFT_AGENT_QMGR_HOST"
+const ENV_AGENT_QMGR_PORT = "MFT_AGENT_QMGR_PORT"
+const ENV_AGENT_QMGR_CHANNEL = "MFT_AGENT_QMGR_CHANNEL"
+const ENV_AGENT_QMGR_CONNECTION_NAME_LIST = "MFT_AGENT_QMGR_CONNECTION_NAME_LIST"
+const ENV_AGENT_QMGR_CCDT_URL = "MFT_AGENT_QMGR_CCDT_URL"
+const ENV_AGENT_CLEAN_ON_START = "MFT_AGENT_CLEAN_ON_START"
+const ENV_AGENT_DELETE_ON_TERMINATION = "MFT_AGENT_DELETE_ON_TERMINATION"
+const ENV_AGENT_PROP_PREFIX = "MFT_AGENT_PROP_"
+const ENV_AGENT_QMGR_USER_ID = "MFT_AGENT_QMGR_USER_ID"
+const ENV_AGENT_QMGR_PASSWORD = "MFT_AGENT_QMGR_PASSWORD"
+const ENV_AGENT_QMGR_PASSWORD_FILE = "MFT_AGENT_QMGR_PASSWORD_FILE"
+
+// Environment variables and the attributes they set
+type envAttribute struct {
+	envName   string
+	attribute string
+}
+
+// Environment variables of the user id and password, or the file holding the
+// password, used for connecting to a queue manager
+type envCredentials struct {
+	userIdEnvName       string
+	passwordEnvName     string
+	passwordFileEnvName string
+}
+
+var coordinationQMgrCredentialsEnv = envCredentials{ENV_COORD_QMGR_USER_ID, ENV_COORD_QMGR_PASSWORD, ENV_COORD_QMGR_PASSWORD_FILE}
+var commandQMgrCredentialsEnv = envCredentials{ENV_CMD_QMGR_USER_ID, ENV_CMD_QMGR_PASSWORD, ENV_CMD_QMGR_PASSWORD_FILE}
+var agentQMgrCredentialsEnv = envCredentials{ENV_AGENT_QMGR_USER_ID, ENV_AGENT_QMGR_PASSWORD, ENV_AGENT_QMGR_PASSWORD_FILE}
+
+var coordinationQMgrEnv = []envAttribute{
+	{ENV_COORD_QMGR_NAME, "name"},
+	{ENV_COORD_QMGR_HOST, "host"},
+	{ENV_COORD_QMGR_PORT, "port"},
+	{ENV_COORD_QMGR_CHANNEL, "channel"},
+	{ENV_COORD_QMGR_CONNECTION_NAME_LIST, "connectionNameList"},
+	{ENV_COORD_QMGR_CCDT_URL, "ccdtUrl"},
+}
+
+var commandQMgrEnv = []envAttribute{
+	{ENV_CMD_QMGR_NAME, "name"},
+	{ENV_CMD_QMGR_HOST, "host"},
+	{ENV_CMD_QMGR_PORT, "port"},
+	{ENV_CMD_QMGR_CHANNEL, "channel"},
+	{ENV_CMD_QMGR_CONNECTION_NAME_LIST, "connectionNameList"},
+	{ENV_CMD_QMGR_CCDT_URL, "ccdtUrl"},
+}
+
+var agentEnv = []envAttribute{
+	{ENV_AGENT_TYPE, "type"},
+	{ENV_AGENT_QMGR_NAME, "qmgrName"},
+	{ENV_AGENT_QMGR_HOST, "qmgrHost"},
+	{ENV_AGENT_QMGR_PORT, "qmgrPort"},
+	{ENV_AGENT_QMGR_CHANNEL, "qmgrChannel"},
+	{ENV_AGENT_QMGR_CONNECTION_NAME_LIST, "qmgrConnectionNameList"},
+	{ENV_AGENT_QMGR_CCDT_URL, "qmgrCcdtUrl"},
+	{ENV_AGENT_CLEAN_ON_START, "cleanOnStart"},
+	{ENV_AGENT_DELETE_ON_TERMINATION, "deleteOnTermination"},
+}
+
+// Determine where the configuration is read from. The file named by
+// MFT_AGENT_CONFIG_FILE is used if the variable is set, otherwise the default
+// configuration file. If neither is available and the coordination queue
+// manager is named by an environment variable, the configuration is built
+// from environment variables and ENVIRONMENT_SOURCE is returned. The returned
+// path is blank if MFT_AGENT_CONFIG_FILE is set to a blank value.
+func Source() string {
+	configFile, configFileSet := os.LookupEnv(ENV_CONFIG_FILE)
+	if configFileSet {
+		return strings.TrimSpace(configFile)
+	}
+	if _, err := os.Stat(utils.MFT_DEFAULT_CONFIG_JSON); err != nil && IsEnvironmentConfigured() {
+		utils.PrintLog(fmt.Sprintf(utils.MFT_ENV_AGNT_CFG_FROM_ENVIRONMENT, utils.MFT_DEFAULT_CONFIG_JSON))
+		return ENVIRONMENT_SOURCE
+	}
+	utils.PrintLog(fmt.Sprintf(utils.MFT_ENV_AGNT_CFG_FILE_NOT_SPECIFIED, utils.MFT_DEFAULT_CONFIG_JSON))
+	return utils.MFT_DEFAULT_CONFIG_JSON
+}
+
+// Determine if the configuration can be built from environment variables
+func IsEnvironmentConfigured() bool {
+	return len(strings.TrimSpace(os.Getenv(ENV_COORD_QMGR_NAME))) > 0
+}
+
+// Read the configuration as JSON text from the given file, or build it from
+// environment variables if the source is ENVIRONMENT_SOURCE.
+func ReadData(source string) (string, error) {
+	if source == ENVIRONMENT_SOURCE {
+		return FromEnvironment(os.Environ())
+	}
+	return utils.ReadConfigurationDataFromFile(source)
+}
+
+// Build the configuration as JSON text from the given environment variables,
+// in the form returned by os.Environ.
+func FromEnvironment(environ []string) (string, error) {
+	env := make(map[string]string)
+	for _, entry := range environ {
+		if name, value, found := strings.Cut(entry, "="); found {
+			env[name] = value
+		}
+	}
+
+	agentName := strings.TrimSpace(env[ENV_AGENT_NAME])
+	if len(agentName) == 0 || agentName == ALL_AGENTS || strings.Contains(agentName, ",") {
+		return "", fmt.Errorf("%s must name a single agent when the configuration is built from environment variables", ENV_AGENT_NAME)
+	}
+
+	// Bridge and Connect:Direct agents need servers or nodes that can only be
+	// described in a configuration file
+	agentType := strings.ToUpper(strings.TrimSpace(env[ENV_AGENT_TYPE]))
+	if agentType == AGENT_TYPE_BRIDGE || agentType == AGENT_TYPE_CD {
+		return "", fmt.Errorf("%s %s is not supported when the configuration is built from environment variables. Specify %s agents in a configuration file", ENV_AGENT_TYPE, agentType, agentType)
+	}
+
+	coordinationQMgr := envAttributes(env, coordinationQMgrEnv, ENV_COORD_QMGR_PROP_PREFIX)
+	coordinationCredentials, err := envQMgrCredentials(env, coordinationQMgrCredentialsEnv)
+	if err != nil {
+		return "", err
+	}
+	setCredentials(coordinationQMgr, coordinationCredentials)
+
+	commandQMgr := envAttributes(env, commandQMgrEnv, ENV_CMD_QMGR_PROP_PREFIX)
+	commandCredentials, err := envQMgrCredentials(env, commandQMgrCredentialsEnv)
+	if err != nil {
+		return "", err
+	}
+	if len(commandQMgr) == 0 {
+		commandQMgr = envAttributes(env, coordinationQMgrEnv, "")
+		if commandCredentials == nil {
+			commandCredentials = coordinationCredentials
+		}
+	}
+	setCredentials(commandQMgr, commandCredentials)
+
+	agent := envAttributes(env, agentEnv, ENV_AGENT_PROP_PREFIX)
+	agent["name"] = agentName
+	agentCredentials, err := envQMgrCredentials(env, agentQMgrCredentialsEnv)
+	if err != nil {
+		return "", err
+	}
+	setCredentials(agent, agentCredentials)
+
+	configData, err := json.Marshal(map[string]interface{}{
+		"coordinationQMgr": coordinationQMgr,
+		"commandQMgr":      commandQMgr,
+		"agents":           []interface{}{agent},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(configData), nil
+}
+
+// Attributes set by the given environment variables. Variables starting with
+// the property prefix set additionalProperties, in order of property name.
+// Variables that are not set are left out so that default values apply.
+func envAttributes(env map[string]string, attributes []envAttribute, propertyPrefix string) map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, attribute := range attributes {
+		if value, found := env[attribute.envName]; found && len(strings.TrimSpace(value)) > 0 {
+			values[attribute.attribute] = strings.TrimSpace(value)
+		}
+	}
+	if len(propertyPrefix) == 0 {
+		return values
+	}
+
+	var names []string
+	for name := range env {
+		if strings.HasPrefix(name, propertyPrefix) && len(name) > len(propertyPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var properties Properties
+	for _, name := range names {
+		properties.Set(strings.TrimPrefix(name, propertyPrefix), env[name])
+	}
+	if len(properties) > 0 {
+		values["additionalProperties"] = properties
+	}
+	return values
+}
+
+// Credentials set by the given environment variables, nil if no user id is
+// set. The password is read from the password file if one is named, with
+// trailing line ends removed.
+func envQMgrCredentials(env map[string]string, credentialsEnv envCredentials) (*Credentials, error) {
+	userId := strings.TrimSpace(env[credentialsEnv.userIdEnvName])
+	password, passwordSet := env[credentialsEnv.passwordEnvName]
+	passwordFile := strings.TrimSpace(env[credentialsEnv.passwordFileEnvName])
+	if passwordSet && len(passwordFile) > 0 {
+		return nil, fmt.Errorf("%s and %s must not both be set", credentialsEnv.passwordEnvName, credentialsEnv.passwordFileEnvName)
+	}
+	if len(userId) == 0 {
+		if passwordSet || len(passwordFile) > 0 {
+			return nil, fmt.Errorf("%s must be set when a password is specified for the queue manager", credentialsEnv.userIdEnvName)
+		}
+		return nil, nil
+	}
+	if len(passwordFile) > 0 {
+		data, err := os.ReadFile(passwordFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the password file named by %s: %v", credentialsEnv.passwordFileEnvName, err)
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	}
+	return &Credentials{UserId: userId, Password: password}, nil
+}
+
+// Set qmgrCredentials of the queue manager or agent if there are credentials
+func setCredentials(values map[string]interface{}, credentials *Credentials) {
+	if credentials != nil {
+		values["qmgrCredentials"] = credentials
+	}
+}
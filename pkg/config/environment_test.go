@@ -0,0 +1,206 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
+)
+
+func TestFromEnvironment(t *testing.T) {
+	environ := []string{
+		"MFT_AGENT_NAME=SRC",
+		"MFT_AGENT_TYPE=standard",
+		"MFT_AGENT_QMGR_NAME=QM2",
+		"MFT_AGENT_QMGR_HOST=qm2.example.com",
+		"MFT_AGENT_QMGR_PORT=1415",
+		"MFT_AGENT_QMGR_CIPHER=ANY_TLS12_OR_HIGHER",
+		"MFT_AGENT_DELETE_ON_TERMINATION=true",
+		"MFT_AGENT_PROP_trace=all",
+		"MFT_AGENT_PROP_enableQueueInputOutput=true",
+		"MFT_COORD_QMGR_NAME=QM1",
+		"MFT_COORD_QMGR_HOST=qm1.example.com",
+		"MFT_COORD_QMGR_CHANNEL=MFT.SVRCONN",
+		"MFT_COORD_QMGR_PROP_coordinationSslCipherSpec=ANY_TLS12",
+		"PATH=/usr/bin",
+	}
+	configData, err := FromEnvironment(environ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration, err := Parse(configData)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coordination := configuration.CoordinationQMgr
+	if coordination.Name != "QM1" || coordination.Host != "qm1.example.com" || coordination.Channel != "MFT.SVRCONN" || coordination.Port != DEFAULT_QMGR_PORT {
+		t.Errorf("Unexpected coordination queue manager %v", coordination)
+	}
+	if value, _ := coordination.AdditionalProperties.Get("coordinationSslCipherSpec"); value != "ANY_TLS12" {
+		t.Errorf("Coordination properties not set: %v", coordination.AdditionalProperties)
+	}
+	// Command queue manager defaults to the coordination queue manager
+	command := configuration.CommandQMgr
+	if command.Name != "QM1" || command.Host != "qm1.example.com" || command.Channel != "MFT.SVRCONN" || len(command.AdditionalProperties) != 0 {
+		t.Errorf("Unexpected command queue manager %v", command)
+	}
+
+	agent := configuration.Agent("SRC")
+	if agent == nil || len(configuration.Agents) != 1 {
+		t.Fatalf("Agent not configured: %v", configuration)
+	}
+	if agent.Type != AGENT_TYPE_STANDARD || agent.QMgrName != "QM2" || agent.QMgrHost != "qm2.example.com" || agent.QMgrPort != 1415 ||
+		!bool(agent.DeleteOnTermination) {
+		t.Errorf("Unexpected agent %v", agent)
+	}
+	expected := Properties{{Name: "enableQueueInputOutput", Value: "true"}, {Name: "trace", Value: "all"}}
+	if !reflect.DeepEqual(agent.AdditionalProperties, expected) {
+		t.Errorf("Unexpected agent properties %v", agent.AdditionalProperties)
+	}
+}
+
+func TestFromEnvironmentCommandQMgr(t *testing.T) {
+	configData, err := FromEnvironment([]string{"MFT_AGENT_NAME=SRC", "MFT_COORD_QMGR_NAME=QM1", "MFT_CMD_QMGR_NAME=QM3", "MFT_CMD_QMGR_HOST=qm3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration, err := Parse(configData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configuration.CommandQMgr.Name != "QM3" || configuration.CommandQMgr.Host != "qm3" {
+		t.Errorf("Unexpected command queue manager %v", configuration.CommandQMgr)
+	}
+
+	for _, agentName := range []string{"", "*", "SRC,DEST"} {
+		if _, err := FromEnvironment([]string{"MFT_AGENT_NAME=" + agentName, "MFT_COORD_QMGR_NAME=QM1"}); err == nil {
+			t.Errorf("Configuration built for agent names %q", agentName)
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	t.Setenv(ENV_CONFIG_FILE, " /mnt/config/agent.json ")
+	if source := Source(); source != "/mnt/config/agent.json" {
+		t.Errorf("Unexpected source %q", source)
+	}
+
+	t.Setenv(ENV_COORD_QMGR_NAME, "QM1")
+	t.Setenv(ENV_AGENT_NAME, "SRC")
+	if source := Source(); source == ENVIRONMENT_SOURCE {
+		t.Error("Environment variables used when a configuration file is specified")
+	}
+
+	os.Unsetenv(ENV_CONFIG_FILE)
+	if _, err := os.Stat(utils.MFT_DEFAULT_CONFIG_JSON); err == nil {
+		t.Skip("Default configuration file exists")
+	}
+	if source := Source(); source != ENVIRONMENT_SOURCE {
+		t.Errorf("Configuration not built from environment variables, source %q", source)
+	}
+}
+
+func TestFromEnvironmentConnectionNameList(t *testing.T) {
+	configData, err := FromEnvironment([]string{"MFT_AGENT_NAME=SRC", "MFT_AGENT_QMGR_NAME=QM2", "MFT_AGENT_QMGR_CCDT_URL=/mnt/ccdt/ccdt.json",
+		"MFT_COORD_QMGR_NAME=QM1", "MFT_COORD_QMGR_CONNECTION_NAME_LIST=qm1a(1414),qm1b(1414)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration, err := Parse(configData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configuration.CoordinationQMgr.ConnectionNameList != "qm1a(1414),qm1b(1414)" || configuration.CommandQMgr.ConnectionNameList != "qm1a(1414),qm1b(1414)" {
+		t.Errorf("Unexpected connection name lists %v %v", configuration.CoordinationQMgr, configuration.CommandQMgr)
+	}
+	if agent := configuration.Agent("SRC"); agent == nil || agent.QMgrCcdtUrl != "/mnt/ccdt/ccdt.json" || len(agent.QMgrHost) != 0 {
+		t.Errorf("Unexpected agent %v", agent)
+	}
+}
+
+func TestFromEnvironmentCredentials(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("cGFzc3cwcmQ=\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	configData, err := FromEnvironment([]string{"MFT_AGENT_NAME=SRC", "MFT_AGENT_QMGR_NAME=QM2",
+		"MFT_AGENT_QMGR_USER_ID=agentuser", "MFT_AGENT_QMGR_PASSWORD_FILE=" + passwordFile,
+		"MFT_COORD_QMGR_NAME=QM1", "MFT_COORD_QMGR_USER_ID=mftuser", "MFT_COORD_QMGR_PASSWORD=bXlwYXNzdzByZA=="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	configuration, err := Parse(configData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &Credentials{UserId: "mftuser", Password: "bXlwYXNzdzByZA=="}
+	if !reflect.DeepEqual(configuration.CoordinationQMgr.Credentials, expected) {
+		t.Errorf("Unexpected coordination queue manager credentials %v", configuration.CoordinationQMgr.Credentials)
+	}
+	// Command queue manager defaults to the coordination queue manager
+	if !reflect.DeepEqual(configuration.CommandQMgr.Credentials, expected) {
+		t.Errorf("Unexpected command queue manager credentials %v", configuration.CommandQMgr.Credentials)
+	}
+	expected = &Credentials{UserId: "agentuser", Password: "cGFzc3cwcmQ="}
+	if agent := configuration.Agent("SRC"); agent == nil || !reflect.DeepEqual(agent.Credentials, expected) {
+		t.Errorf("Unexpected agent %v", agent)
+	}
+
+	// Command queue manager credentials are used with the coordination queue manager
+	configData, err = FromEnvironment([]string{"MFT_AGENT_NAME=SRC", "MFT_COORD_QMGR_NAME=QM1", "MFT_CMD_QMGR_USER_ID=cmduser", "MFT_CMD_QMGR_PASSWORD="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configuration, err = Parse(configData); err != nil {
+		t.Fatal(err)
+	}
+	if command := configuration.CommandQMgr; command.Name != "QM1" || command.Credentials == nil || command.Credentials.UserId != "cmduser" ||
+		configuration.CoordinationQMgr.Credentials != nil {
+		t.Errorf("Unexpected queue managers %v %v", configuration.CoordinationQMgr, command)
+	}
+}
+
+func TestFromEnvironmentCredentialsInvalid(t *testing.T) {
+	tests := map[string][]string{
+		"MFT_COORD_QMGR_PASSWORD and MFT_COORD_QMGR_PASSWORD_FILE must not both be set": {
+			"MFT_COORD_QMGR_USER_ID=mftuser", "MFT_COORD_QMGR_PASSWORD=cGFzc3cwcmQ=", "MFT_COORD_QMGR_PASSWORD_FILE=/run/secrets/password"},
+		"MFT_AGENT_QMGR_USER_ID must be set": {"MFT_AGENT_QMGR_PASSWORD=cGFzc3cwcmQ="},
+		"failed to read the password file named by MFT_CMD_QMGR_PASSWORD_FILE": {
+			"MFT_CMD_QMGR_USER_ID=cmduser", "MFT_CMD_QMGR_PASSWORD_FILE=" + filepath.Join(t.TempDir(), "missing")},
+	}
+	for expected, environ := range tests {
+		environ = append(environ, "MFT_AGENT_NAME=SRC", "MFT_COORD_QMGR_NAME=QM1")
+		if _, err := FromEnvironment(environ); err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("Expected error %q for %v, found %v", expected, environ, err)
+		}
+	}
+}
+
+func TestFromEnvironmentAgentType(t *testing.T) {
+	for _, agentType := range []string{"BRIDGE", "cd"} {
+		_, err := FromEnvironment([]string{"MFT_AGENT_NAME=SRC", "MFT_COORD_QMGR_NAME=QM1", "MFT_AGENT_TYPE=" + agentType})
+		expected := "MFT_AGENT_TYPE " + strings.ToUpper(agentType) + " is not supported when the configuration is built from environment variables"
+		if err == nil || !strings.HasPrefix(err.Error(), expected) {
+			t.Errorf("Expected error %q, found %v", expected, err)
+		}
+	}
+}
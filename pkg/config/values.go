@@ -0,0 +1,159 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package config
+
+/*
+* Contains the types of attribute values that need more than the standard
+* JSON decoding. Existing configuration files supply numbers and booleans
+* as strings, for example "1414" or "true", and additionalProperties are
+* written to properties files in the order they appear in the file.
+ */
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// An integer attribute, specified either as a JSON number or a string
+type Int int
+
+// A boolean attribute, specified either as a JSON boolean or a string
+type Bool bool
+
+// A property written to a properties file
+type Property struct {
+	Name  string
+	Value string
+}
+
+// Properties in the order they were specified
+type Properties []Property
+
+// Decode an integer from a number or a string containing a number
+func (value *Int) UnmarshalJSON(data []byte) error {
+	result := gjson.ParseBytes(data)
+	switch result.Type {
+	case gjson.Number:
+		*value = Int(result.Int())
+		return nil
+	case gjson.String:
+		text := strings.TrimSpace(result.Str)
+		if len(text) == 0 {
+			*value = 0
+			return nil
+		}
+		number, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("%q is not a valid integer", result.Str)
+		}
+		*value = Int(number)
+		return nil
+	case gjson.Null:
+		return nil
+	}
+	return fmt.Errorf("%s is not a valid integer", result.Raw)
+}
+
+// Returns the integer as text
+func (value Int) String() string {
+	return strconv.Itoa(int(value))
+}
+
+// Decode a boolean from a boolean or a string containing true or false
+func (value *Bool) UnmarshalJSON(data []byte) error {
+	result := gjson.ParseBytes(data)
+	switch result.Type {
+	case gjson.True, gjson.False:
+		*value = Bool(result.Bool())
+		return nil
+	case gjson.String:
+		flag, err := strconv.ParseBool(strings.TrimSpace(result.Str))
+		if err != nil {
+			return fmt.Errorf("%q is not a valid boolean", result.Str)
+		}
+		*value = Bool(flag)
+		return nil
+	case gjson.Null:
+		return nil
+	}
+	return fmt.Errorf("%s is not a valid boolean", result.Raw)
+}
+
+// Returns the boolean as text
+func (value Bool) String() string {
+	return strconv.FormatBool(bool(value))
+}
+
+// Decode properties from a JSON object, keeping the order of attributes.
+// Values are stored as text.
+func (properties *Properties) UnmarshalJSON(data []byte) error {
+	result := gjson.ParseBytes(data)
+	if result.Type == gjson.Null {
+		return nil
+	}
+	if !result.IsObject() {
+		return fmt.Errorf("%s is not a valid group of properties", result.Raw)
+	}
+	*properties = nil
+	result.ForEach(func(key, value gjson.Result) bool {
+		*properties = append(*properties, Property{Name: key.String(), Value: value.String()})
+		return true
+	})
+	return nil
+}
+
+// Encode properties as a JSON object with values as strings
+func (properties Properties) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	for index, property := range properties {
+		if index > 0 {
+			buffer.WriteString(",")
+		}
+		name, _ := json.Marshal(property.Name)
+		value, _ := json.Marshal(property.Value)
+		buffer.Write(name)
+		buffer.WriteString(":")
+		buffer.Write(value)
+	}
+	buffer.WriteString("}")
+	return buffer.Bytes(), nil
+}
+
+// Get the value of the named property
+func (properties Properties) Get(name string) (string, bool) {
+	for _, property := range properties {
+		if property.Name == name {
+			return property.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set the value of the named property, replacing the existing value if any
+func (properties *Properties) Set(name string, value string) {
+	for index := range *properties {
+		if (*properties)[index].Name == name {
+			(*properties)[index].Value = value
+			return
+		}
+	}
+	*properties = append(*properties, Property{Name: name, Value: value})
+}
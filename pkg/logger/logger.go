@@ -19,6 +19,7 @@ package logger
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -30,6 +31,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/bfg"
 	"github.com/ibm-messaging/mq-container-mft/pkg/utils"
 	"github.com/tidwall/gjson"
 )
@@ -89,13 +91,13 @@ func NewLogger(writer io.Writer, debug bool, json bool, serverName string, dnaUr
 }
 
 func (l *Logger) format(entry map[string]interface{}) (string, error) {
-	//	if l.json {
-	//		b, err := json.Marshal(entry)
-	//		if err != nil {
-	//			return "", err
-	//		}
-	//		return string(b), err
-	//	}
+	if l.json {
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return "", err
+		}
+		return string(b), err
+	}
 	return fmt.Sprintf("%v\n", entry["message"]), nil
 }
 
@@ -103,7 +105,7 @@ func (l *Logger) format(entry map[string]interface{}) (string, error) {
 // additional fields.
 func (l *Logger) log(level string, msg string) {
 	entry := map[string]interface{}{
-		"message": fmt.Sprint(msg),
+		"message": utils.RedactSecrets(fmt.Sprint(msg)),
 	}
 	s, err := l.format(entry)
 	l.mutex.Lock()
@@ -179,6 +181,7 @@ func (l *Logger) PushToLogToServer(msg string) {
 	if l.logPubsDisabled == true {
 		return
 	}
+	msg = utils.RedactSecrets(msg)
 
 	// Return if this is not a valid JSON
 	if !gjson.Valid(msg) {
@@ -255,15 +258,18 @@ func getLogLevel(msg string) string {
 		level = "ERROR"
 	}
 
+	// Use severity of the messages in eventDescription
+	switch bfg.HighestSeverity(eventDescription) {
+	case bfg.SEVERITY_ERROR:
+		level = "ERROR"
+	case bfg.SEVERITY_WARNING:
+		if level != "ERROR" {
+			level = "WARN"
+		}
+	}
+
 	// Mark resynchronize messages as warnings.
-	if strings.Contains(eventDescription, "BFGTL0015") ||
-		strings.Contains(eventDescription, "BFGTL0017") ||
-		strings.Contains(eventDescription, "BFGTL0019") ||
-		strings.Contains(eventDescription, "BFGTL0021") ||
-		strings.Contains(eventDescription, "BFGTL0032") ||
-		strings.Contains(eventDescription, "BFGTL0033") ||
-		strings.Contains(eventDescription, "BFGTL0034") ||
-		strings.Contains(eventDescription, "BFGTL0035") {
+	if bfg.Contains(eventDescription, bfg.TRANSFER_RESYNCHRONIZING...) {
 		level = "WARN"
 	}
 
@@ -53,3 +53,18 @@ func TestSimpleLogger(t *testing.T) {
 		t.Errorf("Expected log output to contain %v; got %v", s, buf.String())
 	}
 }
+
+func TestGetLogLevel(t *testing.T) {
+	tests := map[string]string{
+		"{\"eventDescription\":\"BFGRP0032I: The file transfer request has successfully completed.\"}": "INFO",
+		"{\"eventDescription\":\"BFGIO0011E: Unable to read file.\"}":                                  "ERROR",
+		"{\"eventDescription\":\"BFGTL0017I: Resynchronizing transfer.\"}":                             "WARN",
+		"{\"eventDescription\":\"Transfer failed\"}":                                                   "ERROR",
+		"{\"eventDescription\":\"Transfer\",\"progressInformation\":{\"warnings\":1}}":                 "WARN",
+	}
+	for msg, expected := range tests {
+		if level := getLogLevel(msg); level != expected {
+			t.Errorf("getLogLevel(%s) = %s, expected %s", msg, level, expected)
+		}
+	}
+}
@@ -119,6 +119,103 @@ const MFT_UPDATED_CONFIGURATION = "Updated coordination configuration - %v."
 const MFT_PBA_HOST_AND_TYPE_NOT_FOUND = "Protocol server host name and type not supplied in the configuration file %s. Configuration will not be updated."
 const MFT_FAILED_PERMISSION_KEYSTORE = "Error occurred while setting persmission to keystore %v. The error is %v."
 const MFT_ENV_AGNT_CFG_FILE_NOT_SPECIFIED = "MFT_AGENT_CONFIG_FILE environment variable has not specified. Container will attempt to load agent configuration from file %s."
+const MFT_ENV_AGNT_CFG_FROM_ENVIRONMENT = "MFT_AGENT_CONFIG_FILE environment variable has not been specified and file %s does not exist. Agent configuration will be built from environment variables."
+const MFT_CONT_CFG_SCHEMA_INVALID_JSON_0079 = "Configuration file does not contain valid JSON."
+const MFT_CONT_CFG_SCHEMA_TYPE_MISMATCH_0080 = "Attribute '%s' must be of type %s but found %s."
+const MFT_CONT_CFG_SCHEMA_MISSING_0081 = "Required attribute '%s' of type %s is missing."
+const MFT_CONT_CFG_SCHEMA_UNKNOWN_0082 = "Attribute '%s' is not a valid attribute. Valid attributes are: %s."
+const MFT_CONT_CFG_SCHEMA_ENUM_0083 = "Attribute '%s' must be one of %s but found %s."
+const MFT_CONT_CFG_SCHEMA_FAILED_0084 = "Configuration file %s failed validation with %d error(s). Container will end now. Correct the errors and resubmit the request."
+const MFT_CONT_DRYRUN_CMD_0085 = "Dry run, command not executed: %s"
+const MFT_CONT_DRYRUN_SUMMARY_0086 = "Dry run complete. Generated configuration has been written to %s. Summary:"
+const MFT_CONT_DRYRUN_PASSED_0087 = "Dry run passed."
+const MFT_CONT_DRYRUN_FAILED_0088 = "Dry run failed. Review and fix any errors and then resubmit request."
+const MFT_CONT_DRYRUN_ENABLED_0089 = "Dry run mode enabled. Configuration will be written to %s and no commands will be run."
+const MFT_CONT_DRYRUN_OUTPUT_DIR_0090 = "Output directory must be specified with --output-dir when --dry-run is specified."
+const MFT_CONT_SUPERVISE_AGENTS_0091 = "Container will configure and run %d agent(s): %s."
+const MFT_CONT_AGNT_ENDED_0092 = "Agent %s has ended unexpectedly. Remaining agents will be stopped and container will end now."
+const MFT_CONT_AGNT_STOPPING_ALL_0093 = "Stopping all agents run by this container."
+const MFT_CONT_PROVISION_UNCHANGED_0094 = "Configuration of %s has not changed since it was created. Existing configuration will be used."
+const MFT_CONT_PROVISION_CHANGED_0095 = "Configuration of %s has changed since it was created and will be created again. Changes: %s."
+const MFT_CONT_PROVISION_FILES_MISSING_0096 = "Configuration of %s will be created again as the following files are missing: %s."
+const MFT_CONT_PROVISION_STATE_ERROR_0097 = "Failed to save provisioning state to file %s. Configuration will be created again on next start. The error is: %v"
+const MFT_CONT_RETRY_ATTEMPT_0098 = "Attempt %d to %s failed as queue manager is not available (%s). Retrying in %v."
+const MFT_CONT_RETRY_FATAL_0099 = "Failed to %s. The error can not be resolved by retrying (%s)."
+const MFT_CONT_RETRY_DEADLINE_0100 = "Failed to %s after %d attempt(s). Retry deadline of %v has been reached."
+const MFT_CONT_RETRY_ENV_INVALID_0101 = "Value '%s' of environment variable %s is not a valid number of seconds. Value %d will be used."
+const MFT_CONT_PREFLIGHT_START_0102 = "Checking connectivity to %d queue manager endpoint(s)."
+const MFT_CONT_PREFLIGHT_FAILED_0103 = "Connectivity checks failed. Correct the host, port or TLS configuration of the queue managers listed above and restart the container."
+const MFT_CONT_PREFLIGHT_PASSED_0104 = "Connectivity checks passed."
+const MFT_CONT_PREFLIGHT_DETAIL_0105 = "Queue manager %s (%s): %s"
+const MFT_CONT_CMD_RUNNING_0106 = "Running command: %s"
+const MFT_CONT_CMD_FAILED_0107 = "Command %s failed. The error is: %v"
+const MFT_CONT_CMD_TIMEOUT_0108 = "command %s did not complete within %v and has been ended"
+const MFT_CONT_CMD_CANCELLED_0109 = "command %s has been ended as the container is stopping"
+const MFT_CONT_CMD_ENDED_0110 = "Command %s ended with exit code %d after %v. Messages: %s"
+const MFT_CONT_CMD_MESSAGE_ACTION_0111 = "%s: %s %s"
+const MFT_CONT_AGNT_START_WAITING_0112 = "Agent %s is starting: %s. Waited %v of %v."
+const MFT_CONT_AGNT_START_FAILED_0113 = "Agent %s failed to start. The error is: %v"
+const MFT_CONT_AGNT_START_PROCESS_ENDED_0114 = "agent process recorded in %s ended before the agent became ready"
+const MFT_CONT_AGNT_START_DEADLINE_0115 = "Agent %s did not become ready within %v. Last state: %s."
+const MFT_CONT_AGNT_START_READY_0116 = "Agent %s is ready after %v."
+const MFT_CONT_UNKNOWN_SUBCOMMAND_0117 = "Unknown subcommand '%s'. Supported subcommand is 'config explain'."
+const MFT_CONT_EXPLAIN_FILE_0118 = "Properties written to %s:"
+const MFT_CONT_EXPLAIN_WARNING_0119 = "Warning for %s: %s."
+const MFT_CONT_EXPLAIN_SUMMARY_0120 = "Explained %d properties file(s) with %d warning(s). No configuration has been created."
+const MFT_CONT_REDACT_PATTERNS_INVALID_0121 = "Environment variable %s contains a pattern that is not valid. The error is: %v"
+const MFT_CONT_BRIDGE_INVALID_AGENT_0122 = "Bridge agent %s: attribute '%s' %v."
+const MFT_CONT_BRIDGE_INVALID_SERVER_0123 = "Protocol server %s of bridge agent %s: attribute '%s' %s."
+const MFT_CONT_BRIDGE_VALIDATION_FAILED_0124 = "Protocol server definitions in %s are not valid. %d error(s) found."
+const MFT_CONT_BRIDGE_VALIDATION_WARNING_0125 = "Protocol server definitions in %s have %d problem(s). Set %s to Yes to stop the container when definitions are not valid."
+const MFT_CONT_CFG_SANDBOX_INVALID_0126 = "Sandboxes of agent %s are not valid: %v."
+const MFT_CONT_CFG_MONITOR_INVALID_0127 = "Resource monitors of agent %s are not valid: %v."
+const MFT_CONT_AGNT_RM_LIST_FAILED_0128 = "Failed to list resource monitors of agent %s. Resource monitors have not been reconciled with the configuration."
+const MFT_CONT_AGNT_RM_REPLACE_0129 = "Replacing resource monitor %s of agent %s as its definition has changed."
+const MFT_CONT_AGNT_RM_DELETE_0130 = "Deleting resource monitor %s of agent %s as it is not defined in the configuration."
+const MFT_CONT_AGNT_RM_RECONCILED_0131 = "Resource monitors of agent %s reconciled: %d created, %d replaced, %d deleted, %d unchanged, %d failed."
+const MFT_CONT_CFG_TEMPLATE_INVALID_0132 = "Transfer templates of agent %s are not valid: %v."
+const MFT_CONT_CFG_SCHEDULE_INVALID_0133 = "Scheduled transfers of agent %s are not valid: %v."
+const MFT_CONT_TEMPLATE_LIST_FAILED_0134 = "Failed to list transfer templates. Transfer templates of agent %s have not been reconciled with the configuration."
+const MFT_CONT_SCHEDULE_LIST_FAILED_0135 = "Failed to list scheduled transfers. Scheduled transfers of agent %s have not been reconciled with the configuration."
+const MFT_CONT_TEMPLATE_CREATE_0136 = "Creating transfer template %s."
+const MFT_CONT_TEMPLATE_REPLACE_0137 = "Replacing transfer template %s as its definition has changed."
+const MFT_CONT_TEMPLATE_DELETE_0138 = "Deleting transfer template %s of agent %s as it is not defined in the configuration."
+const MFT_CONT_SCHEDULE_CREATE_0139 = "Creating scheduled transfer %s of agent %s."
+const MFT_CONT_SCHEDULE_REPLACE_0140 = "Replacing scheduled transfer %s of agent %s, identifier %s, as its definition has changed."
+const MFT_CONT_SCHEDULE_DELETE_0141 = "Deleting scheduled transfer %s of agent %s as it is not defined in the configuration."
+const MFT_CONT_SCHEDULE_ID_MISSING_0142 = "Identifier of scheduled transfer %s of agent %s could not be found. The scheduled transfer will be replaced on next start."
+const MFT_CONT_TEMPLATE_RECONCILED_0143 = "Transfer templates of agent %s reconciled: %d created, %d replaced, %d deleted, %d unchanged, %d failed."
+const MFT_CONT_SCHEDULE_RECONCILED_0144 = "Scheduled transfers of agent %s reconciled: %d created, %d replaced, %d deleted, %d unchanged, %d failed."
+const MFT_CONT_CFG_CD_NODE_INVALID_0145 = "Connect:Direct nodes of agent %s are not valid: %v."
+const MFT_CONT_CD_NOT_ENOUGH_INFO_0146 = "Connect:Direct node of agent %s not found. Information required to setup Connect:Direct bridge agent is missing."
+const MFT_CONT_CD_CONFIG_FAILED_0147 = "Failed to write Connect:Direct configuration file %s: %v."
+const MFT_CONT_CFG_QMGR_CONNECTION_INVALID_0148 = "Connection to %s queue manager %s is not valid: %v."
+const MFT_CONT_CCDT_WRITE_FAILED_0149 = "Failed to write CCDT file %s for %s queue manager %s: %v."
+const MFT_CONT_CCDT_CONFIGURED_0150 = "Connections to %s queue manager %s use CCDT %s."
+const MFT_CONT_JVM_HEAP_COMPUTED_0151 = "Heap of agent %s set to -Xms%s -Xmx%s, %d%% of container memory limit of %d MB shared by %d agent(s)."
+const MFT_CONT_JVM_NO_MEMORY_LIMIT_0152 = "Heap of agent %s not set as the container memory limit could not be determined: %v. JVM defaults apply."
+const MFT_CONT_JVM_OPTIONS_0153 = "JVM options of agent %s: %s"
+const MFT_CONT_CFG_JVM_INVALID_0154 = "JVM options of agent %s are not valid: %v."
+const MFT_CONT_JVM_ENV_INVALID_0155 = "Value '%s' of environment variable %s is not a percentage between 1 and 100. Value %d will be used."
+const MFT_CONT_PROPERTY_INVALID_0156 = "Property '%s' of %s %s."
+const MFT_CONT_PROPERTIES_VALIDATION_FAILED_0157 = "Properties in %s are not valid. %d error(s) found."
+const MFT_CONT_PROPERTIES_VALIDATION_WARNING_0158 = "Properties in %s have %d problem(s). Set %s to Yes to stop the container when properties are not valid."
+const MFT_CONT_CFG_BRIDGE_CREDENTIALS_INVALID_0159 = "Protocol server credentials of agent %s are not valid: %v."
+const MFT_CONT_BRIDGE_CREDENTIALS_FAILED_0160 = "Failed to write protocol server credentials file %s: %v."
+const MFT_CONT_BRIDGE_CREDENTIALS_WRITTEN_0161 = "%d protocol server credential entries of agent %s written to %s."
+const MFT_CONT_BRIDGE_CREDENTIAL_INVALID_0162 = "Protocol server credentials file %s: %s %s."
+const MFT_CONT_BRIDGE_CREDENTIALS_UNREADABLE_0163 = "Protocol server credentials file %s of agent %s can not be checked: %v."
+const MFT_CONT_BRIDGE_CREDENTIALS_CHECK_FAILED_0164 = "Protocol server credentials file %s is not valid. %d error(s) found."
+const MFT_CONT_BRIDGE_CREDENTIALS_CHECK_WARNING_0165 = "Protocol server credentials file %s has %d problem(s). Set %s to Yes to stop the container when credentials are not valid."
+const MFT_CONT_BRIDGE_CREDENTIALS_KEY_VALUE_0166 = "Protocol server credentials file %s is in key-value format and is not checked."
+const MFT_CONT_BRIDGE_HOST_KEY_UNREADABLE_0167 = "Host key of protocol server %s of agent %s could not be read from %s: %v."
+const MFT_CONT_BRIDGE_HOST_KEY_PINNED_0168 = "Host key of protocol server %s at %s pinned in %s. Fingerprint is %s, base64 encoded host key for the credentials is %s."
+const MFT_CONT_BRIDGE_HOST_KEY_VERIFIED_0169 = "Host key of protocol server %s at %s matches the key pinned in %s. Fingerprint is %s."
+const MFT_CONT_BRIDGE_HOST_KEY_CHANGED_0170 = "HOST KEY OF PROTOCOL SERVER %s AT %s HAS CHANGED. Fingerprint %s does not match the key pinned in %s. The server may be impersonated. If the key was changed on purpose, remove the line of %s from the file."
+const MFT_CONT_BRIDGE_HOST_KEY_DRY_RUN_0171 = "Host keys of protocol servers of agent %s are not discovered in dry-run mode."
+const MFT_CONT_BRIDGE_KNOWN_HOSTS_FAILED_0172 = "Failed to use known hosts file %s: %v."
+const MFT_CONT_JVM_HEAP_MINIMUM_0173 = "Heap of agent %s computed as %d MB is below the minimum of %d MB and is not set, JVM defaults apply. Increase the container memory limit or run fewer agents in the container."
+const MFT_CONT_JVM_INITIAL_HEAP_REDUCED_0174 = "Initial heap %s of agent %s is larger than the computed maximum heap of %d MB and is reduced to it."
 
 const AGENT_REDY_ENV_AGENT_NAME_NOT_SET_3001 = "IBMFT3001E: MFT_AGENT_NAME environment variable not specified."
 const AGENT_REDY_ENV_AGENT_CFG_FILE_NOT_SET_3002 = "IBMFT3002E: MFT_AGENT_CONFIG_FILE environment variable not specified."
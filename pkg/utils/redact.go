@@ -0,0 +1,142 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+/*
+* Masks secrets in text before it is written to the console or published to
+* a log server. Secrets are found by built-in patterns covering passwords in
+* JSON configuration, credentials XML, properties and keytool command lines,
+* logDNA ingestion keys, by patterns configured by the user, and by values
+* that have been registered as secrets, for example a decoded password.
+ */
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Text displayed in place of secrets
+const REDACTED_TEXT = "********"
+
+// Names of attributes and properties whose values are secrets
+var secretNames = regexp.MustCompile(`(?i)password|passphrase|secret|token|injestionkey|ingestionkey|apikey`)
+
+// Built-in patterns. The first group of each pattern is the secret.
+var builtinSecretPatterns = []*regexp.Regexp{
+	// JSON, for example "mqPassword":"..." or "logDNA.injestionKey":"..."
+	regexp.MustCompile(`(?i)"[^"]*(?:password|passphrase|secret|token|injestionkey|ingestionkey|apikey)"\s*:\s*"((?:[^"\\]|\\.)*)"`),
+	// XML attributes, for example password="..." or keyStorePassword="..."
+	regexp.MustCompile(`(?i)\s[\w:.-]*(?:password|passphrase)="([^"]*)"`),
+	// Properties, for example agentSslKeyStorePassword=...
+	regexp.MustCompile(`(?i)\b[\w.]*(?:password|passphrase)\s*[=:]\s*([^\s"',;}]+)`),
+	// keytool arguments, for example -storepass ...
+	regexp.MustCompile(`-(?:src|dest)?(?:store|key)pass\s+(\S+)`),
+}
+
+var redactMutex sync.RWMutex
+
+// Patterns configured by the user
+var userSecretPatterns []*regexp.Regexp
+
+// Values known to be secrets
+var secretValues []string
+
+// Set the patterns configured by the user, one regular expression per line.
+// If a pattern has a group, only the text of the first group is masked,
+// otherwise the whole match. Blank lines are ignored. None of the patterns is
+// used if any of them is not valid.
+func SetRedactPatterns(patternsText string) error {
+	var patterns []*regexp.Regexp
+	for _, line := range strings.Split(patternsText, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		pattern, err := regexp.Compile(line)
+		if err != nil {
+			return fmt.Errorf("pattern %q is not valid: %v", line, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	redactMutex.Lock()
+	userSecretPatterns = patterns
+	redactMutex.Unlock()
+	return nil
+}
+
+// Register a value that must not be displayed wherever it appears
+func AddSecretValue(value string) {
+	value = strings.TrimSpace(value)
+	if len(value) == 0 {
+		return
+	}
+	redactMutex.Lock()
+	defer redactMutex.Unlock()
+	for _, secretValue := range secretValues {
+		if secretValue == value {
+			return
+		}
+	}
+	secretValues = append(secretValues, value)
+}
+
+// Determine if the named attribute or property holds a secret
+func IsSecretName(name string) bool {
+	return secretNames.MatchString(name)
+}
+
+// Returns the text with all secrets replaced by REDACTED_TEXT
+func RedactSecrets(text string) string {
+	redactMutex.RLock()
+	defer redactMutex.RUnlock()
+	for _, value := range secretValues {
+		text = strings.ReplaceAll(text, value, REDACTED_TEXT)
+	}
+	for _, pattern := range builtinSecretPatterns {
+		text = redactMatches(pattern, text)
+	}
+	for _, pattern := range userSecretPatterns {
+		text = redactMatches(pattern, text)
+	}
+	return text
+}
+
+// Replace the first group of every match, or the whole match if the pattern
+// has no groups
+func redactMatches(pattern *regexp.Regexp, text string) string {
+	group := 0
+	if pattern.NumSubexp() > 0 {
+		group = 1
+	}
+	var redacted strings.Builder
+	last := 0
+	for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2*group], match[2*group+1]
+		// Group did not take part in the match or the secret is blank
+		if start < 0 || start == end {
+			continue
+		}
+		redacted.WriteString(text[last:start])
+		redacted.WriteString(REDACTED_TEXT)
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	redacted.WriteString(text[last:])
+	return redacted.String()
+}
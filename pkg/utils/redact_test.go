@@ -0,0 +1,82 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactSecretsBuiltin(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{`{"qmgrCredentials":{"mqUserId":"app","mqPassword":"cGFzc3cwcmQ="}}`,
+			`{"qmgrCredentials":{"mqUserId":"app","mqPassword":"********"}}`},
+		{`{"agentSslKeyStorePassword":"Pa\"ss","agentSslKeyStore":"/run/keystores/agentkeystore.p12"}`,
+			`{"agentSslKeyStorePassword":"********","agentSslKeyStore":"/run/keystores/agentkeystore.p12"}`},
+		{`{"type":"logDNA","logDNA.url":"https://logs.example.com","logDNA.injestionKey":"abc123"}`,
+			`{"type":"logDNA","logDNA.url":"https://logs.example.com","logDNA.injestionKey":"********"}`},
+		{`<tns:qmgr name="QM1" user="mqm" mqUserId="app" mqPassword="passw0rd"/>`,
+			`<tns:qmgr name="QM1" user="mqm" mqUserId="app" mqPassword="********"/>`},
+		{`<tns:file path="/run/keystores/agenttruststore.p12" password="Passw0rd"/>`,
+			`<tns:file path="/run/keystores/agenttruststore.p12" password="********"/>`},
+		{`<tns:key keyStorePassword="a" trustStorePassword="b"/>`,
+			`<tns:key keyStorePassword="********" trustStorePassword="********"/>`},
+		{"agentSslTrustStorePassword=Passw0rd", "agentSslTrustStorePassword=********"},
+		{"keytool -importcert -storepass Passw0rd -alias store", "keytool -importcert -storepass ******** -alias store"},
+		{`<tns:file path="/tmp/store.p12" password=""/>`, `<tns:file path="/tmp/store.p12" password=""/>`},
+		{"Agent SRC is ready after 5s.", "Agent SRC is ready after 5s."},
+	}
+	for _, test := range tests {
+		if redacted := RedactSecrets(test.text); redacted != test.expected {
+			t.Errorf("RedactSecrets(%q) returned %q, expected %q", test.text, redacted, test.expected)
+		}
+	}
+}
+
+func TestRedactSecretsUserPatterns(t *testing.T) {
+	defer SetRedactPatterns("")
+	if err := SetRedactPatterns("sk-[0-9a-f]+\n\n  apiKey\\((\\w+)\\)  "); err != nil {
+		t.Fatal(err)
+	}
+	redacted := RedactSecrets("token sk-12ab and apiKey(secret1) used")
+	if redacted != "token ******** and apiKey(********) used" {
+		t.Errorf("User patterns not applied: %s", redacted)
+	}
+
+	// Patterns are kept if any new pattern is not valid
+	if err := SetRedactPatterns("sk-[0-9a-f]+\n(unclosed"); err == nil {
+		t.Error("Invalid pattern not reported")
+	}
+	if redacted = RedactSecrets("apiKey(secret1)"); redacted != "apiKey(********)" {
+		t.Errorf("Previous patterns not kept: %s", redacted)
+	}
+}
+
+func TestRedactSecretValues(t *testing.T) {
+	AddSecretValue("  ")
+	AddSecretValue("xY7kQ2pL9mZ4")
+	AddSecretValue("xY7kQ2pL9mZ4")
+	redacted := RedactSecrets("keytool failed: keystore password xY7kQ2pL9mZ4 was incorrect")
+	if strings.Contains(redacted, "xY7kQ2pL9mZ4") {
+		t.Errorf("Secret value not redacted: %s", redacted)
+	}
+	if !IsSecretName("coordinationSslTrustStorePassword") || IsSecretName("coordinationSslTrustStore") {
+		t.Error("Secret property names not recognised")
+	}
+}
@@ -17,16 +17,17 @@ limitations under the License.
 package utils
 
 import (
-	"bytes"
 	"container/list"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/ibm-messaging/mq-container-mft/pkg/bfg"
 	"github.com/icza/backscanner"
 )
 
@@ -87,7 +88,7 @@ func IsAgentRunning(agentPid int32) (bool, error) {
 	return false, err
 }
 
-// Is agent ready - basically check for BFGAG0059I message in agent's log file
+// Is agent ready - basically check for the agent started message in agent's log file
 func IsAgentReady(bfgDataPath string, agentName string, coordinationQMgr string) (bool, error) {
 	var ready bool = false
 	//var errorMessages []string
@@ -109,21 +110,20 @@ func IsAgentReady(bfgDataPath string, agentName string, coordinationQMgr string)
 			ready = false
 		} else {
 			scanner := backscanner.New(outputLogFile, int(fi.Size()))
-			findBFGAG59I := []byte("BFGAG0059I")
-			// If we don't find BFGAG0059I in last 10 lines, then assume agent
-			// has not started and return false
+			// If we don't find the agent started message in last 10 lines, then
+			// assume agent has not started and return false
 			for count < 10 {
 				line, _, err := scanner.LineBytes()
 				if err != nil {
 					if err == io.EOF {
-						returnError = fmt.Errorf("%q is not found in file", findBFGAG59I)
+						returnError = fmt.Errorf("%q is not found in file", bfg.AGENT_STARTED)
 					} else {
 						returnError = fmt.Errorf("error occurred while processing log file %v", err)
 					}
 					break
 				}
 
-				if bytes.Contains(line, findBFGAG59I) {
+				if bfg.Contains(string(line), bfg.AGENT_STARTED) {
 					ready = true
 					break
 				}
@@ -273,13 +273,13 @@ func ToNumber(s string) (int64, error) {
 	return num, nil
 }
 
-// Print log statement on console
+// Print log statement on console, with secrets redacted
 func PrintLog(logToPrint string) {
 	format := "02/01/2006 15:04:05.000"
 	now := time.Now()
 	zone, _ := now.Local().Zone()
 	loc, _ := time.LoadLocation(zone)
-	fmt.Printf("[%s %s] %s\n", now.In(loc).Format(format), zone, logToPrint)
+	fmt.Printf("[%s %s] %s\n", now.In(loc).Format(format), zone, RedactSecrets(logToPrint))
 }
 
 /**
@@ -315,3 +315,26 @@ func WriteData(fileName string, bufferToWrite string) error {
 
 	return nil
 }
+
+// Write a file that only the container user can read. Data is written to a
+// temporary file created with mode 0600 in the same directory, which then
+// replaces the file, so that an existing file never holds the data with a
+// wider mode.
+func WriteSecretFile(fileName string, data []byte) error {
+	tempFile, err := os.CreateTemp(filepath.Dir(fileName), "."+filepath.Base(fileName)+".*")
+	if err != nil {
+		return err
+	}
+	tempFileName := tempFile.Name()
+	_, err = tempFile.Write(data)
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempFileName, fileName)
+	}
+	if err != nil {
+		os.Remove(tempFileName)
+	}
+	return err
+}
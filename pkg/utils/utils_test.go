@@ -0,0 +1,102 @@
+/*
+© Copyright IBM Corporation 2024, 2024
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestGetAgentPid(t *testing.T) {
+	pidDir := t.TempDir()
+	pidPath := filepath.Join(pidDir, "agent.pid")
+	if err := os.WriteFile(pidPath, []byte("1234"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := GetAgentPid(pidPath); err != nil || pid != 1234 {
+		t.Errorf("Expected pid 1234, found %d %v", pid, err)
+	}
+
+	if err := os.WriteFile(pidPath, []byte("not a pid"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if pid, _ := GetAgentPid(pidPath); pid != -1 {
+		t.Errorf("Expected pid -1 for invalid pid file, found %d", pid)
+	}
+
+	if pid, err := GetAgentPid(filepath.Join(pidDir, "missing.pid")); err == nil || pid != -1 {
+		t.Errorf("Expected error for missing pid file, found %d %v", pid, err)
+	}
+}
+
+func TestIsAgentRunning(t *testing.T) {
+	if running, err := IsAgentRunning(int32(os.Getpid())); err != nil || !running {
+		t.Errorf("Running process reported as ended %v", err)
+	}
+
+	// Process that has ended and been reaped
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Skip("Unable to run command: ", err)
+	}
+	if running, _ := IsAgentRunning(int32(cmd.Process.Pid)); running {
+		t.Error("Ended process reported as running")
+	}
+
+	if running, err := IsAgentRunning(0); err == nil || running {
+		t.Errorf("Invalid pid reported as running %v", err)
+	}
+}
+
+func TestIsAgentRunningPidFile(t *testing.T) {
+	pidPath := filepath.Join(t.TempDir(), "running.pid")
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	agentPid, err := GetAgentPid(pidPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if running, err := IsAgentRunning(agentPid); err != nil || !running {
+		t.Errorf("Process recorded in pid file reported as ended %v", err)
+	}
+}
+
+func TestWriteSecretFile(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "credentials.json")
+	if err := os.WriteFile(fileName, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteSecretFile(fileName, []byte("secret")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Unexpected mode %v of existing file", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(fileName); string(data) != "secret" {
+		t.Errorf("Unexpected content %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(fileName)); len(entries) != 1 {
+		t.Errorf("Temporary file left behind: %v", entries)
+	}
+}